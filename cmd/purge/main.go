@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+	"ulab3/config"
+	"ulab3/internal/app"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/pkg/logger"
+)
+
+// purge removes the products and orders deleted, and the events delivered,
+// longer ago than the retention period, PURGE_RETENTION unless -retention
+// says otherwise, along with every expired cart.
+func main() {
+	cfg := config.NewConfig()
+
+	defaultRetention, err := time.ParseDuration(cfg.PURGE_RETENTION)
+	if err != nil {
+		log.Fatalf("invalid PURGE_RETENTION: %v", err)
+	}
+	retention := flag.Duration("retention", defaultRetention, "keep records deleted within this period")
+	flag.Parse()
+	if *retention < 0 {
+		log.Fatal("retention must not be negative")
+	}
+
+	logger1 := logger.NewLogger()
+	repos, err := app.NewRepositories(cfg, logger1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	productService := usecase.NewProductService(repos.Products, repos.Orders, repos.StockLevels, repos.Audit, repos.Outbox, repos.Transactor, logger1)
+	orderService := usecase.NewOrderService(repos.Orders, repos.Products, repos.Customers, repos.Reservations, repos.Warehouses, repos.StockLevels, repos.Audit, repos.Outbox, nil, nil, repos.Transactor, 0, logger1)
+
+	ctx := usecase.WithActor(context.Background(), usecase.Actor{UserID: "system:purge", Role: entity.RoleAdmin})
+	before := time.Now().Add(-*retention)
+
+	orders, err := orderService.PurgeOrders(ctx, before)
+	if err != nil {
+		log.Fatal(err)
+	}
+	products, err := productService.PurgeProducts(ctx, before)
+	if err != nil {
+		log.Fatal(err)
+	}
+	events, err := usecase.NewEventService(repos.Outbox, logger1).PurgeDeliveredEvents(ctx, before)
+	if err != nil {
+		log.Fatal(err)
+	}
+	carts, err := usecase.NewCartService(repos.Carts, repos.Products, repos.Reservations, repos.Warehouses, repos.StockLevels, orderService, nil, nil, repos.Transactor, 0, 0, logger1).
+		PurgeExpiredCarts(ctx, time.Now())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Purged %d orders and %d products deleted and %d events delivered before %s, and %d expired carts",
+		orders, products, events, before.Format(time.RFC3339), carts)
+}
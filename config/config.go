@@ -7,17 +7,36 @@ import (
 )
 
 type Config struct {
-	DB_NAME string
-	DB_USER string
-	DB_PASS string
-	DB_HOST string
-	DB_PORT string
+	DB_DRIVER string
+	DB_NAME   string
+	DB_USER   string
+	DB_PASS   string
+	DB_HOST   string
+	DB_PORT   string
 
 	ACCESS_TOKEN    string
 	REFRESH_TOKEN   string
 	EXPIRED_ACCESS  string
 	EXPIRED_REFRESH string
 
+	ADMIN_EMAIL    string
+	ADMIN_PASSWORD string
+
+	IDEMPOTENCY_TTL      string
+	IDEMPOTENCY_LOCK_TTL string
+	CART_TTL             string
+	PURGE_RETENTION      string
+
+	STOCK_HOLD_TTL      string
+	HOLD_SWEEP_INTERVAL string
+	FULFILMENT_STRATEGY string
+
+	EVENT_SINKS          string
+	EVENT_WEBHOOK_URL    string
+	OUTBOX_POLL_INTERVAL string
+	OUTBOX_MAX_ATTEMPTS  string
+	ORDER_STREAM_SOURCE  string
+
 	RUN_PORT string
 }
 
@@ -28,6 +47,10 @@ func NewConfig() Config {
 
 	config := Config{}
 
+	config.DB_DRIVER = os.Getenv("DB_DRIVER")
+	if config.DB_DRIVER == "" {
+		config.DB_DRIVER = "mongo"
+	}
 	config.DB_NAME = os.Getenv("DB_NAME")
 	config.DB_USER = os.Getenv("DB_USER")
 	config.DB_PASS = os.Getenv("DB_PASS")
@@ -41,5 +64,55 @@ func NewConfig() Config {
 	config.EXPIRED_ACCESS = os.Getenv("EXPIRED_ACCESS")
 	config.EXPIRED_REFRESH = os.Getenv("EXPIRED_REFRESH")
 
+	config.ADMIN_EMAIL = os.Getenv("ADMIN_EMAIL")
+	config.ADMIN_PASSWORD = os.Getenv("ADMIN_PASSWORD")
+
+	config.IDEMPOTENCY_TTL = os.Getenv("IDEMPOTENCY_TTL")
+	if config.IDEMPOTENCY_TTL == "" {
+		config.IDEMPOTENCY_TTL = "24h"
+	}
+	config.IDEMPOTENCY_LOCK_TTL = os.Getenv("IDEMPOTENCY_LOCK_TTL")
+	if config.IDEMPOTENCY_LOCK_TTL == "" {
+		config.IDEMPOTENCY_LOCK_TTL = "1m"
+	}
+	config.CART_TTL = os.Getenv("CART_TTL")
+	if config.CART_TTL == "" {
+		config.CART_TTL = "24h"
+	}
+	config.PURGE_RETENTION = os.Getenv("PURGE_RETENTION")
+	if config.PURGE_RETENTION == "" {
+		config.PURGE_RETENTION = "720h"
+	}
+	config.STOCK_HOLD_TTL = os.Getenv("STOCK_HOLD_TTL")
+	if config.STOCK_HOLD_TTL == "" {
+		config.STOCK_HOLD_TTL = "15m"
+	}
+	config.HOLD_SWEEP_INTERVAL = os.Getenv("HOLD_SWEEP_INTERVAL")
+	if config.HOLD_SWEEP_INTERVAL == "" {
+		config.HOLD_SWEEP_INTERVAL = "30s"
+	}
+	config.FULFILMENT_STRATEGY = os.Getenv("FULFILMENT_STRATEGY")
+	if config.FULFILMENT_STRATEGY == "" {
+		config.FULFILMENT_STRATEGY = "priority"
+	}
+
+	config.EVENT_SINKS = os.Getenv("EVENT_SINKS")
+	if config.EVENT_SINKS == "" {
+		config.EVENT_SINKS = "log"
+	}
+	config.EVENT_WEBHOOK_URL = os.Getenv("EVENT_WEBHOOK_URL")
+	config.OUTBOX_POLL_INTERVAL = os.Getenv("OUTBOX_POLL_INTERVAL")
+	if config.OUTBOX_POLL_INTERVAL == "" {
+		config.OUTBOX_POLL_INTERVAL = "1s"
+	}
+	config.OUTBOX_MAX_ATTEMPTS = os.Getenv("OUTBOX_MAX_ATTEMPTS")
+	if config.OUTBOX_MAX_ATTEMPTS == "" {
+		config.OUTBOX_MAX_ATTEMPTS = "10"
+	}
+	config.ORDER_STREAM_SOURCE = os.Getenv("ORDER_STREAM_SOURCE")
+	if config.ORDER_STREAM_SOURCE == "" {
+		config.ORDER_STREAM_SOURCE = "local"
+	}
+
 	return config
 }
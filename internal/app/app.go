@@ -1,29 +1,218 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 	"ulab3/config"
 	"ulab3/internal/controller"
 	"ulab3/internal/controller/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/publisher"
+	"ulab3/internal/usecase/repo"
+	"ulab3/internal/usecase/repo/memory"
+	pgrepo "ulab3/internal/usecase/repo/postgres"
 
 	"github.com/gin-gonic/gin"
 	"log"
 	"ulab3/pkg/logger"
 	"ulab3/pkg/mongo"
+	"ulab3/pkg/postgres"
+	"ulab3/pkg/token"
 )
 
 func Run(cfg config.Config) {
 
 	logger1 := logger.NewLogger()
 
-	db, err := mongo.Connection(cfg)
+	repos, err := NewRepositories(cfg, logger1)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	controller1 := controller.NewController(db, logger1, cfg.DB_NAME)
+	tokens, err := NewTokenManager(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	idempotencyTTL, err := time.ParseDuration(cfg.IDEMPOTENCY_TTL)
+	if err != nil {
+		log.Fatalf("invalid IDEMPOTENCY_TTL: %v", err)
+	}
+	idempotencyLockTTL, err := time.ParseDuration(cfg.IDEMPOTENCY_LOCK_TTL)
+	if err != nil || idempotencyLockTTL <= 0 {
+		log.Fatalf("invalid IDEMPOTENCY_LOCK_TTL %q", cfg.IDEMPOTENCY_LOCK_TTL)
+	}
+	cartTTL, err := time.ParseDuration(cfg.CART_TTL)
+	if err != nil || cartTTL <= 0 {
+		log.Fatalf("invalid CART_TTL %q", cfg.CART_TTL)
+	}
+
+	holdTTL, err := time.ParseDuration(cfg.STOCK_HOLD_TTL)
+	if err != nil || holdTTL <= 0 {
+		log.Fatalf("invalid STOCK_HOLD_TTL %q", cfg.STOCK_HOLD_TTL)
+	}
+	sweepInterval, err := time.ParseDuration(cfg.HOLD_SWEEP_INTERVAL)
+	if err != nil || sweepInterval <= 0 {
+		log.Fatalf("invalid HOLD_SWEEP_INTERVAL %q", cfg.HOLD_SWEEP_INTERVAL)
+	}
+	strategy, err := usecase.NewFulfilmentStrategy(cfg.FULFILMENT_STRATEGY)
+	if err != nil {
+		log.Fatalf("invalid FULFILMENT_STRATEGY %q", cfg.FULFILMENT_STRATEGY)
+	}
+
+	controller1 := controller.NewController(repos, tokens, idempotencyTTL, idempotencyLockTTL, cartTTL, holdTTL, strategy, logger1)
+
+	if cfg.ADMIN_EMAIL != "" {
+		credentials := entity.Credentials{Email: cfg.ADMIN_EMAIL, Password: cfg.ADMIN_PASSWORD}
+		if err := controller1.Auth.EnsureAdmin(context.Background(), credentials); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	dispatcherConfig, err := NewDispatcherConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	publisher1, err := NewPublisher(cfg, controller1.Webhooks, logger1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sender := publisher.NewWebhookSender(webhookTimeout)
+	go usecase.NewDispatcher(repos.Outbox, publisher1, dispatcherConfig, logger1).Run(context.Background())
+	go usecase.NewWebhookDispatcher(repos.Webhooks, repos.Deliveries, sender, dispatcherConfig, logger1).Run(context.Background())
+	go usecase.NewHoldSweeper(repos.Products, repos.StockLevels, repos.Reservations, repos.Transactor, sweepInterval, logger1).Run(context.Background())
+
+	switch cfg.ORDER_STREAM_SOURCE {
+	case "local":
+	case "mongo":
+		if repos.Feed == nil {
+			log.Fatalf("ORDER_STREAM_SOURCE=mongo needs DB_DRIVER=mongo")
+		}
+		go controller1.Broker.Follow(context.Background(), repos.Feed, feedRetry)
+	default:
+		log.Fatalf("unknown ORDER_STREAM_SOURCE %q", cfg.ORDER_STREAM_SOURCE)
+	}
 
 	engine := gin.Default()
 	http.NewRouter(engine, controller1)
 
 	log.Fatal(engine.Run(cfg.RUN_PORT))
 }
+
+// NewTokenManager builds the JWT manager from the ACCESS_TOKEN and
+// REFRESH_TOKEN secrets and the EXPIRED_ACCESS and EXPIRED_REFRESH lifetimes.
+func NewTokenManager(cfg config.Config) (*token.Manager, error) {
+	if cfg.ACCESS_TOKEN == "" || cfg.REFRESH_TOKEN == "" {
+		return nil, errors.New("ACCESS_TOKEN and REFRESH_TOKEN must be set")
+	}
+	if cfg.ACCESS_TOKEN == cfg.REFRESH_TOKEN {
+		return nil, errors.New("ACCESS_TOKEN and REFRESH_TOKEN must differ")
+	}
+	accessTTL, err := time.ParseDuration(cfg.EXPIRED_ACCESS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXPIRED_ACCESS: %w", err)
+	}
+	refreshTTL, err := time.ParseDuration(cfg.EXPIRED_REFRESH)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXPIRED_REFRESH: %w", err)
+	}
+	return token.NewManager(cfg.ACCESS_TOKEN, cfg.REFRESH_TOKEN, accessTTL, refreshTTL), nil
+}
+
+// webhookTimeout bounds every POST to a webhook.
+const webhookTimeout = 10 * time.Second
+
+// feedRetry is how long a failed outbox change stream waits to reopen.
+const feedRetry = 5 * time.Second
+
+// NewDispatcherConfig reads how the outbox and the webhook deliveries are
+// drained from OUTBOX_POLL_INTERVAL and OUTBOX_MAX_ATTEMPTS.
+func NewDispatcherConfig(cfg config.Config) (usecase.DispatcherConfig, error) {
+	interval, err := time.ParseDuration(cfg.OUTBOX_POLL_INTERVAL)
+	if err != nil || interval <= 0 {
+		return usecase.DispatcherConfig{}, fmt.Errorf("invalid OUTBOX_POLL_INTERVAL %q", cfg.OUTBOX_POLL_INTERVAL)
+	}
+	maxAttempts, err := strconv.Atoi(cfg.OUTBOX_MAX_ATTEMPTS)
+	if err != nil || maxAttempts < 1 {
+		return usecase.DispatcherConfig{}, fmt.Errorf("invalid OUTBOX_MAX_ATTEMPTS %q", cfg.OUTBOX_MAX_ATTEMPTS)
+	}
+	return usecase.DispatcherConfig{
+		Interval:    interval,
+		BatchSize:   100,
+		MaxAttempts: maxAttempts,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Hour,
+		// A batch is sent one request after another, so the lease has to
+		// cover a batch of webhook timeouts
+		Lease: 20 * time.Minute,
+	}, nil
+}
+
+// NewPublisher builds the publisher the outbox is drained to: the webhook
+// subscriptions plus the sinks named in EVENT_SINKS.
+func NewPublisher(cfg config.Config, subscriptions usecase.Publisher, logger *slog.Logger) (usecase.Publisher, error) {
+	sinks := []usecase.Publisher{subscriptions}
+	for _, sink := range strings.Split(cfg.EVENT_SINKS, ",") {
+		switch strings.TrimSpace(sink) {
+		case "":
+		case "log":
+			sinks = append(sinks, publisher.NewLogPublisher(logger))
+		case "webhook":
+			if cfg.EVENT_WEBHOOK_URL == "" {
+				return nil, errors.New("EVENT_WEBHOOK_URL must be set for the webhook sink")
+			}
+			sinks = append(sinks, publisher.NewWebhookPublisher(cfg.EVENT_WEBHOOK_URL, webhookTimeout))
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", sink)
+		}
+	}
+	return publisher.NewFanout(sinks...), nil
+}
+
+// NewRepositories connects to the storage backend named by cfg.DB_DRIVER and
+// returns its repositories.
+func NewRepositories(cfg config.Config, logger *slog.Logger) (usecase.Repositories, error) {
+	switch cfg.DB_DRIVER {
+	case "mongo":
+		client, err := mongo.Connection(cfg)
+		if err != nil {
+			return usecase.Repositories{}, err
+		}
+		db := client.Database(cfg.DB_NAME)
+
+		migrated, err := repo.MigrateLegacyOrders(context.Background(), db.Collection("orders"))
+		if err != nil {
+			return usecase.Repositories{}, err
+		}
+		logger.Info("Migrated legacy orders", "count", migrated)
+
+		versioned, err := repo.MigrateVersions(context.Background(), db)
+		if err != nil {
+			return usecase.Repositories{}, err
+		}
+		logger.Info("Added versions to unversioned records", "count", versioned)
+
+		if err := repo.EnsureIndexes(context.Background(), db); err != nil {
+			return usecase.Repositories{}, err
+		}
+
+		return repo.NewRepositories(db), nil
+	case "postgres":
+		db, err := postgres.Connection(cfg)
+		if err != nil {
+			return usecase.Repositories{}, err
+		}
+		return pgrepo.NewRepositories(db), nil
+	case "memory":
+		logger.Info("Using the in-memory backend; data is lost on restart")
+		return memory.NewRepositories(), nil
+	default:
+		return usecase.Repositories{}, fmt.Errorf("unknown DB_DRIVER %q", cfg.DB_DRIVER)
+	}
+}
@@ -1,33 +1,54 @@
 package controller
 
 import (
-	"go.mongodb.org/mongo-driver/mongo"
 	"log/slog"
+	"time"
 	"ulab3/internal/usecase"
-	"ulab3/internal/usecase/repo"
+	"ulab3/pkg/token"
 )
 
 type Controller struct {
-	Order   *usecase.OrderService
-	Product *usecase.ProductService
+	Order       *usecase.OrderService
+	Product     *usecase.ProductService
+	Auth        *usecase.AuthService
+	Idempotency *usecase.IdempotencyService
+	Audit       *usecase.AuditService
+	Events      *usecase.EventService
+	Webhooks    *usecase.WebhookService
+	Customers   *usecase.CustomerService
+	Carts       *usecase.CartService
+	Warehouses  *usecase.WarehouseService
+	Broker      *usecase.Broker
+	Logger      *slog.Logger
 }
 
-func NewController(db *mongo.Client, log *slog.Logger, databaseName string) *Controller {
-	// Get a reference to the collections
-	productCollection := db.Database(databaseName).Collection("products")
-	orderCollection := db.Database(databaseName).Collection("orders")
-
-	// Initialize repositories
-	productRepo := repo.NewProductRepository(productCollection)
-	orderRepo := repo.NewOrderRepository(orderCollection)
-
+func NewController(repos usecase.Repositories, tokens *token.Manager, idempotencyTTL, idempotencyLockTTL, cartTTL, holdTTL time.Duration, strategy usecase.FulfilmentStrategy, log *slog.Logger) *Controller {
 	// Initialize services
-	productService := usecase.NewProductService(productRepo, log)
-	orderService := usecase.NewOrderService(orderRepo, productRepo, log)
+	broker := usecase.NewBroker(log)
+	productService := usecase.NewProductService(repos.Products, repos.Orders, repos.StockLevels, repos.Audit, repos.Outbox, repos.Transactor, log)
+	orderService := usecase.NewOrderService(repos.Orders, repos.Products, repos.Customers, repos.Reservations, repos.Warehouses, repos.StockLevels, repos.Audit, repos.Outbox, strategy, broker, repos.Transactor, holdTTL, log)
+	authService := usecase.NewAuthService(repos.Users, repos.RefreshTokens, repos.Transactor, tokens, log)
+	idempotencyService := usecase.NewIdempotencyService(repos.Idempotency, idempotencyTTL, idempotencyLockTTL, log)
+	auditService := usecase.NewAuditService(repos.Audit, log)
+	eventService := usecase.NewEventService(repos.Outbox, log)
+	customerService := usecase.NewCustomerService(repos.Customers, repos.Orders, repos.Transactor, log)
+	cartService := usecase.NewCartService(repos.Carts, repos.Products, repos.Reservations, repos.Warehouses, repos.StockLevels, orderService, strategy, broker, repos.Transactor, cartTTL, holdTTL, log)
+	warehouseService := usecase.NewWarehouseService(repos.Warehouses, repos.StockLevels, repos.Products, repos.Outbox, broker, repos.Transactor, log)
+	webhookService := usecase.NewWebhookService(repos.Webhooks, repos.Deliveries, repos.Users, repos.Transactor, log)
 
 	// Create and return the Controller instance
 	return &Controller{
-		Product: productService,
-		Order:   orderService,
+		Product:     productService,
+		Order:       orderService,
+		Auth:        authService,
+		Idempotency: idempotencyService,
+		Audit:       auditService,
+		Events:      eventService,
+		Webhooks:    webhookService,
+		Customers:   customerService,
+		Carts:       cartService,
+		Warehouses:  warehouseService,
+		Broker:      broker,
+		Logger:      log,
 	}
 }
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/usecase"
+)
+
+// AuditHandler handles HTTP requests for the audit trail.
+type AuditHandler struct {
+	auditService *usecase.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(auditService *usecase.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLog godoc
+// @Summary List audit entries
+// @Description Retrieve one page of the audit trail of product and order changes, newest first by default. Pass next_cursor back as cursor to fetch the following page. Admins only.
+// @Tags audit
+// @Produce  json
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at; prefix with - for descending (default -created_at)"
+// @Param entity_type query string false "Only changes to this type of record: product or order"
+// @Param entity_id query string false "Only changes to the record with this ID"
+// @Param actor_id query string false "Only changes made by this user"
+// @Param from query string false "Made at or after this RFC 3339 time"
+// @Param to query string false "Made before this RFC 3339 time"
+// @Success 200 {object} entity.AuditPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /audit [get]
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter := usecase.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		ActorID:    c.Query("actor_id"),
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		c.Error(err)
+		return
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	entries, err := h.auditService.GetAuditLog(c, filter, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetProductHistory godoc
+// @Summary Get a product's change history
+// @Description Retrieve one page of the changes made to a product, oldest first by default. Deleted and purged products keep their history. Admins only.
+// @Tags products
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at; prefix with - for descending"
+// @Success 200 {object} entity.AuditPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /products/{id}/history [get]
+func (h *AuditHandler) GetProductHistory(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	entries, err := h.auditService.GetProductHistory(c, c.Param("id"), page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// AuthHandler handles HTTP requests for registration and tokens.
+type AuthHandler struct {
+	authService *usecase.AuthService
+}
+
+// NewAuthHandler creates a new AuthHandler.
+func NewAuthHandler(authService *usecase.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// Register godoc
+// @Summary Register a user
+// @Description Create a user account. Passwords must be 8 to 72 characters long.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param credentials body entity.Credentials true "Email and password"
+// @Success 201 {object} entity.User
+// @Failure 400 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var credentials entity.Credentials
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	user, err := h.authService.Register(c, credentials)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Exchange an email and password for an access and refresh token pair.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param credentials body entity.Credentials true "Email and password"
+// @Success 200 {object} entity.TokenPair
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var credentials entity.Credentials
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	tokens, err := h.authService.Login(c, credentials)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. The presented refresh token is revoked.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body entity.RefreshRequest true "Refresh token"
+// @Success 200 {object} entity.TokenPair
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var request entity.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c, request.RefreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke a refresh token. Access tokens stay valid until they expire.
+// @Tags auth
+// @Accept  json
+// @Param request body entity.RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var request entity.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	if err := h.authService.Logout(c, request.RefreshToken); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AssignRole godoc
+// @Summary Assign a role
+// @Description Change a user's role. Only admins may assign roles.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param request body entity.RoleRequest true "Role: admin, catalog-manager, customer or support"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /users/{id}/role [put]
+func (h *AuthHandler) AssignRole(c *gin.Context) {
+	var request entity.RoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	user, err := h.authService.AssignRole(c, c.Param("id"), request.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
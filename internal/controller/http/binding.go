@@ -0,0 +1,41 @@
+package http
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"ulab3/internal/usecase"
+)
+
+// mergePatchContentType is the media type of RFC 7396 merge patches.
+const mergePatchContentType = "application/merge-patch+json"
+
+// bindingValidator makes gin check bound request bodies with the usecase
+// rules, so a bad body is rejected with the same field errors the services
+// would report.
+type bindingValidator struct{}
+
+func (bindingValidator) ValidateStruct(obj any) error {
+	return usecase.Validate(obj)
+}
+
+func (bindingValidator) Engine() any {
+	return nil
+}
+
+// mergePatch reads the merge patch a PATCH request carries. Plain JSON is
+// accepted as well, since not every client can set the merge patch type.
+func mergePatch(c *gin.Context) ([]byte, error) {
+	switch c.ContentType() {
+	case mergePatchContentType, binding.MIMEJSON:
+	default:
+		c.Header("Accept-Patch", mergePatchContentType)
+		return nil, fmt.Errorf("%w: send the patch as %s", errUnsupportedMediaType, mergePatchContentType)
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		return nil, invalidBody(err)
+	}
+	return patch, nil
+}
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// CartHandler handles HTTP requests for carts.
+type CartHandler struct {
+	cartService *usecase.CartService
+}
+
+// NewCartHandler creates a new CartHandler.
+func NewCartHandler(cartService *usecase.CartService) *CartHandler {
+	return &CartHandler{
+		cartService: cartService,
+	}
+}
+
+// CreateCart godoc
+// @Summary Create a cart
+// @Description Start a cart for the caller, optionally holding some lines already. Each line takes the product's current price and holds its stock; every change to the cart holds the stock of all its lines again for the stock hold TTL. A cart expires once nobody changes it for the cart TTL.
+// @Tags carts
+// @Accept  json
+// @Produce  json
+// @Param cart body entity.Cart true "Cart lines"
+// @Success 201 {object} entity.Cart
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /carts [post]
+func (h *CartHandler) CreateCart(c *gin.Context) {
+	var cart entity.Cart
+	if err := c.ShouldBindJSON(&cart); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	createdCart, err := h.cartService.CreateCart(c, &cart)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdCart)
+}
+
+// GetCart godoc
+// @Summary Get a cart
+// @Description Retrieve one of the caller's carts, priced against the catalog as it is now. Issues lists the lines whose price changed since they were added, whose product ran short of stock, or whose product is gone.
+// @Tags carts
+// @Produce  json
+// @Param id path string true "Cart ID"
+// @Success 200 {object} entity.Cart
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /carts/{id} [get]
+func (h *CartHandler) GetCart(c *gin.Context) {
+	cart, err := h.cartService.GetCart(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// AddCartItem godoc
+// @Summary Add a cart line
+// @Description Add a product to a cart. A product the cart already holds has its quantity raised instead. The line takes the product's current price; fails with 422 unless the stock is available.
+// @Tags carts
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Cart ID"
+// @Param item body entity.CartItem true "Cart line"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /carts/{id}/items [post]
+func (h *CartHandler) AddCartItem(c *gin.Context) {
+	var item entity.CartItem
+	if err := c.ShouldBindJSON(&item); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	cart, err := h.cartService.AddItem(c, c.Param("id"), &item)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// UpdateCartItem godoc
+// @Summary Change a cart line
+// @Description Set the quantity of the cart line holding a product. The line takes the product's current price; fails with 422 unless the stock is available.
+// @Tags carts
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Cart ID"
+// @Param product_id path string true "Product ID"
+// @Param quantity body entity.CartQuantity true "New quantity"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /carts/{id}/items/{product_id} [put]
+func (h *CartHandler) UpdateCartItem(c *gin.Context) {
+	var quantity entity.CartQuantity
+	if err := c.ShouldBindJSON(&quantity); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	cart, err := h.cartService.SetItemQuantity(c, c.Param("id"), c.Param("product_id"), &quantity)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// RemoveCartItem godoc
+// @Summary Remove a cart line
+// @Description Remove the cart line holding a product.
+// @Tags carts
+// @Produce  json
+// @Param id path string true "Cart ID"
+// @Param product_id path string true "Product ID"
+// @Success 200 {object} entity.Cart
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /carts/{id}/items/{product_id} [delete]
+func (h *CartHandler) RemoveCartItem(c *gin.Context) {
+	cart, err := h.cartService.RemoveItem(c, c.Param("id"), c.Param("product_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// Checkout godoc
+// @Summary Check out a cart
+// @Description Place an order holding the cart's lines for customer_id, which must name an active customer the caller may manage, and remove the cart; the order takes over the stock the cart holds. If any line's price, stock or product changed since it was added, no order is placed: the response is a 409 cart_changed problem whose issues list the changed lines, and the cart takes the current prices so a second checkout goes through unless stock still falls short. Send an Idempotency-Key to make the request safe to retry.
+// @Tags carts
+// @Accept  json
+// @Produce  json
+// @Param Idempotency-Key header string false "Client-chosen key, at most 255 characters"
+// @Param id path string true "Cart ID"
+// @Param checkout body entity.Checkout true "Customer to order for"
+// @Success 201 {object} entity.Order
+// @Header 201 {string} ETag "Version of the order"
+// @Header 201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /carts/{id}/checkout [post]
+func (h *CartHandler) Checkout(c *gin.Context) {
+	var checkout entity.Checkout
+	if err := c.ShouldBindJSON(&checkout); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	order, err := h.cartService.Checkout(c, c.Param("id"), &checkout)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, order.Version)
+	c.JSON(http.StatusCreated, order)
+}
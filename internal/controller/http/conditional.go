@@ -0,0 +1,58 @@
+package http
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"ulab3/internal/usecase"
+)
+
+// etag formats a record version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// setETag exposes the version of the record in the response as its ETag.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", etag(version))
+}
+
+// ifMatch returns the version the If-Match header requires the record to be
+// at, or 0 when the header is absent or "*". Only the strong tags setETag
+// hands out can match; any other value fails the precondition.
+func ifMatch(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if !ok || err != nil || version < 1 {
+		return 0, fmt.Errorf("%w: If-Match %s does not name a single version", usecase.ErrPreconditionFailed, header)
+	}
+	return version, nil
+}
+
+// notModified answers a GET whose If-None-Match header already names the
+// record's version with 304 and reports whether it did. Tags compare weakly,
+// as RFC 9110 requires for If-None-Match.
+func notModified(c *gin.Context, version int64) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		ok      bool
+	}{
+		{header: "", version: 0, ok: true},
+		{header: "*", version: 0, ok: true},
+		{header: `"3"`, version: 3, ok: true},
+		{header: ` "3" `, version: 3, ok: true},
+		{header: "3"},
+		{header: `W/"3"`},
+		{header: `"0"`},
+		{header: `"-1"`},
+		{header: `"3", "4"`},
+		{header: `"abc"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+			c.Request.Header.Set("If-Match", tt.header)
+			version, err := ifMatch(c)
+			if !tt.ok {
+				if !errors.Is(err, usecase.ErrPreconditionFailed) {
+					t.Errorf("ifMatch = %d, %v, want ErrPreconditionFailed", version, err)
+				}
+				return
+			}
+			if err != nil || version != tt.version {
+				t.Errorf("ifMatch = %d, %v, want %d", version, err, tt.version)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"1", "3"`, want: true},
+		{header: "*", want: true},
+		{header: `"2"`, want: false},
+		{header: "3", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			c.Request.Header.Set("If-None-Match", tt.header)
+			if got := notModified(c, 3); got != tt.want {
+				t.Fatalf("notModified = %v, want %v", got, tt.want)
+			}
+			c.Writer.WriteHeaderNow()
+			if tt.want && rec.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", rec.Code)
+			}
+		})
+	}
+}
+
+// productRouter serves the product routes over memory repositories, acting
+// as an admin.
+func productRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repos := memory.NewRepositories()
+	handler := NewProductHandler(usecase.NewProductService(repos.Products, repos.Orders, repos.StockLevels, repos.Audit,
+		repos.Outbox, repos.Transactor, logger))
+
+	// As in NewRouter, bound bodies are checked with the usecase rules
+	binding.Validator = bindingValidator{}
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(RequestID(), ErrorHandler(logger), func(c *gin.Context) {
+		ctx := usecase.WithActor(c.Request.Context(), usecase.Actor{UserID: "admin", Role: entity.RoleAdmin})
+		c.Request = c.Request.WithContext(ctx)
+	})
+	engine.POST("/products", handler.CreateProduct)
+	engine.GET("/products/:id", handler.GetProductByID)
+	engine.PUT("/products/:id", handler.UpdateProduct)
+	engine.PATCH("/products/:id", handler.PatchProduct)
+	return engine
+}
+
+// serve sends one request to engine and returns the response.
+func serve(engine *gin.Engine, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConditionalRequests(t *testing.T) {
+	engine := productRouter(t)
+	rec := serve(engine, http.MethodPost, "/products", `{"name":"Lamp","price":12.5,"stock":3}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create product: %d %s", rec.Code, rec.Body.String())
+	}
+	var product entity.Product
+	if err := json.Unmarshal(rec.Body.Bytes(), &product); err != nil {
+		t.Fatalf("decode product: %v", err)
+	}
+	path := "/products/" + product.ID
+	created := rec.Header().Get("ETag")
+	if created != `"1"` {
+		t.Fatalf("ETag = %s, want \"1\"", created)
+	}
+
+	rec = serve(engine, http.MethodGet, path, "", map[string]string{"If-None-Match": created})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != created {
+		t.Errorf("GET with a current ETag: %d %q with ETag %s, want an empty 304", rec.Code, rec.Body.String(), rec.Header().Get("ETag"))
+	}
+
+	rec = serve(engine, http.MethodPut, path, `{"name":"Lamp","price":14,"stock":3}`, map[string]string{"If-Match": created})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT with a current ETag: %d with ETag %s, want 200 with \"2\"", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// The product has moved on, so the first ETag no longer matches
+	rec = serve(engine, http.MethodGet, path, "", map[string]string{"If-None-Match": created})
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET with a stale ETag: %d, want 200", rec.Code)
+	}
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		rec = serve(engine, method, path, `{"name":"Lamp","price":16,"stock":3}`, map[string]string{"If-Match": created})
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s with a stale ETag: %d, want 412", method, rec.Code)
+		}
+	}
+	rec = serve(engine, http.MethodPatch, path, `{"price":16}`, map[string]string{"If-Match": "2"})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with an unquoted ETag: %d, want 412", rec.Code)
+	}
+
+	// Without If-Match a write applies to whatever version is stored
+	rec = serve(engine, http.MethodPatch, path, `{"price":18}`, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("PATCH without If-Match: %d with ETag %s, want 200 with \"3\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	rec = serve(engine, http.MethodPut, path, `{"name":"Lamp","price":20,"stock":3}`, map[string]string{"If-Match": "*"})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"4"` {
+		t.Fatalf("PUT with If-Match *: %d with ETag %s, want 200 with \"4\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &product); err != nil {
+		t.Fatalf("decode product: %v", err)
+	}
+	if product.Price != 20 || product.Version != 4 {
+		t.Errorf("product is at version %d with price %.2f, want version 4 at 20.00", product.Version, product.Price)
+	}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// CustomerHandler handles HTTP requests for customers.
+type CustomerHandler struct {
+	customerService *usecase.CustomerService
+}
+
+// NewCustomerHandler creates a new CustomerHandler.
+func NewCustomerHandler(customerService *usecase.CustomerService) *CustomerHandler {
+	return &CustomerHandler{
+		customerService: customerService,
+	}
+}
+
+// CreateCustomer godoc
+// @Summary Create a customer
+// @Description Add a customer orders can be placed for. Customers created by customer accounts are linked to the account; staff-created customers belong to no account. New customers are active unless staff say otherwise.
+// @Tags customers
+// @Accept  json
+// @Produce  json
+// @Param customer body entity.Customer true "Customer data"
+// @Success 201 {object} entity.Customer
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /customers [post]
+func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
+	var customer entity.Customer
+	if err := c.ShouldBindJSON(&customer); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	createdCustomer, err := h.customerService.CreateCustomer(c, &customer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdCustomer)
+}
+
+// GetCustomers godoc
+// @Summary List customers
+// @Description Retrieve one page of the customers linked to the caller's account, newest first by default; staff see every customer. Pass next_cursor back as cursor to fetch the following page.
+// @Tags customers
+// @Produce  json
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at or name; prefix with - for descending (default -created_at)"
+// @Param status query string false "Only customers with this status: active or inactive"
+// @Param email query string false "Only customers with this email address"
+// @Success 200 {object} entity.CustomerPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /customers [get]
+func (h *CustomerHandler) GetCustomers(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter := usecase.CustomerFilter{
+		Status: entity.CustomerStatus(c.Query("status")),
+		Email:  c.Query("email"),
+	}
+
+	customers, err := h.customerService.GetCustomers(c, filter, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, customers)
+}
+
+// GetCustomerByID godoc
+// @Summary Get a customer
+// @Description Retrieve a customer linked to the caller's account; staff may retrieve any.
+// @Tags customers
+// @Produce  json
+// @Param id path string true "Customer ID"
+// @Success 200 {object} entity.Customer
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /customers/{id} [get]
+func (h *CustomerHandler) GetCustomerByID(c *gin.Context) {
+	customer, err := h.customerService.GetCustomerByID(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// UpdateCustomer godoc
+// @Summary Replace a customer
+// @Description Replace a customer's name, email, phone and addresses. Leave the status out to keep it; only staff may change it.
+// @Tags customers
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Customer ID"
+// @Param customer body entity.Customer true "Customer data"
+// @Success 200 {object} entity.Customer
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /customers/{id} [put]
+func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
+	var customer entity.Customer
+	if err := c.ShouldBindJSON(&customer); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	updatedCustomer, err := h.customerService.UpdateCustomer(c, c.Param("id"), &customer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedCustomer)
+}
+
+// DeleteCustomer godoc
+// @Summary Delete a customer
+// @Description Delete a customer no order was placed for. Customers with orders can be made inactive instead.
+// @Tags customers
+// @Param id path string true "Customer ID"
+// @Success 200 {object} entity.Customer
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem "The customer has orders"
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /customers/{id} [delete]
+func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.customerService.DeleteCustomer(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.Customer{ID: id})
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/pkg/requestid"
+)
+
+// problemContentType is the media type of RFC 7807 error responses.
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix prefixes an error code to form the problem type URI.
+const problemTypePrefix = "urn:ulab3:problem:"
+
+// errUnsupportedMediaType is recorded for request bodies of a media type the
+// route does not accept.
+var errUnsupportedMediaType = &usecase.DomainError{Code: "unsupported_media_type", Message: "unsupported media type"}
+
+// errorStatuses maps the usecase error categories to response statuses. The
+// first category an error matches wins.
+var errorStatuses = []struct {
+	kind   error
+	status int
+}{
+	{usecase.ErrUnauthenticated, http.StatusUnauthorized},
+	{usecase.ErrForbidden, http.StatusForbidden},
+	{usecase.ErrNotFound, http.StatusNotFound},
+	{usecase.ErrConflict, http.StatusConflict},
+	{usecase.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{usecase.ErrInsufficientStock, http.StatusUnprocessableEntity},
+	{usecase.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+	{usecase.ErrValidation, http.StatusBadRequest},
+	{errUnsupportedMediaType, http.StatusUnsupportedMediaType},
+}
+
+// ErrorHandler writes the last error a handler recorded with c.Error as an
+// RFC 7807 problem. Errors outside the usecase categories are logged and
+// reported as a bare 500, so internal details never reach the client.
+func ErrorHandler(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+
+		problem := entity.Problem{
+			Type:      "about:blank",
+			Status:    http.StatusInternalServerError,
+			Detail:    "internal server error",
+			Instance:  c.Request.URL.Path,
+			Code:      "internal_error",
+			RequestID: requestid.From(c),
+		}
+		for _, e := range errorStatuses {
+			if errors.Is(err, e.kind) {
+				problem.Status = e.status
+				problem.Code = errorCode(err)
+				problem.Type = problemTypePrefix + problem.Code
+				problem.Detail = err.Error()
+				break
+			}
+		}
+		problem.Title = http.StatusText(problem.Status)
+
+		var validationErr *usecase.ValidationError
+		if errors.As(err, &validationErr) {
+			problem.Errors = validationErr.Fields
+		}
+		var cartErr *usecase.CartChangedError
+		if errors.As(err, &cartErr) {
+			problem.Issues = cartErr.Issues
+		}
+
+		if problem.Status == http.StatusInternalServerError {
+			logger.Error("Request failed", "method", c.Request.Method, "path", c.Request.URL.Path,
+				"request_id", problem.RequestID, "error", err)
+		}
+		if problem.Status == http.StatusUnauthorized {
+			c.Header("WWW-Authenticate", "Bearer")
+		}
+
+		c.Header("Content-Type", problemContentType)
+		c.AbortWithStatusJSON(problem.Status, problem)
+	}
+}
+
+// errorCode returns the code of the most specific coded error in err's chain.
+func errorCode(err error) string {
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return "internal_error"
+}
+
+// invalidBody reports a request body that could not be decoded or failed
+// validation.
+func invalidBody(err error) error {
+	var validationErr *usecase.ValidationError
+	if errors.As(err, &validationErr) {
+		return err
+	}
+	return usecase.DecodeError(err)
+}
+
+// notFound answers requests for routes that do not exist.
+func notFound(c *gin.Context) {
+	c.Error(fmt.Errorf("%w: no route for %s %s", usecase.ErrNotFound, c.Request.Method, c.Request.URL.Path))
+}
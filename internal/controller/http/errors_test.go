@@ -0,0 +1,149 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/pkg/requestid"
+)
+
+// serveError answers one request with a router whose handler records err,
+// and returns the response.
+func serveError(t *testing.T, err error) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	// As in NewRouter, the gin context reads through to the request context
+	engine.ContextWithFallback = true
+	engine.Use(RequestID(), ErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	engine.GET("/fail", func(c *gin.Context) {
+		c.Error(err)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(requestid.Header, "test-request")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeProblem checks that the response is a problem for the request and
+// returns it.
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) entity.Problem {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, problemContentType) {
+		t.Errorf("Content-Type = %q, want %s", got, problemContentType)
+	}
+	var problem entity.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	if problem.RequestID != "test-request" {
+		t.Errorf("request_id = %q, want test-request", problem.RequestID)
+	}
+	if problem.Status != rec.Code || problem.Title != http.StatusText(rec.Code) || problem.Instance != "/fail" {
+		t.Errorf("problem is %d %q at %q for a %d response", problem.Status, problem.Title, problem.Instance, rec.Code)
+	}
+	return problem
+}
+
+func TestErrorHandlerStatuses(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{usecase.ErrUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+		{usecase.ErrInvalidToken, http.StatusUnauthorized, "invalid_token"},
+		{usecase.ErrForbidden, http.StatusForbidden, "forbidden"},
+		{usecase.ErrNotFound, http.StatusNotFound, "not_found"},
+		{usecase.ErrConflict, http.StatusConflict, "conflict"},
+		{usecase.ErrEmailTaken, http.StatusConflict, "email_taken"},
+		{usecase.ErrStockHeld, http.StatusConflict, "stock_held"},
+		{usecase.ErrIdempotencyKeyInFlight, http.StatusConflict, "idempotency_key_in_flight"},
+		{&usecase.TransitionError{From: entity.OrderStatusShipped, To: entity.OrderStatusPaid}, http.StatusConflict, "invalid_transition"},
+		{usecase.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+		{usecase.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+		{usecase.ErrInsufficientStock, http.StatusUnprocessableEntity, "insufficient_stock"},
+		{usecase.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+		{usecase.ErrValidation, http.StatusBadRequest, "validation_failed"},
+		{usecase.ErrInvalidQuery, http.StatusBadRequest, "invalid_query"},
+		{usecase.ErrUnknownProduct, http.StatusBadRequest, "unknown_product"},
+		{errUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		// Wrapping keeps the most specific code
+		{fmt.Errorf("order 42: %w", usecase.ErrCustomerInactive), http.StatusConflict, "customer_inactive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			rec := serveError(t, tt.err)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			problem := decodeProblem(t, rec)
+			if problem.Code != tt.code || problem.Type != problemTypePrefix+tt.code {
+				t.Errorf("problem is %q of type %q, want %q", problem.Code, problem.Type, tt.code)
+			}
+			if problem.Detail != tt.err.Error() {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.err.Error())
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); (got != "") != (tt.status == http.StatusUnauthorized) {
+				t.Errorf("WWW-Authenticate = %q for a %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerDetails(t *testing.T) {
+	fields := []entity.FieldError{{Field: "price", Message: "must be greater than 0"}, {Field: "name", Message: "is required"}}
+	problem := decodeProblem(t, serveError(t, &usecase.ValidationError{Fields: fields}))
+	if !reflect.DeepEqual(problem.Errors, fields) {
+		t.Errorf("errors = %+v, want %+v", problem.Errors, fields)
+	}
+
+	issues := []entity.CartIssue{{ProductID: "42", Code: entity.CartIssuePriceChanged, Message: "price changed from 10.00 to 12.50"}}
+	problem = decodeProblem(t, serveError(t, &usecase.CartChangedError{Issues: issues}))
+	if problem.Status != http.StatusConflict || !reflect.DeepEqual(problem.Issues, issues) {
+		t.Errorf("problem is %d with issues %+v, want 409 with %+v", problem.Status, problem.Issues, issues)
+	}
+}
+
+func TestErrorHandlerHidesInternalErrors(t *testing.T) {
+	err := fmt.Errorf("failed to fetch product: %w", errors.New("dial tcp 10.0.0.5:27017: connection refused"))
+	rec := serveError(t, err)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	problem := decodeProblem(t, rec)
+	if problem.Code != "internal_error" || problem.Type != "about:blank" || problem.Detail != "internal server error" {
+		t.Errorf("problem = %+v, want a bare internal_error", problem)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "10.0.0.5") || strings.Contains(body, "fetch product") {
+		t.Errorf("body leaks the error: %s", body)
+	}
+}
+
+func TestErrorHandlerLeavesWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	engine.GET("/written", func(c *gin.Context) {
+		c.Error(usecase.ErrConflict)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/written", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"ok":true}` {
+		t.Errorf("response = %d %s, want the handler's 200", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// EventHandler handles HTTP requests for the event outbox.
+type EventHandler struct {
+	eventService *usecase.EventService
+}
+
+// NewEventHandler creates a new EventHandler.
+func NewEventHandler(eventService *usecase.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+	}
+}
+
+// GetEvents godoc
+// @Summary List outbox events
+// @Description Retrieve one page of the domain events in the outbox with their delivery state, newest first by default. Pass next_cursor back as cursor to fetch the following page. Admins only.
+// @Tags events
+// @Produce  json
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at; prefix with - for descending (default -created_at)"
+// @Param status query string false "Only events with this delivery status: pending, delivered or dead"
+// @Param type query string false "Only events of this type, e.g. OrderCreated"
+// @Success 200 {object} entity.EventPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /events [get]
+func (h *EventHandler) GetEvents(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter := usecase.EventFilter{
+		Status: entity.EventStatus(c.Query("status")),
+		Type:   entity.EventType(c.Query("type")),
+	}
+
+	events, err := h.eventService.GetEvents(c, filter, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetEventByID godoc
+// @Summary Get an outbox event
+// @Description Retrieve a domain event with its delivery state and last error. Admins only.
+// @Tags events
+// @Produce  json
+// @Param id path string true "Event ID"
+// @Success 200 {object} entity.Event
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /events/{id} [get]
+func (h *EventHandler) GetEventByID(c *gin.Context) {
+	event, err := h.eventService.GetEventByID(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// RetryEvent godoc
+// @Summary Retry a dead event
+// @Description Queue an event that ran out of delivery attempts for delivery again, with a fresh set of attempts. Admins only.
+// @Tags events
+// @Produce  json
+// @Param id path string true "Event ID"
+// @Success 200 {object} entity.Event
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem "The event is not dead"
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /events/{id}/retry [post]
+func (h *EventHandler) RetryEvent(c *gin.Context) {
+	event, err := h.eventService.RetryEvent(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
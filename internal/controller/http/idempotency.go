@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"ulab3/internal/usecase"
+)
+
+// idempotencyKeyHeader names the header clients send to make a request safe
+// to retry.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength caps the length of an idempotency key.
+const maxIdempotencyKeyLength = 255
+
+// Idempotent lets clients retry a request safely by sending an
+// Idempotency-Key header. The first request with a key runs as usual and its
+// response is stored; a retry with the same key and body gets the stored
+// response back, marked with Idempotent-Replayed, without running the handler
+// again. Requests without the header are handled as usual.
+func Idempotent(service *usecase.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			c.Error(fmt.Errorf("%w: %s must be 1 to %d printable ASCII characters",
+				usecase.ErrValidation, idempotencyKeyHeader, maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		body, err := c.GetRawData()
+		if err != nil {
+			c.Error(invalidBody(err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		stored, err := service.Begin(c, key, fingerprint(c, body))
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.Status, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The response is on its way even if the client has gone, so the
+		// outcome is recorded regardless; the service logs any failure
+		ctx := context.WithoutCancel(c)
+		if len(c.Errors) == 0 && recorder.Written() && recorder.Status() < 300 {
+			service.Complete(ctx, key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+			return
+		}
+		// Failed requests change nothing, so their key is released and a
+		// retry runs the request again
+		service.Release(ctx, key)
+	}
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", c.Request.Method, c.Request.URL.Path)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// responseRecorder keeps a copy of the response body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+)
+
+// idempotentRouter serves POST /things through the Idempotent middleware.
+// The handler counts the requests it answers with 201 and fails with a
+// conflict for the body "fail". For the body "wait" it first sends on hold,
+// then waits to receive from it.
+func idempotentRouter(t *testing.T, service *usecase.IdempotencyService, hold chan struct{}) (*gin.Engine, *atomic.Int32) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(RequestID(), ErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	var runs atomic.Int32
+	engine.POST("/things", Idempotent(service), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		switch string(body) {
+		case "fail":
+			c.Error(usecase.ErrConflict)
+			return
+		case "wait":
+			hold <- struct{}{}
+			<-hold
+		}
+		c.JSON(http.StatusCreated, gin.H{"run": runs.Add(1)})
+	})
+	return engine, &runs
+}
+
+func newIdempotencyService(lockTTL time.Duration) *usecase.IdempotencyService {
+	repos := memory.NewRepositories()
+	return usecase.NewIdempotencyService(repos.Idempotency, time.Hour, lockTTL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestIdempotentReplaysResponse(t *testing.T) {
+	engine, runs := idempotentRouter(t, newIdempotencyService(time.Minute), nil)
+	key := map[string]string{idempotencyKeyHeader: "key-1"}
+
+	first := serve(engine, http.MethodPost, "/things", `{"a":1}`, key)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: %d, replayed %q", first.Code, first.Header().Get("Idempotent-Replayed"))
+	}
+	retry := serve(engine, http.MethodPost, "/things", `{"a":1}`, key)
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() ||
+		retry.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("retry = %d %s, want the stored %d %s", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+	if runs.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", runs.Load())
+	}
+
+	// Requests without a key, or with another one, run as usual
+	serve(engine, http.MethodPost, "/things", `{"a":1}`, nil)
+	serve(engine, http.MethodPost, "/things", `{"a":1}`, map[string]string{idempotencyKeyHeader: "key-2"})
+	if runs.Load() != 3 {
+		t.Errorf("handler ran %d times, want 3", runs.Load())
+	}
+}
+
+func TestIdempotentKeyReused(t *testing.T) {
+	engine, runs := idempotentRouter(t, newIdempotencyService(time.Minute), nil)
+	key := map[string]string{idempotencyKeyHeader: "key-1"}
+
+	serve(engine, http.MethodPost, "/things", `{"a":1}`, key)
+	rec := serve(engine, http.MethodPost, "/things", `{"a":2}`, key)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("another body under the key: %d, want 422", rec.Code)
+	}
+	var problem entity.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Code != "idempotency_key_reused" {
+		t.Errorf("problem = %s, want idempotency_key_reused", rec.Body.String())
+	}
+	if runs.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", runs.Load())
+	}
+}
+
+func TestIdempotentKeyInFlight(t *testing.T) {
+	hold := make(chan struct{})
+	engine, runs := idempotentRouter(t, newIdempotencyService(time.Minute), hold)
+	key := map[string]string{idempotencyKeyHeader: "key-1"}
+
+	done := make(chan int)
+	go func() {
+		done <- serve(engine, http.MethodPost, "/things", "wait", key).Code
+	}()
+	// The first request holds the lease while its handler runs
+	<-hold
+	if rec := serve(engine, http.MethodPost, "/things", "wait", key); rec.Code != http.StatusConflict {
+		t.Errorf("request while the first runs: %d, want 409", rec.Code)
+	}
+	hold <- struct{}{}
+	if code := <-done; code != http.StatusCreated {
+		t.Fatalf("first request: %d, want 201", code)
+	}
+	if got := serve(engine, http.MethodPost, "/things", "wait", key); got.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after the first finished: %d, want a replay", got.Code)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", runs.Load())
+	}
+}
+
+func TestIdempotentLeaseExpires(t *testing.T) {
+	service := newIdempotencyService(50 * time.Millisecond)
+	engine, runs := idempotentRouter(t, service, nil)
+	key := map[string]string{idempotencyKeyHeader: "key-1"}
+
+	// A request claims the key, then its process dies before it completes
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/things", nil)
+	if stored, err := service.Begin(context.Background(), "key-1", fingerprint(c, []byte(`{"a":1}`))); err != nil || stored != nil {
+		t.Fatalf("Begin = %v, %v", stored, err)
+	}
+	if rec := serve(engine, http.MethodPost, "/things", `{"a":1}`, key); rec.Code != http.StatusConflict {
+		t.Fatalf("retry while the lease holds: %d, want 409", rec.Code)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if rec := serve(engine, http.MethodPost, "/things", `{"a":1}`, key); rec.Code != http.StatusCreated {
+		t.Fatalf("retry after the lease expired: %d, want 201", rec.Code)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", runs.Load())
+	}
+}
+
+func TestIdempotentReleasesFailedRequests(t *testing.T) {
+	engine, runs := idempotentRouter(t, newIdempotencyService(time.Minute), nil)
+	key := map[string]string{idempotencyKeyHeader: "key-1"}
+
+	if rec := serve(engine, http.MethodPost, "/things", "fail", key); rec.Code != http.StatusConflict {
+		t.Fatalf("failing request: %d, want 409", rec.Code)
+	}
+	// Nothing was stored, so the retry runs the handler again
+	if rec := serve(engine, http.MethodPost, "/things", "fail", key); rec.Code != http.StatusConflict ||
+		rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry of a failed request: %d, replayed %q", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if rec := serve(engine, http.MethodPost, "/things", "{}", map[string]string{idempotencyKeyHeader: "bad\tkey"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("key with a control character: %d, want 400", rec.Code)
+	}
+	if runs.Load() != 0 {
+		t.Errorf("handler succeeded %d times, want never", runs.Load())
+	}
+}
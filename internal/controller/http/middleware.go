@@ -0,0 +1,62 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"ulab3/internal/usecase"
+	"ulab3/pkg/requestid"
+)
+
+// RequestID tags every request with an ID, reusing a valid X-Request-ID sent
+// by the client. The ID is echoed in the response and stored in the request
+// context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.With(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer" access
+// token and stores the authenticated actor in the request context, where the
+// services read it to enforce roles.
+func RequireAuth(authService *usecase.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		accessToken, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || accessToken == "" {
+			c.Error(usecase.ErrUnauthenticated)
+			c.Abort()
+			return
+		}
+
+		actor, err := authService.Authenticate(c, accessToken)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(usecase.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
+
+// OptionalAuth authenticates requests that carry an access token, like
+// RequireAuth, and lets requests without one through anonymously. It guards
+// public routes that offer more to some roles.
+func OptionalAuth(authService *usecase.AuthService) gin.HandlerFunc {
+	requireAuth := RequireAuth(authService)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
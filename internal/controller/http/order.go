@@ -1,13 +1,20 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"time"
 	"ulab3/internal/entity"
 	"ulab3/internal/usecase"
 )
 
+// streamKeepAlive is how often an idle order stream sends a comment, so that
+// proxies keep the connection open.
+const streamKeepAlive = 15 * time.Second
+
 // OrderHandler handles HTTP requests for orders.
 type OrderHandler struct {
 	orderService *usecase.OrderService
@@ -22,114 +29,473 @@ func NewOrderHandler(orderService *usecase.OrderService) *OrderHandler {
 
 // CreateOrder godoc
 // @Summary Create a new order
-// @Description Create a new order in the system.
+// @Description Create a new order in the system for customer_id, which must name an active customer the caller may manage. The order holds the stock of its lines until it is paid or cancelled, or until the stock hold TTL runs out. Send an Idempotency-Key to make the request safe to retry: a retry with the same key and body replays the first response, marked with Idempotent-Replayed, instead of creating another order.
 // @Tags orders
 // @Accept  json
 // @Produce  json
+// @Param Idempotency-Key header string false "Client-chosen key, at most 255 characters"
 // @Param order body entity.Order true "Order data"
 // @Success 201 {object} entity.Order
-// @Failure 400 {object} entity.Error
-// @Failure 500 {object} entity.Error
+// @Header 201 {string} ETag "Version of the order"
+// @Header 201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /orders [post]
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	var order entity.Order
 	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, entity.Error{Message: fmt.Sprintf("invalid request body: %v", err)})
+		c.Error(invalidBody(err))
 		return
 	}
 
 	createdOrder, err := h.orderService.CreateOrder(c, &order)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to create order: %v", err)})
+		c.Error(err)
 		return
 	}
 
+	setETag(c, createdOrder.Version)
 	c.JSON(http.StatusCreated, createdOrder)
 }
 
 // GetAllOrders godoc
-// @Summary Get all orders
-// @Description Retrieve all orders from the system.
+// @Summary List orders
+// @Description Retrieve one page of orders, optionally filtered and sorted. Pass next_cursor back as cursor to fetch the following page.
 // @Tags orders
 // @Produce  json
-// @Success 200 {array} entity.Order
-// @Failure 500 {object} entity.Error
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at, updated_at or total_price; prefix with - for descending"
+// @Param status query string false "Only orders in this status"
+// @Param product_id query string false "Only orders with a line for this product"
+// @Param created_from query string false "Created at or after this RFC 3339 time"
+// @Param created_to query string false "Created before this RFC 3339 time"
+// @Param include_deleted query bool false "Include deleted orders (admins only)"
+// @Success 200 {object} entity.OrderPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /orders [get]
 func (h *OrderHandler) GetAllOrders(c *gin.Context) {
-	orders, err := h.orderService.GetAllOrders(c)
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter, err := orderFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	orders, err := h.orderService.GetAllOrders(c, filter, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetCustomerOrders godoc
+// @Summary List a customer's orders
+// @Description Retrieve one page of the orders placed for a customer, optionally filtered and sorted. Pass next_cursor back as cursor to fetch the following page.
+// @Tags customers
+// @Produce  json
+// @Param id path string true "Customer ID"
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at, updated_at or total_price; prefix with - for descending"
+// @Param status query string false "Only orders in this status"
+// @Param product_id query string false "Only orders with a line for this product"
+// @Param created_from query string false "Created at or after this RFC 3339 time"
+// @Param created_to query string false "Created before this RFC 3339 time"
+// @Param include_deleted query bool false "Include deleted orders (admins only)"
+// @Success 200 {object} entity.OrderPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/orders [get]
+func (h *OrderHandler) GetCustomerOrders(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter, err := orderFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	orders, err := h.orderService.GetCustomerOrders(c, c.Param("id"), filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to fetch orders: %v", err)})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, orders)
 }
 
+// orderFilter reads the order listing filters from the query string.
+func orderFilter(c *gin.Context) (usecase.OrderFilter, error) {
+	filter := usecase.OrderFilter{
+		Status:    entity.OrderStatus(c.Query("status")),
+		ProductID: c.Query("product_id"),
+	}
+	var err error
+	if filter.CreatedFrom, err = queryTime(c, "created_from"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = queryTime(c, "created_to"); err != nil {
+		return filter, err
+	}
+	if filter.IncludeDeleted, err = queryBool(c, "include_deleted"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// StreamOrders godoc
+// @Summary Stream order events
+// @Description Follow orders as they change, as Server-Sent Events. Every order created and every status change is sent as an event named OrderCreated or OrderStatusChanged, with the event ID as its id and the event message as JSON data. Customers only see their own orders. Reconnect with Last-Event-ID to get the events missed since; when they cannot be replayed, the stream opens with a reset event and the client should reload its orders. Idle streams get a comment every 15 seconds.
+// @Tags orders
+// @Produce  text/event-stream
+// @Param status query string false "Only events leaving an order in this status"
+// @Param product_id query string false "Only events for orders with a line for this product"
+// @Param Last-Event-ID header string false "ID of the last event received; last_event_id in the query works too"
+// @Success 200 {object} entity.EventMessage
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/stream [get]
+func (h *OrderHandler) StreamOrders(c *gin.Context) {
+	filter := usecase.OrderStreamFilter{
+		Status:    entity.OrderStatus(c.Query("status")),
+		ProductID: c.Query("product_id"),
+	}
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	stream, err := h.orderService.StreamOrders(c, filter, lastEventID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if stream.Reset {
+		// The empty id clears the client's Last-Event-ID
+		fmt.Fprint(c.Writer, "id:\nevent: reset\ndata: {}\n\n")
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-stream.Events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(usecase.NewEventMessage(event))
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
 // GetOrderByID godoc
 // @Summary Get an order by ID
-// @Description Retrieve an order by its ID.
+// @Description Retrieve an order by its ID. The ETag carries the order's version; send it back in If-None-Match to get 304 while the order is unchanged.
 // @Tags orders
 // @Produce  json
 // @Param id path string true "Order ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param include_deleted query bool false "Find the order even if it is deleted (admins only)"
 // @Success 200 {object} entity.Order
-// @Failure 404 {object} entity.Error
-// @Failure 500 {object} entity.Error
+// @Header 200 {string} ETag "Version of the order"
+// @Success 304
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /orders/{id} [get]
 func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 	id := c.Param("id")
-	order, err := h.orderService.GetOrderByID(c, id)
+	includeDeleted, err := queryBool(c, "include_deleted")
 	if err != nil {
-		c.JSON(http.StatusNotFound, entity.Error{Message: fmt.Sprintf("order not found: %v", err)})
+		c.Error(err)
+		return
+	}
+	order, err := h.orderService.GetOrderByID(c, id, includeDeleted)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
+	setETag(c, order.Version)
+	if notModified(c, order.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, order)
 }
 
 // UpdateOrder godoc
-// @Summary Update an order
-// @Description Update an existing order's details.
+// @Summary Replace an order
+// @Description Replace the line items of an order. Every other field is managed by the server. Returns the stored order. With If-Match, the order is only replaced while it is still at that version.
 // @Tags orders
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Order ID"
+// @Param If-Match header string false "ETag the order must still have"
 // @Param order body entity.Order true "Updated order data"
 // @Success 200 {object} entity.Order
-// @Failure 400 {object} entity.Error
-// @Failure 500 {object} entity.Error
+// @Header 200 {string} ETag "Version of the order"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 412 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /orders/{id} [put]
 func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 	id := c.Param("id")
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 	var order entity.Order
 	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, entity.Error{Message: fmt.Sprintf("invalid request body: %v", err)})
+		c.Error(invalidBody(err))
 		return
 	}
 
-	err := h.orderService.UpdateOrder(c, id, &order)
+	updatedOrder, err := h.orderService.UpdateOrder(c, id, version, &order)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to update order: %v", err)})
+		c.Error(err)
 		return
 	}
 
+	setETag(c, updatedOrder.Version)
+	c.JSON(http.StatusOK, updatedOrder)
+}
+
+// PatchOrder godoc
+// @Summary Patch an order
+// @Description Apply an RFC 7396 JSON merge patch. Only items can change and are replaced as a whole. Returns the stored order. With If-Match, the patch only applies while the order is still at that version.
+// @Tags orders
+// @Accept  json
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Param If-Match header string false "ETag the order must still have"
+// @Param patch body entity.Order true "Fields to change"
+// @Success 200 {object} entity.Order
+// @Header 200 {string} ETag "Version of the order"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 412 {object} entity.Problem
+// @Failure 415 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id} [patch]
+func (h *OrderHandler) PatchOrder(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	patch, err := mergePatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	order, err := h.orderService.PatchOrder(c, c.Param("id"), version, patch)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, order.Version)
 	c.JSON(http.StatusOK, order)
 }
 
 // DeleteOrder godoc
 // @Summary Delete an order
-// @Description Delete an order from the system.
+// @Description Delete an order. It is hidden until an admin restores it or it is purged. An order that still holds stock gives it back.
 // @Tags orders
 // @Param id path string true "Order ID"
 // @Success 200 {object} entity.Order
-// @Failure 500 {object} entity.Error
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /orders/{id} [delete]
 func (h *OrderHandler) DeleteOrder(c *gin.Context) {
 	id := c.Param("id")
 	err := h.orderService.DeleteOrder(c, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to delete order: %v", err)})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, entity.Order{ID: id})
 }
+
+// RestoreOrder godoc
+// @Summary Restore an order
+// @Description Bring back a deleted order. An order whose status holds stock takes it again, so restoring fails if the stock has run out. Admins only.
+// @Tags orders
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Header 200 {string} ETag "Version of the order"
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/restore [post]
+func (h *OrderHandler) RestoreOrder(c *gin.Context) {
+	h.changeStatus(c, h.orderService.RestoreOrder)
+}
+
+// PayOrder godoc
+// @Summary Pay for an order
+// @Description Move a pending order to Paid. The stock the order holds is taken out of stock for good; lines whose hold expired take what is available now, so paying fails with 422 if it has run out.
+// @Tags orders
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 422 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/pay [post]
+func (h *OrderHandler) PayOrder(c *gin.Context) {
+	h.changeStatus(c, h.orderService.PayOrder)
+}
+
+// ShipOrder godoc
+// @Summary Ship an order
+// @Description Move a paid order to Shipped.
+// @Tags orders
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/ship [post]
+func (h *OrderHandler) ShipOrder(c *gin.Context) {
+	h.changeStatus(c, h.orderService.ShipOrder)
+}
+
+// DeliverOrder godoc
+// @Summary Deliver an order
+// @Description Move a shipped order to Delivered.
+// @Tags orders
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/deliver [post]
+func (h *OrderHandler) DeliverOrder(c *gin.Context) {
+	h.changeStatus(c, h.orderService.DeliverOrder)
+}
+
+// CancelOrder godoc
+// @Summary Cancel an order
+// @Description Cancel an order that is pending or paid.
+// @Tags orders
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/cancel [post]
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	h.changeStatus(c, h.orderService.CancelOrder)
+}
+
+// RefundOrder godoc
+// @Summary Refund an order
+// @Description Refund a paid or delivered order.
+// @Tags orders
+// @Produce  json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/refund [post]
+func (h *OrderHandler) RefundOrder(c *gin.Context) {
+	h.changeStatus(c, h.orderService.RefundOrder)
+}
+
+// changeStatus applies a status transition or restore to the order named in
+// the path.
+func (h *OrderHandler) changeStatus(c *gin.Context, change func(ctx context.Context, id string) (*entity.Order, error)) {
+	order, err := change(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, order.Version)
+	c.JSON(http.StatusOK, order)
+}
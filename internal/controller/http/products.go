@@ -1,7 +1,6 @@
 package http
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"ulab3/internal/entity"
@@ -28,108 +27,285 @@ func NewProductHandler(productService *usecase.ProductService) *ProductHandler {
 // @Produce  json
 // @Param product body entity.Product true "Product data"
 // @Success 201 {object} entity.Product
-// @Failure 400 {object} entity.Error
-// @Failure 500 {object} entity.Error
+// @Header 201 {string} ETag "Version of the product"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /products [post]
 func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	var product entity.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, entity.Error{Message: fmt.Sprintf("invalid request body: %v", err)})
+		c.Error(invalidBody(err))
 		return
 	}
 
 	createdProduct, err := h.productService.CreateProduct(c, &product)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to create product: %v", err)})
+		c.Error(err)
 		return
 	}
 
+	setETag(c, createdProduct.Version)
 	c.JSON(http.StatusCreated, createdProduct)
 }
 
 // GetAllProducts godoc
-// @Summary Get all products
-// @Description Retrieve all products from the system.
+// @Summary List products
+// @Description Retrieve one page of products, optionally filtered and sorted. Pass next_cursor back as cursor to fetch the following page.
 // @Tags products
 // @Produce  json
-// @Success 200 {array} entity.Product
-// @Failure 500 {object} entity.Error
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at, name, price or stock; prefix with - for descending"
+// @Param category query string false "Only products in this category"
+// @Param min_price query number false "Minimum price, inclusive"
+// @Param max_price query number false "Maximum price, inclusive"
+// @Param in_stock query bool false "Only products with unreserved stock left"
+// @Param include_deleted query bool false "Include deleted products (admins only)"
+// @Success 200 {object} entity.ProductPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
 // @Router /products [get]
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	products, err := h.productService.GetAllProducts(c)
+	page, err := pageRequest(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to fetch products: %v", err)})
+		c.Error(err)
+		return
+	}
+	filter := usecase.ProductFilter{Category: c.Query("category")}
+	if filter.MinPrice, err = queryFloat(c, "min_price"); err != nil {
+		c.Error(err)
+		return
+	}
+	if filter.MaxPrice, err = queryFloat(c, "max_price"); err != nil {
+		c.Error(err)
+		return
+	}
+	if filter.InStock, err = queryBool(c, "in_stock"); err != nil {
+		c.Error(err)
+		return
+	}
+	if filter.IncludeDeleted, err = queryBool(c, "include_deleted"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	products, err := h.productService.GetAllProducts(c, filter, page)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, products)
 }
 
+// SearchProducts godoc
+// @Summary Search products
+// @Description Full-text search over product name and category, best match first. Small typos are tolerated.
+// @Tags products
+// @Produce  json
+// @Param q query string true "Search text"
+// @Param limit query int false "Maximum number of results, 1 to 100 (default 20)"
+// @Success 200 {object} entity.ProductSearchResult
+// @Failure 400 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Router /products/search [get]
+func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.productService.SearchProducts(c, c.Query("q"), page.Limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GetProductByID godoc
 // @Summary Get a product by ID
-// @Description Retrieve a product by its ID.
+// @Description Retrieve a product by its ID. The ETag carries the product's version; send it back in If-None-Match to get 304 while the product is unchanged.
 // @Tags products
 // @Produce  json
 // @Param id path string true "Product ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param include_deleted query bool false "Find the product even if it is deleted (admins only)"
 // @Success 200 {object} entity.Product
-// @Failure 404 {object} entity.Error
-// @Failure 500 {object} entity.Error
+// @Header 200 {string} ETag "Version of the product"
+// @Success 304
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
 // @Router /products/{id} [get]
 func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	id := c.Param("id")
-	product, err := h.productService.GetProductByID(c, id)
+	includeDeleted, err := queryBool(c, "include_deleted")
 	if err != nil {
-		c.JSON(http.StatusNotFound, entity.Error{Message: fmt.Sprintf("product not found: %v", err)})
+		c.Error(err)
+		return
+	}
+	product, err := h.productService.GetProductByID(c, id, includeDeleted)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
+	setETag(c, product.Version)
+	if notModified(c, product.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, product)
 }
 
 // UpdateProduct godoc
-// @Summary Update a product
-// @Description Update an existing product's details.
+// @Summary Replace a product
+// @Description Replace every writable field of a product; omitted fields are reset. Returns the stored product. With If-Match, the product is only replaced while it is still at that version. Fails with 409 if the stock would drop below what pending orders and carts hold.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag the product must still have"
 // @Param product body entity.Product true "Updated product data"
 // @Success 200 {object} entity.Product
-// @Failure 400 {object} entity.Error
-// @Failure 500 {object} entity.Error
+// @Header 200 {string} ETag "Version of the product"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 412 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /products/{id} [put]
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	id := c.Param("id")
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 	var product entity.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, entity.Error{Message: fmt.Sprintf("invalid request body: %v", err)})
+		c.Error(invalidBody(err))
 		return
 	}
 
-	err := h.productService.UpdateProduct(c, id, &product)
+	updatedProduct, err := h.productService.UpdateProduct(c, id, version, &product)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to update product: %v", err)})
+		c.Error(err)
 		return
 	}
 
+	setETag(c, updatedProduct.Version)
+	c.JSON(http.StatusOK, updatedProduct)
+}
+
+// PatchProduct godoc
+// @Summary Patch a product
+// @Description Apply an RFC 7396 JSON merge patch: only the fields sent change, and null resets a field. Returns the stored product. With If-Match, the patch only applies while the product is still at that version. Fails with 409 if the stock would drop below what pending orders and carts hold.
+// @Tags products
+// @Accept  json
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag the product must still have"
+// @Param patch body entity.Product true "Fields to change"
+// @Success 200 {object} entity.Product
+// @Header 200 {string} ETag "Version of the product"
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 412 {object} entity.Problem
+// @Failure 415 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /products/{id} [patch]
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	patch, err := mergePatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	product, err := h.productService.PatchProduct(c, c.Param("id"), version, patch)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, product)
 }
 
 // DeleteProduct godoc
 // @Summary Delete a product
-// @Description Delete a product from the system.
+// @Description Delete a product. It is hidden until an admin restores it or it is purged. A product that open orders still refer to is only deleted with force.
 // @Tags products
 // @Param id path string true "Product ID"
+// @Param force query bool false "Delete even if open orders refer to the product"
 // @Success 200 {object} entity.Product
-// @Failure 500 {object} entity.Error
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
 // @Router /products/{id} [delete]
 func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	id := c.Param("id")
-	err := h.productService.DeleteProduct(c, id)
+	force, err := queryBool(c, "force")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	err = h.productService.DeleteProduct(c, id, force)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{Message: fmt.Sprintf("failed to delete product: %v", err)})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, entity.Product{ID: id})
 }
+
+// RestoreProduct godoc
+// @Summary Restore a product
+// @Description Bring back a deleted product. Admins only.
+// @Tags products
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Success 200 {object} entity.Product
+// @Header 200 {string} ETag "Version of the product"
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /products/{id}/restore [post]
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	product, err := h.productService.RestoreProduct(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+	"ulab3/internal/usecase"
+)
+
+// pageRequest reads the limit, cursor and sort query parameters.
+func pageRequest(c *gin.Context) (usecase.PageRequest, error) {
+	page := usecase.PageRequest{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return page, fmt.Errorf("%w: limit must be an integer", usecase.ErrInvalidQuery)
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// queryFloat reads an optional numeric query parameter.
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", usecase.ErrInvalidQuery, name)
+	}
+	return &number, nil
+}
+
+// queryBool reads an optional boolean query parameter.
+func queryBool(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s must be true or false", usecase.ErrInvalidQuery, name)
+	}
+	return flag, nil
+}
+
+// queryTime reads an optional RFC 3339 timestamp query parameter.
+func queryTime(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", usecase.ErrInvalidQuery, name)
+	}
+	return &t, nil
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	_ "ulab3/docs"
@@ -11,7 +12,17 @@ import (
 // title Api For ulab
 // version 1.0
 // description Ulab3 API
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /auth/login, sent as "Bearer <token>".
 func NewRouter(engine *gin.Engine, ctr *controller.Controller) {
+	// Handlers pass the gin context to the services, which read the actor
+	// RequireAuth stores in the request context
+	engine.ContextWithFallback = true
+	binding.Validator = bindingValidator{}
+	engine.Use(RequestID(), ErrorHandler(ctr.Logger))
+	engine.NoRoute(notFound)
 
 	// Use CORS middleware
 
@@ -19,21 +30,107 @@ func NewRouter(engine *gin.Engine, ctr *controller.Controller) {
 	engine.GET("/swagger/*eny", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	hp := NewProductHandler(ctr.Product)
 	ho := NewOrderHandler(ctr.Order)
+	ha := NewAuthHandler(ctr.Auth)
+	hau := NewAuditHandler(ctr.Audit)
+	he := NewEventHandler(ctr.Events)
+	hw := NewWebhookHandler(ctr.Webhooks)
+	hc := NewCustomerHandler(ctr.Customers)
+	hca := NewCartHandler(ctr.Carts)
+	hwh := NewWarehouseHandler(ctr.Warehouses)
+	requireAuth := RequireAuth(ctr.Auth)
+	optionalAuth := OptionalAuth(ctr.Auth)
+	idempotent := Idempotent(ctr.Idempotency)
 	// Define route groups
 	products := engine.Group("/products")
-	orders := engine.Group("/orders")
+	orders := engine.Group("/orders", requireAuth)
+	auth := engine.Group("/auth")
+	users := engine.Group("/users", requireAuth)
+	audit := engine.Group("/audit", requireAuth)
+	events := engine.Group("/events", requireAuth)
+	webhooks := engine.Group("/webhooks", requireAuth)
+	customers := engine.Group("/customers", requireAuth)
+	carts := engine.Group("/carts", requireAuth)
+	warehouses := engine.Group("/warehouses", requireAuth)
+
+	// Define auth routes
+	auth.POST("/register", ha.Register) // Register a user
+	auth.POST("/login", ha.Login)       // Log in
+	auth.POST("/refresh", ha.Refresh)   // Refresh tokens
+	auth.POST("/logout", ha.Logout)     // Log out
+
+	// Define user routes
+	users.PUT("/:id/role", ha.AssignRole) // Assign a role
 
 	// Define product routes
-	products.POST("/", hp.CreateProduct)      // Create a new product
-	products.GET("/", hp.GetAllProducts)      // Get all products
-	products.GET("/:id", hp.GetProductByID)   // Get product by ID
-	products.PUT("/:id", hp.UpdateProduct)    // Update a product
-	products.DELETE("/:id", hp.DeleteProduct) // Delete a product
+	products.POST("/", requireAuth, hp.CreateProduct)                // Create a new product
+	products.GET("/", optionalAuth, hp.GetAllProducts)               // Get all products
+	products.GET("/search", hp.SearchProducts)                       // Search products
+	products.GET("/:id", optionalAuth, hp.GetProductByID)            // Get product by ID
+	products.PUT("/:id", requireAuth, hp.UpdateProduct)              // Replace a product
+	products.PATCH("/:id", requireAuth, hp.PatchProduct)             // Patch a product
+	products.DELETE("/:id", requireAuth, hp.DeleteProduct)           // Delete a product
+	products.POST("/:id/restore", requireAuth, hp.RestoreProduct)    // Restore a deleted product
+	products.GET("/:id/history", requireAuth, hau.GetProductHistory) // Get a product's change history
+	products.GET("/:id/stock", requireAuth, hwh.GetProductStock)     // Get a product's stock by warehouse
+
+	// Define audit routes
+	audit.GET("/", hau.GetAuditLog) // List audit entries
+
+	// Define event routes
+	events.GET("/", he.GetEvents)            // List outbox events
+	events.GET("/:id", he.GetEventByID)      // Get an outbox event
+	events.POST("/:id/retry", he.RetryEvent) // Retry a dead event
+
+	// Define webhook routes
+	webhooks.POST("/", hw.CreateWebhook)                                  // Register a webhook
+	webhooks.GET("/", hw.GetWebhooks)                                     // List webhooks
+	webhooks.GET("/:id", hw.GetWebhookByID)                               // Get a webhook
+	webhooks.PUT("/:id", hw.UpdateWebhook)                                // Replace a webhook
+	webhooks.DELETE("/:id", hw.DeleteWebhook)                             // Delete a webhook
+	webhooks.GET("/:id/deliveries", hw.GetDeliveries)                     // List webhook deliveries
+	webhooks.GET("/:id/deliveries/:delivery_id", hw.GetDelivery)          // Get a webhook delivery
+	webhooks.POST("/:id/deliveries/:delivery_id/redeliver", hw.Redeliver) // Redeliver a webhook delivery
+
+	// Define customer routes
+	customers.POST("/", hc.CreateCustomer)             // Create a customer
+	customers.GET("/", hc.GetCustomers)                // List customers
+	customers.GET("/:id", hc.GetCustomerByID)          // Get a customer
+	customers.PUT("/:id", hc.UpdateCustomer)           // Replace a customer
+	customers.DELETE("/:id", hc.DeleteCustomer)        // Delete a customer
+	customers.GET("/:id/orders", ho.GetCustomerOrders) // List a customer's orders
+
+	// Define warehouse routes
+	warehouses.POST("/", hwh.CreateWarehouse)                         // Create a warehouse
+	warehouses.GET("/", hwh.GetWarehouses)                            // List warehouses
+	warehouses.GET("/:id", hwh.GetWarehouseByID)                      // Get a warehouse
+	warehouses.PUT("/:id", hwh.UpdateWarehouse)                       // Replace a warehouse
+	warehouses.DELETE("/:id", hwh.DeleteWarehouse)                    // Delete a warehouse
+	warehouses.GET("/:id/stock", hwh.GetWarehouseStock)               // List a warehouse's stock
+	warehouses.PUT("/:id/stock/:product_id", hwh.SetStockLevel)       // Set a warehouse's stock of a product
+	warehouses.DELETE("/:id/stock/:product_id", hwh.DeleteStockLevel) // Stop a warehouse stocking a product
+
+	// Define cart routes
+	carts.POST("/", hca.CreateCart)                            // Create a cart
+	carts.GET("/:id", hca.GetCart)                             // Get a cart
+	carts.POST("/:id/items", hca.AddCartItem)                  // Add a cart line
+	carts.PUT("/:id/items/:product_id", hca.UpdateCartItem)    // Change a cart line
+	carts.DELETE("/:id/items/:product_id", hca.RemoveCartItem) // Remove a cart line
+	carts.POST("/:id/checkout", idempotent, hca.Checkout)      // Check out a cart
 
 	// Define order routes
-	orders.POST("/", ho.CreateOrder)      // Create a new order
-	orders.GET("/", ho.GetAllOrders)      // Get all orders
-	orders.GET("/:id", ho.GetOrderByID)   // Get order by ID
-	orders.PUT("/:id", ho.UpdateOrder)    // Update an order
-	orders.DELETE("/:id", ho.DeleteOrder) // Delete an order
+	orders.POST("/", idempotent, ho.CreateOrder) // Create a new order
+	orders.GET("/", ho.GetAllOrders)             // Get all orders
+	orders.GET("/stream", ho.StreamOrders)       // Stream order events
+	orders.GET("/:id", ho.GetOrderByID)          // Get order by ID
+	orders.PUT("/:id", ho.UpdateOrder)           // Replace an order
+	orders.PATCH("/:id", ho.PatchOrder)          // Patch an order
+	orders.DELETE("/:id", ho.DeleteOrder)        // Delete an order
+	orders.POST("/:id/restore", ho.RestoreOrder) // Restore a deleted order
+
+	// Define order status transitions
+	orders.POST("/:id/pay", ho.PayOrder)         // Mark an order as paid
+	orders.POST("/:id/ship", ho.ShipOrder)       // Mark an order as shipped
+	orders.POST("/:id/deliver", ho.DeliverOrder) // Mark an order as delivered
+	orders.POST("/:id/cancel", ho.CancelOrder)   // Cancel an order
+	orders.POST("/:id/refund", ho.RefundOrder)   // Refund an order
 }
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// WarehouseHandler handles HTTP requests for warehouses and their stock.
+type WarehouseHandler struct {
+	warehouseService *usecase.WarehouseService
+}
+
+// NewWarehouseHandler creates a new WarehouseHandler.
+func NewWarehouseHandler(warehouseService *usecase.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{
+		warehouseService: warehouseService,
+	}
+}
+
+// CreateWarehouse godoc
+// @Summary Create a warehouse
+// @Description Add a warehouse products can ship from. Its location is used by the closest fulfilment strategy; its priority, lowest first, by the priority strategy and to break ties.
+// @Tags warehouses
+// @Accept  json
+// @Produce  json
+// @Param warehouse body entity.Warehouse true "Warehouse data"
+// @Success 201 {object} entity.Warehouse
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses [post]
+func (h *WarehouseHandler) CreateWarehouse(c *gin.Context) {
+	var warehouse entity.Warehouse
+	if err := c.ShouldBindJSON(&warehouse); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	createdWarehouse, err := h.warehouseService.CreateWarehouse(c, &warehouse)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWarehouse)
+}
+
+// GetWarehouses godoc
+// @Summary List warehouses
+// @Description Retrieve every warehouse by priority, lowest first.
+// @Tags warehouses
+// @Produce  json
+// @Success 200 {array} entity.Warehouse
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses [get]
+func (h *WarehouseHandler) GetWarehouses(c *gin.Context) {
+	warehouses, err := h.warehouseService.GetWarehouses(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouses)
+}
+
+// GetWarehouseByID godoc
+// @Summary Get a warehouse
+// @Description Retrieve a warehouse by its ID.
+// @Tags warehouses
+// @Produce  json
+// @Param id path string true "Warehouse ID"
+// @Success 200 {object} entity.Warehouse
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses/{id} [get]
+func (h *WarehouseHandler) GetWarehouseByID(c *gin.Context) {
+	warehouse, err := h.warehouseService.GetWarehouseByID(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// UpdateWarehouse godoc
+// @Summary Replace a warehouse
+// @Description Replace a warehouse's name, country, location and priority. Its stock is changed through its stock levels.
+// @Tags warehouses
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Warehouse ID"
+// @Param warehouse body entity.Warehouse true "Warehouse data"
+// @Success 200 {object} entity.Warehouse
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses/{id} [put]
+func (h *WarehouseHandler) UpdateWarehouse(c *gin.Context) {
+	var warehouse entity.Warehouse
+	if err := c.ShouldBindJSON(&warehouse); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	updatedWarehouse, err := h.warehouseService.UpdateWarehouse(c, c.Param("id"), &warehouse)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedWarehouse)
+}
+
+// DeleteWarehouse godoc
+// @Summary Delete a warehouse
+// @Description Delete a warehouse and the stock it keeps, which the products it stocked lose. Fails while pending orders or carts hold any of its stock, or while it is the last warehouse of a product and still keeps some of it.
+// @Tags warehouses
+// @Param id path string true "Warehouse ID"
+// @Success 200 {object} entity.Warehouse
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem "Stock of the warehouse is held, or it keeps the last of a product"
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses/{id} [delete]
+func (h *WarehouseHandler) DeleteWarehouse(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.warehouseService.DeleteWarehouse(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.Warehouse{ID: id})
+}
+
+// GetWarehouseStock godoc
+// @Summary List a warehouse's stock
+// @Description Retrieve the stock levels of every product the warehouse keeps, by product ID.
+// @Tags warehouses
+// @Produce  json
+// @Param id path string true "Warehouse ID"
+// @Success 200 {array} entity.StockLevel
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses/{id}/stock [get]
+func (h *WarehouseHandler) GetWarehouseStock(c *gin.Context) {
+	levels, err := h.warehouseService.GetWarehouseStock(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, levels)
+}
+
+// SetStockLevel godoc
+// @Summary Set a warehouse's stock of a product
+// @Description Set how much of a product the warehouse keeps, stocking the product there if it is not yet. The product's stock becomes the sum over its warehouses. Fails with 409 if the stock would drop below what pending orders and carts hold, or if the product is first stocked in a warehouse while some of its stock is held.
+// @Tags warehouses
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Warehouse ID"
+// @Param product_id path string true "Product ID"
+// @Param level body entity.StockLevel true "Stock level"
+// @Success 200 {object} entity.StockLevel
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem "Stock of the product is held"
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses/{id}/stock/{product_id} [put]
+func (h *WarehouseHandler) SetStockLevel(c *gin.Context) {
+	var level entity.StockLevel
+	if err := c.ShouldBindJSON(&level); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	stored, err := h.warehouseService.SetStockLevel(c, c.Param("id"), c.Param("product_id"), &level)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteStockLevel godoc
+// @Summary Stop a warehouse stocking a product
+// @Description Remove a warehouse's stock level of a product, taking that stock out of the product's. Fails while pending orders or carts hold any of it. A product's last level can only be removed once its stock is 0; the product then keeps stock outside any warehouse again, starting from 0.
+// @Tags warehouses
+// @Param id path string true "Warehouse ID"
+// @Param product_id path string true "Product ID"
+// @Success 200 {object} entity.StockLevel
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem "Stock of the product is held, or the level is its last and not empty"
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /warehouses/{id}/stock/{product_id} [delete]
+func (h *WarehouseHandler) DeleteStockLevel(c *gin.Context) {
+	id, productID := c.Param("id"), c.Param("product_id")
+	if err := h.warehouseService.DeleteStockLevel(c, id, productID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.StockLevel{WarehouseID: id, ProductID: productID})
+}
+
+// GetProductStock godoc
+// @Summary List a product's stock by warehouse
+// @Description Retrieve the stock levels of a product in every warehouse that keeps it, by warehouse ID. Products stocked in no warehouse have none.
+// @Tags warehouses
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Success 200 {array} entity.StockLevel
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /products/{id}/stock [get]
+func (h *WarehouseHandler) GetProductStock(c *gin.Context) {
+	levels, err := h.warehouseService.GetProductStock(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, levels)
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// WebhookHandler handles HTTP requests for webhook subscriptions.
+type WebhookHandler struct {
+	webhookService *usecase.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler.
+func NewWebhookHandler(webhookService *usecase.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Subscribe a URL to events. Each event of the listed types is POSTed to the URL as JSON, with X-Webhook-ID, X-Delivery-ID, X-Event-ID and X-Event-Type headers and an X-Webhook-Signature of the form t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed by the secret>. Responses other than 2xx, redirects included, are retried with exponential backoff. The URL must resolve to public internet addresses only; it is checked again on every attempt. Order events only reach webhooks whose owner may read the order. The secret is never returned.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param webhook body entity.Webhook true "Webhook data; secret is required, 16 to 200 characters"
+// @Success 201 {object} entity.Webhook
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var webhook entity.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	createdWebhook, err := h.webhookService.CreateWebhook(c, &webhook)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWebhook)
+}
+
+// GetWebhooks godoc
+// @Summary List webhooks
+// @Description Retrieve one page of the webhooks the caller registered, newest first by default; admins see every webhook. Pass next_cursor back as cursor to fetch the following page.
+// @Tags webhooks
+// @Produce  json
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at; prefix with - for descending (default -created_at)"
+// @Success 200 {object} entity.WebhookPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhooks, err := h.webhookService.GetWebhooks(c, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhookByID godoc
+// @Summary Get a webhook
+// @Description Retrieve a webhook the caller registered; admins may retrieve any.
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} entity.Webhook
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhookByID(c *gin.Context) {
+	webhook, err := h.webhookService.GetWebhookByID(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary Replace a webhook
+// @Description Replace a webhook's URL and event types. Leave the secret out to keep the current one. The URL must resolve to public internet addresses only.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param webhook body entity.Webhook true "Webhook data"
+// @Success 200 {object} entity.Webhook
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var webhook entity.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	updatedWebhook, err := h.webhookService.UpdateWebhook(c, c.Param("id"), &webhook)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedWebhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook together with its deliveries. Deliveries still pending are dropped.
+// @Tags webhooks
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} entity.Webhook
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.webhookService.DeleteWebhook(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.Webhook{ID: id})
+}
+
+// GetDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Retrieve one page of the deliveries to a webhook with their attempt logs, newest first by default. Pass next_cursor back as cursor to fetch the following page.
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Page size, 1 to 100 (default 20)"
+// @Param cursor query string false "Cursor returned with the previous page"
+// @Param sort query string false "Sort field: created_at; prefix with - for descending (default -created_at)"
+// @Param status query string false "Only deliveries with this status: pending, succeeded or failed"
+// @Success 200 {object} entity.WebhookDeliveryPage
+// @Failure 400 {object} entity.Problem
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	status := entity.WebhookDeliveryStatus(c.Query("status"))
+
+	deliveries, err := h.webhookService.GetDeliveries(c, c.Param("id"), status, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery godoc
+// @Summary Get a webhook delivery
+// @Description Retrieve a delivery to a webhook with the body sent and its attempt log.
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 200 {object} entity.WebhookDelivery
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries/{delivery_id} [get]
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	delivery, err := h.webhookService.GetDelivery(c, c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Send a delivery that succeeded or failed again, with a fresh set of attempts. The same body is sent, with a new signature.
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} entity.WebhookDelivery
+// @Failure 401 {object} entity.Problem
+// @Failure 403 {object} entity.Problem
+// @Failure 404 {object} entity.Problem
+// @Failure 409 {object} entity.Problem "The delivery is still pending"
+// @Failure 500 {object} entity.Problem
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	delivery, err := h.webhookService.Redeliver(c, c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditActionCreate  AuditAction = "create"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+)
+
+// AuditEntry records one change made to a product or order: who made it, in
+// which request, and how each field changed. Entries are only ever appended,
+// never updated or removed. ActorID is empty for changes made without an
+// authenticated actor.
+type AuditEntry struct {
+	ID         string                 `json:"id" bson:"id" db:"id"`
+	ActorID    string                 `json:"actor_id" bson:"actor_id" db:"actor_id"`
+	ActorRole  Role                   `json:"actor_role,omitempty" bson:"actor_role,omitempty" db:"actor_role"`
+	Action     AuditAction            `json:"action" bson:"action" db:"action"`
+	EntityType string                 `json:"entity_type" bson:"entity_type" db:"entity_type"`
+	EntityID   string                 `json:"entity_id" bson:"entity_id" db:"entity_id"`
+	Changes    map[string]FieldChange `json:"changes" bson:"changes" db:"-"`
+	RequestID  string                 `json:"request_id,omitempty" bson:"request_id,omitempty" db:"request_id"`
+	CreatedAt  time.Time              `json:"created_at" bson:"created_at" db:"created_at"`
+}
+
+// FieldChange holds the JSON value of one field before and after a change.
+// Before is absent for fields a change set from nothing, After for fields it
+// cleared.
+type FieldChange struct {
+	Before json.RawMessage `json:"before,omitempty" bson:"before,omitempty" swaggertype:"object"`
+	After  json.RawMessage `json:"after,omitempty" bson:"after,omitempty" swaggertype:"object"`
+}
+
+type AuditPage struct {
+	Data       []AuditEntry `json:"data"`
+	Pagination Pagination   `json:"pagination"`
+}
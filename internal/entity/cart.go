@@ -0,0 +1,61 @@
+package entity
+
+import "time"
+
+// CartIssueCode names the way a cart line stopped matching the catalog.
+type CartIssueCode string
+
+const (
+	// CartIssuePriceChanged reports a product whose price differs from the one
+	// the line was added at.
+	CartIssuePriceChanged CartIssueCode = "price_changed"
+	// CartIssueInsufficientStock reports a product that no longer holds enough
+	// stock for the line.
+	CartIssueInsufficientStock CartIssueCode = "insufficient_stock"
+	// CartIssueProductUnavailable reports a product that was deleted.
+	CartIssueProductUnavailable CartIssueCode = "product_unavailable"
+)
+
+// Cart collects the products a user means to order. Carts belong to the user
+// who created them and expire once left alone for the cart TTL; every change
+// pushes ExpiresAt back and holds the stock of every line afresh. Total and
+// Issues are worked out against the catalog whenever the cart is read and are
+// never stored.
+type Cart struct {
+	ID        string      `json:"id" bson:"id,omitempty" db:"id" readonly:"true"`
+	UserID    string      `json:"user_id" bson:"user_id" db:"user_id" readonly:"true"`
+	Items     []CartItem  `json:"items" bson:"items" db:"-" binding:"max=100,dive"`
+	Total     float64     `json:"total" bson:"-" db:"-" readonly:"true"`
+	Issues    []CartIssue `json:"issues" bson:"-" db:"-" readonly:"true"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at" db:"created_at" readonly:"true"`
+	UpdatedAt time.Time   `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+	ExpiresAt time.Time   `json:"expires_at" bson:"expires_at" db:"expires_at" readonly:"true"`
+}
+
+// CartItem is one line of a cart. UnitPrice is the product's price when the
+// line was added or last changed; CurrentPrice and LineTotal follow the
+// catalog.
+type CartItem struct {
+	ProductID    string  `json:"product_id" bson:"product_id" binding:"required,notblank"`
+	Quantity     int     `json:"quantity" bson:"quantity" binding:"gte=1,lte=10000"`
+	UnitPrice    float64 `json:"unit_price" bson:"unit_price" readonly:"true"`
+	CurrentPrice float64 `json:"current_price" bson:"-" readonly:"true"`
+	LineTotal    float64 `json:"line_total" bson:"-" readonly:"true"`
+}
+
+// CartQuantity is the request body that sets the quantity of a cart line.
+type CartQuantity struct {
+	Quantity int `json:"quantity" binding:"gte=1,lte=10000"`
+}
+
+// CartIssue reports a cart line that no longer matches the catalog.
+type CartIssue struct {
+	ProductID string        `json:"product_id" example:"42"`
+	Code      CartIssueCode `json:"code" example:"price_changed"`
+	Message   string        `json:"message" example:"price changed from 10.00 to 12.50"`
+}
+
+// Checkout is the request body that turns a cart into an order.
+type Checkout struct {
+	CustomerID string `json:"customer_id" binding:"required,notblank"`
+}
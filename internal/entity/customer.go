@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+// CustomerStatus decides whether a customer may place orders.
+type CustomerStatus string
+
+const (
+	CustomerStatusActive   CustomerStatus = "active"
+	CustomerStatusInactive CustomerStatus = "inactive"
+)
+
+// Customer is the person or business orders are placed for. UserID links the
+// customer to the account that created it; customers created by staff belong
+// to no account. Only staff may change Status.
+type Customer struct {
+	ID        string         `json:"id" bson:"id,omitempty" db:"id" readonly:"true"`
+	UserID    string         `json:"user_id,omitempty" bson:"user_id,omitempty" db:"user_id" readonly:"true"`
+	Name      string         `json:"name" bson:"name" db:"name" binding:"required,notblank,max=200"`
+	Email     string         `json:"email" bson:"email" db:"email" binding:"required,email,max=254"`
+	Phone     string         `json:"phone,omitempty" bson:"phone,omitempty" db:"phone" binding:"omitempty,e164"`
+	Addresses []Address      `json:"addresses" bson:"addresses" db:"-" binding:"max=10,dive"`
+	Status    CustomerStatus `json:"status" bson:"status" db:"status" binding:"omitempty,oneof=active inactive"`
+	CreatedAt time.Time      `json:"created_at" bson:"created_at" db:"created_at" readonly:"true"`
+	UpdatedAt time.Time      `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+}
+
+// Address is a postal address of a customer. Label tells a customer's
+// addresses apart, such as "billing" or "shipping". Latitude and Longitude are
+// optional; orders shipped to an address that has them go to the closest
+// warehouse under the closest-warehouse strategy.
+type Address struct {
+	Label      string   `json:"label,omitempty" bson:"label,omitempty" binding:"max=50"`
+	Line1      string   `json:"line1" bson:"line1" binding:"required,notblank,max=200"`
+	Line2      string   `json:"line2,omitempty" bson:"line2,omitempty" binding:"max=200"`
+	City       string   `json:"city" bson:"city" binding:"required,notblank,max=100"`
+	Region     string   `json:"region,omitempty" bson:"region,omitempty" binding:"max=100"`
+	PostalCode string   `json:"postal_code" bson:"postal_code" binding:"required,notblank,max=20"`
+	Country    string   `json:"country" bson:"country" binding:"required,iso3166_1_alpha2"`
+	Latitude   *float64 `json:"latitude,omitempty" bson:"latitude,omitempty" binding:"required_with=Longitude,omitempty,gte=-90,lte=90"`
+	Longitude  *float64 `json:"longitude,omitempty" bson:"longitude,omitempty" binding:"required_with=Latitude,omitempty,gte=-180,lte=180"`
+}
+
+type CustomerPage struct {
+	Data       []Customer `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
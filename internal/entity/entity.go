@@ -2,24 +2,114 @@ package entity
 
 import "time"
 
+// Product is a catalog entry. ID, Reserved, Version, CreatedAt, UpdatedAt and
+// DeletedAt are managed by the server and ignored in requests. Reserved is the
+// part of Stock held for pending orders and carts; only Stock - Reserved is
+// available to new ones. Once the product is stocked in a warehouse, Stock is
+// the sum of its warehouse stock levels and ignored in requests too. Version
+// starts at 1 and goes up with every write, stock and reservation changes
+// included. DeletedAt is set while the product is deleted but not yet purged.
 type Product struct {
-	ID        string    `json:"id" bson:"id,omitempty"`
-	Name      string    `json:"name" bson:"name"`
-	Price     float64   `json:"price" bson:"price"`
-	Stock     int       `json:"stock" bson:"stock"`
-	Category  string    `json:"category" bson:"category"`
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	ID        string     `json:"id" bson:"id,omitempty" db:"id" readonly:"true"`
+	Name      string     `json:"name" bson:"name" db:"name" binding:"required,notblank,max=200"`
+	Price     float64    `json:"price" bson:"price" db:"price" binding:"gt=0,cents"`
+	Stock     int        `json:"stock" bson:"stock" db:"stock" binding:"gte=0"`
+	Reserved  int        `json:"reserved" bson:"reserved" db:"reserved" readonly:"true"`
+	Category  string     `json:"category" bson:"category" db:"category" binding:"max=100"`
+	Version   int64      `json:"version" bson:"version" db:"version" readonly:"true"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at" db:"created_at" readonly:"true"`
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty" db:"deleted_at" readonly:"true"`
 }
+type ProductMatch struct {
+	Product
+	Score float64 `json:"score"`
+}
+type ProductSearchResult struct {
+	Query string         `json:"query"`
+	Data  []ProductMatch `json:"data"`
+}
+
+// Order is a customer's purchase. Only the customer, which is fixed when the
+// order is placed, and the product and quantity of each line are taken from
+// requests; every other field is managed by the server.
+// StockHeld is set while the order's quantities are held as stock
+// reservations rather than taken out of stock, which happens once it is paid.
+// Version starts at 1 and goes up with every write. DeletedAt is set while the
+// order is deleted but not yet purged.
 type Order struct {
-	ID         string    `json:"id" bson:"id,omitempty"`
-	ProductID  string    `json:"product_id" bson:"product_id"`
-	Quantity   int       `json:"quantity" bson:"quantity"`
-	TotalPrice float64   `json:"total_price" bson:"total_price"`
-	Status     string    `json:"status" bson:"status"`
-	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
-}
-type Error struct {
-	Message string `json:"message"`
+	ID            string         `json:"id" bson:"id,omitempty" db:"id" readonly:"true"`
+	UserID        string         `json:"user_id" bson:"user_id,omitempty" db:"user_id" readonly:"true"`
+	CustomerID    string         `json:"customer_id,omitempty" bson:"customer_id,omitempty" db:"customer_id"`
+	Items         []OrderItem    `json:"items" bson:"items" db:"-" binding:"required,min=1,max=100,dive"`
+	TotalPrice    float64        `json:"total_price" bson:"total_price" db:"total_price" readonly:"true"`
+	Status        OrderStatus    `json:"status" bson:"status" db:"status" readonly:"true"`
+	StatusHistory []StatusChange `json:"status_history" bson:"status_history" db:"-" readonly:"true"`
+	StockReleased bool           `json:"stock_released" bson:"stock_released" db:"stock_released" readonly:"true"`
+	StockHeld     bool           `json:"stock_held" bson:"stock_held" db:"stock_held" readonly:"true"`
+	Version       int64          `json:"version" bson:"version" db:"version" readonly:"true"`
+	CreatedAt     time.Time      `json:"created_at" bson:"created_at" db:"created_at" readonly:"true"`
+	UpdatedAt     time.Time      `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+	DeletedAt     *time.Time     `json:"deleted_at,omitempty" bson:"deleted_at,omitempty" db:"deleted_at" readonly:"true"`
+}
+
+// OrderItem is one line of an order. Allocations tell which warehouses
+// fulfil the line; an order no single warehouse can cover is split across
+// several. Lines of products stocked in no warehouse have none.
+type OrderItem struct {
+	ProductID   string       `json:"product_id" bson:"product_id" binding:"required,notblank"`
+	Quantity    int          `json:"quantity" bson:"quantity" binding:"gte=1,lte=10000"`
+	UnitPrice   float64      `json:"unit_price" bson:"unit_price" readonly:"true"`
+	LineTotal   float64      `json:"line_total" bson:"line_total" readonly:"true"`
+	Allocations []Allocation `json:"allocations,omitempty" bson:"allocations,omitempty" readonly:"true"`
+}
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "Pending"
+	OrderStatusPaid      OrderStatus = "Paid"
+	OrderStatusShipped   OrderStatus = "Shipped"
+	OrderStatusDelivered OrderStatus = "Delivered"
+	OrderStatusCancelled OrderStatus = "Cancelled"
+	OrderStatusRefunded  OrderStatus = "Refunded"
+)
+
+type StatusChange struct {
+	From OrderStatus `json:"from,omitempty" bson:"from,omitempty"`
+	To   OrderStatus `json:"to" bson:"to"`
+	At   time.Time   `json:"at" bson:"at"`
+}
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+type ProductPage struct {
+	Data       []Product  `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+type OrderPage struct {
+	Data       []Order    `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// Problem is an RFC 7807 problem details error response, extended with a
+// machine-readable code, the request ID, per-field validation errors and the
+// cart lines that changed before a checkout.
+type Problem struct {
+	Type      string       `json:"type" example:"urn:ulab3:problem:insufficient_stock"`
+	Title     string       `json:"title" example:"Unprocessable Entity"`
+	Status    int          `json:"status" example:"422"`
+	Detail    string       `json:"detail,omitempty" example:"product 42: insufficient stock"`
+	Instance  string       `json:"instance,omitempty" example:"/orders"`
+	Code      string       `json:"code" example:"insufficient_stock"`
+	RequestID string       `json:"request_id" example:"0b6f3f8e-8f0c-4a43-9d38-7b1f0c5c2d1e"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Issues    []CartIssue  `json:"issues,omitempty"`
+}
+
+// FieldError describes why one input field was rejected.
+type FieldError struct {
+	Field   string `json:"field" example:"price"`
+	Message string `json:"message" example:"must be greater than 0"`
 }
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType names a kind of domain event other services can react to.
+type EventType string
+
+const (
+	EventOrderCreated        EventType = "OrderCreated"
+	EventOrderStatusChanged  EventType = "OrderStatusChanged"
+	EventOrderCancelled      EventType = "OrderCancelled"
+	EventStockChanged        EventType = "StockChanged"
+	EventProductPriceChanged EventType = "ProductPriceChanged"
+)
+
+// EventStatus tracks the delivery of an event from the outbox.
+type EventStatus string
+
+const (
+	// EventStatusPending events wait for their next delivery attempt.
+	EventStatusPending EventStatus = "pending"
+	// EventStatusDelivered events reached every sink.
+	EventStatusDelivered EventStatus = "delivered"
+	// EventStatusDead events ran out of attempts and wait for an operator.
+	EventStatusDead EventStatus = "dead"
+)
+
+// Event is a domain event recorded in the outbox together with the change
+// that raised it. Payload holds the event's JSON body; the remaining fields
+// after CreatedAt track its delivery.
+type Event struct {
+	ID            string          `json:"id" bson:"id" db:"id"`
+	Type          EventType       `json:"type" bson:"type" db:"type"`
+	AggregateType string          `json:"aggregate_type" bson:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" bson:"aggregate_id" db:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" bson:"payload" db:"-" swaggertype:"object"`
+	RequestID     string          `json:"request_id,omitempty" bson:"request_id,omitempty" db:"request_id"`
+	CreatedAt     time.Time       `json:"created_at" bson:"created_at" db:"created_at"`
+	Status        EventStatus     `json:"status" bson:"status" db:"status"`
+	Attempts      int             `json:"attempts" bson:"attempts" db:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" bson:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty" bson:"last_error,omitempty" db:"last_error"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty" bson:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// EventMessage is the body event sinks outside the service receive: the event
+// without its delivery state.
+type EventMessage struct {
+	ID            string          `json:"id"`
+	Type          EventType       `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	RequestID     string          `json:"request_id,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// OrderStatusChange is the payload of an OrderStatusChanged event. Items are
+// the order's lines, so that consumers can tell which products it concerns.
+type OrderStatusChange struct {
+	OrderID string      `json:"order_id"`
+	UserID  string      `json:"user_id"`
+	From    OrderStatus `json:"from"`
+	To      OrderStatus `json:"to"`
+	At      time.Time   `json:"at"`
+	Items   []OrderItem `json:"items"`
+}
+
+// StockChange is the payload of a StockChanged event. Stock is the level the
+// change left the product at.
+type StockChange struct {
+	ProductID string `json:"product_id"`
+	Delta     int    `json:"delta"`
+	Stock     int    `json:"stock"`
+}
+
+// PriceChange is the payload of a ProductPriceChanged event.
+type PriceChange struct {
+	ProductID string  `json:"product_id"`
+	OldPrice  float64 `json:"old_price"`
+	NewPrice  float64 `json:"new_price"`
+}
+
+type EventPage struct {
+	Data       []Event    `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
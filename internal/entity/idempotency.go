@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// IdempotencyRecord remembers the response to a request sent with an
+// idempotency key, so that a retry of the request gets the same response.
+// Status is 0 while the first request is still being handled.
+type IdempotencyRecord struct {
+	Key         string    `json:"key" bson:"id" db:"id"`
+	Fingerprint string    `json:"fingerprint" bson:"fingerprint" db:"fingerprint"`
+	Status      int       `json:"status" bson:"status" db:"status"`
+	ContentType string    `json:"content_type" bson:"content_type" db:"content_type"`
+	Body        []byte    `json:"body" bson:"body" db:"body"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at" db:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" bson:"expires_at" db:"expires_at"`
+}
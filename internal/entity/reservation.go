@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// HolderType names what a stock reservation is held for.
+type HolderType string
+
+const (
+	HolderOrder HolderType = "order"
+	HolderCart  HolderType = "cart"
+)
+
+// Reservation holds Quantity of a product's stock for a pending order or a
+// cart until ExpiresAt, after which the sweeper lets go of it. Paying the order
+// turns the hold into a real decrement of the stock. WarehouseID names the
+// warehouse whose stock is held; it is empty for products stocked in none.
+type Reservation struct {
+	ID          string     `json:"id" bson:"id,omitempty" db:"id"`
+	ProductID   string     `json:"product_id" bson:"product_id" db:"product_id"`
+	Quantity    int        `json:"quantity" bson:"quantity" db:"quantity"`
+	HolderType  HolderType `json:"holder_type" bson:"holder_type" db:"holder_type"`
+	HolderID    string     `json:"holder_id" bson:"holder_id" db:"holder_id"`
+	WarehouseID string     `json:"warehouse_id,omitempty" bson:"warehouse_id,omitempty" db:"warehouse_id"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at" db:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at" bson:"expires_at" db:"expires_at"`
+}
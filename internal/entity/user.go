@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// Role decides which operations a user may perform.
+type Role string
+
+const (
+	RoleAdmin          Role = "admin"
+	RoleCatalogManager Role = "catalog-manager"
+	RoleCustomer       Role = "customer"
+	RoleSupport        Role = "support"
+)
+
+type User struct {
+	ID           string    `json:"id" bson:"id,omitempty" db:"id"`
+	Email        string    `json:"email" bson:"email" db:"email"`
+	PasswordHash string    `json:"-" bson:"password_hash" db:"password_hash"`
+	Role         Role      `json:"role" bson:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at" db:"updated_at"`
+}
+
+// RefreshToken is the server-side record of an issued refresh token. The
+// token itself is never stored; ID is the token's unique ID claim.
+type RefreshToken struct {
+	ID        string     `json:"id" bson:"id" db:"id"`
+	UserID    string     `json:"user_id" bson:"user_id" db:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at" db:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at" db:"created_at"`
+}
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+type RoleRequest struct {
+	Role Role `json:"role"`
+}
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
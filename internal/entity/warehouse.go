@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// Warehouse is a place products ship from. Latitude and Longitude locate it
+// for the closest-warehouse fulfilment strategy; Priority ranks it for the
+// priority strategy, lowest first, and breaks ties for the others.
+type Warehouse struct {
+	ID        string    `json:"id" bson:"id,omitempty" db:"id" readonly:"true"`
+	Name      string    `json:"name" bson:"name" db:"name" binding:"required,notblank,max=200"`
+	Country   string    `json:"country" bson:"country" db:"country" binding:"required,iso3166_1_alpha2"`
+	Latitude  float64   `json:"latitude" bson:"latitude" db:"latitude" binding:"gte=-90,lte=90"`
+	Longitude float64   `json:"longitude" bson:"longitude" db:"longitude" binding:"gte=-180,lte=180"`
+	Priority  int       `json:"priority" bson:"priority" db:"priority" binding:"gte=0"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at" db:"created_at" readonly:"true"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+}
+
+// StockLevel is how much of a product one warehouse keeps. Reserved is the
+// part of Stock held for pending orders and carts. Once a product is stocked
+// in any warehouse, its Stock and Reserved are the sums over its stock levels.
+type StockLevel struct {
+	WarehouseID string    `json:"warehouse_id" bson:"warehouse_id" db:"warehouse_id" readonly:"true"`
+	ProductID   string    `json:"product_id" bson:"product_id" db:"product_id" readonly:"true"`
+	Stock       int       `json:"stock" bson:"stock" db:"stock" binding:"gte=0"`
+	Reserved    int       `json:"reserved" bson:"reserved" db:"reserved" readonly:"true"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+}
+
+// Allocation is the part of an order line that one warehouse fulfils.
+type Allocation struct {
+	WarehouseID string `json:"warehouse_id" bson:"warehouse_id"`
+	Quantity    int    `json:"quantity" bson:"quantity"`
+}
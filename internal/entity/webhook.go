@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a subscription that has the events of the listed types POSTed to
+// URL, signed with Secret. The secret is write-only: it is taken from
+// requests but never returned. Owners who may not read every order only
+// receive the order events about their own orders.
+type Webhook struct {
+	ID         string      `json:"id" bson:"id,omitempty" db:"id" readonly:"true"`
+	OwnerID    string      `json:"owner_id" bson:"owner_id" db:"owner_id" readonly:"true"`
+	URL        string      `json:"url" bson:"url" db:"url" binding:"required,http_url,max=2000"`
+	EventTypes []EventType `json:"event_types" bson:"event_types" db:"-" binding:"required,min=1,max=10,unique,dive,oneof=OrderCreated OrderStatusChanged OrderCancelled StockChanged ProductPriceChanged"`
+	Secret     string      `json:"secret,omitempty" bson:"secret" db:"secret" binding:"omitempty,min=16,max=200"`
+	CreatedAt  time.Time   `json:"created_at" bson:"created_at" db:"created_at" readonly:"true"`
+	UpdatedAt  time.Time   `json:"updated_at" bson:"updated_at" db:"updated_at" readonly:"true"`
+}
+
+// WebhookDeliveryStatus tracks the delivery of an event to one webhook.
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries wait for their next attempt.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded deliveries got a 2xx response.
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed deliveries ran out of attempts, or their webhook
+	// was deleted. They can be redelivered by hand.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the delivery of one event to one webhook. Body is the
+// exact JSON POSTed on every attempt; Attempts logs the most recent attempts,
+// oldest first.
+type WebhookDelivery struct {
+	ID            string                `json:"id" bson:"id" db:"id"`
+	WebhookID     string                `json:"webhook_id" bson:"webhook_id" db:"webhook_id"`
+	EventID       string                `json:"event_id" bson:"event_id" db:"event_id"`
+	EventType     EventType             `json:"event_type" bson:"event_type" db:"event_type"`
+	Body          json.RawMessage       `json:"body" bson:"body" db:"-" swaggertype:"object"`
+	Status        WebhookDeliveryStatus `json:"status" bson:"status" db:"status"`
+	AttemptCount  int                   `json:"attempt_count" bson:"attempt_count" db:"attempt_count"`
+	Attempts      []DeliveryAttempt     `json:"attempts" bson:"attempts" db:"-"`
+	NextAttemptAt time.Time             `json:"next_attempt_at" bson:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time             `json:"created_at" bson:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty" bson:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// DeliveryAttempt records one POST of a webhook delivery. StatusCode is
+// absent when no response came back.
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+type WebhookPage struct {
+	Data       []Webhook  `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+type WebhookDeliveryPage struct {
+	Data       []WebhookDelivery `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/pkg/requestid"
+)
+
+// Record types named in audit entries and events.
+const (
+	entityProduct = "product"
+	entityOrder   = "order"
+)
+
+// unauditedFields change with every write and would only clutter the diffs;
+// the entry itself records when and how the record moved on.
+var unauditedFields = []string{"version", "updated_at", "status_history"}
+
+// AuditService reads the audit trail the product and order services write.
+type AuditService struct {
+	auditRepo AuditRepository
+	logger    *slog.Logger
+}
+
+func NewAuditService(auditRepo AuditRepository, logger *slog.Logger) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// GetAuditLog lists audit entries, newest first unless the page asks for
+// another order.
+func (s *AuditService) GetAuditLog(ctx context.Context, filter AuditFilter, page PageRequest) (*entity.AuditPage, error) {
+	if page.Sort == "" {
+		page.Sort = "-created_at"
+	}
+	return s.list(ctx, filter, page)
+}
+
+// GetProductHistory lists the changes made to one product, oldest first
+// unless the page asks for another order. Deleted and purged products keep
+// their history.
+func (s *AuditService) GetProductHistory(ctx context.Context, id string, page PageRequest) (*entity.AuditPage, error) {
+	return s.list(ctx, AuditFilter{EntityType: entityProduct, EntityID: id}, page)
+}
+
+func (s *AuditService) list(ctx context.Context, filter AuditFilter, page PageRequest) (*entity.AuditPage, error) {
+	if _, err := authorize(ctx, PermReadAudit); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching audit entries", "entity_type", filter.EntityType, "entity_id", filter.EntityID,
+		"actor_id", filter.ActorID, "sort", page.Sort, "limit", page.Limit)
+
+	sort, after, limit, err := parsePage(page, auditSortFields)
+	if err != nil {
+		return nil, err
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidQuery)
+	}
+
+	// Ask for one extra record to learn whether another page follows
+	query := AuditQuery{AuditFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	entries, err := s.auditRepo.FindAll(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to fetch audit entries", "error", err)
+		return nil, fmt.Errorf("failed to fetch audit entries: %w", err)
+	}
+
+	data, pagination := paginate(entries, limit, sort, auditSortFields, func(e entity.AuditEntry) string { return e.ID })
+	return &entity.AuditPage{Data: data, Pagination: pagination}, nil
+}
+
+// audit appends the entry for a change to the record of the type with the ID.
+// before is nil for created records. Callers run it in the transaction that
+// made the change, so that the change is stored only together with its entry.
+func audit(ctx context.Context, repo AuditRepository, action entity.AuditAction, entityType, id string, before, after any) error {
+	changes, err := diff(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s %s: %w", entityType, id, err)
+	}
+
+	actor, _ := ActorFrom(ctx)
+	entry := &entity.AuditEntry{
+		ActorID:    actor.UserID,
+		ActorRole:  actor.Role,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   id,
+		Changes:    changes,
+		RequestID:  requestid.From(ctx),
+		CreatedAt:  time.Now(),
+	}
+	if err := repo.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return nil
+}
+
+// diff compares the JSON fields of two versions of a record. A nil version
+// has no fields.
+func diff(before, after any) (map[string]entity.FieldChange, error) {
+	old, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	current, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]entity.FieldChange)
+	for name, value := range old {
+		if !bytes.Equal(value, current[name]) {
+			changes[name] = entity.FieldChange{Before: value, After: current[name]}
+		}
+	}
+	for name, value := range current {
+		if _, ok := old[name]; !ok {
+			changes[name] = entity.FieldChange{After: value}
+		}
+	}
+	for _, name := range unauditedFields {
+		delete(changes, name)
+	}
+	return changes, nil
+}
+
+func jsonFields(record any) (map[string]json.RawMessage, error) {
+	if record == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"strings"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/pkg/token"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after the first 72 bytes
+	maxPasswordLength = 72
+)
+
+type AuthService struct {
+	userRepo  UserRepository
+	tokenRepo RefreshTokenRepository
+	tx        Transactor
+	tokens    *token.Manager
+	logger    *slog.Logger
+}
+
+func NewAuthService(userRepo UserRepository, tokenRepo RefreshTokenRepository, tx Transactor, tokens *token.Manager, logger *slog.Logger) *AuthService {
+	return &AuthService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		tx:        tx,
+		tokens:    tokens,
+		logger:    logger,
+	}
+}
+
+// Register creates a customer account. Other roles are granted by an admin
+// through AssignRole.
+func (s *AuthService) Register(ctx context.Context, credentials entity.Credentials) (*entity.User, error) {
+	return s.createUser(ctx, credentials, entity.RoleCustomer)
+}
+
+// EnsureAdmin makes sure the user with the given email exists and is an admin,
+// creating it with the given password if needed. It bootstraps the first admin
+// at startup; the password of an existing user is left unchanged.
+func (s *AuthService) EnsureAdmin(ctx context.Context, credentials entity.Credentials) error {
+	user, err := s.userRepo.FindByEmail(ctx, normalizeEmail(credentials.Email))
+	if errors.Is(err, ErrNotFound) {
+		_, err = s.createUser(ctx, credentials, entity.RoleAdmin)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user.Role == entity.RoleAdmin {
+		return nil
+	}
+
+	s.logger.Info("Promoting user to admin", "id", user.ID)
+	if err := s.userRepo.UpdateRole(ctx, user.ID, entity.RoleAdmin, time.Now()); err != nil {
+		s.logger.Error("Failed to update user role", "id", user.ID, "error", err)
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	return nil
+}
+
+// AssignRole changes a user's role. Only admins may call it, and an admin
+// cannot change their own role so the last admin cannot lock everyone out.
+func (s *AuthService) AssignRole(ctx context.Context, id string, role entity.Role) (*entity.User, error) {
+	actor, err := authorize(ctx, PermManageUsers)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Assigning role", "id", id, "role", role)
+
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrValidation, role)
+	}
+	if id == actor.UserID {
+		return nil, fmt.Errorf("%w: admins cannot change their own role", ErrValidation)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("User not found", "id", id, "error", err)
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.UpdateRole(ctx, id, role, user.UpdatedAt); err != nil {
+		s.logger.Error("Failed to update user role", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	s.logger.Info("Role assigned successfully", "id", id, "role", role)
+	return user, nil
+}
+
+func (s *AuthService) createUser(ctx context.Context, credentials entity.Credentials, role entity.Role) (*entity.User, error) {
+	email := normalizeEmail(credentials.Email)
+	s.logger.Info("Registering user", "email", email, "role", role)
+
+	if !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("%w: email is not valid", ErrValidation)
+	}
+	if n := len(credentials.Password); n < minPasswordLength || n > maxPasswordLength {
+		return nil, fmt.Errorf("%w: password must be %d to %d characters long", ErrValidation, minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Error("Failed to hash password", "error", err)
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now()
+	user, err := s.userRepo.Create(ctx, &entity.User{
+		Email:        email,
+		PasswordHash: string(hash),
+		Role:         role,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			return nil, err
+		}
+		s.logger.Error("Failed to create user", "error", err)
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	s.logger.Info("User registered successfully", "id", user.ID)
+	return user, nil
+}
+
+func (s *AuthService) Login(ctx context.Context, credentials entity.Credentials) (*entity.TokenPair, error) {
+	email := normalizeEmail(credentials.Email)
+	s.logger.Info("Logging in", "email", email)
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Info("Login for unknown email", "email", email)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch user", "error", err)
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password)); err != nil {
+		s.logger.Info("Login with wrong password", "id", user.ID)
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issueTokens(ctx, user.ID)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// is revoked, so each refresh token can be used once.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*entity.TokenPair, error) {
+	claims, err := s.tokens.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	s.logger.Info("Refreshing tokens", "user_id", claims.Subject)
+
+	var pair *entity.TokenPair
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.revoke(ctx, claims.ID, claims.Subject); err != nil {
+			return err
+		}
+		pair, err = s.issueTokens(ctx, claims.Subject)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Logout revokes the refresh token. Access tokens already issued stay valid
+// until they expire.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.tokens.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	s.logger.Info("Logging out", "user_id", claims.Subject)
+
+	return s.revoke(ctx, claims.ID, claims.Subject)
+}
+
+// Authenticate verifies an access token and returns the actor it was issued
+// to. The role is read from the user record, so role changes apply at once.
+func (s *AuthService) Authenticate(ctx context.Context, accessToken string) (Actor, error) {
+	claims, err := s.tokens.ParseAccessToken(accessToken)
+	if err != nil {
+		return Actor{}, ErrInvalidToken
+	}
+	user, err := s.userRepo.FindByID(ctx, claims.Subject)
+	if errors.Is(err, ErrNotFound) {
+		return Actor{}, ErrInvalidToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch user", "error", err)
+		return Actor{}, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	role := user.Role
+	if role == "" {
+		// Users registered before roles existed are customers
+		role = entity.RoleCustomer
+	}
+	return Actor{UserID: user.ID, Role: role}, nil
+}
+
+func (s *AuthService) revoke(ctx context.Context, id, userID string) error {
+	stored, err := s.tokenRepo.FindByID(ctx, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		s.logger.Error("Failed to fetch refresh token", "error", err)
+		return fmt.Errorf("failed to fetch refresh token: %w", err)
+	}
+	if err != nil || stored.UserID != userID || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidToken
+	}
+
+	revoked, err := s.tokenRepo.Revoke(ctx, id, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh token", "error", err)
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !revoked {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, userID string) (*entity.TokenPair, error) {
+	access, err := s.tokens.NewAccessToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.tokens.NewRefreshToken(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tokenRepo.Create(ctx, &entity.RefreshToken{
+		ID:        refresh.ID,
+		UserID:    userID,
+		ExpiresAt: refresh.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to store refresh token", "error", err)
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &entity.TokenPair{
+		AccessToken:  access.Value,
+		RefreshToken: refresh.Value,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokens.AccessTTL().Seconds()),
+	}, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,135 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+	"ulab3/pkg/token"
+)
+
+func newAuthService(tokens *token.Manager) *usecase.AuthService {
+	repos := memory.NewRepositories()
+	return usecase.NewAuthService(repos.Users, repos.RefreshTokens, repos.Transactor, tokens,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRegisterPasswordLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		ok       bool
+	}{
+		{"empty", "", false},
+		{"7 bytes", strings.Repeat("a", 7), false},
+		{"8 bytes", strings.Repeat("a", 8), true},
+		{"72 bytes", strings.Repeat("a", 72), true},
+		{"73 bytes", strings.Repeat("a", 73), false},
+		// Length counts bytes: 37 two-byte runes are 74 bytes
+		{"74 bytes in 37 runes", strings.Repeat("é", 37), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newAuthService(token.NewManager("access", "refresh", time.Minute, time.Hour))
+			user, err := auth.Register(context.Background(), entity.Credentials{Email: "user@example.com", Password: tt.password})
+			if tt.ok && err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, usecase.ErrValidation) {
+				t.Fatalf("Register = %v, want ErrValidation", err)
+			}
+			if tt.ok && user.Role != entity.RoleCustomer {
+				t.Errorf("registered user is %s, want customer", user.Role)
+			}
+		})
+	}
+}
+
+func TestLoginRefreshLogout(t *testing.T) {
+	ctx := context.Background()
+	auth := newAuthService(token.NewManager("access", "refresh", time.Minute, time.Hour))
+	credentials := entity.Credentials{Email: "User@Example.com", Password: "correct horse"}
+	user, err := auth.Register(ctx, credentials)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := auth.Register(ctx, entity.Credentials{Email: "user@example.com ", Password: "another one"}); !errors.Is(err, usecase.ErrEmailTaken) {
+		t.Errorf("registering the email again: %v, want ErrEmailTaken", err)
+	}
+
+	if _, err := auth.Login(ctx, entity.Credentials{Email: credentials.Email, Password: "wrong horse"}); !errors.Is(err, usecase.ErrInvalidCredentials) {
+		t.Errorf("login with a wrong password: %v, want ErrInvalidCredentials", err)
+	}
+	pair, err := auth.Login(ctx, entity.Credentials{Email: "user@example.com", Password: credentials.Password})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	actor, err := auth.Authenticate(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if actor.UserID != user.ID || actor.Role != entity.RoleCustomer {
+		t.Errorf("actor = %+v, want customer %s", actor, user.ID)
+	}
+	if _, err := auth.Authenticate(ctx, pair.RefreshToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("authenticating with a refresh token: %v, want ErrInvalidToken", err)
+	}
+	if _, err := auth.Refresh(ctx, pair.AccessToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("refreshing with an access token: %v, want ErrInvalidToken", err)
+	}
+
+	// A refresh token is rotated: it works once, then its successor does
+	rotated, err := auth.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := auth.Refresh(ctx, pair.RefreshToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("reusing a refresh token: %v, want ErrInvalidToken", err)
+	}
+
+	if err := auth.Logout(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := auth.Refresh(ctx, rotated.RefreshToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("refreshing after logout: %v, want ErrInvalidToken", err)
+	}
+	if err := auth.Logout(ctx, rotated.RefreshToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("logging out twice: %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRefreshWithUnknownToken(t *testing.T) {
+	ctx := context.Background()
+	tokens := token.NewManager("access", "refresh", time.Minute, time.Hour)
+	auth := newAuthService(tokens)
+
+	// Well signed, but never issued by the service
+	stray, err := tokens.NewRefreshToken("user-1")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := auth.Refresh(ctx, stray.Value); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("refreshing with a token that was never issued: %v, want ErrInvalidToken", err)
+	}
+
+	expired := newAuthService(token.NewManager("access", "refresh", -time.Minute, -time.Minute))
+	if _, err := expired.Register(ctx, entity.Credentials{Email: "user@example.com", Password: "correct horse"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	pair, err := expired.Login(ctx, entity.Credentials{Email: "user@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := expired.Authenticate(ctx, pair.AccessToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("authenticating with an expired token: %v, want ErrInvalidToken", err)
+	}
+	if _, err := expired.Refresh(ctx, pair.RefreshToken); !errors.Is(err, usecase.ErrInvalidToken) {
+		t.Errorf("refreshing with an expired token: %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"ulab3/internal/entity"
+)
+
+// Actor is the authenticated user a service call is made on behalf of.
+type Actor struct {
+	UserID string
+	Role   entity.Role
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that carries the actor. Transports call it
+// once they have authenticated a request, before calling the services.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx by WithActor.
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// Permission names an operation that only some roles may perform.
+type Permission string
+
+const (
+	PermWriteProducts   Permission = "products:write"
+	PermCreateOrders    Permission = "orders:create"
+	PermReadOwnOrders   Permission = "orders:read-own"
+	PermReadAllOrders   Permission = "orders:read-all"
+	PermUpdateOrders    Permission = "orders:update"
+	PermDeleteOrders    Permission = "orders:delete"
+	PermCancelOwnOrders Permission = "orders:cancel-own"
+	PermCancelAllOrders Permission = "orders:cancel-all"
+	// PermFulfilOrders covers paying, shipping, delivering and refunding.
+	PermFulfilOrders Permission = "orders:fulfil"
+	PermManageUsers  Permission = "users:manage"
+	// PermManageDeleted covers seeing, restoring and purging deleted records.
+	PermManageDeleted Permission = "deleted:manage"
+	PermReadAudit     Permission = "audit:read"
+	// PermManageEvents covers inspecting the outbox and retrying dead events.
+	PermManageEvents Permission = "events:manage"
+	// PermManageOwnWebhooks covers registering webhooks and managing the ones
+	// the actor registered.
+	PermManageOwnWebhooks Permission = "webhooks:manage-own"
+	PermManageAllWebhooks Permission = "webhooks:manage-all"
+	// PermManageOwnCustomers covers creating customers and managing the ones
+	// linked to the actor's account.
+	PermManageOwnCustomers Permission = "customers:manage-own"
+	PermManageAllCustomers Permission = "customers:manage-all"
+	// PermManageWarehouses covers managing warehouses and their stock levels.
+	PermManageWarehouses Permission = "warehouses:manage"
+)
+
+// rolePermissions lists what each role may do. Admins may do everything.
+var rolePermissions = map[entity.Role][]Permission{
+	entity.RoleCatalogManager: {PermWriteProducts, PermManageOwnWebhooks, PermManageWarehouses},
+	entity.RoleCustomer:       {PermCreateOrders, PermReadOwnOrders, PermCancelOwnOrders, PermManageOwnWebhooks, PermManageOwnCustomers},
+	entity.RoleSupport:        {PermReadAllOrders, PermCancelAllOrders, PermFulfilOrders, PermManageOwnWebhooks, PermManageAllCustomers},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role entity.Role) bool {
+	_, ok := rolePermissions[role]
+	return ok || role == entity.RoleAdmin
+}
+
+// Can reports whether the actor's role grants the permission.
+func (a Actor) Can(perm Permission) bool {
+	return a.Role == entity.RoleAdmin || slices.Contains(rolePermissions[a.Role], perm)
+}
+
+// authorize returns the actor in ctx if its role grants any of the
+// permissions.
+func authorize(ctx context.Context, perms ...Permission) (Actor, error) {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		return Actor{}, ErrUnauthenticated
+	}
+	if !slices.ContainsFunc(perms, actor.Can) {
+		return actor, fmt.Errorf("%w: role %q lacks %s", ErrForbidden, actor.Role, perms[0])
+	}
+	return actor, nil
+}
+
+// authorizeOrder checks access to one order: actors granted all may act on
+// any order, actors granted own only on the orders they placed. An empty own
+// means nobody is limited to their own orders.
+func authorizeOrder(ctx context.Context, order *entity.Order, all, own Permission) error {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if actor.Can(all) || own != "" && actor.Can(own) && order.UserID == actor.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: role %q cannot access order %s", ErrForbidden, actor.Role, order.ID)
+}
+
+// authorizeWebhook checks access to one webhook: actors who may manage every
+// webhook may act on any, the others only on the ones they registered.
+func authorizeWebhook(ctx context.Context, webhook *entity.Webhook) error {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if actor.Can(PermManageAllWebhooks) || actor.Can(PermManageOwnWebhooks) && webhook.OwnerID == actor.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: role %q cannot access webhook %s", ErrForbidden, actor.Role, webhook.ID)
+}
+
+// authorizeCustomer checks access to one customer: actors who may manage every
+// customer may act on any, the others only on the ones linked to their
+// account.
+func authorizeCustomer(ctx context.Context, customer *entity.Customer) error {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if actor.Can(PermManageAllCustomers) || actor.Can(PermManageOwnCustomers) && customer.UserID == actor.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: role %q cannot access customer %s", ErrForbidden, actor.Role, customer.ID)
+}
+
+// authorizeCart checks access to one cart: carts are private to the actor who
+// created them.
+func authorizeCart(ctx context.Context, cart *entity.Cart) error {
+	actor, err := authorize(ctx, PermCreateOrders)
+	if err != nil {
+		return err
+	}
+	if cart.UserID != actor.UserID {
+		return fmt.Errorf("%w: cart %s belongs to another user", ErrForbidden, cart.ID)
+	}
+	return nil
+}
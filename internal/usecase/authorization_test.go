@@ -0,0 +1,140 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// as returns the fixture's context acting as the user instead.
+func (f *orderFixture) as(userID string, role entity.Role) context.Context {
+	return usecase.WithActor(f.ctx, usecase.Actor{UserID: userID, Role: role})
+}
+
+// carts returns a cart service over the fixture's repositories whose carts
+// expire after ttl and whose stock holds expire after holdTTL.
+func (f *orderFixture) carts(ttl, holdTTL time.Duration) *usecase.CartService {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return usecase.NewCartService(f.repos.Carts, f.repos.Products, f.repos.Reservations, f.repos.Warehouses, f.repos.StockLevels,
+		f.orders, usecase.PriorityStrategy{}, usecase.NewBroker(logger), f.repos.Transactor, ttl, holdTTL, logger)
+}
+
+func TestCustomersOnlyReachTheirOwn(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	customer, err := f.repos.Customers.Create(f.ctx, &entity.Customer{
+		UserID:    "alice",
+		Name:      "Alice",
+		Email:     "alice@example.com",
+		Status:    entity.CustomerStatusActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	alice, bob := f.as("alice", entity.RoleCustomer), f.as("bob", entity.RoleCustomer)
+
+	order, err := f.orders.CreateOrder(alice, &entity.Order{CustomerID: customer.ID, Items: []entity.OrderItem{{ProductID: productID, Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := f.orders.CreateOrder(bob, &entity.Order{CustomerID: customer.ID, Items: []entity.OrderItem{{ProductID: productID, Quantity: 1}}}); !errors.Is(err, usecase.ErrForbidden) {
+		t.Errorf("ordering for another user's customer: %v, want ErrForbidden", err)
+	}
+	if _, err := f.orders.GetOrderByID(alice, order.ID, false); err != nil {
+		t.Errorf("reading an own order: %v", err)
+	}
+	if _, err := f.orders.GetOrderByID(bob, order.ID, false); !errors.Is(err, usecase.ErrForbidden) {
+		t.Errorf("reading another user's order: %v, want ErrForbidden", err)
+	}
+	if _, err := f.orders.GetOrderByID(f.as("sam", entity.RoleSupport), order.ID, false); err != nil {
+		t.Errorf("support reading an order: %v", err)
+	}
+	if _, err := f.orders.CancelOrder(bob, order.ID); !errors.Is(err, usecase.ErrForbidden) {
+		t.Errorf("cancelling another user's order: %v, want ErrForbidden", err)
+	}
+	page, err := f.orders.GetAllOrders(bob, usecase.OrderFilter{UserID: "alice"}, usecase.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetAllOrders: %v", err)
+	}
+	if len(page.Data) != 0 {
+		t.Errorf("listing another user's orders returned %d orders", len(page.Data))
+	}
+
+	carts := f.carts(time.Hour, time.Hour)
+	cart, err := carts.CreateCart(alice, &entity.Cart{Items: []entity.CartItem{{ProductID: productID, Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("CreateCart: %v", err)
+	}
+	if _, err := carts.GetCart(alice, cart.ID); err != nil {
+		t.Errorf("reading an own cart: %v", err)
+	}
+	// Carts are private, even from admins
+	for name, ctx := range map[string]context.Context{"customer": bob, "admin": f.ctx} {
+		if _, err := carts.GetCart(ctx, cart.ID); !errors.Is(err, usecase.ErrForbidden) {
+			t.Errorf("%s reading another user's cart: %v, want ErrForbidden", name, err)
+		}
+		if _, err := carts.AddItem(ctx, cart.ID, &entity.CartItem{ProductID: productID, Quantity: 1}); !errors.Is(err, usecase.ErrForbidden) {
+			t.Errorf("%s changing another user's cart: %v, want ErrForbidden", name, err)
+		}
+	}
+}
+
+func TestAdminOnlyOperations(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	order, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	auth := newAuthService(nil)
+
+	operations := []struct {
+		name string
+		do   func(ctx context.Context) error
+	}{
+		{"update order", func(ctx context.Context) error {
+			_, err := f.orders.UpdateOrder(ctx, order.ID, 0, &entity.Order{Items: []entity.OrderItem{{ProductID: productID, Quantity: 2}}})
+			return err
+		}},
+		{"delete order", func(ctx context.Context) error {
+			return f.orders.DeleteOrder(ctx, order.ID)
+		}},
+		{"read deleted order", func(ctx context.Context) error {
+			_, err := f.orders.GetOrderByID(ctx, order.ID, true)
+			return err
+		}},
+		{"restore order", func(ctx context.Context) error {
+			_, err := f.orders.RestoreOrder(ctx, order.ID)
+			return err
+		}},
+		{"purge orders", func(ctx context.Context) error {
+			_, err := f.orders.PurgeOrders(ctx, time.Now())
+			return err
+		}},
+		{"assign role", func(ctx context.Context) error {
+			_, err := auth.AssignRole(ctx, "someone", entity.RoleSupport)
+			return err
+		}},
+	}
+	roles := []entity.Role{entity.RoleCustomer, entity.RoleSupport, entity.RoleCatalogManager}
+	for _, op := range operations {
+		for _, role := range roles {
+			if err := op.do(f.as("user", role)); !errors.Is(err, usecase.ErrForbidden) {
+				t.Errorf("%s as %s: %v, want ErrForbidden", op.name, role, err)
+			}
+		}
+		if err := op.do(context.Background()); !errors.Is(err, usecase.ErrUnauthenticated) {
+			t.Errorf("%s without an actor: %v, want ErrUnauthenticated", op.name, err)
+		}
+	}
+	if stock, reserved := f.stock(t, productID); stock != 10 || reserved != 1 {
+		t.Errorf("refused operations left stock %d (%d reserved), want 10 (1 reserved)", stock, reserved)
+	}
+}
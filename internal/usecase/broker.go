@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+	"ulab3/internal/entity"
+)
+
+const (
+	// subscriberBuffer is how many events a subscriber may fall behind by
+	// before the broker drops it.
+	subscriberBuffer = 64
+	// brokerSeen is how many event IDs the broker remembers to drop events
+	// that reach it twice.
+	brokerSeen = 4096
+)
+
+// Broker fans committed events out to the subscribers in this process. It is
+// fed by the services that record the events and, in multi-instance
+// deployments, by an EventFeed carrying the events other instances record;
+// an event that arrives both ways is passed on once.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan entity.Event]struct{}
+	seen        map[string]struct{}
+	order       []string
+	next        int
+	logger      *slog.Logger
+}
+
+func NewBroker(logger *slog.Logger) *Broker {
+	return &Broker{
+		subscribers: make(map[chan entity.Event]struct{}),
+		seen:        make(map[string]struct{}, brokerSeen),
+		order:       make([]string, brokerSeen),
+		logger:      logger,
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on,
+// and a function that ends the subscription. A subscriber that falls too far
+// behind has its channel closed.
+func (b *Broker) Subscribe() (<-chan entity.Event, func()) {
+	ch := make(chan entity.Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish passes the event on to every subscriber. It never blocks: a
+// subscriber whose buffer is full is dropped. Publishing to a nil Broker does
+// nothing.
+func (b *Broker) Publish(event entity.Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.seen[event.ID]; ok {
+		return
+	}
+	delete(b.seen, b.order[b.next])
+	b.order[b.next] = event.ID
+	b.next = (b.next + 1) % len(b.order)
+	b.seen[event.ID] = struct{}{}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.logger.Info("Dropping slow event subscriber", "event_id", event.ID)
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Follow publishes the events from the feed until ctx is done, reopening the
+// feed retry after it fails.
+func (b *Broker) Follow(ctx context.Context, feed EventFeed, retry time.Duration) {
+	b.logger.Info("Following event feed")
+	for {
+		if err := feed.Watch(ctx, b.Publish); err != nil {
+			b.logger.Error("Event feed failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+type recorderKey struct{}
+
+// withRecorder returns a context in which recordEvent also appends each event
+// it records to events.
+func withRecorder(ctx context.Context, events *[]entity.Event) context.Context {
+	return context.WithValue(ctx, recorderKey{}, events)
+}
+
+// recorded returns the events slice of the enclosing withRecorder, if any.
+func recorded(ctx context.Context) *[]entity.Event {
+	events, _ := ctx.Value(recorderKey{}).(*[]entity.Event)
+	return events
+}
+
+// withinTransaction runs fn in a transaction and, once it commits, publishes
+// the events fn recorded to the broker. Nested calls leave publishing to the
+// outermost one.
+func withinTransaction(ctx context.Context, tx Transactor, broker *Broker, fn func(ctx context.Context) error) error {
+	if recorded(ctx) != nil {
+		return tx.WithinTransaction(ctx, fn)
+	}
+	var events []entity.Event
+	err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Backends may run fn again after a transient failure; only the
+		// attempt that commits counts
+		events = events[:0]
+		return fn(withRecorder(ctx, &events))
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		broker.Publish(event)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package usecase_test
+
+import (
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// receive returns the next event on ch, or reports whether ch was closed.
+func receive(t *testing.T, ch <-chan entity.Event) (entity.Event, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-ch:
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("no event within a second")
+		return entity.Event{}, false
+	}
+}
+
+func TestBrokerFansOut(t *testing.T) {
+	broker := usecase.NewBroker(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	first, unsubscribeFirst := broker.Subscribe()
+	second, unsubscribeSecond := broker.Subscribe()
+	defer unsubscribeSecond()
+
+	broker.Publish(entity.Event{ID: "1"})
+	broker.Publish(entity.Event{ID: "2"})
+	// An event that reaches the broker a second time is passed on once
+	broker.Publish(entity.Event{ID: "1"})
+	broker.Publish(entity.Event{ID: "3"})
+	for name, ch := range map[string]<-chan entity.Event{"first": first, "second": second} {
+		for _, want := range []string{"1", "2", "3"} {
+			if event, ok := receive(t, ch); !ok || event.ID != want {
+				t.Fatalf("%s subscriber got %q (open %v), want %s", name, event.ID, ok, want)
+			}
+		}
+	}
+
+	unsubscribeFirst()
+	if _, ok := <-first; ok {
+		t.Error("channel still open after unsubscribing")
+	}
+	unsubscribeFirst()
+	broker.Publish(entity.Event{ID: "4"})
+	if event, ok := receive(t, second); !ok || event.ID != "4" {
+		t.Errorf("remaining subscriber got %q (open %v), want 4", event.ID, ok)
+	}
+
+	var none *usecase.Broker
+	none.Publish(entity.Event{ID: "5"})
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := usecase.NewBroker(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	slow, unsubscribeSlow := broker.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := broker.Subscribe()
+	defer unsubscribeFast()
+
+	// Publishing never blocks: once the slow subscriber's buffer is full it
+	// is dropped, while the one keeping up gets every event
+	const events = 200
+	for i := range events {
+		broker.Publish(entity.Event{ID: strconv.Itoa(i)})
+		if event, ok := receive(t, fast); !ok || event.ID != strconv.Itoa(i) {
+			t.Fatalf("fast subscriber got %q (open %v), want %d", event.ID, ok, i)
+		}
+	}
+
+	buffered := 0
+	for range slow {
+		buffered++
+	}
+	if buffered == 0 || buffered >= events {
+		t.Errorf("slow subscriber got %d of %d events before it was dropped", buffered, events)
+	}
+}
@@ -0,0 +1,408 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"ulab3/internal/entity"
+)
+
+const (
+	// maxCartItems is how many lines a cart holds at most.
+	maxCartItems = 100
+	// maxCartQuantity is the largest quantity of one cart line, matching the
+	// largest quantity of an order line.
+	maxCartQuantity = 10000
+)
+
+// CartService keeps the carts users fill before placing an order. Every change
+// to a cart holds the stock of all its lines afresh, for the hold TTL; lines
+// are checked against the catalog when they change, when the cart is read and
+// once more at checkout.
+type CartService struct {
+	cartRepo    CartRepository
+	productRepo ProductRepository
+	orders      *OrderService
+	inventory   *inventory
+	holds       *stockHolds
+	broker      *Broker
+	tx          Transactor
+	ttl         time.Duration
+	logger      *slog.Logger
+}
+
+// NewCartService returns a CartService whose carts expire after being left
+// alone for ttl and hold their stock, in the warehouses strategy picks, for
+// holdTTL.
+func NewCartService(cartRepo CartRepository, productRepo ProductRepository, reservationRepo ReservationRepository, warehouseRepo WarehouseRepository, levelRepo StockLevelRepository, orders *OrderService, strategy FulfilmentStrategy, broker *Broker, tx Transactor, ttl, holdTTL time.Duration, logger *slog.Logger) *CartService {
+	inventory := &inventory{productRepo: productRepo, warehouseRepo: warehouseRepo, levelRepo: levelRepo, strategy: strategy, logger: logger}
+	return &CartService{
+		cartRepo:    cartRepo,
+		productRepo: productRepo,
+		orders:      orders,
+		inventory:   inventory,
+		holds:       &stockHolds{inventory: inventory, reservationRepo: reservationRepo, ttl: holdTTL, logger: logger},
+		broker:      broker,
+		tx:          tx,
+		ttl:         ttl,
+		logger:      logger,
+	}
+}
+
+// CreateCart starts a cart for the actor, holding the lines the request
+// carries and their stock. Lines naming the same product are merged.
+func (s *CartService) CreateCart(ctx context.Context, cart *entity.Cart) (*entity.Cart, error) {
+	actor, err := authorize(ctx, PermCreateOrders)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(cart); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Creating cart", "items", len(cart.Items), "user_id", actor.UserID)
+
+	items := cart.Items
+	var createdCart *entity.Cart
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		cart := &entity.Cart{UserID: actor.UserID, Items: []entity.CartItem{}}
+		for _, item := range items {
+			if err := s.putItem(ctx, cart, item.ProductID, cartQuantity(cart, item.ProductID)+item.Quantity); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		cart.CreatedAt = now
+		s.touch(cart, now)
+
+		var err error
+		createdCart, err = s.cartRepo.Create(ctx, cart)
+		if err != nil {
+			s.logger.Error("Failed to create cart", "error", err)
+			return fmt.Errorf("failed to create cart: %w", err)
+		}
+		required := make([]string, len(createdCart.Items))
+		for i, item := range createdCart.Items {
+			required[i] = item.ProductID
+		}
+		return s.holdItems(ctx, createdCart, required...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Cart created successfully", "id", createdCart.ID)
+	return s.price(ctx, createdCart)
+}
+
+// GetCart returns the actor's cart with its lines priced against the catalog
+// and any line that no longer matches it listed in Issues.
+func (s *CartService) GetCart(ctx context.Context, id string) (*entity.Cart, error) {
+	s.logger.Info("Fetching cart", "id", id)
+	cart, err := s.findCart(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.price(ctx, cart)
+}
+
+// AddItem adds a line to the cart, or raises the quantity of the line that
+// already holds the product.
+func (s *CartService) AddItem(ctx context.Context, id string, item *entity.CartItem) (*entity.Cart, error) {
+	if err := Validate(item); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Adding cart item", "id", id, "product_id", item.ProductID, "quantity", item.Quantity)
+
+	return s.change(ctx, id, func(ctx context.Context, cart *entity.Cart) error {
+		return s.putItem(ctx, cart, item.ProductID, cartQuantity(cart, item.ProductID)+item.Quantity)
+	}, item.ProductID)
+}
+
+// SetItemQuantity changes the quantity of the cart line holding the product.
+func (s *CartService) SetItemQuantity(ctx context.Context, id, productID string, quantity *entity.CartQuantity) (*entity.Cart, error) {
+	if err := Validate(quantity); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Updating cart item", "id", id, "product_id", productID, "quantity", quantity.Quantity)
+
+	return s.change(ctx, id, func(ctx context.Context, cart *entity.Cart) error {
+		if cartQuantity(cart, productID) == 0 {
+			return fmt.Errorf("cart %s has no line for product %s: %w", id, productID, ErrNotFound)
+		}
+		return s.putItem(ctx, cart, productID, quantity.Quantity)
+	}, productID)
+}
+
+// RemoveItem removes the cart line holding the product.
+func (s *CartService) RemoveItem(ctx context.Context, id, productID string) (*entity.Cart, error) {
+	s.logger.Info("Removing cart item", "id", id, "product_id", productID)
+
+	return s.change(ctx, id, func(ctx context.Context, cart *entity.Cart) error {
+		if cartQuantity(cart, productID) == 0 {
+			return fmt.Errorf("cart %s has no line for product %s: %w", id, productID, ErrNotFound)
+		}
+		cart.Items = slices.DeleteFunc(cart.Items, func(item entity.CartItem) bool { return item.ProductID == productID })
+		return nil
+	})
+}
+
+// Checkout places an order for the customer holding the cart's lines and
+// removes the cart. The lines are checked against the catalog first: if any
+// changed since it was added, no order is placed, the cart takes the current
+// prices, and a CartChangedError lists what changed.
+func (s *CartService) Checkout(ctx context.Context, id string, checkout *entity.Checkout) (*entity.Order, error) {
+	if err := Validate(checkout); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Checking out cart", "id", id, "customer_id", checkout.CustomerID)
+
+	var order *entity.Order
+	var issues []entity.CartIssue
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		cart, err := s.findCart(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(cart.Items) == 0 {
+			return fmt.Errorf("%w: cart %s", ErrCartEmpty, id)
+		}
+		// The cart's holds count as available while it is priced and are
+		// handed over to the order below
+		if cart, err = s.price(ctx, cart); err != nil {
+			return err
+		}
+
+		if issues = cart.Issues; len(issues) > 0 {
+			// Take the current prices so that checking out again succeeds
+			// unless the stock falls short too
+			for i, item := range cart.Items {
+				if slices.ContainsFunc(issues, func(issue entity.CartIssue) bool {
+					return issue.ProductID == item.ProductID && issue.Code == entity.CartIssuePriceChanged
+				}) {
+					cart.Items[i].UnitPrice = item.CurrentPrice
+				}
+			}
+			return s.save(ctx, cart)
+		}
+
+		if err := s.holds.release(ctx, entity.HolderCart, id); err != nil {
+			return err
+		}
+		items := make([]entity.OrderItem, len(cart.Items))
+		for i, item := range cart.Items {
+			items[i] = entity.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity}
+		}
+		order, err = s.orders.CreateOrder(ctx, &entity.Order{CustomerID: checkout.CustomerID, Items: items})
+		if err != nil {
+			return err
+		}
+		if err := s.cartRepo.Delete(ctx, id); err != nil {
+			s.logger.Error("Failed to delete cart", "id", id, "error", err)
+			return fmt.Errorf("failed to delete cart: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) > 0 {
+		s.logger.Info("Cart changed since its lines were added", "id", id, "issues", len(issues))
+		return nil, &CartChangedError{Issues: issues}
+	}
+
+	s.logger.Info("Cart checked out successfully", "id", id, "order_id", order.ID)
+	return order, nil
+}
+
+// PurgeExpiredCarts removes the carts that expired before the given time. The
+// database drops them by itself where it can; purging keeps the other
+// backends from piling them up.
+func (s *CartService) PurgeExpiredCarts(ctx context.Context, before time.Time) (int64, error) {
+	if _, err := authorize(ctx, PermManageDeleted); err != nil {
+		return 0, err
+	}
+	s.logger.Info("Purging expired carts", "before", before)
+
+	purged, err := s.cartRepo.DeleteExpired(ctx, before)
+	if err != nil {
+		s.logger.Error("Failed to purge expired carts", "error", err)
+		return 0, fmt.Errorf("failed to purge expired carts: %w", err)
+	}
+
+	s.logger.Info("Expired carts purged successfully", "count", purged)
+	return purged, nil
+}
+
+// change applies fn to the actor's cart in a transaction, stores the result,
+// holds its stock again and returns the cart priced against the catalog. The
+// change fails unless the stock of the products named by required is held.
+func (s *CartService) change(ctx context.Context, id string, fn func(ctx context.Context, cart *entity.Cart) error, required ...string) (*entity.Cart, error) {
+	var cart *entity.Cart
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if cart, err = s.findCart(ctx, id); err != nil {
+			return err
+		}
+		if err := fn(ctx, cart); err != nil {
+			return err
+		}
+		if err := s.save(ctx, cart); err != nil {
+			return err
+		}
+		return s.holdItems(ctx, cart, required...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Cart updated successfully", "id", id)
+	return s.price(ctx, cart)
+}
+
+// save stores the cart's lines and pushes its expiry back.
+func (s *CartService) save(ctx context.Context, cart *entity.Cart) error {
+	s.touch(cart, time.Now())
+	if err := s.cartRepo.Update(ctx, cart); err != nil {
+		s.logger.Error("Failed to update cart", "id", cart.ID, "error", err)
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+	return nil
+}
+
+// touch marks the cart as changed at now.
+func (s *CartService) touch(cart *entity.Cart, now time.Time) {
+	cart.UpdatedAt = now
+	cart.ExpiresAt = now.Add(s.ttl)
+}
+
+// findCart returns the cart with the ID if it belongs to the actor.
+func (s *CartService) findCart(ctx context.Context, id string) (*entity.Cart, error) {
+	cart, err := s.cartRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Cart not found", "id", id, "error", err)
+		return nil, fmt.Errorf("cart not found: %w", err)
+	}
+	if err := authorizeCart(ctx, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// putItem sets the quantity of the cart line holding the product, adding the
+// line if there is none, after checking that the product exists. The line
+// takes the product's current price; its stock is checked when the cart's
+// stock is held.
+func (s *CartService) putItem(ctx context.Context, cart *entity.Cart, productID string, quantity int) error {
+	if quantity > maxCartQuantity {
+		return &ValidationError{Fields: []entity.FieldError{{Field: "quantity", Message: fmt.Sprintf("must be at most %d", maxCartQuantity)}}}
+	}
+	product, err := s.productRepo.FindByID(ctx, productID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Info("Product not found", "product_id", productID)
+		return fmt.Errorf("%w: %s", ErrUnknownProduct, productID)
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch product", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to fetch product: %w", err)
+	}
+	i := slices.IndexFunc(cart.Items, func(item entity.CartItem) bool { return item.ProductID == productID })
+	if i < 0 {
+		if len(cart.Items) >= maxCartItems {
+			return fmt.Errorf("%w: a cart holds at most %d lines", ErrCartFull, maxCartItems)
+		}
+		cart.Items = append(cart.Items, entity.CartItem{ProductID: productID})
+		i = len(cart.Items) - 1
+	}
+	cart.Items[i].Quantity = quantity
+	cart.Items[i].UnitPrice = product.Price
+	return nil
+}
+
+// price fills in the cart's current prices, line totals and total from the
+// catalog, and lists the lines that no longer match it. Lines whose product
+// is gone count towards no total. The stock the cart holds counts as
+// available to it.
+func (s *CartService) price(ctx context.Context, cart *entity.Cart) (*entity.Cart, error) {
+	held, err := s.holds.held(ctx, entity.HolderCart, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	cart.Total = 0
+	cart.Issues = []entity.CartIssue{}
+	for i, item := range cart.Items {
+		product, err := s.productRepo.FindByID(ctx, item.ProductID)
+		if errors.Is(err, ErrNotFound) {
+			cart.Items[i].CurrentPrice = 0
+			cart.Items[i].LineTotal = 0
+			cart.Issues = append(cart.Issues, entity.CartIssue{
+				ProductID: item.ProductID,
+				Code:      entity.CartIssueProductUnavailable,
+				Message:   "product is no longer available",
+			})
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to fetch product", "product_id", item.ProductID, "error", err)
+			return nil, fmt.Errorf("failed to fetch product: %w", err)
+		}
+
+		cart.Items[i].CurrentPrice = product.Price
+		cart.Items[i].LineTotal = float64(item.Quantity) * product.Price
+		cart.Total += cart.Items[i].LineTotal
+		if product.Price != item.UnitPrice {
+			cart.Issues = append(cart.Issues, entity.CartIssue{
+				ProductID: item.ProductID,
+				Code:      entity.CartIssuePriceChanged,
+				Message:   fmt.Sprintf("price changed from %.2f to %.2f", item.UnitPrice, product.Price),
+			})
+		}
+		if available := max(product.Stock-product.Reserved, 0) + held[item.ProductID]; available < item.Quantity {
+			cart.Issues = append(cart.Issues, entity.CartIssue{
+				ProductID: item.ProductID,
+				Code:      entity.CartIssueInsufficientStock,
+				Message:   fmt.Sprintf("only %d left, %d wanted", available, item.Quantity),
+			})
+		}
+	}
+	return cart, nil
+}
+
+// holdItems holds the stock of every line of the cart afresh, letting go of
+// what it held before. Lines for the required products fail with
+// ErrInsufficientStock unless their stock is available; the other lines go
+// without a hold, so that a line that ran short does not block changes to the
+// rest of the cart, and show up as issues when the cart is priced. Carts have
+// no address yet, so each line is held wherever the strategy prefers without
+// one.
+func (s *CartService) holdItems(ctx context.Context, cart *entity.Cart, required ...string) error {
+	if err := s.holds.release(ctx, entity.HolderCart, cart.ID); err != nil {
+		return err
+	}
+	for _, item := range cart.Items {
+		items, err := s.inventory.allocate(ctx, nil, []entity.OrderItem{{ProductID: item.ProductID, Quantity: item.Quantity}})
+		if err == nil {
+			err = s.holds.holdItems(ctx, entity.HolderCart, cart.ID, items)
+		}
+		if errors.Is(err, ErrInsufficientStock) && !slices.Contains(required, item.ProductID) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cartQuantity returns the quantity of the cart line holding the product, or
+// 0 if there is none.
+func cartQuantity(cart *entity.Cart, productID string) int {
+	for _, item := range cart.Items {
+		if item.ProductID == productID {
+			return item.Quantity
+		}
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package usecase_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+func TestCheckoutAfterPriceChange(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	carts := f.carts(time.Hour, time.Hour)
+	cart, err := carts.CreateCart(f.ctx, &entity.Cart{Items: []entity.CartItem{{ProductID: productID, Quantity: 2}}})
+	if err != nil {
+		t.Fatalf("CreateCart: %v", err)
+	}
+	if _, err := newProductService(f.repos).PatchProduct(f.ctx, productID, 0, []byte(`{"price":3}`)); err != nil {
+		t.Fatalf("PatchProduct: %v", err)
+	}
+
+	_, err = carts.Checkout(f.ctx, cart.ID, &entity.Checkout{CustomerID: f.customer.ID})
+	var changed *usecase.CartChangedError
+	if !errors.As(err, &changed) {
+		t.Fatalf("Checkout = %v, want a *CartChangedError", err)
+	}
+	if len(changed.Issues) != 1 || changed.Issues[0].Code != entity.CartIssuePriceChanged || changed.Issues[0].ProductID != productID {
+		t.Errorf("issues = %+v, want the price change", changed.Issues)
+	}
+	page, err := f.orders.GetAllOrders(f.ctx, usecase.OrderFilter{}, usecase.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetAllOrders: %v", err)
+	}
+	if len(page.Data) != 0 {
+		t.Errorf("checkout placed %d orders, want none", len(page.Data))
+	}
+
+	// The cart took the new price, so checking out again succeeds
+	repriced, err := carts.GetCart(f.ctx, cart.ID)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if len(repriced.Issues) != 0 || repriced.Items[0].UnitPrice != 3 || repriced.Total != 6 {
+		t.Errorf("cart after checkout = %+v, want it at the new price", repriced)
+	}
+	order, err := carts.Checkout(f.ctx, cart.ID, &entity.Checkout{CustomerID: f.customer.ID})
+	if err != nil {
+		t.Fatalf("second Checkout: %v", err)
+	}
+	if len(order.Items) != 1 || order.Items[0].ProductID != productID || order.Items[0].Quantity != 2 {
+		t.Errorf("order items = %+v, want 2 of %s", order.Items, productID)
+	}
+	if _, err := carts.GetCart(f.ctx, cart.ID); !errors.Is(err, usecase.ErrNotFound) {
+		t.Errorf("cart after checkout: %v, want ErrNotFound", err)
+	}
+	// The cart's hold was handed over to the order
+	if stock, reserved := f.stock(t, productID); stock != 10 || reserved != 2 {
+		t.Errorf("after checkout: stock %d (%d reserved), want 10 (2 reserved)", stock, reserved)
+	}
+}
+
+func TestCheckoutEmptyCart(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	carts := f.carts(time.Hour, time.Hour)
+	checkout := &entity.Checkout{CustomerID: f.customer.ID}
+
+	empty, err := carts.CreateCart(f.ctx, &entity.Cart{})
+	if err != nil {
+		t.Fatalf("CreateCart: %v", err)
+	}
+	if _, err := carts.Checkout(f.ctx, empty.ID, checkout); !errors.Is(err, usecase.ErrCartEmpty) {
+		t.Errorf("checking out a new empty cart: %v, want ErrCartEmpty", err)
+	}
+
+	emptied, err := carts.CreateCart(f.ctx, &entity.Cart{Items: []entity.CartItem{{ProductID: productID, Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("CreateCart: %v", err)
+	}
+	if _, err := carts.RemoveItem(f.ctx, emptied.ID, productID); err != nil {
+		t.Fatalf("RemoveItem: %v", err)
+	}
+	if _, err := carts.Checkout(f.ctx, emptied.ID, checkout); !errors.Is(err, usecase.ErrCartEmpty) {
+		t.Errorf("checking out an emptied cart: %v, want ErrCartEmpty", err)
+	}
+	if stock, reserved := f.stock(t, productID); stock != 10 || reserved != 0 {
+		t.Errorf("after removing the line: stock %d (%d reserved), want 10 (0 reserved)", stock, reserved)
+	}
+}
+
+func TestExpiredCartsAreGone(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	// Carts of this service expire as they are made
+	carts := f.carts(-time.Minute, time.Hour)
+	cart, err := carts.CreateCart(f.ctx, &entity.Cart{Items: []entity.CartItem{{ProductID: productID, Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("CreateCart: %v", err)
+	}
+
+	if _, err := carts.GetCart(f.ctx, cart.ID); !errors.Is(err, usecase.ErrNotFound) {
+		t.Errorf("reading an expired cart: %v, want ErrNotFound", err)
+	}
+	if _, err := carts.AddItem(f.ctx, cart.ID, &entity.CartItem{ProductID: productID, Quantity: 1}); !errors.Is(err, usecase.ErrNotFound) {
+		t.Errorf("changing an expired cart: %v, want ErrNotFound", err)
+	}
+	if _, err := carts.Checkout(f.ctx, cart.ID, &entity.Checkout{CustomerID: f.customer.ID}); !errors.Is(err, usecase.ErrNotFound) {
+		t.Errorf("checking out an expired cart: %v, want ErrNotFound", err)
+	}
+	if purged, err := carts.PurgeExpiredCarts(f.ctx, time.Now()); err != nil || purged != 1 {
+		t.Errorf("PurgeExpiredCarts = %d, %v, want 1", purged, err)
+	}
+}
+
+func TestCartLineRunningShort(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sweeper := usecase.NewHoldSweeper(f.repos.Products, f.repos.StockLevels, f.repos.Reservations, f.repos.Transactor, time.Minute, logger)
+	short, plenty := f.product(t, 5), f.product(t, 5)
+	// Holds of this service expire as they are made
+	carts := f.carts(time.Hour, -time.Minute)
+	cart, err := carts.CreateCart(f.ctx, &entity.Cart{Items: []entity.CartItem{
+		{ProductID: short, Quantity: 3},
+		{ProductID: plenty, Quantity: 1},
+	}})
+	if err != nil {
+		t.Fatalf("CreateCart: %v", err)
+	}
+
+	// Once the cart's holds lapse, an order takes most of the stock it wanted
+	sweeper.SweepBatch(f.ctx)
+	if _, err := f.order(entity.OrderItem{ProductID: short, Quantity: 4}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	updated, err := carts.AddItem(f.ctx, cart.ID, &entity.CartItem{ProductID: plenty, Quantity: 1})
+	if err != nil {
+		t.Fatalf("changing another line of the cart: %v", err)
+	}
+	if len(updated.Issues) != 1 || updated.Issues[0].Code != entity.CartIssueInsufficientStock || updated.Issues[0].ProductID != short {
+		t.Errorf("issues = %+v, want the short line", updated.Issues)
+	}
+	if _, err := carts.SetItemQuantity(f.ctx, cart.ID, short, &entity.CartQuantity{Quantity: 2}); !errors.Is(err, usecase.ErrInsufficientStock) {
+		t.Errorf("changing the short line: %v, want ErrInsufficientStock", err)
+	}
+	var changed *usecase.CartChangedError
+	if _, err := carts.Checkout(f.ctx, cart.ID, &entity.Checkout{CustomerID: f.customer.ID}); !errors.As(err, &changed) {
+		t.Errorf("checking out with a short line: %v, want a *CartChangedError", err)
+	}
+
+	// Cutting the line to what is left clears the issue
+	fixed, err := carts.SetItemQuantity(f.ctx, cart.ID, short, &entity.CartQuantity{Quantity: 1})
+	if err != nil {
+		t.Fatalf("SetItemQuantity: %v", err)
+	}
+	if len(fixed.Issues) != 0 {
+		t.Errorf("issues = %+v, want none", fixed.Issues)
+	}
+}
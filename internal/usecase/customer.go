@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+)
+
+// CustomerService manages the customers orders are placed for.
+type CustomerService struct {
+	customerRepo CustomerRepository
+	orderRepo    OrderRepository
+	tx           Transactor
+	logger       *slog.Logger
+}
+
+func NewCustomerService(customerRepo CustomerRepository, orderRepo OrderRepository, tx Transactor, logger *slog.Logger) *CustomerService {
+	return &CustomerService{
+		customerRepo: customerRepo,
+		orderRepo:    orderRepo,
+		tx:           tx,
+		logger:       logger,
+	}
+}
+
+// CreateCustomer adds a customer, active unless the request says otherwise.
+// Customers created by actors who may only manage their own are linked to the
+// actor's account, and only actors who may manage every customer may create
+// inactive ones.
+func (s *CustomerService) CreateCustomer(ctx context.Context, customer *entity.Customer) (*entity.Customer, error) {
+	actor, err := authorize(ctx, PermManageOwnCustomers, PermManageAllCustomers)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(customer); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Creating customer", "user_id", actor.UserID)
+
+	customer.UserID = ""
+	if !actor.Can(PermManageAllCustomers) {
+		if customer.Status == entity.CustomerStatusInactive {
+			return nil, fmt.Errorf("%w: role %q cannot create inactive customers", ErrForbidden, actor.Role)
+		}
+		customer.UserID = actor.UserID
+	}
+	if customer.Status == "" {
+		customer.Status = entity.CustomerStatusActive
+	}
+	if customer.Addresses == nil {
+		customer.Addresses = []entity.Address{}
+	}
+	now := time.Now()
+	customer.CreatedAt = now
+	customer.UpdatedAt = now
+	createdCustomer, err := s.customerRepo.Create(ctx, customer)
+	if err != nil {
+		s.logger.Error("Failed to create customer", "error", err)
+		return nil, fmt.Errorf("failed to create customer: %w", err)
+	}
+
+	s.logger.Info("Customer created successfully", "id", createdCustomer.ID)
+	return createdCustomer, nil
+}
+
+// GetCustomers lists the customers the actor may manage, newest first unless
+// the page asks for another order.
+func (s *CustomerService) GetCustomers(ctx context.Context, filter CustomerFilter, page PageRequest) (*entity.CustomerPage, error) {
+	actor, err := authorize(ctx, PermManageOwnCustomers, PermManageAllCustomers)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Can(PermManageAllCustomers) {
+		filter.UserID = actor.UserID
+	}
+	s.logger.Info("Fetching customers", "status", filter.Status, "sort", page.Sort, "limit", page.Limit)
+
+	if page.Sort == "" {
+		page.Sort = "-created_at"
+	}
+	sort, after, limit, err := parsePage(page, customerSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ask for one extra record to learn whether another page follows
+	query := CustomerQuery{CustomerFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	customers, err := s.customerRepo.FindAll(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to fetch customers", "error", err)
+		return nil, fmt.Errorf("failed to fetch customers: %w", err)
+	}
+
+	data, pagination := paginate(customers, limit, sort, customerSortFields, func(c entity.Customer) string { return c.ID })
+	return &entity.CustomerPage{Data: data, Pagination: pagination}, nil
+}
+
+func (s *CustomerService) GetCustomerByID(ctx context.Context, id string) (*entity.Customer, error) {
+	s.logger.Info("Fetching customer by ID", "id", id)
+	return findCustomer(ctx, s.customerRepo, s.logger, id)
+}
+
+// UpdateCustomer replaces the customer's details. The status is kept when the
+// request leaves it empty; only actors who may manage every customer may
+// change it.
+func (s *CustomerService) UpdateCustomer(ctx context.Context, id string, customer *entity.Customer) (*entity.Customer, error) {
+	if err := Validate(customer); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Updating customer", "id", id)
+
+	existing, err := findCustomer(ctx, s.customerRepo, s.logger, id)
+	if err != nil {
+		return nil, err
+	}
+	if customer.Status != "" && customer.Status != existing.Status {
+		if _, err := authorize(ctx, PermManageAllCustomers); err != nil {
+			return nil, err
+		}
+		existing.Status = customer.Status
+	}
+	existing.Name = customer.Name
+	existing.Email = customer.Email
+	existing.Phone = customer.Phone
+	existing.Addresses = customer.Addresses
+	if existing.Addresses == nil {
+		existing.Addresses = []entity.Address{}
+	}
+	existing.UpdatedAt = time.Now()
+	if err := s.customerRepo.Update(ctx, existing); err != nil {
+		s.logger.Error("Failed to update customer", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update customer: %w", err)
+	}
+
+	s.logger.Info("Customer updated successfully", "id", id)
+	return existing, nil
+}
+
+// DeleteCustomer removes a customer no order refers to, deleted orders
+// included. Customers with orders can be made inactive instead.
+func (s *CustomerService) DeleteCustomer(ctx context.Context, id string) error {
+	s.logger.Info("Deleting customer", "id", id)
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := findCustomer(ctx, s.customerRepo, s.logger, id); err != nil {
+			return err
+		}
+
+		filter := OrderFilter{CustomerID: id, IncludeDeleted: true}
+		orders, err := s.orderRepo.FindAll(ctx, OrderQuery{OrderFilter: filter, Sort: Sort{Field: "created_at"}, Limit: 1})
+		if err != nil {
+			s.logger.Error("Failed to check customer orders", "id", id, "error", err)
+			return fmt.Errorf("failed to check customer orders: %w", err)
+		}
+		if len(orders) > 0 {
+			s.logger.Info("Customer has orders", "id", id)
+			return fmt.Errorf("%w: customer %s; make it inactive instead", ErrCustomerHasOrders, id)
+		}
+
+		if err := s.customerRepo.Delete(ctx, id); err != nil {
+			s.logger.Error("Failed to delete customer", "id", id, "error", err)
+			return fmt.Errorf("failed to delete customer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Customer deleted successfully", "id", id)
+	return nil
+}
+
+// findCustomer returns the customer with the ID if the actor may manage it.
+func findCustomer(ctx context.Context, repo CustomerRepository, logger *slog.Logger, id string) (*entity.Customer, error) {
+	customer, err := repo.FindByID(ctx, id)
+	if err != nil {
+		logger.Error("Customer not found", "id", id, "error", err)
+		return nil, fmt.Errorf("customer not found: %w", err)
+	}
+	if err := authorizeCustomer(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
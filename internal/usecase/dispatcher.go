@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+	"ulab3/internal/entity"
+)
+
+// DispatcherConfig tunes how the outbox and the webhook deliveries are
+// drained.
+type DispatcherConfig struct {
+	// Interval is how long the dispatcher waits once nothing is due.
+	Interval time.Duration
+	// BatchSize is the number of events or deliveries claimed at a time.
+	BatchSize int
+	// MaxAttempts is the number of failed attempts after which an event is
+	// dead, or a webhook delivery failed.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the wait before the next attempt, which
+	// doubles with every failure.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Lease is how long a claimed event or delivery is left alone by other
+	// dispatchers. It must outlast the slowest batch.
+	Lease time.Duration
+}
+
+// Dispatcher delivers the events in the outbox to a publisher, at least once
+// each. Failed deliveries are retried with exponential backoff until the
+// event runs out of attempts and becomes a dead letter.
+type Dispatcher struct {
+	outboxRepo OutboxRepository
+	publisher  Publisher
+	config     DispatcherConfig
+	logger     *slog.Logger
+}
+
+func NewDispatcher(outboxRepo OutboxRepository, publisher Publisher, config DispatcherConfig, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// Run delivers due events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.logger.Info("Outbox dispatcher started", "interval", d.config.Interval)
+	poll(ctx, d.config, d.DispatchBatch)
+	d.logger.Info("Outbox dispatcher stopped")
+}
+
+// DispatchBatch claims one batch of due events, attempts to deliver each of
+// them and returns how many it claimed.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) int {
+	now := time.Now()
+	events, err := d.outboxRepo.Claim(ctx, now, now.Add(d.config.Lease), d.config.BatchSize)
+	if err != nil {
+		d.logger.Error("Failed to claim events", "error", err)
+		return 0
+	}
+	for i := range events {
+		d.deliver(ctx, &events[i])
+	}
+	return len(events)
+}
+
+// deliver publishes one event and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, event *entity.Event) {
+	event.Attempts++
+	err := d.publisher.Publish(ctx, *event)
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		event.Status = entity.EventStatusDelivered
+		event.DeliveredAt = &now
+		event.LastError = ""
+	case event.Attempts >= d.config.MaxAttempts:
+		d.logger.Error("Event moved to dead letters", "id", event.ID, "type", event.Type,
+			"attempts", event.Attempts, "error", err)
+		event.Status = entity.EventStatusDead
+		event.LastError = err.Error()
+	default:
+		d.logger.Info("Event delivery failed", "id", event.ID, "type", event.Type,
+			"attempts", event.Attempts, "error", err)
+		event.NextAttemptAt = now.Add(backoff(d.config, event.Attempts))
+		event.LastError = err.Error()
+	}
+
+	// Should this fail, the lease runs out and the event is delivered again
+	if err := d.outboxRepo.UpdateDelivery(ctx, event); err != nil {
+		d.logger.Error("Failed to store event delivery", "id", event.ID, "error", err)
+	}
+}
+
+// poll runs batch every config.Interval until ctx is cancelled. A full batch
+// suggests more work is due, so batch runs again straight away.
+func poll(ctx context.Context, config DispatcherConfig, batch func(ctx context.Context) int) {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && batch(ctx) == config.BatchSize {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backoff returns the wait before the attempt after the given number of
+// failures. Jitter keeps deliveries that failed together from retrying
+// together.
+func backoff(config DispatcherConfig, failures int) time.Duration {
+	wait := config.MinBackoff
+	for i := 1; i < failures && wait < config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, config.MaxBackoff)
+	return wait/2 + rand.N(wait/2+1)
+}
@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"ulab3/internal/entity"
+)
+
+// DomainError is an error the services report on purpose, with a stable code
+// clients can match on. Kind is the broader category the error belongs to;
+// errors.Is matches both the error and its kind.
+type DomainError struct {
+	Code    string
+	Message string
+	Kind    error
+}
+
+func (e *DomainError) Error() string {
+	return e.Message
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Kind
+}
+
+// ErrorCode returns the machine-readable code of the error.
+func (e *DomainError) ErrorCode() string {
+	return e.Code
+}
+
+// The error categories. Every error the services return on purpose wraps one
+// of them; anything else is an internal failure.
+var (
+	// ErrNotFound is returned by repositories when no record matches the
+	// lookup, update or delete.
+	ErrNotFound = &DomainError{Code: "not_found", Message: "not found"}
+
+	// ErrConflict is returned when a request clashes with the current state of
+	// a record.
+	ErrConflict = &DomainError{Code: "conflict", Message: "conflict"}
+
+	// ErrValidation is returned when input does not satisfy the service's rules.
+	ErrValidation = &DomainError{Code: "validation_failed", Message: "validation failed"}
+
+	// ErrInsufficientStock is returned when a product does not hold enough
+	// stock to cover the requested quantity.
+	ErrInsufficientStock = &DomainError{Code: "insufficient_stock", Message: "insufficient stock"}
+
+	// ErrUnauthenticated is returned when an operation that needs an actor is
+	// called without one.
+	ErrUnauthenticated = &DomainError{Code: "unauthenticated", Message: "authentication required"}
+
+	// ErrForbidden is returned when the actor's role does not allow the
+	// operation.
+	ErrForbidden = &DomainError{Code: "forbidden", Message: "forbidden"}
+
+	// ErrPreconditionFailed is returned when a conditional write finds the
+	// record in another state than the caller expected.
+	ErrPreconditionFailed = &DomainError{Code: "precondition_failed", Message: "precondition failed"}
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key comes back
+	// with a different request than the one it was first used for.
+	ErrIdempotencyKeyReused = &DomainError{Code: "idempotency_key_reused", Message: "idempotency key was already used for a different request"}
+)
+
+// ErrEmailTaken is returned when registering an email that already has a user.
+var ErrEmailTaken = &DomainError{Code: "email_taken", Message: "email is already registered", Kind: ErrConflict}
+
+// ErrInvalidCredentials is returned when a login email or password is wrong.
+var ErrInvalidCredentials = &DomainError{Code: "invalid_credentials", Message: "invalid email or password", Kind: ErrUnauthenticated}
+
+// ErrInvalidToken is returned for access or refresh tokens that are
+// malformed, expired or revoked.
+var ErrInvalidToken = &DomainError{Code: "invalid_token", Message: "invalid or expired token", Kind: ErrUnauthenticated}
+
+// ErrInvalidQuery is returned when listing parameters such as the sort field,
+// limit or cursor cannot be used.
+var ErrInvalidQuery = &DomainError{Code: "invalid_query", Message: "invalid query", Kind: ErrValidation}
+
+// ErrUnknownProduct is returned when an order line names a product that does
+// not exist.
+var ErrUnknownProduct = &DomainError{Code: "unknown_product", Message: "unknown product", Kind: ErrValidation}
+
+// ErrOrderStatusChanged is returned when an order's status no longer matches
+// the one a transition was computed from, because another request moved it.
+var ErrOrderStatusChanged = &DomainError{Code: "order_status_changed", Message: "order status changed concurrently", Kind: ErrConflict}
+
+// ErrOrderNotEditable is returned when the items of an order that is no longer
+// pending are changed.
+var ErrOrderNotEditable = &DomainError{Code: "order_not_editable", Message: "order items can only change while the order is pending", Kind: ErrConflict}
+
+// ErrVersionConflict is returned by repositories when a versioned write finds
+// that another write moved the record to a newer version first.
+var ErrVersionConflict = &DomainError{Code: "version_conflict", Message: "record was changed by another request", Kind: ErrConflict}
+
+// ErrVersionMismatch is returned when an update names the version it expects
+// the record to be at and the record is at another one.
+var ErrVersionMismatch = &DomainError{Code: "version_mismatch", Message: "record is not at the expected version", Kind: ErrPreconditionFailed}
+
+// ErrIdempotencyKeyInFlight is returned when a request repeats an idempotency
+// key while the first request with that key is still being handled.
+var ErrIdempotencyKeyInFlight = &DomainError{Code: "idempotency_key_in_flight", Message: "a request with this idempotency key is still in progress", Kind: ErrConflict}
+
+// ErrProductHasOpenOrders is returned when deleting a product that orders in
+// progress still refer to, without forcing the delete.
+var ErrProductHasOpenOrders = &DomainError{Code: "product_has_open_orders", Message: "product has open orders", Kind: ErrConflict}
+
+// ErrEventNotDead is returned when retrying an event that has not run out of
+// delivery attempts.
+var ErrEventNotDead = &DomainError{Code: "event_not_dead", Message: "only dead events can be retried", Kind: ErrConflict}
+
+// ErrDeliveryPending is returned when redelivering a webhook delivery that is
+// still waiting for its next attempt.
+var ErrDeliveryPending = &DomainError{Code: "delivery_pending", Message: "delivery is still pending", Kind: ErrConflict}
+
+// ErrUnknownCustomer is returned when an order names a customer that does not
+// exist.
+var ErrUnknownCustomer = &DomainError{Code: "unknown_customer", Message: "unknown customer", Kind: ErrValidation}
+
+// ErrCustomerInactive is returned when placing an order for an inactive
+// customer.
+var ErrCustomerInactive = &DomainError{Code: "customer_inactive", Message: "customer is inactive", Kind: ErrConflict}
+
+// ErrCustomerHasOrders is returned when deleting a customer that orders refer
+// to. Such customers can be made inactive instead.
+var ErrCustomerHasOrders = &DomainError{Code: "customer_has_orders", Message: "customer has orders", Kind: ErrConflict}
+
+// ErrCartEmpty is returned when checking out a cart without lines.
+var ErrCartEmpty = &DomainError{Code: "cart_empty", Message: "cart is empty", Kind: ErrConflict}
+
+// ErrCartFull is returned when a line is added to a cart that already holds
+// the most lines a cart may have.
+var ErrCartFull = &DomainError{Code: "cart_full", Message: "cart is full", Kind: ErrConflict}
+
+// ErrStockHeld is returned when a product's or warehouse's stock would drop
+// below what pending orders or carts hold, or when a product whose stock is
+// held is first stocked in a warehouse.
+var ErrStockHeld = &DomainError{Code: "stock_held", Message: "stock is held for pending orders or carts", Kind: ErrConflict}
+
+// ErrLastStockLevel is returned when the last warehouse that stocks a product
+// would stop stocking it while it still keeps some.
+var ErrLastStockLevel = &DomainError{Code: "last_stock_level", Message: "the product's last stock level still has stock", Kind: ErrConflict}
+
+// ValidationError reports input that failed validation, field by field.
+type ValidationError struct {
+	Fields []entity.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// ErrorCode returns the machine-readable code of the error.
+func (e *ValidationError) ErrorCode() string {
+	return ErrValidation.Code
+}
+
+// TransitionError reports an order status change the lifecycle does not allow.
+type TransitionError struct {
+	From entity.OrderStatus
+	To   entity.OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change order status from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrConflict
+}
+
+// ErrorCode returns the machine-readable code of the error.
+func (e *TransitionError) ErrorCode() string {
+	return "invalid_transition"
+}
+
+// CartChangedError reports a checkout stopped because cart lines no longer
+// match the catalog. The cart has been brought up to date by then, so
+// checking out again places the order at the current prices.
+type CartChangedError struct {
+	Issues []entity.CartIssue
+}
+
+func (e *CartChangedError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = "product " + issue.ProductID + ": " + issue.Message
+	}
+	return "cart changed: " + strings.Join(messages, "; ")
+}
+
+func (e *CartChangedError) Unwrap() error {
+	return ErrConflict
+}
+
+// ErrorCode returns the machine-readable code of the error.
+func (e *CartChangedError) ErrorCode() string {
+	return "cart_changed"
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"ulab3/internal/entity"
+)
+
+// earthRadiusKm is the mean radius of the Earth, for great-circle distances.
+const earthRadiusKm = 6371
+
+// FulfilmentCandidate is a warehouse that stocks some of an order's products.
+type FulfilmentCandidate struct {
+	Warehouse entity.Warehouse
+	// Available is how much of each of the order's products the warehouse
+	// has that is not held yet.
+	Available map[string]int
+}
+
+// FulfilmentStrategy decides which warehouses fulfil an order. Rank orders
+// the candidates from most to least preferred for shipping to destination,
+// which is nil when the order has no address to ship to. The order then goes
+// to the first candidate that can cover all of it, or is split across the
+// candidates in rank order when none can.
+type FulfilmentStrategy interface {
+	Rank(destination *entity.Address, candidates []FulfilmentCandidate)
+}
+
+// NewFulfilmentStrategy returns the strategy with the given name: priority,
+// closest or most_stock.
+func NewFulfilmentStrategy(name string) (FulfilmentStrategy, error) {
+	switch name {
+	case "priority":
+		return PriorityStrategy{}, nil
+	case "closest":
+		return ClosestStrategy{}, nil
+	case "most_stock":
+		return MostStockStrategy{}, nil
+	}
+	return nil, fmt.Errorf("unknown fulfilment strategy %q", name)
+}
+
+// PriorityStrategy prefers warehouses by their priority, lowest first.
+type PriorityStrategy struct{}
+
+func (PriorityStrategy) Rank(_ *entity.Address, candidates []FulfilmentCandidate) {
+	slices.SortStableFunc(candidates, byPriority)
+}
+
+// ClosestStrategy prefers the warehouses closest to the destination. Without
+// coordinates to go by, warehouses in the destination's country come first.
+// Ties go by priority.
+type ClosestStrategy struct{}
+
+func (ClosestStrategy) Rank(destination *entity.Address, candidates []FulfilmentCandidate) {
+	slices.SortStableFunc(candidates, func(a, b FulfilmentCandidate) int {
+		return cmp.Or(cmp.Compare(distance(destination, a.Warehouse), distance(destination, b.Warehouse)), byPriority(a, b))
+	})
+}
+
+// MostStockStrategy prefers the warehouses with the most of the order's
+// products available. Ties go by priority.
+type MostStockStrategy struct{}
+
+func (MostStockStrategy) Rank(_ *entity.Address, candidates []FulfilmentCandidate) {
+	total := func(c FulfilmentCandidate) int {
+		sum := 0
+		for _, available := range c.Available {
+			sum += available
+		}
+		return sum
+	}
+	slices.SortStableFunc(candidates, func(a, b FulfilmentCandidate) int {
+		return cmp.Or(cmp.Compare(total(b), total(a)), byPriority(a, b))
+	})
+}
+
+func byPriority(a, b FulfilmentCandidate) int {
+	return cmp.Compare(a.Warehouse.Priority, b.Warehouse.Priority)
+}
+
+// distance returns how far the warehouse is from the destination in
+// kilometres. Without coordinates it can only tell whether the warehouse is
+// in the destination's country: 0 if so, +Inf if not. Every warehouse is at
+// distance 0 from no destination.
+func distance(destination *entity.Address, warehouse entity.Warehouse) float64 {
+	switch {
+	case destination == nil:
+		return 0
+	case destination.Latitude != nil && destination.Longitude != nil:
+		return haversine(*destination.Latitude, *destination.Longitude, warehouse.Latitude, warehouse.Longitude)
+	case strings.EqualFold(destination.Country, warehouse.Country):
+		return 0
+	}
+	return math.Inf(1)
+}
+
+// haversine returns the great-circle distance in kilometres between two
+// points given in degrees.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := rad(lat2-lat1), rad(lon2-lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// shippingAddress returns the address orders for the customer ship to: the
+// one labelled shipping, or else the first. It returns nil for customers
+// without addresses.
+func shippingAddress(customer *entity.Customer) *entity.Address {
+	if customer == nil || len(customer.Addresses) == 0 {
+		return nil
+	}
+	for i, address := range customer.Addresses {
+		if strings.EqualFold(address.Label, "shipping") {
+			return &customer.Addresses[i]
+		}
+	}
+	return &customer.Addresses[0]
+}
@@ -0,0 +1,206 @@
+package usecase_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// The warehouses the fulfilment tests ship from, by priority.
+var testWarehouses = []entity.Warehouse{
+	{Name: "Berlin", Country: "DE", Latitude: 52.52, Longitude: 13.405, Priority: 1},
+	{Name: "Paris", Country: "FR", Latitude: 48.857, Longitude: 2.352, Priority: 2},
+	{Name: "Munich", Country: "DE", Latitude: 48.135, Longitude: 11.582, Priority: 3},
+}
+
+// testLevels is the stock of each test product in each test warehouse; 0
+// means the warehouse does not stock the product.
+var testLevels = [][]int{
+	{5, 20, 10},
+	{0, 0, 2},
+}
+
+// stockWarehouses adds the test warehouses and products and returns their
+// IDs.
+func (f *orderFixture) stockWarehouses(t *testing.T) (warehouses, products []string) {
+	t.Helper()
+	for _, warehouse := range testWarehouses {
+		created, err := f.repos.Warehouses.Create(f.ctx, &warehouse)
+		if err != nil {
+			t.Fatalf("create warehouse: %v", err)
+		}
+		warehouses = append(warehouses, created.ID)
+	}
+	for _, levels := range testLevels {
+		total := 0
+		for _, stock := range levels {
+			total += stock
+		}
+		productID := f.product(t, total)
+		for i, stock := range levels {
+			if stock == 0 {
+				continue
+			}
+			level := &entity.StockLevel{WarehouseID: warehouses[i], ProductID: productID, Stock: stock, UpdatedAt: time.Now()}
+			if err := f.repos.StockLevels.Set(f.ctx, level); err != nil {
+				t.Fatalf("set stock level: %v", err)
+			}
+		}
+		products = append(products, productID)
+	}
+	return warehouses, products
+}
+
+// levels returns the stock and reserved stock of the product in each test
+// warehouse.
+func (f *orderFixture) levels(t *testing.T, warehouses []string, productID string) (stock, reserved []int) {
+	t.Helper()
+	stored, err := f.repos.StockLevels.FindByProduct(f.ctx, productID)
+	if err != nil {
+		t.Fatalf("find stock levels: %v", err)
+	}
+	stock, reserved = make([]int, len(warehouses)), make([]int, len(warehouses))
+	for _, level := range stored {
+		for i, id := range warehouses {
+			if level.WarehouseID == id {
+				stock[i], reserved[i] = level.Stock, level.Reserved
+			}
+		}
+	}
+	return stock, reserved
+}
+
+func coordinates(lat, lon float64) entity.Address {
+	return entity.Address{Line1: "1 Main St", City: "Somewhere", PostalCode: "12345", Country: "DE", Latitude: &lat, Longitude: &lon}
+}
+
+func TestFulfilment(t *testing.T) {
+	// Warehouses are named by their index in testWarehouses: 0 Berlin,
+	// 1 Paris, 2 Munich
+	augsburg := coordinates(48.371, 10.898)
+	france := entity.Address{Line1: "1 Rue de Rivoli", City: "Lyon", PostalCode: "69001", Country: "FR"}
+	type allocation struct{ warehouse, quantity int }
+	tests := []struct {
+		name     string
+		strategy usecase.FulfilmentStrategy
+		address  []entity.Address
+		lines    []int // quantity of each test product; 0 leaves it out
+		err      error
+		want     [][]allocation // allocations of each line
+	}{
+		{name: "priority", strategy: usecase.PriorityStrategy{}, lines: []int{3}, want: [][]allocation{{{0, 3}}}},
+		{name: "priority skips warehouses short of the order", strategy: usecase.PriorityStrategy{}, lines: []int{8}, want: [][]allocation{{{1, 8}}}},
+		{name: "priority keeps the order together", strategy: usecase.PriorityStrategy{}, lines: []int{3, 1}, want: [][]allocation{{{2, 3}}, {{2, 1}}}},
+		{name: "closest by coordinates", strategy: usecase.ClosestStrategy{}, address: []entity.Address{augsburg}, lines: []int{3}, want: [][]allocation{{{2, 3}}}},
+		{name: "closest by country", strategy: usecase.ClosestStrategy{}, address: []entity.Address{france}, lines: []int{3}, want: [][]allocation{{{1, 3}}}},
+		{name: "closest without an address goes by priority", strategy: usecase.ClosestStrategy{}, lines: []int{3}, want: [][]allocation{{{0, 3}}}},
+		{name: "closest ships to the shipping address", strategy: usecase.ClosestStrategy{},
+			address: []entity.Address{augsburg, {Label: "shipping", Line1: "2 Rue", City: "Lyon", PostalCode: "69002", Country: "FR"}},
+			lines:   []int{3}, want: [][]allocation{{{1, 3}}}},
+		{name: "most stock", strategy: usecase.MostStockStrategy{}, lines: []int{3}, want: [][]allocation{{{1, 3}}}},
+		{name: "split by priority", strategy: usecase.PriorityStrategy{}, lines: []int{30}, want: [][]allocation{{{0, 5}, {1, 20}, {2, 5}}}},
+		{name: "split by distance", strategy: usecase.ClosestStrategy{}, address: []entity.Address{augsburg}, lines: []int{30},
+			want: [][]allocation{{{2, 10}, {0, 5}, {1, 15}}}},
+		{name: "split by stock", strategy: usecase.MostStockStrategy{}, lines: []int{30}, want: [][]allocation{{{1, 20}, {2, 10}}}},
+		{name: "split lines separately", strategy: usecase.PriorityStrategy{}, lines: []int{30, 2},
+			want: [][]allocation{{{0, 5}, {1, 20}, {2, 5}}, {{2, 2}}}},
+		{name: "short across all warehouses", strategy: usecase.PriorityStrategy{}, lines: []int{36}, err: usecase.ErrInsufficientStock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderFixture(t, tt.strategy, tt.address...)
+			warehouses, products := f.stockWarehouses(t)
+			var items []entity.OrderItem
+			for i, quantity := range tt.lines {
+				if quantity > 0 {
+					items = append(items, entity.OrderItem{ProductID: products[i], Quantity: quantity})
+				}
+			}
+
+			order, err := f.order(items...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("CreateOrder error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				for _, productID := range products {
+					if _, reserved := f.levels(t, warehouses, productID); !reflect.DeepEqual(reserved, make([]int, len(warehouses))) {
+						t.Errorf("refused order reserved %v", reserved)
+					}
+				}
+				return
+			}
+
+			for i, item := range order.Items {
+				var want []entity.Allocation
+				for _, a := range tt.want[i] {
+					want = append(want, entity.Allocation{WarehouseID: warehouses[a.warehouse], Quantity: a.quantity})
+				}
+				if !reflect.DeepEqual(item.Allocations, want) {
+					t.Errorf("line %d allocated %v, want %v", i, item.Allocations, want)
+				}
+
+				// Each warehouse holds what it was allocated
+				wantReserved := make([]int, len(warehouses))
+				for _, a := range tt.want[i] {
+					wantReserved[a.warehouse] = a.quantity
+				}
+				if _, reserved := f.levels(t, warehouses, item.ProductID); !reflect.DeepEqual(reserved, wantReserved) {
+					t.Errorf("line %d reserved %v by warehouse, want %v", i, reserved, wantReserved)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitOrderStockEffects(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	warehouses, products := f.stockWarehouses(t)
+	order, err := f.order(entity.OrderItem{ProductID: products[0], Quantity: 30})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		do       transition
+		stock    []int
+		reserved []int
+	}{
+		{name: "pay", do: pay, stock: []int{0, 0, 5}, reserved: []int{0, 0, 0}},
+		{name: "cancel", do: cancel, stock: []int{5, 20, 10}, reserved: []int{0, 0, 0}},
+	}
+	for _, step := range steps {
+		if _, err := step.do(f.orders, f.ctx, order.ID); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		stock, reserved := f.levels(t, warehouses, products[0])
+		if !reflect.DeepEqual(stock, step.stock) || !reflect.DeepEqual(reserved, step.reserved) {
+			t.Errorf("after %s: warehouses have stock %v (%v reserved), want %v (%v reserved)", step.name, stock, reserved, step.stock, step.reserved)
+		}
+		total, totalReserved := f.stock(t, products[0])
+		if sum := step.stock[0] + step.stock[1] + step.stock[2]; total != sum || totalReserved != 0 {
+			t.Errorf("after %s: product has stock %d (%d reserved), want %d (0 reserved)", step.name, total, totalReserved, sum)
+		}
+	}
+}
+
+func TestNewFulfilmentStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		want usecase.FulfilmentStrategy
+	}{
+		{"priority", usecase.PriorityStrategy{}},
+		{"closest", usecase.ClosestStrategy{}},
+		{"most_stock", usecase.MostStockStrategy{}},
+		{"nearest", nil},
+	}
+	for _, tt := range tests {
+		got, err := usecase.NewFulfilmentStrategy(tt.name)
+		if got != tt.want || (err != nil) != (tt.want == nil) {
+			t.Errorf("NewFulfilmentStrategy(%q) = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+)
+
+// IdempotencyService remembers the responses to requests sent with an
+// idempotency key, so that a retried request replays the first response
+// instead of repeating its effects. Keys are scoped to the actor that sent
+// them.
+type IdempotencyService struct {
+	repo    IdempotencyRepository
+	ttl     time.Duration
+	lockTTL time.Duration
+	logger  *slog.Logger
+}
+
+// NewIdempotencyService returns an IdempotencyService that keeps responses
+// for ttl. A request holds its key for lockTTL while it runs, so that the key
+// of a request that never finished, say because the process died, frees up
+// soon; lockTTL should outlast the slowest request.
+func NewIdempotencyService(repo IdempotencyRepository, ttl, lockTTL time.Duration, logger *slog.Logger) *IdempotencyService {
+	return &IdempotencyService{
+		repo:    repo,
+		ttl:     ttl,
+		lockTTL: lockTTL,
+		logger:  logger,
+	}
+}
+
+// Begin claims key for a request identified by fingerprint. It returns nil
+// when the caller should handle the request and then call Complete or
+// Release, or the stored record when the key already completed the same
+// request. A different request under the key fails with
+// ErrIdempotencyKeyReused, and one that arrives while the first is still
+// running with ErrIdempotencyKeyInFlight. A claim whose lock TTL ran out
+// without Complete or Release is taken over.
+func (s *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*entity.IdempotencyRecord, error) {
+	scoped := scopeKey(ctx, key)
+
+	now := time.Now()
+	record := &entity.IdempotencyRecord{
+		Key:         scoped,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.lockTTL),
+	}
+	err := s.repo.Create(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, ErrConflict) {
+		s.logger.Error("Failed to claim idempotency key", "key", key, "error", err)
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	stored, err := s.repo.FindByKey(ctx, scoped)
+	if errors.Is(err, ErrNotFound) {
+		// The first request failed and let go of the key a moment ago
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch idempotency record", "key", key, "error", err)
+		return nil, fmt.Errorf("failed to fetch idempotency record: %w", err)
+	}
+
+	switch {
+	case stored.Fingerprint != fingerprint:
+		s.logger.Info("Idempotency key reused for another request", "key", key)
+		return nil, ErrIdempotencyKeyReused
+	case stored.Status == 0:
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	s.logger.Info("Replaying stored response", "key", key, "status", stored.Status)
+	return stored, nil
+}
+
+// Complete stores the response to the request that claimed key and keeps it
+// for the full TTL.
+func (s *IdempotencyService) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	record := &entity.IdempotencyRecord{
+		Key:         scopeKey(ctx, key),
+		Status:      status,
+		ContentType: contentType,
+		Body:        body,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	if err := s.repo.Complete(ctx, record); err != nil {
+		s.logger.Error("Failed to store idempotent response", "key", key, "error", err)
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release gives up key after the request that claimed it failed. A failed
+// request changes nothing, so a retry may run it again.
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	err := s.repo.Delete(ctx, scopeKey(ctx, key))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		s.logger.Error("Failed to release idempotency key", "key", key, "error", err)
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// scopeKey prefixes key with the actor's user ID, so that clients cannot
+// collide with or replay each other's keys.
+func scopeKey(ctx context.Context, key string) string {
+	actor, _ := ActorFrom(ctx)
+	return actor.UserID + ":" + key
+}
@@ -2,21 +2,312 @@ package usecase
 
 import (
 	"context"
+	"time"
 	"ulab3/internal/entity"
 )
 
 type ProductRepository interface {
 	Create(ctx context.Context, product *entity.Product) (*entity.Product, error)
-	FindAll(ctx context.Context) ([]entity.Product, error)
+	// FindAll returns up to query.Limit products matching the filter, in sort
+	// order, starting after query.After.
+	// Deleted products are left out unless query.IncludeDeleted is set.
+	FindAll(ctx context.Context, query ProductQuery) ([]entity.Product, error)
+	// FindByID returns the product unless it is deleted.
 	FindByID(ctx context.Context, id string) (*entity.Product, error)
+	// FindByIDIncludingDeleted returns the product whether or not it is
+	// deleted.
+	FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Product, error)
+	// Search returns up to limit products matching the free-text query on
+	// name and category, best match first. Small typos are tolerated.
+	// Deleted products never match.
+	Search(ctx context.Context, query string, limit int) ([]entity.ProductMatch, error)
+	// Update replaces the product provided it is still at product.Version,
+	// and moves it to the next version. It returns ErrVersionConflict if
+	// another write got there first. Deleted products cannot be updated.
 	Update(ctx context.Context, id string, product *entity.Product) error
+	// Delete removes the product for good, whether or not it is deleted.
+	Delete(ctx context.Context, id string) error
+	// SoftDelete marks the product deleted at the given time, returning
+	// ErrNotFound unless it exists and is not deleted yet.
+	SoftDelete(ctx context.Context, id string, at time.Time) error
+	// Restore clears the product's deletion, returning ErrNotFound unless it
+	// exists and is deleted.
+	Restore(ctx context.Context, id string, at time.Time) error
+	// Purge removes the products deleted before the given time for good and
+	// returns how many it removed.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// DecrementStock takes quantity from the product's stock only if at least
+	// that much is available, that is not reserved, returning
+	// ErrInsufficientStock otherwise. Deleted products have no stock to take.
+	DecrementStock(ctx context.Context, id string, quantity int) error
+	// IncrementStock returns quantity to the product's stock. Restocking a
+	// product that no longer exists is a no-op.
+	IncrementStock(ctx context.Context, id string, quantity int) error
+	// Reserve adds quantity to the product's reserved stock only if at least
+	// that much is available, returning ErrInsufficientStock otherwise.
+	// Deleted products have no stock to reserve.
+	Reserve(ctx context.Context, id string, quantity int) error
+	// Unreserve takes quantity off the product's reserved stock. Releasing a
+	// hold on a product that no longer exists is a no-op.
+	Unreserve(ctx context.Context, id string, quantity int) error
+	// CommitReserved takes quantity off both the product's reserved stock and
+	// its stock, returning ErrInsufficientStock if the stock was lowered below
+	// it in the meantime.
+	CommitReserved(ctx context.Context, id string, quantity int) error
+	// SetStock overwrites the product's stock, deleted or not. Setting the
+	// stock of a product that no longer exists is a no-op.
+	SetStock(ctx context.Context, id string, stock int) error
+}
+
+// WarehouseRepository stores the warehouses products ship from.
+type WarehouseRepository interface {
+	Create(ctx context.Context, warehouse *entity.Warehouse) (*entity.Warehouse, error)
+	FindByID(ctx context.Context, id string) (*entity.Warehouse, error)
+	// FindAll returns every warehouse by priority, then ID.
+	FindAll(ctx context.Context) ([]entity.Warehouse, error)
+	// Update stores the warehouse's Name, Country, Latitude, Longitude,
+	// Priority and UpdatedAt.
+	Update(ctx context.Context, warehouse *entity.Warehouse) error
+	Delete(ctx context.Context, id string) error
+}
+
+// StockLevelRepository stores how much of each product every warehouse
+// keeps. Its stock moves act on one warehouse the way the ProductRepository
+// ones act on a product's totals; callers move both together.
+type StockLevelRepository interface {
+	// FindByProduct returns the product's stock levels by warehouse ID.
+	FindByProduct(ctx context.Context, productID string) ([]entity.StockLevel, error)
+	// FindByWarehouse returns the warehouse's stock levels by product ID.
+	FindByWarehouse(ctx context.Context, warehouseID string) ([]entity.StockLevel, error)
+	// Set stores level.Stock and level.UpdatedAt, adding the level if the
+	// warehouse does not stock the product yet. Reserved keeps its stored
+	// value.
+	Set(ctx context.Context, level *entity.StockLevel) error
+	// Delete removes the level, returning ErrNotFound if there is none.
+	Delete(ctx context.Context, warehouseID, productID string) error
+	// DecrementStock takes quantity from the level's stock only if at least
+	// that much is available, returning ErrInsufficientStock otherwise.
+	DecrementStock(ctx context.Context, warehouseID, productID string, quantity int) error
+	// IncrementStock returns quantity to the level's stock. Restocking a
+	// level that no longer exists is a no-op.
+	IncrementStock(ctx context.Context, warehouseID, productID string, quantity int) error
+	// Reserve adds quantity to the level's reserved stock only if at least
+	// that much is available, returning ErrInsufficientStock otherwise.
+	Reserve(ctx context.Context, warehouseID, productID string, quantity int) error
+	// Unreserve takes quantity off the level's reserved stock. Releasing a
+	// hold on a level that no longer exists is a no-op.
+	Unreserve(ctx context.Context, warehouseID, productID string, quantity int) error
+	// CommitReserved takes quantity off both the level's reserved stock and
+	// its stock, returning ErrInsufficientStock if the level is gone or its
+	// stock was lowered below it.
+	CommitReserved(ctx context.Context, warehouseID, productID string, quantity int) error
+}
+
+// ReservationRepository stores the stock holds of orders and carts. Expired
+// holds stay until they are released, so the reserved stock of the products
+// always matches the stored holds.
+type ReservationRepository interface {
+	Create(ctx context.Context, reservation *entity.Reservation) (*entity.Reservation, error)
+	// FindByHolder returns every hold of the order or cart, expired ones
+	// included.
+	FindByHolder(ctx context.Context, holderType entity.HolderType, holderID string) ([]entity.Reservation, error)
+	// FindExpired returns up to limit holds that expired before the given
+	// time, the longest expired first.
+	FindExpired(ctx context.Context, before time.Time, limit int) ([]entity.Reservation, error)
+	// Delete removes the hold, returning ErrNotFound if it is gone already;
+	// whoever deletes a hold is the one to release its stock.
 	Delete(ctx context.Context, id string) error
 }
 
 type OrderRepository interface {
 	Create(ctx context.Context, order *entity.Order) (*entity.Order, error)
-	FindAll(ctx context.Context) ([]entity.Order, error)
+	// FindAll returns up to query.Limit orders matching the filter, in sort
+	// order, starting after query.After.
+	// Deleted orders are left out unless query.IncludeDeleted is set.
+	FindAll(ctx context.Context, query OrderQuery) ([]entity.Order, error)
+	// FindByID returns the order unless it is deleted.
 	FindByID(ctx context.Context, id string) (*entity.Order, error)
+	// FindByIDIncludingDeleted returns the order whether or not it is
+	// deleted.
+	FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Order, error)
+	// Update replaces the order provided it is still at order.Version, and
+	// moves it to the next version. It returns ErrVersionConflict if another
+	// write got there first. Deleted orders cannot be updated.
 	Update(ctx context.Context, id string, order *entity.Order) error
+	// Delete removes the order for good, whether or not it is deleted.
 	Delete(ctx context.Context, id string) error
+	// SoftDelete marks the order deleted at the given time, returning
+	// ErrNotFound unless it exists and is not deleted yet.
+	SoftDelete(ctx context.Context, id string, at time.Time) error
+	// Restore clears the order's deletion, returning ErrNotFound unless it
+	// exists and is deleted.
+	Restore(ctx context.Context, id string, at time.Time) error
+	// Purge removes the orders deleted before the given time for good and
+	// returns how many it removed.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// UpdateStatus moves the order to change.To and appends change to its
+	// status history, provided the order is still in status from. Otherwise it
+	// returns ErrOrderStatusChanged.
+	UpdateStatus(ctx context.Context, id string, from entity.OrderStatus, change entity.StatusChange) error
+	// MarkStockReleased flags the order's stock as returned to the catalog and
+	// reports whether this call set the flag, so callers restock only once.
+	MarkStockReleased(ctx context.Context, id string) (bool, error)
+}
+
+type UserRepository interface {
+	// Create stores a new user, returning ErrEmailTaken if another user
+	// already registered the email.
+	Create(ctx context.Context, user *entity.User) (*entity.User, error)
+	FindByID(ctx context.Context, id string) (*entity.User, error)
+	FindByEmail(ctx context.Context, email string) (*entity.User, error)
+	UpdateRole(ctx context.Context, id string, role entity.Role, updatedAt time.Time) error
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *entity.RefreshToken) error
+	FindByID(ctx context.Context, id string) (*entity.RefreshToken, error)
+	// Revoke marks the token revoked and reports whether this call did so, so
+	// a refresh token can be exchanged only once.
+	Revoke(ctx context.Context, id string, at time.Time) (bool, error)
+}
+
+type IdempotencyRepository interface {
+	// Create claims record.Key, returning ErrConflict if an unexpired record
+	// already holds the key. Expired records give way to the new one.
+	Create(ctx context.Context, record *entity.IdempotencyRecord) error
+	// FindByKey returns the record holding key, or ErrNotFound if there is
+	// none or it has expired.
+	FindByKey(ctx context.Context, key string) (*entity.IdempotencyRecord, error)
+	// Complete stores the response in the record's Status, ContentType and
+	// Body, and its new ExpiresAt, under record.Key.
+	Complete(ctx context.Context, record *entity.IdempotencyRecord) error
+	Delete(ctx context.Context, key string) error
+}
+
+// AuditRepository keeps the audit trail. It only appends: entries are never
+// changed or removed once written.
+type AuditRepository interface {
+	// Append stores a new entry and assigns its ID.
+	Append(ctx context.Context, entry *entity.AuditEntry) error
+	FindAll(ctx context.Context, query AuditQuery) ([]entity.AuditEntry, error)
+}
+
+// OutboxRepository holds domain events from the moment the change that raised
+// them commits until they are delivered.
+type OutboxRepository interface {
+	// Append stores a new event and assigns its ID.
+	Append(ctx context.Context, event *entity.Event) error
+	// Claim returns up to limit pending events whose next attempt is due at
+	// now, oldest first, and moves their next attempt to until, so that other
+	// dispatchers leave them alone while they are being delivered.
+	Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.Event, error)
+	// UpdateDelivery stores the event's Status, Attempts, NextAttemptAt,
+	// LastError and DeliveredAt.
+	UpdateDelivery(ctx context.Context, event *entity.Event) error
+	FindByID(ctx context.Context, id string) (*entity.Event, error)
+	FindAll(ctx context.Context, query EventQuery) ([]entity.Event, error)
+	// PurgeDelivered removes the events delivered before the given time and
+	// returns how many it removed.
+	PurgeDelivered(ctx context.Context, before time.Time) (int64, error)
+}
+
+type CustomerRepository interface {
+	Create(ctx context.Context, customer *entity.Customer) (*entity.Customer, error)
+	FindByID(ctx context.Context, id string) (*entity.Customer, error)
+	FindAll(ctx context.Context, query CustomerQuery) ([]entity.Customer, error)
+	// Update stores the customer's Name, Email, Phone, Addresses, Status and
+	// UpdatedAt.
+	Update(ctx context.Context, customer *entity.Customer) error
+	Delete(ctx context.Context, id string) error
+}
+
+// CartRepository stores carts. Expired carts are treated as missing by every
+// lookup and write.
+type CartRepository interface {
+	Create(ctx context.Context, cart *entity.Cart) (*entity.Cart, error)
+	FindByID(ctx context.Context, id string) (*entity.Cart, error)
+	// Update stores the cart's Items, UpdatedAt and ExpiresAt.
+	Update(ctx context.Context, cart *entity.Cart) error
+	Delete(ctx context.Context, id string) error
+	// DeleteExpired removes the carts that expired before the given time and
+	// returns how many it removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entity.Webhook) (*entity.Webhook, error)
+	FindByID(ctx context.Context, id string) (*entity.Webhook, error)
+	FindAll(ctx context.Context, query WebhookQuery) ([]entity.Webhook, error)
+	// FindSubscribed returns every webhook subscribed to the event type.
+	FindSubscribed(ctx context.Context, eventType entity.EventType) ([]entity.Webhook, error)
+	// Update stores the webhook's URL, EventTypes, Secret and UpdatedAt.
+	Update(ctx context.Context, webhook *entity.Webhook) error
+	Delete(ctx context.Context, id string) error
+}
+
+// WebhookDeliveryRepository holds the deliveries of events to webhooks and
+// their attempt logs.
+type WebhookDeliveryRepository interface {
+	// Create stores a new delivery and assigns its ID, returning ErrConflict
+	// if the webhook already has a delivery for the event.
+	Create(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// Claim returns up to limit pending deliveries whose next attempt is due
+	// at now, oldest first, and moves their next attempt to until, so that
+	// other workers leave them alone while they are being sent.
+	Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.WebhookDelivery, error)
+	// UpdateDelivery stores the delivery's Status, AttemptCount, Attempts,
+	// NextAttemptAt and DeliveredAt.
+	UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	FindByID(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+	FindAll(ctx context.Context, query WebhookDeliveryQuery) ([]entity.WebhookDelivery, error)
+	// DeleteByWebhook removes every delivery to the webhook.
+	DeleteByWebhook(ctx context.Context, webhookID string) error
+}
+
+// Publisher delivers domain events to a sink outside the service. Events are
+// delivered at least once, so sinks may see the same event again.
+type Publisher interface {
+	Publish(ctx context.Context, event entity.Event) error
+}
+
+// EventFeed follows the events appended to the outbox by every instance that
+// shares the database.
+type EventFeed interface {
+	// Watch calls fn with each event committed from now on until ctx is done
+	// or the feed fails.
+	Watch(ctx context.Context, fn func(event entity.Event)) error
+}
+
+// WebhookSender POSTs a delivery's body to its webhook, signed with the
+// webhook's secret, and returns the response status. An error means no
+// response came back.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) (int, error)
+}
+
+// Transactor groups repository calls into a single unit of work. Either every
+// write made through the ctx handed to fn is committed, or none of them is.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Repositories bundles the storage one backend provides to the services.
+type Repositories struct {
+	Products      ProductRepository
+	Orders        OrderRepository
+	Users         UserRepository
+	Customers     CustomerRepository
+	Carts         CartRepository
+	Reservations  ReservationRepository
+	Warehouses    WarehouseRepository
+	StockLevels   StockLevelRepository
+	RefreshTokens RefreshTokenRepository
+	Idempotency   IdempotencyRepository
+	Audit         AuditRepository
+	Outbox        OutboxRepository
+	Webhooks      WebhookRepository
+	Deliveries    WebhookDeliveryRepository
+	Transactor    Transactor
+	// Feed is nil for backends that cannot follow the outbox.
+	Feed EventFeed
 }
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"ulab3/internal/entity"
+)
+
+// inventory moves stock. A product's totals always move; for products stocked
+// in warehouses, the stock level of the warehouse named moves with them, so
+// the totals stay the sums over the levels. An empty warehouse ID names the
+// stock of a product stocked in no warehouse.
+type inventory struct {
+	productRepo   ProductRepository
+	warehouseRepo WarehouseRepository
+	levelRepo     StockLevelRepository
+	strategy      FulfilmentStrategy
+	logger        *slog.Logger
+}
+
+// allocate returns the lines with the warehouses that fulfil them. Lines of
+// products stocked in warehouses go to the first warehouse, in the order the
+// strategy ranks them for the destination, that can cover all of them;
+// failing that, each line takes what it can from every warehouse in turn. A
+// line that all warehouses together cannot cover fails with
+// ErrInsufficientStock. Lines of other products get no allocations.
+func (inv *inventory) allocate(ctx context.Context, destination *entity.Address, items []entity.OrderItem) ([]entity.OrderItem, error) {
+	items = slices.Clone(items)
+	stocked := make(map[string][]entity.StockLevel)
+	for i, item := range items {
+		items[i].Allocations = nil
+		levels, err := inv.levels(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if len(levels) > 0 {
+			stocked[item.ProductID] = levels
+		}
+	}
+	if len(stocked) == 0 {
+		return items, nil
+	}
+
+	candidates, err := inv.candidates(ctx, stocked)
+	if err != nil {
+		return nil, err
+	}
+	inv.strategy.Rank(destination, candidates)
+
+	for _, candidate := range candidates {
+		covers := true
+		for _, item := range items {
+			if stocked[item.ProductID] != nil && candidate.Available[item.ProductID] < item.Quantity {
+				covers = false
+				break
+			}
+		}
+		if covers {
+			for i, item := range items {
+				if stocked[item.ProductID] != nil {
+					items[i].Allocations = []entity.Allocation{{WarehouseID: candidate.Warehouse.ID, Quantity: item.Quantity}}
+				}
+			}
+			return items, nil
+		}
+	}
+
+	// No single warehouse can ship the order, so split it
+	for i, item := range items {
+		if stocked[item.ProductID] == nil {
+			continue
+		}
+		rest := item.Quantity
+		for _, candidate := range candidates {
+			if quantity := min(rest, candidate.Available[item.ProductID]); quantity > 0 {
+				items[i].Allocations = append(items[i].Allocations, entity.Allocation{WarehouseID: candidate.Warehouse.ID, Quantity: quantity})
+				rest -= quantity
+			}
+		}
+		if rest > 0 {
+			inv.logger.Info("Insufficient stock for product", "product_id", item.ProductID)
+			return nil, fmt.Errorf("product %s: %w", item.ProductID, ErrInsufficientStock)
+		}
+	}
+	return items, nil
+}
+
+// candidates lists the warehouses that stock any of the products, with what
+// they have available of each, by priority.
+func (inv *inventory) candidates(ctx context.Context, stocked map[string][]entity.StockLevel) ([]FulfilmentCandidate, error) {
+	warehouses, err := inv.warehouseRepo.FindAll(ctx)
+	if err != nil {
+		inv.logger.Error("Failed to fetch warehouses", "error", err)
+		return nil, fmt.Errorf("failed to fetch warehouses: %w", err)
+	}
+
+	var candidates []FulfilmentCandidate
+	for _, warehouse := range warehouses {
+		available := make(map[string]int)
+		for productID, levels := range stocked {
+			for _, level := range levels {
+				if level.WarehouseID == warehouse.ID {
+					available[productID] = max(level.Stock-level.Reserved, 0)
+				}
+			}
+		}
+		if len(available) > 0 {
+			candidates = append(candidates, FulfilmentCandidate{Warehouse: warehouse, Available: available})
+		}
+	}
+	return candidates, nil
+}
+
+// reserve holds quantity of the product in the warehouse, failing with
+// ErrInsufficientStock unless that much is available.
+func (inv *inventory) reserve(ctx context.Context, productID, warehouseID string, quantity int) error {
+	if err := inv.productRepo.Reserve(ctx, productID, quantity); err != nil {
+		return inv.stockError(err, "reserve product stock", productID)
+	}
+	if warehouseID == "" {
+		return nil
+	}
+	if err := inv.levelRepo.Reserve(ctx, warehouseID, productID, quantity); err != nil {
+		return inv.stockError(err, "reserve warehouse stock", productID)
+	}
+	return nil
+}
+
+// unreserve lets go of quantity of the product held in the warehouse.
+func (inv *inventory) unreserve(ctx context.Context, productID, warehouseID string, quantity int) error {
+	if err := inv.productRepo.Unreserve(ctx, productID, quantity); err != nil {
+		inv.logger.Error("Failed to release product stock", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to release product stock: %w", err)
+	}
+	if warehouseID == "" {
+		return nil
+	}
+	if err := inv.levelRepo.Unreserve(ctx, warehouseID, productID, quantity); err != nil {
+		inv.logger.Error("Failed to release warehouse stock", "product_id", productID, "warehouse_id", warehouseID, "error", err)
+		return fmt.Errorf("failed to release warehouse stock: %w", err)
+	}
+	return nil
+}
+
+// commit takes quantity of the product held in the warehouse out of stock.
+func (inv *inventory) commit(ctx context.Context, productID, warehouseID string, quantity int) error {
+	if err := inv.productRepo.CommitReserved(ctx, productID, quantity); err != nil {
+		return inv.stockError(err, "commit product stock", productID)
+	}
+	if warehouseID == "" {
+		return nil
+	}
+	if err := inv.levelRepo.CommitReserved(ctx, warehouseID, productID, quantity); err != nil {
+		return inv.stockError(err, "commit warehouse stock", productID)
+	}
+	return nil
+}
+
+// take takes quantity of the product out of the warehouse's available stock,
+// failing with ErrInsufficientStock unless that much is available.
+func (inv *inventory) take(ctx context.Context, productID, warehouseID string, quantity int) error {
+	if err := inv.productRepo.DecrementStock(ctx, productID, quantity); err != nil {
+		return inv.stockError(err, "update product stock", productID)
+	}
+	if warehouseID == "" {
+		return nil
+	}
+	if err := inv.levelRepo.DecrementStock(ctx, warehouseID, productID, quantity); err != nil {
+		return inv.stockError(err, "update warehouse stock", productID)
+	}
+	return nil
+}
+
+// restock returns quantity of the product to the warehouse. Stock that comes
+// back from a warehouse that no longer stocks the product, or from no
+// warehouse at all, goes to the product's first warehouse by priority.
+func (inv *inventory) restock(ctx context.Context, productID, warehouseID string, quantity int) error {
+	levels, err := inv.levels(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if len(levels) > 0 && !slices.ContainsFunc(levels, func(level entity.StockLevel) bool { return level.WarehouseID == warehouseID }) {
+		candidates, err := inv.candidates(ctx, map[string][]entity.StockLevel{productID: levels})
+		if err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("product %s is stocked in no known warehouse", productID)
+		}
+		warehouseID = candidates[0].Warehouse.ID
+	}
+
+	if err := inv.productRepo.IncrementStock(ctx, productID, quantity); err != nil {
+		inv.logger.Error("Failed to restock product", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to restock product: %w", err)
+	}
+	if len(levels) == 0 {
+		return nil
+	}
+	if err := inv.levelRepo.IncrementStock(ctx, warehouseID, productID, quantity); err != nil {
+		inv.logger.Error("Failed to restock warehouse", "product_id", productID, "warehouse_id", warehouseID, "error", err)
+		return fmt.Errorf("failed to restock warehouse: %w", err)
+	}
+	return nil
+}
+
+func (inv *inventory) levels(ctx context.Context, productID string) ([]entity.StockLevel, error) {
+	levels, err := inv.levelRepo.FindByProduct(ctx, productID)
+	if err != nil {
+		inv.logger.Error("Failed to fetch stock levels", "product_id", productID, "error", err)
+		return nil, fmt.Errorf("failed to fetch stock levels: %w", err)
+	}
+	return levels, nil
+}
+
+// stockError reports a failed attempt to do what to the product's stock:
+// ErrInsufficientStock for the product, or anything else as a failure.
+func (inv *inventory) stockError(err error, what, productID string) error {
+	if errors.Is(err, ErrInsufficientStock) {
+		inv.logger.Info("Insufficient stock for product", "product_id", productID)
+		return fmt.Errorf("product %s: %w", productID, err)
+	}
+	inv.logger.Error("Failed to "+what, "product_id", productID, "error", err)
+	return fmt.Errorf("failed to %s: %w", what, err)
+}
+
+// parts returns the line's quantities by warehouse. A line without
+// allocations is one part from no warehouse.
+func parts(item entity.OrderItem) []entity.Allocation {
+	if len(item.Allocations) == 0 {
+		return []entity.Allocation{{Quantity: item.Quantity}}
+	}
+	return item.Allocations
+}
+
+// addAllocation adds the allocation to the ones of a line, merging it into
+// the one for the same warehouse if there is one. It never modifies the
+// allocations it is given.
+func addAllocation(allocations []entity.Allocation, allocation entity.Allocation) []entity.Allocation {
+	allocations = slices.Clone(allocations)
+	for i := range allocations {
+		if allocations[i].WarehouseID == allocation.WarehouseID {
+			allocations[i].Quantity += allocation.Quantity
+			return allocations
+		}
+	}
+	return append(allocations, allocation)
+}
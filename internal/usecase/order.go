@@ -2,114 +2,808 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 	"ulab3/internal/entity"
 )
 
 type OrderService struct {
-	orderRepo   OrderRepository
-	productRepo ProductRepository
-	logger      *slog.Logger
+	orderRepo    OrderRepository
+	productRepo  ProductRepository
+	customerRepo CustomerRepository
+	auditRepo    AuditRepository
+	outboxRepo   OutboxRepository
+	inventory    *inventory
+	holds        *stockHolds
+	broker       *Broker
+	tx           Transactor
+	logger       *slog.Logger
 }
 
-func NewOrderService(orderRepo OrderRepository, productRepo ProductRepository, logger *slog.Logger) *OrderService {
+// NewOrderService returns an OrderService that ships orders from the
+// warehouses strategy picks and whose pending orders hold their stock for
+// holdTTL.
+func NewOrderService(orderRepo OrderRepository, productRepo ProductRepository, customerRepo CustomerRepository, reservationRepo ReservationRepository, warehouseRepo WarehouseRepository, levelRepo StockLevelRepository, auditRepo AuditRepository, outboxRepo OutboxRepository, strategy FulfilmentStrategy, broker *Broker, tx Transactor, holdTTL time.Duration, logger *slog.Logger) *OrderService {
+	inventory := &inventory{productRepo: productRepo, warehouseRepo: warehouseRepo, levelRepo: levelRepo, strategy: strategy, logger: logger}
 	return &OrderService{
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
-		logger:      logger,
+		orderRepo:    orderRepo,
+		productRepo:  productRepo,
+		customerRepo: customerRepo,
+		auditRepo:    auditRepo,
+		outboxRepo:   outboxRepo,
+		inventory:    inventory,
+		holds:        &stockHolds{inventory: inventory, reservationRepo: reservationRepo, ttl: holdTTL, logger: logger},
+		broker:       broker,
+		tx:           tx,
+		logger:       logger,
 	}
 }
 
 func (s *OrderService) CreateOrder(ctx context.Context, order *entity.Order) (*entity.Order, error) {
-	s.logger.Info("Creating order for product ID:", order.ProductID)
-
-	// Check if product exists
-	product, err := s.productRepo.FindByID(ctx, order.ProductID)
+	actor, err := authorize(ctx, PermCreateOrders)
 	if err != nil {
-		s.logger.Error("Product not found:", err)
-		return nil, fmt.Errorf("invalid product ID")
+		return nil, err
 	}
+	s.logger.Info("Creating order", "items", len(order.Items), "user_id", actor.UserID)
 
-	// Check stock availability
-	if product.Stock < order.Quantity {
-		s.logger.Info("Insufficient stock for product:", product.Name)
-		return nil, fmt.Errorf("insufficient stock")
+	if err := Validate(order); err != nil {
+		return nil, err
+	}
+	if order.CustomerID == "" {
+		return nil, &ValidationError{Fields: []entity.FieldError{{Field: "customer_id", Message: "is required"}}}
 	}
+	items := mergeOrderItems(order.Items)
 
-	// Calculate the total price for the order
-	order.TotalPrice = float64(order.Quantity) * product.Price
-	order.Status = "Pending"
-	order.CreatedAt = time.Now()
-	order.UpdatedAt = time.Now()
+	var createdOrder *entity.Order
+	err = withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		customer, err := s.checkCustomer(ctx, order.CustomerID)
+		if err != nil {
+			return err
+		}
+		order.UserID = actor.UserID
+		order.Items = make([]entity.OrderItem, 0, len(items))
+		order.TotalPrice = 0
 
-	// Create the order
-	createdOrder, err := s.orderRepo.Create(ctx, order)
-	if err != nil {
-		s.logger.Error("Failed to create order:", err)
-		return nil, fmt.Errorf("failed to create order: %w", err)
-	}
+		for _, item := range items {
+			priced, err := s.priceItem(ctx, item)
+			if err != nil {
+				return err
+			}
+			order.Items = append(order.Items, priced)
+			order.TotalPrice += priced.LineTotal
+		}
+		if order.Items, err = s.inventory.allocate(ctx, shippingAddress(customer), order.Items); err != nil {
+			return err
+		}
 
-	// Update product stock
-	product.Stock -= order.Quantity
-	err = s.productRepo.Update(ctx, product.ID, product)
+		now := time.Now()
+		order.Status = entity.OrderStatusPending
+		order.StatusHistory = []entity.StatusChange{{To: entity.OrderStatusPending, At: now}}
+		order.StockReleased = false
+		order.StockHeld = true
+		order.Version = 1
+		order.CreatedAt = now
+		order.UpdatedAt = now
+
+		// Create the order
+		createdOrder, err = s.orderRepo.Create(ctx, order)
+		if err != nil {
+			s.logger.Error("Failed to create order", "error", err)
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+		// Hold the stock until the order is paid; any line short of stock
+		// rolls back the whole order
+		if err := s.holds.holdItems(ctx, entity.HolderOrder, createdOrder.ID, createdOrder.Items); err != nil {
+			return err
+		}
+		if err := s.audit(ctx, entity.AuditActionCreate, createdOrder.ID, nil, createdOrder); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, entity.EventOrderCreated, entityOrder, createdOrder.ID, createdOrder)
+	})
 	if err != nil {
-		s.logger.Error("Failed to update product stock:", err)
-		return nil, fmt.Errorf("failed to update product stock: %w", err)
+		return nil, err
 	}
 
-	s.logger.Info("Order created successfully:", createdOrder.ID)
+	s.logger.Info("Order created successfully", "id", createdOrder.ID)
 	return createdOrder, nil
 }
 
-func (s *OrderService) GetAllOrders(ctx context.Context) ([]entity.Order, error) {
-	s.logger.Info("Fetching all orders")
+// GetAllOrders lists orders. Actors who may only read their own orders see
+// just those, whatever user the filter asks for. Only admins may ask for
+// deleted orders to be included.
+func (s *OrderService) GetAllOrders(ctx context.Context, filter OrderFilter, page PageRequest) (*entity.OrderPage, error) {
+	actor, err := authorize(ctx, PermReadAllOrders, PermReadOwnOrders)
+	if err != nil {
+		return nil, err
+	}
+	if filter.IncludeDeleted {
+		if _, err := authorize(ctx, PermManageDeleted); err != nil {
+			return nil, err
+		}
+	}
+	if !actor.Can(PermReadAllOrders) {
+		filter.UserID = actor.UserID
+	}
+	s.logger.Info("Fetching orders", "sort", page.Sort, "limit", page.Limit)
+
+	sort, after, limit, err := parsePage(page, orderSortFields)
+	if err != nil {
+		return nil, err
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return nil, fmt.Errorf("%w: created_from must be before created_to", ErrInvalidQuery)
+	}
 
-	orders, err := s.orderRepo.FindAll(ctx)
+	// Ask for one extra record to learn whether another page follows
+	query := OrderQuery{OrderFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	orders, err := s.orderRepo.FindAll(ctx, query)
 	if err != nil {
-		s.logger.Error("Failed to fetch orders:", err)
+		s.logger.Error("Failed to fetch orders", "error", err)
 		return nil, fmt.Errorf("failed to fetch orders: %w", err)
 	}
 
-	return orders, nil
+	data, pagination := paginate(orders, limit, sort, orderSortFields, func(o entity.Order) string { return o.ID })
+	return &entity.OrderPage{Data: data, Pagination: pagination}, nil
+}
+
+// GetCustomerOrders lists the orders placed for a customer the actor may
+// manage, with the same filters and pages as GetAllOrders.
+func (s *OrderService) GetCustomerOrders(ctx context.Context, customerID string, filter OrderFilter, page PageRequest) (*entity.OrderPage, error) {
+	if _, err := findCustomer(ctx, s.customerRepo, s.logger, customerID); err != nil {
+		return nil, err
+	}
+	filter.CustomerID = customerID
+	return s.GetAllOrders(ctx, filter, page)
 }
 
-func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*entity.Order, error) {
-	s.logger.Info("Fetching order by ID:", id)
+// GetOrderByID returns an order. Deleted orders are only found when
+// includeDeleted is set, which only admins may do.
+func (s *OrderService) GetOrderByID(ctx context.Context, id string, includeDeleted bool) (*entity.Order, error) {
+	s.logger.Info("Fetching order by ID", "id", id)
 
-	order, err := s.orderRepo.FindByID(ctx, id)
+	find := s.orderRepo.FindByID
+	if includeDeleted {
+		if _, err := authorize(ctx, PermManageDeleted); err != nil {
+			return nil, err
+		}
+		find = s.orderRepo.FindByIDIncludingDeleted
+	}
+	order, err := find(ctx, id)
 	if err != nil {
-		s.logger.Error("Order not found:", err)
+		s.logger.Error("Order not found", "id", id, "error", err)
 		return nil, fmt.Errorf("order not found: %w", err)
 	}
+	if err := authorizeOrder(ctx, order, PermReadAllOrders, PermReadOwnOrders); err != nil {
+		return nil, err
+	}
 
 	return order, nil
 }
 
-func (s *OrderService) UpdateOrder(ctx context.Context, id string, order *entity.Order) error {
-	s.logger.Info("Updating order:", id)
+// UpdateOrder replaces the line items of an order and returns the stored
+// order. Every other field is managed by the server and keeps its value. A
+// non-zero version makes the update conditional: it fails with
+// ErrVersionMismatch unless the order is still at that version.
+func (s *OrderService) UpdateOrder(ctx context.Context, id string, version int64, order *entity.Order) (*entity.Order, error) {
+	if _, err := authorize(ctx, PermUpdateOrders); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Updating order", "id", id)
 
-	order.UpdatedAt = time.Now()
-	err := s.orderRepo.Update(ctx, id, order)
+	if err := Validate(order); err != nil {
+		return nil, err
+	}
+	return s.updateOrder(ctx, id, version, func(*entity.Order) (*entity.Order, error) {
+		return order, nil
+	})
+}
+
+// PatchOrder applies an RFC 7396 merge patch to an order and returns the
+// stored order. Only the line items can change, and a patch replaces them as
+// a whole; patches to server-managed fields are ignored. version works as in
+// UpdateOrder.
+func (s *OrderService) PatchOrder(ctx context.Context, id string, version int64, patch []byte) (*entity.Order, error) {
+	if _, err := authorize(ctx, PermUpdateOrders); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Patching order", "id", id)
+
+	return s.updateOrder(ctx, id, version, func(existing *entity.Order) (*entity.Order, error) {
+		var order entity.Order
+		if err := applyMergePatch(existing, patch, &order); err != nil {
+			return nil, err
+		}
+		return &order, Validate(&order)
+	})
+}
+
+// updateOrder stores the order change builds from the current one, moving
+// product stock by the difference in line items. The write only succeeds if
+// no other write moved the order on in the meantime.
+func (s *OrderService) updateOrder(ctx context.Context, id string, version int64, change func(existing *entity.Order) (*entity.Order, error)) (*entity.Order, error) {
+	var stored *entity.Order
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		existing, err := s.orderRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Order not found", "id", id, "error", err)
+			return fmt.Errorf("order not found: %w", err)
+		}
+		if version != 0 && existing.Version != version {
+			s.logger.Info("Order version mismatch", "id", id, "expected", version, "actual", existing.Version)
+			return fmt.Errorf("%w: order %s is at version %d", ErrVersionMismatch, id, existing.Version)
+		}
+		order, err := change(existing)
+		if err != nil {
+			return err
+		}
+
+		// Server-managed fields keep their stored values; status only
+		// changes through the transition methods
+		order.ID = id
+		order.UserID = existing.UserID
+		order.CustomerID = existing.CustomerID
+		order.CreatedAt = existing.CreatedAt
+		order.DeletedAt = existing.DeletedAt
+		order.Status = existing.Status
+		order.StatusHistory = existing.StatusHistory
+		order.StockReleased = existing.StockReleased
+		order.StockHeld = existing.StockHeld
+		order.Version = existing.Version
+
+		order.Items = mergeOrderItems(order.Items)
+		if err := s.adjustItems(ctx, existing, order); err != nil {
+			return err
+		}
+		order.TotalPrice = 0
+		for _, item := range order.Items {
+			order.TotalPrice += item.LineTotal
+		}
+
+		order.UpdatedAt = time.Now()
+		if err := s.orderRepo.Update(ctx, id, order); err != nil {
+			// A caller that named a version asked for exactly this to fail
+			if version != 0 && errors.Is(err, ErrVersionConflict) {
+				s.logger.Info("Order changed during update", "id", id)
+				return fmt.Errorf("%w: order %s changed during the update", ErrVersionMismatch, id)
+			}
+			s.logger.Error("Failed to update order", "id", id, "error", err)
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+
+		stored, err = s.orderRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Failed to fetch updated order", "id", id, "error", err)
+			return fmt.Errorf("failed to fetch updated order: %w", err)
+		}
+		return s.audit(ctx, entity.AuditActionUpdate, id, existing, stored)
+	})
 	if err != nil {
-		s.logger.Error("Failed to update order:", err)
-		return fmt.Errorf("failed to update order: %w", err)
+		return nil, err
 	}
 
-	s.logger.Info("Order updated successfully:", id)
-	return nil
+	s.logger.Info("Order updated successfully", "id", id)
+	return stored, nil
 }
 
+// DeleteOrder hides an order until it is restored or purged. An order that
+// still holds or took stock gives it back.
 func (s *OrderService) DeleteOrder(ctx context.Context, id string) error {
-	s.logger.Info("Deleting order:", id)
+	if _, err := authorize(ctx, PermDeleteOrders); err != nil {
+		return err
+	}
+	s.logger.Info("Deleting order", "id", id)
+
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		order, err := s.orderRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Order not found", "id", id, "error", err)
+			return fmt.Errorf("order not found: %w", err)
+		}
+		before := *order
+
+		// An order that still holds stock gives it back before it disappears
+		if holdsStock(order.Status) {
+			if err := s.releaseStock(ctx, order); err != nil {
+				return err
+			}
+		}
 
-	err := s.orderRepo.Delete(ctx, id)
+		now := time.Now()
+		if err := s.orderRepo.SoftDelete(ctx, id, now); err != nil {
+			s.logger.Error("Failed to delete order", "id", id, "error", err)
+			return fmt.Errorf("failed to delete order: %w", err)
+		}
+		order.DeletedAt = &now
+		return s.audit(ctx, entity.AuditActionDelete, id, &before, order)
+	})
 	if err != nil {
-		s.logger.Error("Failed to delete order:", err)
-		return fmt.Errorf("failed to delete order: %w", err)
+		return err
 	}
 
-	s.logger.Info("Order deleted successfully:", id)
+	s.logger.Info("Order deleted successfully", "id", id)
 	return nil
 }
+
+// RestoreOrder brings a deleted order back. An order whose status holds stock
+// takes back the stock its deletion released, a pending one as a fresh hold,
+// from the warehouses that can ship it now, so restoring fails if the stock
+// has run out since.
+func (s *OrderService) RestoreOrder(ctx context.Context, id string) (*entity.Order, error) {
+	if _, err := authorize(ctx, PermManageDeleted); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Restoring order", "id", id)
+
+	var order *entity.Order
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		deleted, err := s.orderRepo.FindByIDIncludingDeleted(ctx, id)
+		if err != nil {
+			s.logger.Error("Order not found", "id", id, "error", err)
+			return fmt.Errorf("order not found: %w", err)
+		}
+		if err := s.orderRepo.Restore(ctx, id, time.Now()); err != nil {
+			s.logger.Error("Failed to restore order", "id", id, "error", err)
+			return fmt.Errorf("failed to restore order: %w", err)
+		}
+		order, err = s.orderRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Failed to fetch restored order", "id", id, "error", err)
+			return fmt.Errorf("failed to fetch restored order: %w", err)
+		}
+
+		if holdsStock(order.Status) && order.StockReleased {
+			destination, err := s.destination(ctx, order)
+			if err != nil {
+				return err
+			}
+			if order.Status == entity.OrderStatusPending {
+				if order.Items, err = s.inventory.allocate(ctx, destination, order.Items); err != nil {
+					return err
+				}
+				if err := s.holds.holdItems(ctx, entity.HolderOrder, id, order.Items); err != nil {
+					return err
+				}
+				order.StockHeld = true
+			} else if order.Items, err = s.takeItems(ctx, destination, order.Items); err != nil {
+				return err
+			}
+			order.StockReleased = false
+			order.UpdatedAt = time.Now()
+			if err := s.orderRepo.Update(ctx, id, order); err != nil {
+				s.logger.Error("Failed to update restored order", "id", id, "error", err)
+				return fmt.Errorf("failed to update restored order: %w", err)
+			}
+			order.Version++
+		}
+		return s.audit(ctx, entity.AuditActionRestore, id, deleted, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Order restored successfully", "id", id)
+	return order, nil
+}
+
+// PurgeOrders removes the orders deleted before the given time for good and
+// returns how many it removed.
+func (s *OrderService) PurgeOrders(ctx context.Context, before time.Time) (int64, error) {
+	if _, err := authorize(ctx, PermManageDeleted); err != nil {
+		return 0, err
+	}
+	s.logger.Info("Purging deleted orders", "before", before)
+
+	purged, err := s.orderRepo.Purge(ctx, before)
+	if err != nil {
+		s.logger.Error("Failed to purge orders", "error", err)
+		return 0, fmt.Errorf("failed to purge orders: %w", err)
+	}
+
+	s.logger.Info("Orders purged successfully", "count", purged)
+	return purged, nil
+}
+
+// PayOrder marks a pending order as paid, taking the stock it holds. Lines
+// whose hold expired take the stock available now, so paying fails if it ran
+// out in the meantime.
+func (s *OrderService) PayOrder(ctx context.Context, id string) (*entity.Order, error) {
+	return s.transitionOrder(ctx, id, entity.OrderStatusPaid, PermFulfilOrders, "")
+}
+
+// ShipOrder marks a paid order as shipped.
+func (s *OrderService) ShipOrder(ctx context.Context, id string) (*entity.Order, error) {
+	return s.transitionOrder(ctx, id, entity.OrderStatusShipped, PermFulfilOrders, "")
+}
+
+// DeliverOrder marks a shipped order as delivered.
+func (s *OrderService) DeliverOrder(ctx context.Context, id string) (*entity.Order, error) {
+	return s.transitionOrder(ctx, id, entity.OrderStatusDelivered, PermFulfilOrders, "")
+}
+
+// CancelOrder cancels an order that has not shipped yet. Customers may cancel
+// the orders they placed.
+func (s *OrderService) CancelOrder(ctx context.Context, id string) (*entity.Order, error) {
+	return s.transitionOrder(ctx, id, entity.OrderStatusCancelled, PermCancelAllOrders, PermCancelOwnOrders)
+}
+
+// RefundOrder refunds a paid or delivered order.
+func (s *OrderService) RefundOrder(ctx context.Context, id string) (*entity.Order, error) {
+	return s.transitionOrder(ctx, id, entity.OrderStatusRefunded, PermFulfilOrders, "")
+}
+
+// transitionOrder moves an order to status to, taking the stock its items hold
+// when it is paid and restocking them when the transition releases them.
+// Asking for the status the order is already in is a no-op, so retried
+// requests neither fail nor restock twice. The actor needs all, or own if it
+// placed the order.
+func (s *OrderService) transitionOrder(ctx context.Context, id string, to entity.OrderStatus, all, own Permission) (*entity.Order, error) {
+	s.logger.Info("Changing order status", "id", id, "status", to)
+
+	var order *entity.Order
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		var err error
+		order, err = s.orderRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Order not found", "id", id, "error", err)
+			return fmt.Errorf("order not found: %w", err)
+		}
+		if err := authorizeOrder(ctx, order, all, own); err != nil {
+			return err
+		}
+
+		if order.Status == to {
+			return nil
+		}
+		if !canTransition(order.Status, to) {
+			s.logger.Info("Illegal order status transition", "id", id, "from", order.Status, "to", to)
+			return &TransitionError{From: order.Status, To: to}
+		}
+
+		before := *order
+		from := order.Status
+		change := entity.StatusChange{From: from, To: to, At: time.Now()}
+		if err := s.orderRepo.UpdateStatus(ctx, id, from, change); err != nil {
+			s.logger.Error("Failed to change order status", "id", id, "error", err)
+			return fmt.Errorf("failed to change order status: %w", err)
+		}
+		order.Status = to
+		order.StatusHistory = append(order.StatusHistory, change)
+		order.UpdatedAt = change.At
+		order.Version++
+
+		if takesStock(from, to) {
+			if err := s.commitStock(ctx, order); err != nil {
+				return err
+			}
+		}
+		if releasesStock(from, to) {
+			if err := s.releaseStock(ctx, order); err != nil {
+				return err
+			}
+		}
+		if err := s.audit(ctx, entity.AuditActionUpdate, id, &before, order); err != nil {
+			return err
+		}
+		statusChange := entity.OrderStatusChange{OrderID: id, UserID: order.UserID, From: from, To: to, At: change.At, Items: order.Items}
+		if err := s.recordEvent(ctx, entity.EventOrderStatusChanged, entityOrder, id, statusChange); err != nil {
+			return err
+		}
+		if to == entity.OrderStatusCancelled {
+			return s.recordEvent(ctx, entity.EventOrderCancelled, entityOrder, id, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Order status changed successfully", "id", id, "status", to)
+	return order, nil
+}
+
+// checkCustomer returns the customer with the ID after making sure it exists,
+// may be ordered for by the actor and is active.
+func (s *OrderService) checkCustomer(ctx context.Context, id string) (*entity.Customer, error) {
+	customer, err := s.customerRepo.FindByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Info("Unknown customer", "customer_id", id)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCustomer, id)
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch customer", "customer_id", id, "error", err)
+		return nil, fmt.Errorf("failed to fetch customer: %w", err)
+	}
+	if err := authorizeCustomer(ctx, customer); err != nil {
+		return nil, err
+	}
+	if customer.Status != entity.CustomerStatusActive {
+		s.logger.Info("Customer is inactive", "customer_id", id)
+		return nil, fmt.Errorf("%w: %s", ErrCustomerInactive, id)
+	}
+	return customer, nil
+}
+
+// destination returns the address the order ships to, or nil if its customer
+// has none or is gone.
+func (s *OrderService) destination(ctx context.Context, order *entity.Order) (*entity.Address, error) {
+	if order.CustomerID == "" {
+		return nil, nil
+	}
+	customer, err := s.customerRepo.FindByID(ctx, order.CustomerID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch customer", "customer_id", order.CustomerID, "error", err)
+		return nil, fmt.Errorf("failed to fetch customer: %w", err)
+	}
+	return shippingAddress(customer), nil
+}
+
+// audit records a change to the order with the ID.
+func (s *OrderService) audit(ctx context.Context, action entity.AuditAction, id string, before, after any) error {
+	if err := audit(ctx, s.auditRepo, action, entityOrder, id, before, after); err != nil {
+		s.logger.Error("Failed to audit order change", "id", id, "action", action, "error", err)
+		return err
+	}
+	return nil
+}
+
+// priceItem fills in the line's price snapshot from the product it names.
+func (s *OrderService) priceItem(ctx context.Context, item entity.OrderItem) (entity.OrderItem, error) {
+	// Check if product exists
+	product, err := s.productRepo.FindByID(ctx, item.ProductID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Info("Product not found", "product_id", item.ProductID)
+		return item, fmt.Errorf("%w: %s", ErrUnknownProduct, item.ProductID)
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch product", "product_id", item.ProductID, "error", err)
+		return item, fmt.Errorf("failed to fetch product: %w", err)
+	}
+
+	// Snapshot the price so later catalog changes do not alter the order
+	item.UnitPrice = product.Price
+	item.LineTotal = float64(item.Quantity) * product.Price
+	return item, nil
+}
+
+// adjustItems replaces an order's lines with the requested ones in order,
+// and allocates and holds stock for them afresh. Existing lines keep their
+// price snapshot.
+func (s *OrderService) adjustItems(ctx context.Context, existing, order *entity.Order) error {
+	current := make(map[string]entity.OrderItem, len(existing.Items))
+	for _, item := range existing.Items {
+		current[item.ProductID] = item
+	}
+	if !itemsChanged(current, order.Items) {
+		order.Items = existing.Items
+		return nil
+	}
+	if existing.Status != entity.OrderStatusPending || existing.StockReleased {
+		return ErrOrderNotEditable
+	}
+
+	items := make([]entity.OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		prev, ok := current[item.ProductID]
+		if !ok {
+			priced, err := s.priceItem(ctx, item)
+			if err != nil {
+				return err
+			}
+			items = append(items, priced)
+			continue
+		}
+		prev.Quantity = item.Quantity
+		prev.LineTotal = float64(prev.Quantity) * prev.UnitPrice
+		items = append(items, prev)
+	}
+
+	// Let go of the stock the order holds, or took before it held stock,
+	// then hold the new quantities; the order's own stock counts as available
+	if existing.StockHeld {
+		if err := s.holds.release(ctx, entity.HolderOrder, existing.ID); err != nil {
+			return err
+		}
+	} else if err := s.returnItems(ctx, existing.Items); err != nil {
+		return err
+	}
+	destination, err := s.destination(ctx, existing)
+	if err != nil {
+		return err
+	}
+	if items, err = s.inventory.allocate(ctx, destination, items); err != nil {
+		return err
+	}
+	if err := s.holds.holdItems(ctx, entity.HolderOrder, existing.ID, items); err != nil {
+		return err
+	}
+	order.Items = items
+	order.StockHeld = true
+	return nil
+}
+
+// commitStock takes the stock a pending order holds out of the products as
+// the order is paid. Lines whose hold expired take the stock available now,
+// from the warehouses that can ship it now, and their allocations follow.
+func (s *OrderService) commitStock(ctx context.Context, order *entity.Order) error {
+	// Orders placed before stock was held took it right away
+	if !order.StockHeld {
+		return nil
+	}
+
+	committed, err := s.holds.commit(ctx, entity.HolderOrder, order.ID)
+	if err != nil {
+		return err
+	}
+	items := make([]entity.OrderItem, len(order.Items))
+	var lapsed []entity.OrderItem
+	for i, item := range order.Items {
+		item.Allocations = nil
+		held := 0
+		for _, reservation := range committed {
+			if reservation.ProductID != item.ProductID {
+				continue
+			}
+			held += reservation.Quantity
+			if reservation.WarehouseID != "" {
+				item.Allocations = addAllocation(item.Allocations, entity.Allocation{WarehouseID: reservation.WarehouseID, Quantity: reservation.Quantity})
+			}
+		}
+		if held > 0 {
+			if err := s.recordStockChange(ctx, item.ProductID, -held); err != nil {
+				return err
+			}
+		}
+		if rest := item.Quantity - held; rest > 0 {
+			lapsed = append(lapsed, entity.OrderItem{ProductID: item.ProductID, Quantity: rest})
+		}
+		items[i] = item
+	}
+	if len(lapsed) > 0 {
+		destination, err := s.destination(ctx, order)
+		if err != nil {
+			return err
+		}
+		if lapsed, err = s.takeItems(ctx, destination, lapsed); err != nil {
+			return err
+		}
+		for _, taken := range lapsed {
+			i := slices.IndexFunc(items, func(item entity.OrderItem) bool { return item.ProductID == taken.ProductID })
+			for _, allocation := range taken.Allocations {
+				items[i].Allocations = addAllocation(items[i].Allocations, allocation)
+			}
+		}
+	}
+
+	order.Items = items
+	order.StockHeld = false
+	if err := s.orderRepo.Update(ctx, order.ID, order); err != nil {
+		s.logger.Error("Failed to update paid order", "id", order.ID, "error", err)
+		return fmt.Errorf("failed to update paid order: %w", err)
+	}
+	order.Version++
+	return nil
+}
+
+// releaseStock gives every line of the order back: the stock it holds, or the
+// stock it took. The order is flagged first, so its stock is released at most
+// once even across retries.
+func (s *OrderService) releaseStock(ctx context.Context, order *entity.Order) error {
+	released, err := s.orderRepo.MarkStockReleased(ctx, order.ID)
+	if err != nil {
+		s.logger.Error("Failed to release order stock", "id", order.ID, "error", err)
+		return fmt.Errorf("failed to release order stock: %w", err)
+	}
+	if !released {
+		return nil
+	}
+
+	if order.StockHeld {
+		if err := s.holds.release(ctx, entity.HolderOrder, order.ID); err != nil {
+			return err
+		}
+	} else if err := s.returnItems(ctx, order.Items); err != nil {
+		return err
+	}
+	order.StockReleased = true
+	order.Version++
+	return nil
+}
+
+// takeItems allocates the lines to the warehouses that can ship them to the
+// destination and takes their stock, returning the allocated lines.
+func (s *OrderService) takeItems(ctx context.Context, destination *entity.Address, items []entity.OrderItem) ([]entity.OrderItem, error) {
+	items, err := s.inventory.allocate(ctx, destination, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		for _, part := range parts(item) {
+			if err := s.inventory.take(ctx, item.ProductID, part.WarehouseID, part.Quantity); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.recordStockChange(ctx, item.ProductID, -item.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// returnItems gives the stock the lines took back to the warehouses they
+// took it from.
+func (s *OrderService) returnItems(ctx context.Context, items []entity.OrderItem) error {
+	for _, item := range items {
+		for _, part := range parts(item) {
+			if err := s.inventory.restock(ctx, item.ProductID, part.WarehouseID, part.Quantity); err != nil {
+				return err
+			}
+		}
+		if err := s.recordStockChange(ctx, item.ProductID, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordStockChange records a StockChanged event for a product whose stock
+// just moved by delta.
+func (s *OrderService) recordStockChange(ctx context.Context, productID string, delta int) error {
+	product, err := s.productRepo.FindByIDIncludingDeleted(ctx, productID)
+	if errors.Is(err, ErrNotFound) {
+		// Restocking a product that no longer exists changes nothing
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch product", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to fetch product: %w", err)
+	}
+	change := entity.StockChange{ProductID: productID, Delta: delta, Stock: product.Stock}
+	return s.recordEvent(ctx, entity.EventStockChanged, entityProduct, productID, change)
+}
+
+// recordEvent adds an event about the record of the type with the ID to the
+// outbox.
+func (s *OrderService) recordEvent(ctx context.Context, eventType entity.EventType, aggregateType, id string, payload any) error {
+	if err := recordEvent(ctx, s.outboxRepo, eventType, aggregateType, id, payload); err != nil {
+		s.logger.Error("Failed to record event", "aggregate_type", aggregateType, "id", id, "type", eventType, "error", err)
+		return err
+	}
+	return nil
+}
+
+// itemsChanged reports whether the requested lines differ in products or
+// quantities from the order's current lines.
+func itemsChanged(current map[string]entity.OrderItem, requested []entity.OrderItem) bool {
+	if len(current) != len(requested) {
+		return true
+	}
+	for _, item := range requested {
+		if prev, ok := current[item.ProductID]; !ok || prev.Quantity != item.Quantity {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeOrderItems folds lines for the same product into one, keeping the order
+// in which products first appear. Client supplied prices are dropped.
+func mergeOrderItems(items []entity.OrderItem) []entity.OrderItem {
+	merged := make([]entity.OrderItem, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, item := range items {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, entity.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return merged
+}
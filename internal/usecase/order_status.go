@@ -0,0 +1,43 @@
+package usecase
+
+import "ulab3/internal/entity"
+
+// orderTransitions lists, for every status, the statuses an order may move to.
+// Cancelled and Refunded are terminal.
+var orderTransitions = map[entity.OrderStatus][]entity.OrderStatus{
+	entity.OrderStatusPending:   {entity.OrderStatusPaid, entity.OrderStatusCancelled},
+	entity.OrderStatusPaid:      {entity.OrderStatusShipped, entity.OrderStatusCancelled, entity.OrderStatusRefunded},
+	entity.OrderStatusShipped:   {entity.OrderStatusDelivered},
+	entity.OrderStatusDelivered: {entity.OrderStatusRefunded},
+}
+
+// canTransition reports whether an order in status from may move to status to.
+func canTransition(from, to entity.OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsStock reports whether an order in the given status still has its
+// quantities held or taken out of product stock, i.e. the goods have not left.
+func holdsStock(status entity.OrderStatus) bool {
+	return status == entity.OrderStatusPending || status == entity.OrderStatusPaid
+}
+
+// openOrderStatuses are the statuses of orders that are still in progress.
+var openOrderStatuses = []entity.OrderStatus{entity.OrderStatusPending, entity.OrderStatusPaid, entity.OrderStatusShipped}
+
+// releasesStock reports whether moving an order from one status to another
+// gives its quantities back to product stock.
+func releasesStock(from, to entity.OrderStatus) bool {
+	return holdsStock(from) && (to == entity.OrderStatusCancelled || to == entity.OrderStatusRefunded)
+}
+
+// takesStock reports whether moving an order from one status to another turns
+// the stock it holds into a real decrement.
+func takesStock(from, to entity.OrderStatus) bool {
+	return from == entity.OrderStatusPending && to == entity.OrderStatusPaid
+}
@@ -0,0 +1,470 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+)
+
+// orderFixture is an order service over the memory backend, acting as an
+// admin for an active customer.
+type orderFixture struct {
+	ctx      context.Context
+	repos    usecase.Repositories
+	orders   *usecase.OrderService
+	customer *entity.Customer
+}
+
+func newOrderFixture(t *testing.T, strategy usecase.FulfilmentStrategy, addresses ...entity.Address) *orderFixture {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repos := memory.NewRepositories()
+	orders := usecase.NewOrderService(repos.Orders, repos.Products, repos.Customers, repos.Reservations, repos.Warehouses,
+		repos.StockLevels, repos.Audit, repos.Outbox, strategy, usecase.NewBroker(logger), repos.Transactor, time.Hour, logger)
+	ctx := usecase.WithActor(context.Background(), usecase.Actor{UserID: "admin", Role: entity.RoleAdmin})
+
+	customer, err := repos.Customers.Create(ctx, &entity.Customer{
+		Name:      "Test Customer",
+		Email:     "customer@example.com",
+		Addresses: addresses,
+		Status:    entity.CustomerStatusActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	return &orderFixture{ctx: ctx, repos: repos, orders: orders, customer: customer}
+}
+
+// product adds a product with the stock and returns its ID.
+func (f *orderFixture) product(t *testing.T, stock int) string {
+	t.Helper()
+	product, err := f.repos.Products.Create(f.ctx, &entity.Product{
+		Name:      "Test Product",
+		Price:     2.5,
+		Stock:     stock,
+		Version:   1,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	return product.ID
+}
+
+// stock returns the product's stock and how much of it is reserved.
+func (f *orderFixture) stock(t *testing.T, productID string) (stock, reserved int) {
+	t.Helper()
+	product, err := f.repos.Products.FindByID(f.ctx, productID)
+	if err != nil {
+		t.Fatalf("find product: %v", err)
+	}
+	return product.Stock, product.Reserved
+}
+
+func (f *orderFixture) order(items ...entity.OrderItem) (*entity.Order, error) {
+	return f.orders.CreateOrder(f.ctx, &entity.Order{CustomerID: f.customer.ID, Items: items})
+}
+
+type transition func(s *usecase.OrderService, ctx context.Context, id string) (*entity.Order, error)
+
+var (
+	pay     transition = (*usecase.OrderService).PayOrder
+	ship    transition = (*usecase.OrderService).ShipOrder
+	deliver transition = (*usecase.OrderService).DeliverOrder
+	cancel  transition = (*usecase.OrderService).CancelOrder
+	refund  transition = (*usecase.OrderService).RefundOrder
+)
+
+func TestOrderTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  []transition
+		do      transition
+		illegal bool
+		status  entity.OrderStatus
+		history int // status changes recorded after the initial pending
+	}{
+		{name: "pending to paid", do: pay, status: entity.OrderStatusPaid, history: 1},
+		{name: "pending to cancelled", do: cancel, status: entity.OrderStatusCancelled, history: 1},
+		{name: "pending to shipped", do: ship, illegal: true, status: entity.OrderStatusPending},
+		{name: "pending to delivered", do: deliver, illegal: true, status: entity.OrderStatusPending},
+		{name: "pending to refunded", do: refund, illegal: true, status: entity.OrderStatusPending},
+		{name: "paid to shipped", before: []transition{pay}, do: ship, status: entity.OrderStatusShipped, history: 2},
+		{name: "paid to cancelled", before: []transition{pay}, do: cancel, status: entity.OrderStatusCancelled, history: 2},
+		{name: "paid to refunded", before: []transition{pay}, do: refund, status: entity.OrderStatusRefunded, history: 2},
+		{name: "paid to delivered", before: []transition{pay}, do: deliver, illegal: true, status: entity.OrderStatusPaid, history: 1},
+		{name: "shipped to delivered", before: []transition{pay, ship}, do: deliver, status: entity.OrderStatusDelivered, history: 3},
+		{name: "shipped to cancelled", before: []transition{pay, ship}, do: cancel, illegal: true, status: entity.OrderStatusShipped, history: 2},
+		{name: "shipped to refunded", before: []transition{pay, ship}, do: refund, illegal: true, status: entity.OrderStatusShipped, history: 2},
+		{name: "delivered to refunded", before: []transition{pay, ship, deliver}, do: refund, status: entity.OrderStatusRefunded, history: 4},
+		{name: "delivered to cancelled", before: []transition{pay, ship, deliver}, do: cancel, illegal: true, status: entity.OrderStatusDelivered, history: 3},
+		{name: "cancelled to paid", before: []transition{cancel}, do: pay, illegal: true, status: entity.OrderStatusCancelled, history: 1},
+		{name: "refunded to shipped", before: []transition{pay, refund}, do: ship, illegal: true, status: entity.OrderStatusRefunded, history: 2},
+		// Asking for the current status is a no-op and records nothing
+		{name: "paid again", before: []transition{pay}, do: pay, status: entity.OrderStatusPaid, history: 1},
+		{name: "cancelled again", before: []transition{cancel}, do: cancel, status: entity.OrderStatusCancelled, history: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderFixture(t, usecase.PriorityStrategy{})
+			order, err := f.order(entity.OrderItem{ProductID: f.product(t, 10), Quantity: 3})
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			for _, step := range tt.before {
+				if _, err := step(f.orders, f.ctx, order.ID); err != nil {
+					t.Fatalf("setting up: %v", err)
+				}
+			}
+
+			_, err = tt.do(f.orders, f.ctx, order.ID)
+			var transitionErr *usecase.TransitionError
+			if tt.illegal != errors.As(err, &transitionErr) {
+				t.Fatalf("transition error = %v, want illegal %v", err, tt.illegal)
+			}
+			if !tt.illegal && err != nil {
+				t.Fatalf("transition: %v", err)
+			}
+			if tt.illegal && !errors.Is(err, usecase.ErrConflict) {
+				t.Errorf("illegal transition error %v does not wrap ErrConflict", err)
+			}
+
+			stored, err := f.repos.Orders.FindByID(f.ctx, order.ID)
+			if err != nil {
+				t.Fatalf("find order: %v", err)
+			}
+			if stored.Status != tt.status {
+				t.Errorf("status = %s, want %s", stored.Status, tt.status)
+			}
+			if len(stored.StatusHistory) != tt.history+1 {
+				t.Errorf("status history has %d entries, want %d", len(stored.StatusHistory), tt.history+1)
+			}
+			if last := stored.StatusHistory[len(stored.StatusHistory)-1]; last.To != stored.Status {
+				t.Errorf("status history ends at %s, order is %s", last.To, stored.Status)
+			}
+		})
+	}
+}
+
+func TestOrderReturnsStock(t *testing.T) {
+	// An action on an order for 3 of a product with 10 in stock
+	type action func(f *orderFixture, orderID, productID string) error
+	step := func(do transition) action {
+		return func(f *orderFixture, orderID, _ string) error {
+			_, err := do(f.orders, f.ctx, orderID)
+			return err
+		}
+	}
+	quantity := func(quantity int) action {
+		return func(f *orderFixture, orderID, productID string) error {
+			order := &entity.Order{Items: []entity.OrderItem{{ProductID: productID, Quantity: quantity}}}
+			_, err := f.orders.UpdateOrder(f.ctx, orderID, 0, order)
+			return err
+		}
+	}
+	remove := func(f *orderFixture, orderID, _ string) error {
+		return f.orders.DeleteOrder(f.ctx, orderID)
+	}
+
+	tests := []struct {
+		name      string
+		actions   []action
+		available int // stock left for other orders afterwards
+	}{
+		{name: "pending", available: 7},
+		{name: "pending cancelled", actions: []action{step(cancel)}, available: 10},
+		{name: "paid", actions: []action{step(pay)}, available: 7},
+		{name: "paid cancelled", actions: []action{step(pay), step(cancel)}, available: 10},
+		{name: "paid refunded", actions: []action{step(pay), step(refund)}, available: 10},
+		{name: "shipped", actions: []action{step(pay), step(ship)}, available: 7},
+		// The goods have left, so a refund does not restock them
+		{name: "delivered refunded", actions: []action{step(pay), step(ship), step(deliver), step(refund)}, available: 7},
+		{name: "cancelled twice", actions: []action{step(cancel), step(cancel)}, available: 10},
+		{name: "refunded twice", actions: []action{step(pay), step(refund), step(refund)}, available: 10},
+		{name: "pending deleted", actions: []action{remove}, available: 10},
+		{name: "paid deleted", actions: []action{step(pay), remove}, available: 10},
+		// A cancelled order gave its stock back already
+		{name: "cancelled deleted", actions: []action{step(cancel), remove}, available: 10},
+		{name: "quantity cut", actions: []action{quantity(1)}, available: 9},
+		{name: "quantity raised", actions: []action{quantity(5)}, available: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderFixture(t, usecase.PriorityStrategy{})
+			productID := f.product(t, 10)
+			order, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 3})
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			for i, act := range tt.actions {
+				if err := act(f, order.ID, productID); err != nil {
+					t.Fatalf("action %d: %v", i, err)
+				}
+			}
+			if stock, reserved := f.stock(t, productID); stock-reserved != tt.available {
+				t.Errorf("stock %d (%d reserved) leaves %d available, want %d", stock, reserved, stock-reserved, tt.available)
+			}
+		})
+	}
+}
+
+func TestOrderQuantityShortOfStock(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	order, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	raised := &entity.Order{Items: []entity.OrderItem{{ProductID: productID, Quantity: 11}}}
+	if _, err := f.orders.UpdateOrder(f.ctx, order.ID, 0, raised); !errors.Is(err, usecase.ErrInsufficientStock) {
+		t.Fatalf("raising past the stock: %v, want ErrInsufficientStock", err)
+	}
+	if stock, reserved := f.stock(t, productID); stock-reserved != 7 {
+		t.Errorf("refused update left %d available, want 7", stock-reserved)
+	}
+}
+
+func TestCreateOrderChecksCustomer(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	item := entity.OrderItem{ProductID: productID, Quantity: 1}
+
+	if _, err := f.orders.CreateOrder(f.ctx, &entity.Order{Items: []entity.OrderItem{item}}); err == nil {
+		t.Error("order without a customer was placed")
+	}
+	missing := &entity.Order{CustomerID: "missing", Items: []entity.OrderItem{item}}
+	if _, err := f.orders.CreateOrder(f.ctx, missing); !errors.Is(err, usecase.ErrUnknownCustomer) {
+		t.Errorf("order for an unknown customer: %v, want ErrUnknownCustomer", err)
+	}
+	f.customer.Status = entity.CustomerStatusInactive
+	if err := f.repos.Customers.Update(f.ctx, f.customer); err != nil {
+		t.Fatalf("deactivate customer: %v", err)
+	}
+	if _, err := f.order(item); !errors.Is(err, usecase.ErrCustomerInactive) {
+		t.Errorf("order for an inactive customer: %v, want ErrCustomerInactive", err)
+	}
+	if stock, reserved := f.stock(t, productID); stock-reserved != 10 {
+		t.Errorf("refused orders left %d available, want 10", stock-reserved)
+	}
+
+	f.customer.Status = entity.CustomerStatusActive
+	if err := f.repos.Customers.Update(f.ctx, f.customer); err != nil {
+		t.Fatalf("activate customer: %v", err)
+	}
+	order, err := f.order(item)
+	if err != nil {
+		t.Fatalf("order for a reactivated customer: %v", err)
+	}
+	if order.CustomerID != f.customer.ID {
+		t.Errorf("order is for customer %q, want %q", order.CustomerID, f.customer.ID)
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	type line struct {
+		product  int // index into the products
+		quantity int
+	}
+	tests := []struct {
+		name     string
+		stock    []int
+		lines    []line
+		err      error
+		items    int // lines of the created order
+		total    float64
+		reserved []int // reserved stock of each product afterwards
+	}{
+		{name: "holds the stock", stock: []int{10}, lines: []line{{0, 4}}, items: 1, total: 10, reserved: []int{4}},
+		{name: "takes all the stock", stock: []int{4}, lines: []line{{0, 4}}, items: 1, total: 10, reserved: []int{4}},
+		{name: "merges lines of a product", stock: []int{10}, lines: []line{{0, 2}, {0, 3}}, items: 1, total: 12.5, reserved: []int{5}},
+		{name: "several products", stock: []int{10, 5}, lines: []line{{0, 1}, {1, 5}}, items: 2, total: 15, reserved: []int{1, 5}},
+		{name: "short of stock", stock: []int{3}, lines: []line{{0, 4}}, err: usecase.ErrInsufficientStock, reserved: []int{0}},
+		{name: "merged lines short of stock", stock: []int{4}, lines: []line{{0, 2}, {0, 3}}, err: usecase.ErrInsufficientStock, reserved: []int{0}},
+		// A short line rolls back the holds of the lines before it
+		{name: "one product short", stock: []int{10, 1}, lines: []line{{0, 2}, {1, 2}}, err: usecase.ErrInsufficientStock, reserved: []int{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderFixture(t, usecase.PriorityStrategy{})
+			products := make([]string, len(tt.stock))
+			for i, stock := range tt.stock {
+				products[i] = f.product(t, stock)
+			}
+			items := make([]entity.OrderItem, len(tt.lines))
+			for i, l := range tt.lines {
+				items[i] = entity.OrderItem{ProductID: products[l.product], Quantity: l.quantity}
+			}
+
+			order, err := f.order(items...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("CreateOrder error = %v, want %v", err, tt.err)
+			}
+			if err == nil {
+				if order.Status != entity.OrderStatusPending || !order.StockHeld {
+					t.Errorf("order is %s with stock held %v, want pending and held", order.Status, order.StockHeld)
+				}
+				if len(order.Items) != tt.items || order.TotalPrice != tt.total {
+					t.Errorf("order has %d lines totalling %v, want %d totalling %v", len(order.Items), order.TotalPrice, tt.items, tt.total)
+				}
+				holds, err := f.repos.Reservations.FindByHolder(f.ctx, entity.HolderOrder, order.ID)
+				if err != nil {
+					t.Fatalf("find holds: %v", err)
+				}
+				if len(holds) != tt.items {
+					t.Errorf("order has %d holds, want %d", len(holds), tt.items)
+				}
+			} else {
+				page, err := f.repos.Orders.FindAll(f.ctx, usecase.OrderQuery{Limit: usecase.MaxPageLimit})
+				if err != nil {
+					t.Fatalf("find orders: %v", err)
+				}
+				if len(page) != 0 {
+					t.Errorf("a failed order left %d orders behind", len(page))
+				}
+			}
+			for i, productID := range products {
+				if stock, reserved := f.stock(t, productID); stock != tt.stock[i] || reserved != tt.reserved[i] {
+					t.Errorf("product %d has stock %d (%d reserved), want %d (%d reserved)", i, stock, reserved, tt.stock[i], tt.reserved[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOrderHoldsStock(t *testing.T) {
+	// Every order is for 3 of a product with 10 in stock
+	tests := []struct {
+		name     string
+		steps    []transition
+		stock    int
+		reserved int
+	}{
+		{name: "pending holds", stock: 10, reserved: 3},
+		{name: "paid takes the hold", steps: []transition{pay}, stock: 7},
+		{name: "cancelled releases the hold", steps: []transition{cancel}, stock: 10},
+		{name: "paid then cancelled", steps: []transition{pay, cancel}, stock: 10},
+		{name: "illegal transition keeps the hold", steps: []transition{ship}, stock: 10, reserved: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderFixture(t, usecase.PriorityStrategy{})
+			productID := f.product(t, 10)
+			order, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 3})
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			// An illegal step fails and must leave the stock alone
+			for _, step := range tt.steps {
+				step(f.orders, f.ctx, order.ID)
+			}
+			if stock, reserved := f.stock(t, productID); stock != tt.stock || reserved != tt.reserved {
+				t.Errorf("stock %d (%d reserved), want %d (%d reserved)", stock, reserved, tt.stock, tt.reserved)
+			}
+			holds, err := f.repos.Reservations.FindByHolder(f.ctx, entity.HolderOrder, order.ID)
+			if err != nil {
+				t.Fatalf("find holds: %v", err)
+			}
+			if want := min(tt.reserved, 1); len(holds) != want {
+				t.Errorf("order has %d holds, want %d", len(holds), want)
+			}
+		})
+	}
+}
+
+func TestExpiredHolds(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Holds of this service expire as they are made
+	expiring := usecase.NewOrderService(f.repos.Orders, f.repos.Products, f.repos.Customers, f.repos.Reservations, f.repos.Warehouses,
+		f.repos.StockLevels, f.repos.Audit, f.repos.Outbox, usecase.PriorityStrategy{}, usecase.NewBroker(logger), f.repos.Transactor, -time.Minute, logger)
+	sweeper := usecase.NewHoldSweeper(f.repos.Products, f.repos.StockLevels, f.repos.Reservations, f.repos.Transactor, time.Minute, logger)
+	productID := f.product(t, 10)
+	placeExpired := func() *entity.Order {
+		t.Helper()
+		order, err := expiring.CreateOrder(f.ctx, &entity.Order{CustomerID: f.customer.ID, Items: []entity.OrderItem{{ProductID: productID, Quantity: 3}}})
+		if err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		return order
+	}
+
+	lapsed, outsold := placeExpired(), placeExpired()
+	held, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if released := sweeper.SweepBatch(f.ctx); released != 2 {
+		t.Errorf("sweeper released %d holds, want 2", released)
+	}
+	if stock, reserved := f.stock(t, productID); stock != 10 || reserved != 1 {
+		t.Errorf("after the sweep: stock %d (%d reserved), want 10 (1 reserved)", stock, reserved)
+	}
+	if released := sweeper.SweepBatch(f.ctx); released != 0 {
+		t.Errorf("second sweep released %d holds, want 0", released)
+	}
+
+	// A lapsed order takes the stock at payment if it is still there
+	if _, err := pay(f.orders, f.ctx, lapsed.ID); err != nil {
+		t.Fatalf("paying a lapsed order: %v", err)
+	}
+	if _, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 5}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := pay(f.orders, f.ctx, outsold.ID); !errors.Is(err, usecase.ErrInsufficientStock) {
+		t.Errorf("paying an outsold lapsed order: %v, want ErrInsufficientStock", err)
+	}
+	if _, err := pay(f.orders, f.ctx, held.ID); err != nil {
+		t.Errorf("paying an order that still holds its stock: %v", err)
+	}
+	if stock, reserved := f.stock(t, productID); stock != 6 || reserved != 5 {
+		t.Errorf("after payment: stock %d (%d reserved), want 6 (5 reserved)", stock, reserved)
+	}
+}
+
+func TestPatchOrderReadOnlyFields(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	order, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	patched, err := f.orders.PatchOrder(f.ctx, order.ID, 0, []byte(`{
+		"id": "other",
+		"user_id": "mallory",
+		"customer_id": "someone-else",
+		"total_price": 0.01,
+		"status": "Delivered",
+		"status_history": [],
+		"stock_released": true,
+		"stock_held": false,
+		"version": 40,
+		"created_at": "2000-01-01T00:00:00Z",
+		"deleted_at": "2000-01-01T00:00:00Z",
+		"items": [{"product_id": "`+productID+`", "quantity": 2, "unit_price": 0.01, "line_total": 0.01}]
+	}`))
+	if err != nil {
+		t.Fatalf("PatchOrder: %v", err)
+	}
+	if len(patched.Items) != 1 || patched.Items[0].Quantity != 2 || patched.Items[0].UnitPrice != 2.5 || patched.TotalPrice != 5 {
+		t.Errorf("items = %+v totalling %v, want 2 at the catalog price of 2.5", patched.Items, patched.TotalPrice)
+	}
+	if patched.ID != order.ID || patched.UserID != order.UserID || patched.CustomerID != order.CustomerID ||
+		patched.Status != entity.OrderStatusPending || len(patched.StatusHistory) != 1 || patched.StockReleased ||
+		!patched.StockHeld || patched.Version != order.Version+1 || !patched.CreatedAt.Equal(order.CreatedAt) || patched.DeletedAt != nil {
+		t.Errorf("read-only fields changed: %+v, created as %+v", patched, order)
+	}
+	if stock, reserved := f.stock(t, productID); stock != 10 || reserved != 2 {
+		t.Errorf("stock %d (%d reserved), want 10 (2 reserved)", stock, reserved)
+	}
+}
@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"ulab3/internal/entity"
+)
+
+const (
+	// maxReplay is how many outbox events a stream resuming after an event
+	// reads at most before it gives up and asks the client to start over.
+	maxReplay = 1000
+	// replayBatch is how many outbox events a resuming stream reads at once.
+	replayBatch = 100
+)
+
+// OrderStream carries the order events a client follows. Reset reports that
+// the events after the one the client resumed from could not be replayed, so
+// the client should reload the orders it shows before following the stream.
+type OrderStream struct {
+	Events <-chan entity.Event
+	Reset  bool
+}
+
+// StreamOrders follows OrderCreated and OrderStatusChanged events matching the
+// filter until ctx is done. Actors who may only read their own orders get
+// just those. A non-empty lastEventID replays the matching events recorded
+// after that one first. The Events channel is closed when ctx is done or the
+// client falls too far behind.
+func (s *OrderService) StreamOrders(ctx context.Context, filter OrderStreamFilter, lastEventID string) (*OrderStream, error) {
+	actor, err := authorize(ctx, PermReadAllOrders, PermReadOwnOrders)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Streaming orders", "status", filter.Status, "product_id", filter.ProductID, "last_event_id", lastEventID)
+
+	// Subscribe before replaying so nothing recorded in between is lost
+	live, unsubscribe := s.broker.Subscribe()
+	stream := &OrderStream{}
+	var missed []entity.Event
+	if lastEventID != "" {
+		missed, err = s.eventsAfter(ctx, lastEventID)
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		stream.Reset = missed == nil
+	}
+
+	events := make(chan entity.Event)
+	stream.Events = events
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+
+		replayed := make(map[string]struct{}, len(missed))
+		send := func(event entity.Event) bool {
+			if !streamMatches(actor, filter, event) {
+				return true
+			}
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, event := range missed {
+			replayed[event.ID] = struct{}{}
+			if !send(event) {
+				return
+			}
+		}
+		for {
+			select {
+			case event, ok := <-live:
+				if !ok {
+					s.logger.Info("Order stream fell behind", "user_id", actor.UserID)
+					return
+				}
+				if _, ok := replayed[event.ID]; ok {
+					continue
+				}
+				if !send(event) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return stream, nil
+}
+
+// eventsAfter returns the outbox events recorded after the one with the ID,
+// oldest first. It returns nil if that event is unknown or too many events
+// followed it.
+func (s *OrderService) eventsAfter(ctx context.Context, id string) ([]entity.Event, error) {
+	last, err := s.outboxRepo.FindByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Info("Cannot resume order stream from unknown event", "id", id)
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch event", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to fetch event: %w", err)
+	}
+
+	sort := Sort{Field: "created_at"}
+	events := []entity.Event{}
+	for {
+		after := &Cursor{Value: EventSortValue(*last, sort.Field), ID: last.ID}
+		batch, err := s.outboxRepo.FindAll(ctx, EventQuery{Sort: sort, After: after, Limit: replayBatch})
+		if err != nil {
+			s.logger.Error("Failed to fetch events", "error", err)
+			return nil, fmt.Errorf("failed to fetch events: %w", err)
+		}
+		events = append(events, batch...)
+		if len(batch) < replayBatch {
+			return events, nil
+		}
+		if len(events) >= maxReplay {
+			s.logger.Info("Too many events to resume order stream", "id", id)
+			return nil, nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
+
+// streamMatches reports whether the event belongs in the actor's order stream
+// with the filter.
+func streamMatches(actor Actor, filter OrderStreamFilter, event entity.Event) bool {
+	// OrderCreated carries the order, OrderStatusChanged the change; both name
+	// the customer and the lines
+	var order struct {
+		UserID string             `json:"user_id"`
+		Status entity.OrderStatus `json:"status"`
+		To     entity.OrderStatus `json:"to"`
+		Items  []entity.OrderItem `json:"items"`
+	}
+	switch event.Type {
+	case entity.EventOrderCreated, entity.EventOrderStatusChanged:
+	default:
+		return false
+	}
+	if err := json.Unmarshal(event.Payload, &order); err != nil {
+		return false
+	}
+
+	if !actor.Can(PermReadAllOrders) && order.UserID != actor.UserID {
+		return false
+	}
+	status := order.Status
+	if event.Type == entity.EventOrderStatusChanged {
+		status = order.To
+	}
+	if filter.Status != "" && status != filter.Status {
+		return false
+	}
+	if filter.ProductID != "" && !slices.ContainsFunc(order.Items, func(item entity.OrderItem) bool {
+		return item.ProductID == filter.ProductID
+	}) {
+		return false
+	}
+	return true
+}
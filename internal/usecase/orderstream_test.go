@@ -0,0 +1,145 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// stream follows the fixture's order stream until the test ends.
+func (f *orderFixture) stream(t *testing.T, ctx context.Context, filter usecase.OrderStreamFilter, lastEventID string) *usecase.OrderStream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	stream, err := f.orders.StreamOrders(ctx, filter, lastEventID)
+	if err != nil {
+		t.Fatalf("StreamOrders: %v", err)
+	}
+	return stream
+}
+
+// orderEvents returns the IDs of the order events in the outbox, oldest
+// first.
+func (f *orderFixture) orderEvents(t *testing.T) []string {
+	t.Helper()
+	events, err := f.repos.Outbox.FindAll(f.ctx, usecase.EventQuery{Sort: usecase.Sort{Field: "created_at"}, Limit: 1000})
+	if err != nil {
+		t.Fatalf("find events: %v", err)
+	}
+	var ids []string
+	for _, event := range events {
+		if event.Type == entity.EventOrderCreated || event.Type == entity.EventOrderStatusChanged {
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
+
+func TestStreamOrdersFollowsLiveEvents(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	all := f.stream(t, f.ctx, usecase.OrderStreamFilter{}, "")
+	paid := f.stream(t, f.ctx, usecase.OrderStreamFilter{Status: entity.OrderStatusPaid}, "")
+	other := f.stream(t, f.as("bob", entity.RoleCustomer), usecase.OrderStreamFilter{}, "")
+
+	order, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := pay(f.orders, f.ctx, order.ID); err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+
+	for _, want := range []entity.EventType{entity.EventOrderCreated, entity.EventOrderStatusChanged} {
+		if event, ok := receive(t, all.Events); !ok || event.Type != want || event.AggregateID != order.ID {
+			t.Fatalf("stream got %s for %s (open %v), want %s for %s", event.Type, event.AggregateID, ok, want, order.ID)
+		}
+	}
+	if event, ok := receive(t, paid.Events); !ok || event.Type != entity.EventOrderStatusChanged {
+		t.Errorf("paid stream got %s (open %v), want the payment", event.Type, ok)
+	}
+	// Streams are filtered before events are sent, so a customer following
+	// the stream sees nothing of other users' orders
+	select {
+	case event := <-other.Events:
+		t.Errorf("another customer's stream got %s for %s", event.Type, event.AggregateID)
+	default:
+	}
+}
+
+func TestStreamOrdersReplaysMissedEvents(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 10)
+	first, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := pay(f.orders, f.ctx, first.ID); err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	if _, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 2}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	recorded := f.orderEvents(t)
+	if len(recorded) != 3 {
+		t.Fatalf("outbox holds %d order events, want 3", len(recorded))
+	}
+
+	// The client saw the first event before it lost the connection
+	stream := f.stream(t, f.ctx, usecase.OrderStreamFilter{}, recorded[0])
+	if stream.Reset {
+		t.Error("stream resuming from a known event asks for a reset")
+	}
+	third, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	for _, want := range recorded[1:] {
+		if event, ok := receive(t, stream.Events); !ok || event.ID != want {
+			t.Fatalf("replay got %s (open %v), want %s", event.ID, ok, want)
+		}
+	}
+	if event, ok := receive(t, stream.Events); !ok || event.AggregateID != third.ID {
+		t.Errorf("after the replay the stream got %s for %s (open %v), want the new order %s", event.Type, event.AggregateID, ok, third.ID)
+	}
+
+	unknown := f.stream(t, f.ctx, usecase.OrderStreamFilter{}, "no-such-event")
+	if !unknown.Reset {
+		t.Error("stream resuming from an unknown event does not ask for a reset")
+	}
+}
+
+func TestStreamOrdersEnds(t *testing.T) {
+	f := newOrderFixture(t, usecase.PriorityStrategy{})
+	productID := f.product(t, 1000)
+
+	ctx, cancel := context.WithCancel(f.ctx)
+	stream, err := f.orders.StreamOrders(ctx, usecase.OrderStreamFilter{}, "")
+	if err != nil {
+		t.Fatalf("StreamOrders: %v", err)
+	}
+	cancel()
+	if _, ok := receive(t, stream.Events); ok {
+		t.Error("stream still open after its context was cancelled")
+	}
+
+	// A client that stops reading is dropped once it falls too far behind
+	slow := f.stream(t, f.ctx, usecase.OrderStreamFilter{}, "")
+	const orders = 100
+	for range orders {
+		if _, err := f.order(entity.OrderItem{ProductID: productID, Quantity: 1}); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+	}
+	received := 0
+	for {
+		if _, ok := receive(t, slow.Events); !ok {
+			break
+		}
+		received++
+	}
+	if received >= orders {
+		t.Errorf("slow stream got all %d orders, want it dropped", received)
+	}
+}
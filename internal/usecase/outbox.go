@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/pkg/requestid"
+)
+
+// EventService lets operators inspect the outbox and send dead events off
+// again.
+type EventService struct {
+	outboxRepo OutboxRepository
+	logger     *slog.Logger
+}
+
+func NewEventService(outboxRepo OutboxRepository, logger *slog.Logger) *EventService {
+	return &EventService{
+		outboxRepo: outboxRepo,
+		logger:     logger,
+	}
+}
+
+// GetEvents lists outbox events, newest first unless the page asks for
+// another order.
+func (s *EventService) GetEvents(ctx context.Context, filter EventFilter, page PageRequest) (*entity.EventPage, error) {
+	if _, err := authorize(ctx, PermManageEvents); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching events", "status", filter.Status, "type", filter.Type, "sort", page.Sort, "limit", page.Limit)
+
+	if page.Sort == "" {
+		page.Sort = "-created_at"
+	}
+	sort, after, limit, err := parsePage(page, eventSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ask for one extra record to learn whether another page follows
+	query := EventQuery{EventFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	events, err := s.outboxRepo.FindAll(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to fetch events", "error", err)
+		return nil, fmt.Errorf("failed to fetch events: %w", err)
+	}
+
+	data, pagination := paginate(events, limit, sort, eventSortFields, func(e entity.Event) string { return e.ID })
+	return &entity.EventPage{Data: data, Pagination: pagination}, nil
+}
+
+func (s *EventService) GetEventByID(ctx context.Context, id string) (*entity.Event, error) {
+	if _, err := authorize(ctx, PermManageEvents); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching event by ID", "id", id)
+
+	event, err := s.outboxRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Event not found", "id", id, "error", err)
+		return nil, fmt.Errorf("event not found: %w", err)
+	}
+	return event, nil
+}
+
+// RetryEvent queues a dead event for delivery again, with a fresh set of
+// attempts.
+func (s *EventService) RetryEvent(ctx context.Context, id string) (*entity.Event, error) {
+	if _, err := authorize(ctx, PermManageEvents); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Retrying event", "id", id)
+
+	event, err := s.outboxRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Event not found", "id", id, "error", err)
+		return nil, fmt.Errorf("event not found: %w", err)
+	}
+	if event.Status != entity.EventStatusDead {
+		return nil, fmt.Errorf("%w: event %s is %s", ErrEventNotDead, id, event.Status)
+	}
+
+	event.Status = entity.EventStatusPending
+	event.Attempts = 0
+	event.NextAttemptAt = time.Now()
+	if err := s.outboxRepo.UpdateDelivery(ctx, event); err != nil {
+		s.logger.Error("Failed to retry event", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to retry event: %w", err)
+	}
+
+	s.logger.Info("Event queued for retry", "id", id)
+	return event, nil
+}
+
+// PurgeDeliveredEvents removes the events delivered before the given time and
+// returns how many it removed.
+func (s *EventService) PurgeDeliveredEvents(ctx context.Context, before time.Time) (int64, error) {
+	if _, err := authorize(ctx, PermManageEvents); err != nil {
+		return 0, err
+	}
+	s.logger.Info("Purging delivered events", "before", before)
+
+	purged, err := s.outboxRepo.PurgeDelivered(ctx, before)
+	if err != nil {
+		s.logger.Error("Failed to purge events", "error", err)
+		return 0, fmt.Errorf("failed to purge events: %w", err)
+	}
+
+	s.logger.Info("Events purged successfully", "count", purged)
+	return purged, nil
+}
+
+// NewEventMessage returns the body sinks outside the service receive for the
+// event.
+func NewEventMessage(event entity.Event) entity.EventMessage {
+	return entity.EventMessage{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Payload:       event.Payload,
+		RequestID:     event.RequestID,
+		CreatedAt:     event.CreatedAt,
+	}
+}
+
+// recordEvent adds a domain event about the record of the type with the ID to
+// the outbox. Callers run it in the transaction that made the change, so that
+// the event is published if and only if the change commits.
+func recordEvent(ctx context.Context, repo OutboxRepository, eventType entity.EventType, aggregateType, id string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	now := time.Now()
+	event := &entity.Event{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   id,
+		Payload:       data,
+		RequestID:     requestid.From(ctx),
+		CreatedAt:     now,
+		Status:        entity.EventStatusPending,
+		NextAttemptAt: now,
+	}
+	if err := repo.Append(ctx, event); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	if events := recorded(ctx); events != nil {
+		*events = append(*events, *event)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"ulab3/internal/entity"
+	"ulab3/pkg/mergepatch"
+)
+
+// applyMergePatch applies an RFC 7396 merge patch to the JSON form of current
+// and decodes the result into target. Malformed patches are validation errors.
+func applyMergePatch(current any, patch []byte, target any) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	merged, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return DecodeError(err)
+	}
+	if err := json.Unmarshal(merged, target); err != nil {
+		return DecodeError(err)
+	}
+	return nil
+}
+
+// DecodeError reports JSON input that could not be decoded as a validation
+// error. A value of the wrong JSON type is reported against its field.
+func DecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &ValidationError{Fields: []entity.FieldError{{
+			Field:   typeErr.Field,
+			Message: "must be " + jsonType(typeErr.Type.Kind()),
+		}}}
+	}
+	return fmt.Errorf("%w: %v", ErrValidation, err)
+}
+
+// jsonType names a Go kind the way JSON documents call it, with an article.
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a " + kind.String()
+}
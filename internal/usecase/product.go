@@ -2,93 +2,398 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 	"ulab3/internal/entity"
 )
 
 type ProductService struct {
 	productRepo ProductRepository
+	orderRepo   OrderRepository
+	levelRepo   StockLevelRepository
+	auditRepo   AuditRepository
+	outboxRepo  OutboxRepository
+	tx          Transactor
 	logger      *slog.Logger
 }
 
-func NewProductService(productRepo ProductRepository, logger *slog.Logger) *ProductService {
+func NewProductService(productRepo ProductRepository, orderRepo OrderRepository, levelRepo StockLevelRepository, auditRepo AuditRepository, outboxRepo OutboxRepository, tx Transactor, logger *slog.Logger) *ProductService {
 	return &ProductService{
 		productRepo: productRepo,
+		orderRepo:   orderRepo,
+		levelRepo:   levelRepo,
+		auditRepo:   auditRepo,
+		outboxRepo:  outboxRepo,
+		tx:          tx,
 		logger:      logger,
 	}
 }
 
 func (s *ProductService) CreateProduct(ctx context.Context, product *entity.Product) (*entity.Product, error) {
-	s.logger.Info("Creating product:", product.Name)
+	if _, err := authorize(ctx, PermWriteProducts); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Creating product", "name", product.Name)
 
-	existingProduct, err := s.productRepo.FindByID(ctx, product.ID)
-	if err == nil && existingProduct != nil {
-		s.logger.Info("Product already exists:", product.ID)
-		return nil, fmt.Errorf("product already exists")
+	if err := Validate(product); err != nil {
+		return nil, err
 	}
 
+	product.Version = 1
+	product.Reserved = 0
 	product.CreatedAt = time.Now()
 	product.UpdatedAt = time.Now()
 
-	createdProduct, err := s.productRepo.Create(ctx, product)
+	var createdProduct *entity.Product
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		createdProduct, err = s.productRepo.Create(ctx, product)
+		if err != nil {
+			s.logger.Error("Failed to create product", "error", err)
+			return fmt.Errorf("failed to create product: %w", err)
+		}
+		return s.audit(ctx, entity.AuditActionCreate, createdProduct.ID, nil, createdProduct)
+	})
 	if err != nil {
-		s.logger.Error("Failed to create product:", err)
-		return nil, fmt.Errorf("failed to create product: %w", err)
+		return nil, err
 	}
 
-	s.logger.Info("Product created successfully:", createdProduct.ID)
+	s.logger.Info("Product created successfully", "id", createdProduct.ID)
 	return createdProduct, nil
 }
 
-func (s *ProductService) GetAllProducts(ctx context.Context) ([]entity.Product, error) {
-	s.logger.Info("Fetching all products")
+// GetAllProducts lists products. Only admins may ask for deleted products to
+// be included.
+func (s *ProductService) GetAllProducts(ctx context.Context, filter ProductFilter, page PageRequest) (*entity.ProductPage, error) {
+	if filter.IncludeDeleted {
+		if _, err := authorize(ctx, PermManageDeleted); err != nil {
+			return nil, err
+		}
+	}
+	s.logger.Info("Fetching products", "sort", page.Sort, "limit", page.Limit)
+
+	sort, after, limit, err := parsePage(page, productSortFields)
+	if err != nil {
+		return nil, err
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, fmt.Errorf("%w: min_price is greater than max_price", ErrInvalidQuery)
+	}
 
-	products, err := s.productRepo.FindAll(ctx)
+	// Ask for one extra record to learn whether another page follows
+	query := ProductQuery{ProductFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	products, err := s.productRepo.FindAll(ctx, query)
 	if err != nil {
-		s.logger.Error("Failed to fetch products:", err)
+		s.logger.Error("Failed to fetch products", "error", err)
 		return nil, fmt.Errorf("failed to fetch products: %w", err)
 	}
 
-	return products, nil
+	data, pagination := paginate(products, limit, sort, productSortFields, func(p entity.Product) string { return p.ID })
+	return &entity.ProductPage{Data: data, Pagination: pagination}, nil
+}
+
+func (s *ProductService) SearchProducts(ctx context.Context, query string, limit int) (*entity.ProductSearchResult, error) {
+	s.logger.Info("Searching products", "query", query)
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: search query is empty", ErrInvalidQuery)
+	}
+	switch {
+	case limit == 0:
+		limit = DefaultPageLimit
+	case limit < 0 || limit > MaxPageLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageLimit)
+	}
+
+	matches, err := s.productRepo.Search(ctx, query, limit)
+	if err != nil {
+		s.logger.Error("Failed to search products", "error", err)
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	if matches == nil {
+		matches = []entity.ProductMatch{}
+	}
+
+	return &entity.ProductSearchResult{Query: query, Data: matches}, nil
 }
 
-func (s *ProductService) GetProductByID(ctx context.Context, id string) (*entity.Product, error) {
-	s.logger.Info("Fetching product by ID:", id)
+// GetProductByID returns a product. Deleted products are only found when
+// includeDeleted is set, which only admins may do.
+func (s *ProductService) GetProductByID(ctx context.Context, id string, includeDeleted bool) (*entity.Product, error) {
+	s.logger.Info("Fetching product by ID", "id", id)
 
-	product, err := s.productRepo.FindByID(ctx, id)
+	find := s.productRepo.FindByID
+	if includeDeleted {
+		if _, err := authorize(ctx, PermManageDeleted); err != nil {
+			return nil, err
+		}
+		find = s.productRepo.FindByIDIncludingDeleted
+	}
+	product, err := find(ctx, id)
 	if err != nil {
-		s.logger.Error("Product not found:", err)
+		s.logger.Error("Product not found", "id", id, "error", err)
 		return nil, fmt.Errorf("product not found: %w", err)
 	}
 
 	return product, nil
 }
 
-func (s *ProductService) UpdateProduct(ctx context.Context, id string, product *entity.Product) error {
-	s.logger.Info("Updating product:", id)
+// UpdateProduct replaces every client-writable field of a product and returns
+// the stored product. ID and CreatedAt keep their stored values. A non-zero
+// version makes the update conditional: it fails with ErrVersionMismatch
+// unless the product is still at that version.
+func (s *ProductService) UpdateProduct(ctx context.Context, id string, version int64, product *entity.Product) (*entity.Product, error) {
+	if _, err := authorize(ctx, PermWriteProducts); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Updating product", "id", id)
 
-	product.UpdatedAt = time.Now()
-	err := s.productRepo.Update(ctx, id, product)
+	if err := Validate(product); err != nil {
+		return nil, err
+	}
+	return s.updateProduct(ctx, id, version, func(*entity.Product) (*entity.Product, error) {
+		return product, nil
+	})
+}
+
+// PatchProduct applies an RFC 7396 merge patch to a product and returns the
+// stored product. Fields the patch leaves out keep their values; patches to
+// server-managed fields are ignored. version works as in UpdateProduct.
+func (s *ProductService) PatchProduct(ctx context.Context, id string, version int64, patch []byte) (*entity.Product, error) {
+	if _, err := authorize(ctx, PermWriteProducts); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Patching product", "id", id)
+
+	return s.updateProduct(ctx, id, version, func(existing *entity.Product) (*entity.Product, error) {
+		var product entity.Product
+		if err := applyMergePatch(existing, patch, &product); err != nil {
+			return nil, err
+		}
+		return &product, Validate(&product)
+	})
+}
+
+// updateProduct stores the product change builds from the current one. The
+// write only succeeds if no other write moved the product on in the meantime,
+// and never sets the stock below what is held of it.
+func (s *ProductService) updateProduct(ctx context.Context, id string, version int64, change func(existing *entity.Product) (*entity.Product, error)) (*entity.Product, error) {
+	var stored *entity.Product
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		existing, err := s.productRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Product not found", "id", id, "error", err)
+			return fmt.Errorf("product not found: %w", err)
+		}
+		if version != 0 && existing.Version != version {
+			s.logger.Info("Product version mismatch", "id", id, "expected", version, "actual", existing.Version)
+			return fmt.Errorf("%w: product %s is at version %d", ErrVersionMismatch, id, existing.Version)
+		}
+		product, err := change(existing)
+		if err != nil {
+			return err
+		}
+
+		// Server-managed fields keep their stored values
+		product.ID = id
+		product.Version = existing.Version
+		product.Reserved = existing.Reserved
+		product.CreatedAt = existing.CreatedAt
+		product.DeletedAt = existing.DeletedAt
+		// The stock of a product kept in warehouses is theirs to change
+		levels, err := s.levelRepo.FindByProduct(ctx, id)
+		if err != nil {
+			s.logger.Error("Failed to fetch stock levels", "product_id", id, "error", err)
+			return fmt.Errorf("failed to fetch stock levels: %w", err)
+		}
+		if len(levels) > 0 {
+			product.Stock = existing.Stock
+		} else if product.Stock < existing.Reserved {
+			s.logger.Info("Product stock is held", "id", id, "stock", product.Stock, "reserved", existing.Reserved)
+			return fmt.Errorf("%w: %d of product %s is held, cannot set its stock to %d", ErrStockHeld, existing.Reserved, id, product.Stock)
+		}
+		product.UpdatedAt = time.Now()
+		if err := s.productRepo.Update(ctx, id, product); err != nil {
+			// A caller that named a version asked for exactly this to fail
+			if version != 0 && errors.Is(err, ErrVersionConflict) {
+				s.logger.Info("Product changed during update", "id", id)
+				return fmt.Errorf("%w: product %s changed during the update", ErrVersionMismatch, id)
+			}
+			s.logger.Error("Failed to update product", "id", id, "error", err)
+			return fmt.Errorf("failed to update product: %w", err)
+		}
+
+		stored, err = s.productRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Failed to fetch updated product", "id", id, "error", err)
+			return fmt.Errorf("failed to fetch updated product: %w", err)
+		}
+		if err := s.audit(ctx, entity.AuditActionUpdate, id, existing, stored); err != nil {
+			return err
+		}
+		return s.recordChangeEvents(ctx, existing, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Product updated successfully", "id", id)
+	return stored, nil
+}
+
+// DeleteProduct hides a product from the catalog until it is restored or
+// purged; orders keep referring to it. A product that open orders still refer
+// to is only deleted when force is set.
+func (s *ProductService) DeleteProduct(ctx context.Context, id string, force bool) error {
+	if _, err := authorize(ctx, PermWriteProducts); err != nil {
+		return err
+	}
+	s.logger.Info("Deleting product", "id", id, "force", force)
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		product, err := s.productRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Product not found", "id", id, "error", err)
+			return fmt.Errorf("product not found: %w", err)
+		}
+
+		now := time.Now()
+		if err := s.productRepo.SoftDelete(ctx, id, now); err != nil {
+			s.logger.Error("Failed to delete product", "id", id, "error", err)
+			return fmt.Errorf("failed to delete product: %w", err)
+		}
+		// Checked after the product is written, so that an order placed
+		// concurrently either waits for the delete and then fails to take the
+		// product's stock, or commits first and is seen here
+		if !force {
+			open, err := s.hasOpenOrders(ctx, id)
+			if err != nil {
+				return err
+			}
+			if open {
+				s.logger.Info("Product has open orders", "id", id)
+				return fmt.Errorf("%w: product %s; delete with force to proceed", ErrProductHasOpenOrders, id)
+			}
+		}
+		deleted := *product
+		deleted.DeletedAt = &now
+		return s.audit(ctx, entity.AuditActionDelete, id, product, &deleted)
+	})
 	if err != nil {
-		s.logger.Error("Failed to update product:", err)
-		return fmt.Errorf("failed to update product: %w", err)
+		return err
 	}
 
-	s.logger.Info("Product updated successfully:", id)
+	s.logger.Info("Product deleted successfully", "id", id)
 	return nil
 }
 
-func (s *ProductService) DeleteProduct(ctx context.Context, id string) error {
-	s.logger.Info("Deleting product:", id)
+// RestoreProduct brings a deleted product back into the catalog.
+func (s *ProductService) RestoreProduct(ctx context.Context, id string) (*entity.Product, error) {
+	if _, err := authorize(ctx, PermManageDeleted); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Restoring product", "id", id)
+
+	var product *entity.Product
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		deleted, err := s.productRepo.FindByIDIncludingDeleted(ctx, id)
+		if err != nil {
+			s.logger.Error("Product not found", "id", id, "error", err)
+			return fmt.Errorf("product not found: %w", err)
+		}
+		if err := s.productRepo.Restore(ctx, id, time.Now()); err != nil {
+			s.logger.Error("Failed to restore product", "id", id, "error", err)
+			return fmt.Errorf("failed to restore product: %w", err)
+		}
+		product, err = s.productRepo.FindByID(ctx, id)
+		if err != nil {
+			s.logger.Error("Failed to fetch restored product", "id", id, "error", err)
+			return fmt.Errorf("failed to fetch restored product: %w", err)
+		}
+		return s.audit(ctx, entity.AuditActionRestore, id, deleted, product)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Product restored successfully", "id", id)
+	return product, nil
+}
+
+// PurgeProducts removes the products deleted before the given time for good
+// and returns how many it removed.
+func (s *ProductService) PurgeProducts(ctx context.Context, before time.Time) (int64, error) {
+	if _, err := authorize(ctx, PermManageDeleted); err != nil {
+		return 0, err
+	}
+	s.logger.Info("Purging deleted products", "before", before)
 
-	err := s.productRepo.Delete(ctx, id)
+	purged, err := s.productRepo.Purge(ctx, before)
 	if err != nil {
-		s.logger.Error("Failed to delete product:", err)
-		return fmt.Errorf("failed to delete product: %w", err)
+		s.logger.Error("Failed to purge products", "error", err)
+		return 0, fmt.Errorf("failed to purge products: %w", err)
 	}
 
-	s.logger.Info("Product deleted successfully:", id)
+	s.logger.Info("Products purged successfully", "count", purged)
+	return purged, nil
+}
+
+// audit records a change to the product with the ID.
+func (s *ProductService) audit(ctx context.Context, action entity.AuditAction, id string, before, after any) error {
+	if err := audit(ctx, s.auditRepo, action, entityProduct, id, before, after); err != nil {
+		s.logger.Error("Failed to audit product change", "id", id, "action", action, "error", err)
+		return err
+	}
+	return nil
+}
+
+// recordChangeEvents records the events an update of the product raised.
+func (s *ProductService) recordChangeEvents(ctx context.Context, before, after *entity.Product) error {
+	if before.Price != after.Price {
+		change := entity.PriceChange{ProductID: after.ID, OldPrice: before.Price, NewPrice: after.Price}
+		if err := s.recordEvent(ctx, entity.EventProductPriceChanged, after.ID, change); err != nil {
+			return err
+		}
+	}
+	if before.Stock != after.Stock {
+		change := entity.StockChange{ProductID: after.ID, Delta: after.Stock - before.Stock, Stock: after.Stock}
+		if err := s.recordEvent(ctx, entity.EventStockChanged, after.ID, change); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// recordEvent adds an event about the product with the ID to the outbox.
+func (s *ProductService) recordEvent(ctx context.Context, eventType entity.EventType, id string, payload any) error {
+	if err := recordEvent(ctx, s.outboxRepo, eventType, entityProduct, id, payload); err != nil {
+		s.logger.Error("Failed to record product event", "id", id, "type", eventType, "error", err)
+		return err
+	}
+	return nil
+}
+
+// hasOpenOrders reports whether any order in progress has a line for the
+// product.
+func (s *ProductService) hasOpenOrders(ctx context.Context, id string) (bool, error) {
+	for _, status := range openOrderStatuses {
+		query := OrderQuery{
+			OrderFilter: OrderFilter{ProductID: id, Status: status},
+			Sort:        Sort{Field: "created_at"},
+			Limit:       1,
+		}
+		orders, err := s.orderRepo.FindAll(ctx, query)
+		if err != nil {
+			s.logger.Error("Failed to fetch open orders", "product_id", id, "error", err)
+			return false, fmt.Errorf("failed to fetch open orders: %w", err)
+		}
+		if len(orders) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
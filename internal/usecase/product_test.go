@@ -0,0 +1,111 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+)
+
+func newProductService(repos usecase.Repositories) *usecase.ProductService {
+	return usecase.NewProductService(repos.Products, repos.Orders, repos.StockLevels, repos.Audit, repos.Outbox, repos.Transactor,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestUpdateProductKeepsHeldStock(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(s *usecase.ProductService, ctx context.Context, id string, stock int) (*entity.Product, error)
+	}{
+		{"put", func(s *usecase.ProductService, ctx context.Context, id string, stock int) (*entity.Product, error) {
+			return s.UpdateProduct(ctx, id, 0, &entity.Product{Name: "Widget", Price: 2.5, Stock: stock})
+		}},
+		{"patch", func(s *usecase.ProductService, ctx context.Context, id string, stock int) (*entity.Product, error) {
+			return s.PatchProduct(ctx, id, 0, []byte(`{"stock":`+fmt.Sprint(stock)+`}`))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := memory.NewRepositories()
+			service := newProductService(repos)
+			ctx := usecase.WithActor(context.Background(), usecase.Actor{UserID: "admin", Role: entity.RoleAdmin})
+
+			product, err := repos.Products.Create(ctx, &entity.Product{Name: "Widget", Price: 2.5, Stock: 10, Version: 1,
+				CreatedAt: time.Now(), UpdatedAt: time.Now()})
+			if err != nil {
+				t.Fatalf("create product: %v", err)
+			}
+			if err := repos.Products.Reserve(ctx, product.ID, 4); err != nil {
+				t.Fatalf("reserve: %v", err)
+			}
+
+			if _, err := tt.update(service, ctx, product.ID, 3); !errors.Is(err, usecase.ErrStockHeld) {
+				t.Errorf("stock below what is held: %v, want ErrStockHeld", err)
+			}
+			if stored, err := repos.Products.FindByID(ctx, product.ID); err != nil || stored.Stock != 10 {
+				t.Errorf("refused update left stock %d (%v), want 10", stored.Stock, err)
+			}
+
+			updated, err := tt.update(service, ctx, product.ID, 4)
+			if err != nil {
+				t.Fatalf("stock equal to what is held: %v", err)
+			}
+			if updated.Stock != 4 || updated.Reserved != 4 {
+				t.Errorf("product has stock %d (%d reserved), want 4 (4 reserved)", updated.Stock, updated.Reserved)
+			}
+		})
+	}
+}
+
+func TestPatchProductReadOnlyFields(t *testing.T) {
+	repos := memory.NewRepositories()
+	service := newProductService(repos)
+	ctx := usecase.WithActor(context.Background(), usecase.Actor{UserID: "admin", Role: entity.RoleAdmin})
+	created, err := service.CreateProduct(ctx, &entity.Product{Name: "Widget", Price: 2.5, Stock: 10})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	if err := repos.Products.Reserve(ctx, created.ID, 2); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	reserved, err := repos.Products.FindByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("find product: %v", err)
+	}
+
+	patched, err := service.PatchProduct(ctx, created.ID, 0, []byte(`{
+		"id": "other",
+		"reserved": 0,
+		"version": 40,
+		"created_at": "2000-01-01T00:00:00Z",
+		"deleted_at": "2000-01-01T00:00:00Z",
+		"name": "Gadget"
+	}`))
+	if err != nil {
+		t.Fatalf("PatchProduct: %v", err)
+	}
+	if patched.Name != "Gadget" {
+		t.Errorf("name = %q, want Gadget", patched.Name)
+	}
+	if patched.ID != created.ID || patched.Reserved != 2 || patched.Version != reserved.Version+1 ||
+		!patched.CreatedAt.Equal(created.CreatedAt) || patched.DeletedAt != nil {
+		t.Errorf("read-only fields changed: %+v, stored as %+v", patched, reserved)
+	}
+	if _, err := repos.Products.FindByID(ctx, "other"); !errors.Is(err, usecase.ErrNotFound) {
+		t.Errorf("patch created a product under another ID: %v", err)
+	}
+
+	// A patch that is not an object replaces the whole product, which then
+	// lacks its required fields
+	for _, patch := range []string{`null`, `["name"]`, `"Gadget"`} {
+		if _, err := service.PatchProduct(ctx, created.ID, 0, []byte(patch)); !errors.Is(err, usecase.ErrValidation) {
+			t.Errorf("patch %s: %v, want ErrValidation", patch, err)
+		}
+	}
+}
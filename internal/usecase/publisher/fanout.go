@@ -0,0 +1,30 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type fanout []usecase.Publisher
+
+// NewFanout returns a publisher that hands every event to all the publishers.
+// The event counts as delivered only once every publisher took it; a retry
+// goes to all of them again.
+func NewFanout(publishers ...usecase.Publisher) usecase.Publisher {
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return fanout(publishers)
+}
+
+func (f fanout) Publish(ctx context.Context, event entity.Event) error {
+	var errs []error
+	for _, p := range f {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
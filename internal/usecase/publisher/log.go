@@ -0,0 +1,25 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+// NewLogPublisher returns a publisher that writes every event to the log. It
+// never fails.
+func NewLogPublisher(logger *slog.Logger) usecase.Publisher {
+	return &logPublisher{logger}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, event entity.Event) error {
+	p.logger.Info("Event published", "id", event.ID, "type", event.Type,
+		"aggregate_type", event.AggregateType, "aggregate_id", event.AggregateID,
+		"request_id", event.RequestID, "payload", string(event.Payload))
+	return nil
+}
@@ -0,0 +1,69 @@
+package publisher
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/pkg/netguard"
+)
+
+// SignatureHeader carries the signature of a webhook delivery, in the form
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed by the secret>.
+// Receivers should recompute it and reject stale timestamps.
+const SignatureHeader = "X-Webhook-Signature"
+
+type sender struct {
+	client *http.Client
+}
+
+// NewWebhookSender returns the sender that POSTs deliveries to subscribed
+// webhooks, each attempt signed afresh. Anybody may subscribe a URL, so the
+// sender only connects to public addresses, checked as it dials, and does
+// not follow redirects.
+func NewWebhookSender(timeout time.Duration) usecase.WebhookSender {
+	return newSender(timeout, netguard.Control)
+}
+
+// newSender returns a sender whose connections control vets; nil lets every
+// address through.
+func newSender(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *sender {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialled instead of the webhook, escaping the check
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: timeout, Control: control}).DialContext
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &sender{client: client}
+}
+
+func (s *sender) Send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) (int, error) {
+	header := http.Header{}
+	header.Set("X-Webhook-ID", webhook.ID)
+	header.Set("X-Delivery-ID", delivery.ID)
+	header.Set("X-Event-ID", delivery.EventID)
+	header.Set("X-Event-Type", string(delivery.EventType))
+	header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), delivery.Body))
+	return post(ctx, s.client, webhook.URL, delivery.Body, header)
+}
+
+// Sign returns the signature header value for a body sent at the given time.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,72 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// maxDrainedBody bounds how much of a response body is read and thrown away
+// so that the connection can be reused; longer bodies close it instead.
+const maxDrainedBody = 64 << 10
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher returns a publisher that POSTs every event as JSON to
+// the URL. Responses other than 2xx count as failed deliveries. Receivers
+// should deduplicate on the X-Event-ID header, since an event may arrive more
+// than once. The URL comes from the server's configuration, not from users,
+// so unlike subscribed webhooks it may be on the server's own network, such
+// as an internal event collector; like them, it is not followed through
+// redirects.
+func NewWebhookPublisher(url string, timeout time.Duration) usecase.Publisher {
+	return &webhookPublisher{url: url, client: newSender(timeout, nil).client}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, event entity.Event) error {
+	body, err := json.Marshal(usecase.NewEventMessage(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Event-ID", event.ID)
+	header.Set("X-Event-Type", string(event.Type))
+	status, err := post(ctx, p.client, p.url, body, header)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("webhook responded %d", status)
+	}
+	return nil
+}
+
+// post sends a JSON body to the URL and returns the response status.
+func post(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ulab3-webhooks")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBody))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,329 @@
+package publisher
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+	"ulab3/pkg/netguard"
+)
+
+const testSecret = "0123456789abcdef-secret"
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fixture is a webhook pointing at an httptest receiver, with one delivery
+// queued for it.
+type fixture struct {
+	repos    usecase.Repositories
+	webhook  *entity.Webhook
+	delivery *entity.WebhookDelivery
+	server   *httptest.Server
+}
+
+func newFixture(t *testing.T, handler http.HandlerFunc) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	repos := memory.NewRepositories()
+	webhook := &entity.Webhook{
+		OwnerID:    "owner",
+		URL:        server.URL + "/hook",
+		EventTypes: []entity.EventType{entity.EventOrderCreated},
+		Secret:     testSecret,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	webhook, err := repos.Webhooks.Create(ctx, webhook)
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	delivery := &entity.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       "event-1",
+		EventType:     entity.EventOrderCreated,
+		Body:          []byte(`{"id":"event-1","type":"OrderCreated","data":{"total":12.5}}`),
+		Status:        entity.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	}
+	if err := repos.Deliveries.Create(ctx, delivery); err != nil {
+		t.Fatalf("create delivery: %v", err)
+	}
+	return &fixture{repos: repos, webhook: webhook, delivery: delivery, server: server}
+}
+
+// dispatcher returns a dispatcher over the fixture's repositories whose sender
+// may reach the loopback receiver.
+func (f *fixture) dispatcher(config usecase.DispatcherConfig) *usecase.WebhookDispatcher {
+	config.BatchSize = 10
+	config.Lease = time.Minute
+	return usecase.NewWebhookDispatcher(f.repos.Webhooks, f.repos.Deliveries, newSender(200*time.Millisecond, nil), config, discard)
+}
+
+func (f *fixture) stored(t *testing.T) *entity.WebhookDelivery {
+	t.Helper()
+	delivery, err := f.repos.Deliveries.FindByID(context.Background(), f.delivery.ID)
+	if err != nil {
+		t.Fatalf("find delivery: %v", err)
+	}
+	return delivery
+}
+
+func TestSendSignsRawBody(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		body      []byte
+		signature string
+		headers   http.Header
+	)
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		headers = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if n := f.dispatcher(usecase.DispatcherConfig{MaxAttempts: 3, MinBackoff: time.Second, MaxBackoff: time.Second}).DispatchBatch(context.Background()); n != 1 {
+		t.Fatalf("DispatchBatch claimed %d deliveries, want 1", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if string(body) != string(f.delivery.Body) {
+		t.Errorf("receiver got body %s, want %s", body, f.delivery.Body)
+	}
+	timestamp, mac, ok := strings.Cut(signature, ",v1=")
+	timestamp, found := strings.CutPrefix(timestamp, "t=")
+	if !ok || !found {
+		t.Fatalf("signature %q is not t=<unix>,v1=<hex>", signature)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		t.Fatalf("signature timestamp %q: %v", timestamp, err)
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age < -time.Second || age > time.Minute {
+		t.Errorf("signature timestamp is %v old", age)
+	}
+	expected := hmac.New(sha256.New, []byte(testSecret))
+	expected.Write([]byte(timestamp + "."))
+	expected.Write(body)
+	if got, err := hex.DecodeString(mac); err != nil || !hmac.Equal(got, expected.Sum(nil)) {
+		t.Errorf("signature %q does not match the body", signature)
+	}
+
+	for header, want := range map[string]string{
+		"X-Webhook-ID":  f.webhook.ID,
+		"X-Delivery-ID": f.delivery.ID,
+		"X-Event-ID":    f.delivery.EventID,
+		"X-Event-Type":  string(f.delivery.EventType),
+	} {
+		if got := headers.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	stored := f.stored(t)
+	if stored.Status != entity.WebhookDeliverySucceeded || stored.DeliveredAt == nil {
+		t.Errorf("delivery is %s, delivered at %v; want succeeded", stored.Status, stored.DeliveredAt)
+	}
+	if len(stored.Attempts) != 1 || stored.Attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("attempts = %+v, want one with status 204", stored.Attempts)
+	}
+}
+
+func TestDispatchBacksOffAfterFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{
+			name:    "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			status:  http.StatusBadGateway,
+		},
+		{
+			name: "timeout",
+			// Outlasts the sender's 200ms timeout
+			handler: func(w http.ResponseWriter, r *http.Request) { time.Sleep(300 * time.Millisecond) },
+		},
+		{
+			name: "redirect",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			},
+			status: http.StatusFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				tt.handler(w, r)
+			})
+			ctx := context.Background()
+			dispatcher := f.dispatcher(usecase.DispatcherConfig{MaxAttempts: 5, MinBackoff: time.Hour, MaxBackoff: 4 * time.Hour})
+
+			before := time.Now()
+			if n := dispatcher.DispatchBatch(ctx); n != 1 {
+				t.Fatalf("DispatchBatch claimed %d deliveries, want 1", n)
+			}
+			stored := f.stored(t)
+			if stored.Status != entity.WebhookDeliveryPending || stored.AttemptCount != 1 {
+				t.Fatalf("delivery is %s after %d attempts, want pending after 1", stored.Status, stored.AttemptCount)
+			}
+			if len(stored.Attempts) != 1 || stored.Attempts[0].StatusCode != tt.status || stored.Attempts[0].Error == "" {
+				t.Errorf("attempts = %+v, want one failed with status %d", stored.Attempts, tt.status)
+			}
+			// The first retry waits between half and all of MinBackoff
+			if wait := stored.NextAttemptAt.Sub(before); wait < 30*time.Minute || wait > time.Hour+time.Minute {
+				t.Errorf("next attempt in %v, want 30m-1h", wait)
+			}
+
+			if n := dispatcher.DispatchBatch(ctx); n != 0 {
+				t.Errorf("DispatchBatch claimed %d deliveries during the backoff, want 0", n)
+			}
+			if got := hits.Load(); got != 1 {
+				t.Errorf("receiver was called %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestDispatchFailsAfterMaxAttempts(t *testing.T) {
+	var hits atomic.Int32
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	dispatcher := f.dispatcher(usecase.DispatcherConfig{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for f.stored(t).Status == entity.WebhookDeliveryPending {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery still pending after 5s")
+		}
+		dispatcher.DispatchBatch(context.Background())
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	stored := f.stored(t)
+	if stored.Status != entity.WebhookDeliveryFailed || stored.AttemptCount != 3 || len(stored.Attempts) != 3 {
+		t.Errorf("delivery is %s after %d attempts (%d logged), want failed after 3", stored.Status, stored.AttemptCount, len(stored.Attempts))
+	}
+	if stored.DeliveredAt != nil {
+		t.Errorf("failed delivery has DeliveredAt %v", stored.DeliveredAt)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("receiver was called %d times, want 3", got)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	var healthy atomic.Bool
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ctx := usecase.WithActor(context.Background(), usecase.Actor{UserID: f.webhook.OwnerID, Role: entity.RoleCustomer})
+	dispatcher := f.dispatcher(usecase.DispatcherConfig{MaxAttempts: 1, MinBackoff: time.Hour, MaxBackoff: time.Hour})
+	service := usecase.NewWebhookService(f.repos.Webhooks, f.repos.Deliveries, f.repos.Users, f.repos.Transactor, discard)
+
+	if _, err := service.Redeliver(ctx, f.webhook.ID, f.delivery.ID); !errors.Is(err, usecase.ErrDeliveryPending) {
+		t.Errorf("Redeliver of a pending delivery = %v, want ErrDeliveryPending", err)
+	}
+
+	dispatcher.DispatchBatch(ctx)
+	if status := f.stored(t).Status; status != entity.WebhookDeliveryFailed {
+		t.Fatalf("delivery is %s, want failed", status)
+	}
+
+	healthy.Store(true)
+	queued, err := service.Redeliver(ctx, f.webhook.ID, f.delivery.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if queued.Status != entity.WebhookDeliveryPending || queued.AttemptCount != 0 {
+		t.Errorf("redelivered delivery is %s after %d attempts, want pending after 0", queued.Status, queued.AttemptCount)
+	}
+
+	if n := dispatcher.DispatchBatch(ctx); n != 1 {
+		t.Fatalf("DispatchBatch claimed %d deliveries, want 1", n)
+	}
+	stored := f.stored(t)
+	if stored.Status != entity.WebhookDeliverySucceeded || stored.AttemptCount != 1 {
+		t.Errorf("delivery is %s after %d attempts, want succeeded after 1", stored.Status, stored.AttemptCount)
+	}
+	// The attempt log survives the redelivery
+	if len(stored.Attempts) != 2 || stored.Attempts[0].StatusCode != http.StatusInternalServerError || stored.Attempts[1].StatusCode != http.StatusOK {
+		t.Errorf("attempts = %+v, want a 500 then a 200", stored.Attempts)
+	}
+}
+
+func TestWebhookSenderRefusesLoopback(t *testing.T) {
+	var hits atomic.Int32
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	})
+
+	status, err := NewWebhookSender(time.Second).Send(context.Background(), f.webhook, f.delivery)
+	if !errors.Is(err, netguard.ErrNotPublic) {
+		t.Errorf("Send to %s = %d, %v; want ErrNotPublic", f.server.URL, status, err)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Errorf("receiver was called %d times, want 0", got)
+	}
+}
+
+func TestWebhookPublisherReachesConfiguredURL(t *testing.T) {
+	var eventIDs []string
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		eventIDs = append(eventIDs, r.Header.Get("X-Event-ID"))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/events", http.StatusTemporaryRedirect)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	event := entity.Event{ID: "event-1", Type: entity.EventOrderCreated, Payload: []byte(`{}`), CreatedAt: time.Now()}
+
+	// The configured sink may be on the loopback interface
+	if err := NewWebhookPublisher(server.URL+"/events", time.Second).Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := NewWebhookPublisher(server.URL+"/moved", time.Second).Publish(context.Background(), event); err == nil {
+		t.Error("Publish through a redirect succeeded, want the redirect to count as a failure")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(eventIDs) != 1 || eventIDs[0] != event.ID {
+		t.Errorf("receiver saw events %v, want only %s", eventIDs, event.ID)
+	}
+}
@@ -0,0 +1,387 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"ulab3/internal/entity"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest is what a client asks for when listing records: how many, the
+// cursor returned with the previous page, and a sort expression such as
+// "price" or "-price" for descending order.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// Sort orders a listing by one field. Records with equal values are ordered
+// by ID, in the same direction.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor is a keyset position: the sort value and ID of the last record on the
+// previous page. The next page starts strictly after it.
+type Cursor struct {
+	Value interface{}
+	ID    string
+}
+
+type ProductFilter struct {
+	Category string
+	MinPrice *float64
+	MaxPrice *float64
+	// InStock lists only products with stock that is not reserved.
+	InStock bool
+	// IncludeDeleted lists deleted products along with the others.
+	IncludeDeleted bool
+}
+
+// ProductQuery is the listing request a ProductRepository serves. Limit is
+// the maximum number of records to return.
+type ProductQuery struct {
+	ProductFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+type OrderFilter struct {
+	// UserID limits the listing to the orders one user placed.
+	UserID     string
+	CustomerID string
+	Status     entity.OrderStatus
+	ProductID  string
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// IncludeDeleted lists deleted orders along with the others.
+	IncludeDeleted bool
+}
+
+// OrderQuery is the listing request an OrderRepository serves. Limit is the
+// maximum number of records to return.
+type OrderQuery struct {
+	OrderFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+// OrderStreamFilter narrows an order event stream. Status matches the status
+// an event leaves the order in; ProductID matches orders with a line for the
+// product.
+type OrderStreamFilter struct {
+	Status    entity.OrderStatus
+	ProductID string
+}
+
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorID    string
+	// From is inclusive, To exclusive.
+	From *time.Time
+	To   *time.Time
+}
+
+// AuditQuery is the listing request an AuditRepository serves. Limit is the
+// maximum number of records to return.
+type AuditQuery struct {
+	AuditFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+type EventFilter struct {
+	Status entity.EventStatus
+	Type   entity.EventType
+}
+
+// EventQuery is the listing request an OutboxRepository serves. Limit is the
+// maximum number of records to return.
+type EventQuery struct {
+	EventFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+// CustomerFilter narrows customer listings. UserID is set by the service for
+// actors who may only manage the customers linked to their account.
+type CustomerFilter struct {
+	UserID string
+	Status entity.CustomerStatus
+	Email  string
+}
+
+// CustomerQuery is the listing request a CustomerRepository serves. Limit is
+// the maximum number of records to return.
+type CustomerQuery struct {
+	CustomerFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+// WebhookFilter narrows webhook listings. OwnerID is set by the service for
+// actors who may only manage their own webhooks.
+type WebhookFilter struct {
+	OwnerID string
+}
+
+// WebhookQuery is the listing request a WebhookRepository serves. Limit is
+// the maximum number of records to return.
+type WebhookQuery struct {
+	WebhookFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+type WebhookDeliveryFilter struct {
+	WebhookID string
+	Status    entity.WebhookDeliveryStatus
+}
+
+// WebhookDeliveryQuery is the listing request a WebhookDeliveryRepository
+// serves. Limit is the maximum number of records to return.
+type WebhookDeliveryQuery struct {
+	WebhookDeliveryFilter
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindNumber
+	kindTime
+)
+
+type sortField[T any] struct {
+	kind  valueKind
+	value func(T) interface{}
+}
+
+// productSortFields lists the fields products can be sorted by; each one is
+// backed by an index in every backend.
+var productSortFields = map[string]sortField[entity.Product]{
+	"created_at": {kindTime, func(p entity.Product) interface{} { return p.CreatedAt }},
+	"name":       {kindString, func(p entity.Product) interface{} { return p.Name }},
+	"price":      {kindNumber, func(p entity.Product) interface{} { return p.Price }},
+	"stock":      {kindNumber, func(p entity.Product) interface{} { return float64(p.Stock) }},
+}
+
+// orderSortFields lists the fields orders can be sorted by; each one is backed
+// by an index in every backend.
+var orderSortFields = map[string]sortField[entity.Order]{
+	"created_at":  {kindTime, func(o entity.Order) interface{} { return o.CreatedAt }},
+	"updated_at":  {kindTime, func(o entity.Order) interface{} { return o.UpdatedAt }},
+	"total_price": {kindNumber, func(o entity.Order) interface{} { return o.TotalPrice }},
+}
+
+// auditSortFields lists the fields audit entries can be sorted by; each one is
+// backed by an index in every backend.
+var auditSortFields = map[string]sortField[entity.AuditEntry]{
+	"created_at": {kindTime, func(e entity.AuditEntry) interface{} { return e.CreatedAt }},
+}
+
+// eventSortFields lists the fields events can be sorted by; each one is backed
+// by an index in every backend.
+var eventSortFields = map[string]sortField[entity.Event]{
+	"created_at": {kindTime, func(e entity.Event) interface{} { return e.CreatedAt }},
+}
+
+// customerSortFields lists the fields customers can be sorted by; each one is
+// backed by an index in every backend.
+var customerSortFields = map[string]sortField[entity.Customer]{
+	"created_at": {kindTime, func(c entity.Customer) interface{} { return c.CreatedAt }},
+	"name":       {kindString, func(c entity.Customer) interface{} { return c.Name }},
+}
+
+// webhookSortFields lists the fields webhooks can be sorted by; each one is
+// backed by an index in every backend.
+var webhookSortFields = map[string]sortField[entity.Webhook]{
+	"created_at": {kindTime, func(w entity.Webhook) interface{} { return w.CreatedAt }},
+}
+
+// deliverySortFields lists the fields webhook deliveries can be sorted by;
+// each one is backed by an index in every backend.
+var deliverySortFields = map[string]sortField[entity.WebhookDelivery]{
+	"created_at": {kindTime, func(d entity.WebhookDelivery) interface{} { return d.CreatedAt }},
+}
+
+// ProductSortValue returns the value of the named sort field, for backends
+// that sort in process.
+func ProductSortValue(product entity.Product, field string) interface{} {
+	return productSortFields[field].value(product)
+}
+
+// OrderSortValue returns the value of the named sort field, for backends that
+// sort in process.
+func OrderSortValue(order entity.Order, field string) interface{} {
+	return orderSortFields[field].value(order)
+}
+
+// AuditSortValue returns the value of the named sort field, for backends that
+// sort in process.
+func AuditSortValue(entry entity.AuditEntry, field string) interface{} {
+	return auditSortFields[field].value(entry)
+}
+
+// EventSortValue returns the value of the named sort field, for backends that
+// sort in process.
+func EventSortValue(event entity.Event, field string) interface{} {
+	return eventSortFields[field].value(event)
+}
+
+// CustomerSortValue returns the value of the named sort field, for backends
+// that sort in process.
+func CustomerSortValue(customer entity.Customer, field string) interface{} {
+	return customerSortFields[field].value(customer)
+}
+
+// WebhookSortValue returns the value of the named sort field, for backends
+// that sort in process.
+func WebhookSortValue(webhook entity.Webhook, field string) interface{} {
+	return webhookSortFields[field].value(webhook)
+}
+
+// WebhookDeliverySortValue returns the value of the named sort field, for
+// backends that sort in process.
+func WebhookDeliverySortValue(delivery entity.WebhookDelivery, field string) interface{} {
+	return deliverySortFields[field].value(delivery)
+}
+
+// CompareSortValues orders two values produced by the sort value functions
+// above, returning -1, 0 or 1.
+func CompareSortValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// cursorToken is the JSON carried inside an opaque cursor string. It records
+// the sort it was issued for, so it cannot be replayed against another one.
+type cursorToken struct {
+	Field string          `json:"f"`
+	Desc  bool            `json:"d,omitempty"`
+	Value json.RawMessage `json:"v"`
+	ID    string          `json:"id"`
+}
+
+// parsePage validates a page request against the sortable fields and decodes
+// its cursor.
+func parsePage[T any](page PageRequest, fields map[string]sortField[T]) (Sort, *Cursor, int, error) {
+	limit := page.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultPageLimit
+	case limit < 0 || limit > MaxPageLimit:
+		return Sort{}, nil, 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageLimit)
+	}
+
+	sort := Sort{Field: "created_at"}
+	if page.Sort != "" {
+		sort.Field = strings.TrimPrefix(page.Sort, "-")
+		sort.Desc = strings.HasPrefix(page.Sort, "-")
+	}
+	field, ok := fields[sort.Field]
+	if !ok {
+		return Sort{}, nil, 0, fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, sort.Field)
+	}
+
+	if page.Cursor == "" {
+		return sort, nil, limit, nil
+	}
+	cursor, err := decodeCursor(page.Cursor, sort, field.kind)
+	if err != nil {
+		return Sort{}, nil, 0, err
+	}
+	return sort, cursor, limit, nil
+}
+
+func decodeCursor(token string, sort Sort, kind valueKind) (*Cursor, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	var decoded cursorToken
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, invalid
+	}
+	if decoded.Field != sort.Field || decoded.Desc != sort.Desc {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidQuery)
+	}
+
+	cursor := &Cursor{ID: decoded.ID}
+	switch kind {
+	case kindTime:
+		var value time.Time
+		err = json.Unmarshal(decoded.Value, &value)
+		cursor.Value = value
+	case kindNumber:
+		var value float64
+		err = json.Unmarshal(decoded.Value, &value)
+		cursor.Value = value
+	default:
+		var value string
+		err = json.Unmarshal(decoded.Value, &value)
+		cursor.Value = value
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	return cursor, nil
+}
+
+func encodeCursor(sort Sort, value interface{}, id string) string {
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(cursorToken{Field: sort.Field, Desc: sort.Desc, Value: raw, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// paginate trims records fetched with limit+1 down to one page and builds the
+// pagination metadata, including the cursor for the next page.
+func paginate[T any](records []T, limit int, sort Sort, fields map[string]sortField[T], id func(T) string) ([]T, entity.Pagination) {
+	pagination := entity.Pagination{Limit: limit}
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[limit-1]
+		pagination.HasMore = true
+		pagination.NextCursor = encodeCursor(sort, fields[sort.Field].value(last), id(last))
+	}
+	if records == nil {
+		records = []T{}
+	}
+	return records, pagination
+}
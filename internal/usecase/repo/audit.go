@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type auditRepo struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(collection *mongo.Collection) usecase.AuditRepository {
+	return &auditRepo{collection}
+}
+
+func (repo *auditRepo) Append(ctx context.Context, entry *entity.AuditEntry) error {
+	entry.ID = uuid.New().String()
+	_, err := repo.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (repo *auditRepo) FindAll(ctx context.Context, query usecase.AuditQuery) ([]entity.AuditEntry, error) {
+	filter := bson.M{}
+	if query.EntityType != "" {
+		filter["entity_type"] = query.EntityType
+	}
+	if query.EntityID != "" {
+		filter["entity_id"] = query.EntityID
+	}
+	if query.ActorID != "" {
+		filter["actor_id"] = query.ActorID
+	}
+	created := bson.M{}
+	if query.From != nil {
+		created["$gte"] = *query.From
+	}
+	if query.To != nil {
+		created["$lt"] = *query.To
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []entity.AuditEntry
+	for cursor.Next(ctx) {
+		var entry entity.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
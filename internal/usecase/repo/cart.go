@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type cartRepo struct {
+	collection *mongo.Collection
+}
+
+func NewCartRepository(collection *mongo.Collection) usecase.CartRepository {
+	return &cartRepo{collection}
+}
+
+// liveCart matches the cart with the ID unless it expired; the TTL monitor only
+// runs once a minute, so expired carts may linger for a while.
+func liveCart(id string) bson.M {
+	return bson.M{"id": id, "expires_at": bson.M{"$gt": time.Now()}}
+}
+
+func (repo *cartRepo) Create(ctx context.Context, cart *entity.Cart) (*entity.Cart, error) {
+	cart.ID = uuid.New().String()
+	if _, err := repo.collection.InsertOne(ctx, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (repo *cartRepo) FindByID(ctx context.Context, id string) (*entity.Cart, error) {
+	var cart entity.Cart
+	if err := repo.collection.FindOne(ctx, liveCart(id)).Decode(&cart); err != nil {
+		return nil, findError(err, "cart", id)
+	}
+	return &cart, nil
+}
+
+func (repo *cartRepo) Update(ctx context.Context, cart *entity.Cart) error {
+	update := bson.M{"$set": bson.M{
+		"items":      cart.Items,
+		"updated_at": cart.UpdatedAt,
+		"expires_at": cart.ExpiresAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, liveCart(cart.ID), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("cart", cart.ID)
+	}
+	return nil
+}
+
+func (repo *cartRepo) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, liveCart(id))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("cart", id)
+	}
+	return nil
+}
+
+func (repo *cartRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := repo.collection.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type customerRepo struct {
+	collection *mongo.Collection
+}
+
+func NewCustomerRepository(collection *mongo.Collection) usecase.CustomerRepository {
+	return &customerRepo{collection}
+}
+
+func (repo *customerRepo) Create(ctx context.Context, customer *entity.Customer) (*entity.Customer, error) {
+	customer.ID = uuid.New().String()
+	if _, err := repo.collection.InsertOne(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+func (repo *customerRepo) FindByID(ctx context.Context, id string) (*entity.Customer, error) {
+	var customer entity.Customer
+	if err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&customer); err != nil {
+		return nil, findError(err, "customer", id)
+	}
+	return &customer, nil
+}
+
+func (repo *customerRepo) FindAll(ctx context.Context, query usecase.CustomerQuery) ([]entity.Customer, error) {
+	filter := bson.M{}
+	if query.UserID != "" {
+		filter["user_id"] = query.UserID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.Email != "" {
+		filter["email"] = query.Email
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var customers []entity.Customer
+	for cursor.Next(ctx) {
+		var customer entity.Customer
+		if err := cursor.Decode(&customer); err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, nil
+}
+
+func (repo *customerRepo) Update(ctx context.Context, customer *entity.Customer) error {
+	update := bson.M{"$set": bson.M{
+		"name":       customer.Name,
+		"email":      customer.Email,
+		"phone":      customer.Phone,
+		"addresses":  customer.Addresses,
+		"status":     customer.Status,
+		"updated_at": customer.UpdatedAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"id": customer.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("customer", customer.ID)
+	}
+	return nil
+}
+
+func (repo *customerRepo) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("customer", id)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"ulab3/internal/usecase"
+)
+
+// findError translates the driver's no-documents error into
+// usecase.ErrNotFound and passes every other error through.
+func findError(err error, kind, key string) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return notFound(kind, key)
+	}
+	return err
+}
+
+// notFound reports that no record of the kind matches key.
+func notFound(kind, key string) error {
+	return fmt.Errorf("%s %s: %w", kind, key, usecase.ErrNotFound)
+}
+
+// versionError explains why a versioned update matched no document: either
+// no live record of the kind has the ID, or another write moved its version
+// on.
+func versionError(ctx context.Context, collection *mongo.Collection, kind, id string) error {
+	count, err := collection.CountDocuments(ctx, bson.M{"id": id, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return notFound(kind, id)
+	}
+	return fmt.Errorf("%s %s: %w", kind, id, usecase.ErrVersionConflict)
+}
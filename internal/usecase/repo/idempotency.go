@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type idempotencyRepo struct {
+	collection *mongo.Collection
+}
+
+func NewIdempotencyRepository(collection *mongo.Collection) usecase.IdempotencyRepository {
+	return &idempotencyRepo{collection}
+}
+
+func (repo *idempotencyRepo) Create(ctx context.Context, record *entity.IdempotencyRecord) error {
+	// The TTL monitor only runs once a minute; clear an expired record it has
+	// not reached yet
+	expired := bson.M{"id": record.Key, "expires_at": bson.M{"$lte": time.Now()}}
+	if _, err := repo.collection.DeleteOne(ctx, expired); err != nil {
+		return err
+	}
+
+	_, err := repo.collection.InsertOne(ctx, record)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("idempotency key %s: %w", record.Key, usecase.ErrConflict)
+	}
+	return err
+}
+
+func (repo *idempotencyRepo) FindByKey(ctx context.Context, key string) (*entity.IdempotencyRecord, error) {
+	var record entity.IdempotencyRecord
+	filter := bson.M{"id": key, "expires_at": bson.M{"$gt": time.Now()}}
+	err := repo.collection.FindOne(ctx, filter).Decode(&record)
+	if err != nil {
+		return nil, findError(err, "idempotency key", key)
+	}
+	return &record, nil
+}
+
+func (repo *idempotencyRepo) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	update := bson.M{"$set": bson.M{
+		"status":       record.Status,
+		"content_type": record.ContentType,
+		"body":         record.Body,
+		"expires_at":   record.ExpiresAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"id": record.Key}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("idempotency key", record.Key)
+	}
+	return nil
+}
+
+func (repo *idempotencyRepo) Delete(ctx context.Context, key string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": key})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("idempotency key", key)
+	}
+	return nil
+}
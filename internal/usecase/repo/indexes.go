@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes lookups, filters and keyset pagination
+// rely on. Creating an index that already exists is a no-op.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		"products": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "category", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "price", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "stock", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+			{
+				Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "category", Value: "text"}},
+				Options: options.Index().SetWeights(bson.M{"name": 2, "category": 1}),
+			},
+		},
+		"orders": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "customer_id", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}}},
+			{Keys: bson.D{{Key: "items.product_id", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "updated_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "total_price", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+		"users": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"customers": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},
+			{Keys: bson.D{{Key: "email", Value: 1}}},
+		},
+		"carts": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// Abandoned carts are useless, let MongoDB delete them
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"reservations": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "holder_type", Value: 1}, {Key: "holder_id", Value: 1}}},
+			// No TTL here: expired holds must be released by the sweeper, which
+			// gives their stock back
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}},
+		},
+		"warehouses": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"stock_levels": {
+			{Keys: bson.D{{Key: "warehouse_id", Value: 1}, {Key: "product_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "warehouse_id", Value: 1}}},
+		},
+		"idempotency_keys": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"audit_log": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "created_at", Value: 1}}},
+			{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+		"outbox": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+		},
+		"webhooks": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "event_types", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+		"webhook_deliveries": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// An event is delivered to each webhook once, however often it is published
+			{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "event_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+			{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+		"refresh_tokens": {
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// Expired tokens are useless, let MongoDB delete them
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type auditRepo struct {
+	store *Store
+}
+
+func NewAuditRepository(store *Store) usecase.AuditRepository {
+	return &auditRepo{store}
+}
+
+func (repo *auditRepo) Append(ctx context.Context, entry *entity.AuditEntry) error {
+	defer repo.store.lock(ctx)()
+
+	entry.ID = uuid.New().String()
+	repo.store.audit = append(repo.store.audit, *entry)
+	return nil
+}
+
+func (repo *auditRepo) FindAll(ctx context.Context, query usecase.AuditQuery) ([]entity.AuditEntry, error) {
+	defer repo.store.lock(ctx)()
+
+	var entries []entity.AuditEntry
+	for _, entry := range repo.store.audit {
+		if matchAuditEntry(entry, query.AuditFilter) {
+			entries = append(entries, entry)
+		}
+	}
+	return page(entries, query.Sort, query.After, query.Limit, usecase.AuditSortValue,
+		func(e entity.AuditEntry) string { return e.ID }), nil
+}
+
+func matchAuditEntry(entry entity.AuditEntry, filter usecase.AuditFilter) bool {
+	switch {
+	case filter.EntityType != "" && entry.EntityType != filter.EntityType:
+		return false
+	case filter.EntityID != "" && entry.EntityID != filter.EntityID:
+		return false
+	case filter.ActorID != "" && entry.ActorID != filter.ActorID:
+		return false
+	case filter.From != nil && entry.CreatedAt.Before(*filter.From):
+		return false
+	case filter.To != nil && !entry.CreatedAt.Before(*filter.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type cartRepo struct {
+	store *Store
+}
+
+func NewCartRepository(store *Store) usecase.CartRepository {
+	return &cartRepo{store}
+}
+
+// find returns the stored cart with the ID unless it is missing or expired.
+// The caller holds the store lock.
+func (repo *cartRepo) find(id string) (entity.Cart, error) {
+	cart, ok := repo.store.carts[id]
+	if !ok || !cart.ExpiresAt.After(time.Now()) {
+		return cart, fmt.Errorf("cart %s: %w", id, usecase.ErrNotFound)
+	}
+	return cart, nil
+}
+
+func (repo *cartRepo) Create(ctx context.Context, cart *entity.Cart) (*entity.Cart, error) {
+	defer repo.store.lock(ctx)()
+
+	cart.ID = uuid.New().String()
+	stored := *cart
+	stored.Items = slices.Clone(cart.Items)
+	repo.store.carts[cart.ID] = stored
+	return cart, nil
+}
+
+func (repo *cartRepo) FindByID(ctx context.Context, id string) (*entity.Cart, error) {
+	defer repo.store.lock(ctx)()
+
+	cart, err := repo.find(id)
+	if err != nil {
+		return nil, err
+	}
+	cart.Items = slices.Clone(cart.Items)
+	return &cart, nil
+}
+
+func (repo *cartRepo) Update(ctx context.Context, cart *entity.Cart) error {
+	defer repo.store.lock(ctx)()
+
+	stored, err := repo.find(cart.ID)
+	if err != nil {
+		return err
+	}
+	stored.Items = slices.Clone(cart.Items)
+	stored.UpdatedAt = cart.UpdatedAt
+	stored.ExpiresAt = cart.ExpiresAt
+	repo.store.carts[cart.ID] = stored
+	return nil
+}
+
+func (repo *cartRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, err := repo.find(id); err != nil {
+		return err
+	}
+	delete(repo.store.carts, id)
+	return nil
+}
+
+func (repo *cartRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer repo.store.lock(ctx)()
+
+	var deleted int64
+	for id, cart := range repo.store.carts {
+		if cart.ExpiresAt.Before(before) {
+			delete(repo.store.carts, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
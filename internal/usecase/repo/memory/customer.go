@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type customerRepo struct {
+	store *Store
+}
+
+func NewCustomerRepository(store *Store) usecase.CustomerRepository {
+	return &customerRepo{store}
+}
+
+// cloneCustomer copies the customer's addresses so callers never share memory
+// with the stored record.
+func cloneCustomer(customer entity.Customer) entity.Customer {
+	customer.Addresses = slices.Clone(customer.Addresses)
+	return customer
+}
+
+func (repo *customerRepo) Create(ctx context.Context, customer *entity.Customer) (*entity.Customer, error) {
+	defer repo.store.lock(ctx)()
+
+	customer.ID = uuid.New().String()
+	repo.store.customers[customer.ID] = cloneCustomer(*customer)
+	return customer, nil
+}
+
+func (repo *customerRepo) FindByID(ctx context.Context, id string) (*entity.Customer, error) {
+	defer repo.store.lock(ctx)()
+
+	customer, ok := repo.store.customers[id]
+	if !ok {
+		return nil, fmt.Errorf("customer %s: %w", id, usecase.ErrNotFound)
+	}
+	customer = cloneCustomer(customer)
+	return &customer, nil
+}
+
+func (repo *customerRepo) FindAll(ctx context.Context, query usecase.CustomerQuery) ([]entity.Customer, error) {
+	defer repo.store.lock(ctx)()
+
+	var customers []entity.Customer
+	for _, customer := range repo.store.customers {
+		if matchCustomer(customer, query.CustomerFilter) {
+			customers = append(customers, cloneCustomer(customer))
+		}
+	}
+	return page(customers, query.Sort, query.After, query.Limit, usecase.CustomerSortValue,
+		func(c entity.Customer) string { return c.ID }), nil
+}
+
+func (repo *customerRepo) Update(ctx context.Context, customer *entity.Customer) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.customers[customer.ID]
+	if !ok {
+		return fmt.Errorf("customer %s: %w", customer.ID, usecase.ErrNotFound)
+	}
+	stored.Name = customer.Name
+	stored.Email = customer.Email
+	stored.Phone = customer.Phone
+	stored.Addresses = slices.Clone(customer.Addresses)
+	stored.Status = customer.Status
+	stored.UpdatedAt = customer.UpdatedAt
+	repo.store.customers[customer.ID] = stored
+	return nil
+}
+
+func (repo *customerRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.customers[id]; !ok {
+		return fmt.Errorf("customer %s: %w", id, usecase.ErrNotFound)
+	}
+	delete(repo.store.customers, id)
+	return nil
+}
+
+func matchCustomer(customer entity.Customer, filter usecase.CustomerFilter) bool {
+	switch {
+	case filter.UserID != "" && customer.UserID != filter.UserID:
+		return false
+	case filter.Status != "" && customer.Status != filter.Status:
+		return false
+	case filter.Email != "" && customer.Email != filter.Email:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type idempotencyRepo struct {
+	store *Store
+}
+
+func NewIdempotencyRepository(store *Store) usecase.IdempotencyRepository {
+	return &idempotencyRepo{store}
+}
+
+func (repo *idempotencyRepo) Create(ctx context.Context, record *entity.IdempotencyRecord) error {
+	defer repo.store.lock(ctx)()
+
+	if stored, ok := repo.store.idempotency[record.Key]; ok && stored.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("idempotency key %s: %w", record.Key, usecase.ErrConflict)
+	}
+	repo.store.idempotency[record.Key] = *record
+	return nil
+}
+
+func (repo *idempotencyRepo) FindByKey(ctx context.Context, key string) (*entity.IdempotencyRecord, error) {
+	defer repo.store.lock(ctx)()
+
+	record, ok := repo.store.idempotency[key]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("idempotency key %s: %w", key, usecase.ErrNotFound)
+	}
+	return &record, nil
+}
+
+func (repo *idempotencyRepo) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.idempotency[record.Key]
+	if !ok {
+		return fmt.Errorf("idempotency key %s: %w", record.Key, usecase.ErrNotFound)
+	}
+	stored.Status = record.Status
+	stored.ContentType = record.ContentType
+	stored.Body = record.Body
+	stored.ExpiresAt = record.ExpiresAt
+	repo.store.idempotency[record.Key] = stored
+	return nil
+}
+
+func (repo *idempotencyRepo) Delete(ctx context.Context, key string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.idempotency[key]; !ok {
+		return fmt.Errorf("idempotency key %s: %w", key, usecase.ErrNotFound)
+	}
+	delete(repo.store.idempotency, key)
+	return nil
+}
@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type orderRepo struct {
+	store *Store
+}
+
+func NewOrderRepository(store *Store) usecase.OrderRepository {
+	return &orderRepo{store}
+}
+
+// cloneOrder copies the order's slices, the allocations of its items
+// included, so callers never share memory with the stored record.
+func cloneOrder(order entity.Order) entity.Order {
+	order.Items = slices.Clone(order.Items)
+	for i := range order.Items {
+		order.Items[i].Allocations = slices.Clone(order.Items[i].Allocations)
+	}
+	order.StatusHistory = slices.Clone(order.StatusHistory)
+	return order
+}
+
+func (repo *orderRepo) Create(ctx context.Context, order *entity.Order) (*entity.Order, error) {
+	defer repo.store.lock(ctx)()
+
+	order.ID = uuid.New().String()
+	repo.store.orders[order.ID] = cloneOrder(*order)
+	return order, nil
+}
+
+func (repo *orderRepo) FindAll(ctx context.Context, query usecase.OrderQuery) ([]entity.Order, error) {
+	defer repo.store.lock(ctx)()
+
+	var orders []entity.Order
+	for _, order := range repo.store.orders {
+		if matchOrder(order, query.OrderFilter) {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+	return page(orders, query.Sort, query.After, query.Limit, usecase.OrderSortValue,
+		func(o entity.Order) string { return o.ID }), nil
+}
+
+func (repo *orderRepo) FindByID(ctx context.Context, id string) (*entity.Order, error) {
+	defer repo.store.lock(ctx)()
+
+	order, ok := repo.store.orders[id]
+	if !ok || order.DeletedAt != nil {
+		return nil, fmt.Errorf("order %s: %w", id, usecase.ErrNotFound)
+	}
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+func (repo *orderRepo) FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Order, error) {
+	defer repo.store.lock(ctx)()
+
+	order, ok := repo.store.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("order %s: %w", id, usecase.ErrNotFound)
+	}
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+func (repo *orderRepo) Update(ctx context.Context, id string, order *entity.Order) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.orders[id]
+	if !ok || stored.DeletedAt != nil {
+		return fmt.Errorf("order %s: %w", id, usecase.ErrNotFound)
+	}
+	if stored.Version != order.Version {
+		return fmt.Errorf("order %s: %w", id, usecase.ErrVersionConflict)
+	}
+	updated := cloneOrder(*order)
+	updated.ID = id
+	updated.Version++
+	repo.store.orders[id] = updated
+	return nil
+}
+
+func (repo *orderRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.orders[id]; !ok {
+		return fmt.Errorf("order %s: %w", id, usecase.ErrNotFound)
+	}
+	delete(repo.store.orders, id)
+	return nil
+}
+
+func (repo *orderRepo) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	defer repo.store.lock(ctx)()
+
+	order, ok := repo.store.orders[id]
+	if !ok || order.DeletedAt != nil {
+		return fmt.Errorf("order %s: %w", id, usecase.ErrNotFound)
+	}
+	order.DeletedAt = &at
+	order.UpdatedAt = at
+	order.Version++
+	repo.store.orders[id] = order
+	return nil
+}
+
+func (repo *orderRepo) Restore(ctx context.Context, id string, at time.Time) error {
+	defer repo.store.lock(ctx)()
+
+	order, ok := repo.store.orders[id]
+	if !ok || order.DeletedAt == nil {
+		return fmt.Errorf("order %s: %w", id, usecase.ErrNotFound)
+	}
+	order.DeletedAt = nil
+	order.UpdatedAt = at
+	order.Version++
+	repo.store.orders[id] = order
+	return nil
+}
+
+func (repo *orderRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	defer repo.store.lock(ctx)()
+
+	var purged int64
+	for id, order := range repo.store.orders {
+		if order.DeletedAt != nil && order.DeletedAt.Before(before) {
+			delete(repo.store.orders, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (repo *orderRepo) UpdateStatus(ctx context.Context, id string, from entity.OrderStatus, change entity.StatusChange) error {
+	defer repo.store.lock(ctx)()
+
+	order, ok := repo.store.orders[id]
+	if !ok || order.Status != from {
+		return usecase.ErrOrderStatusChanged
+	}
+	order = cloneOrder(order)
+	order.Status = change.To
+	order.StatusHistory = append(order.StatusHistory, change)
+	order.UpdatedAt = change.At
+	order.Version++
+	repo.store.orders[id] = order
+	return nil
+}
+
+func (repo *orderRepo) MarkStockReleased(ctx context.Context, id string) (bool, error) {
+	defer repo.store.lock(ctx)()
+
+	order, ok := repo.store.orders[id]
+	if !ok || order.StockReleased {
+		return false, nil
+	}
+	order.StockReleased = true
+	order.Version++
+	repo.store.orders[id] = order
+	return true, nil
+}
+
+func matchOrder(order entity.Order, filter usecase.OrderFilter) bool {
+	switch {
+	case !filter.IncludeDeleted && order.DeletedAt != nil:
+		return false
+	case filter.UserID != "" && order.UserID != filter.UserID:
+		return false
+	case filter.CustomerID != "" && order.CustomerID != filter.CustomerID:
+		return false
+	case filter.Status != "" && order.Status != filter.Status:
+		return false
+	case filter.ProductID != "" && !slices.ContainsFunc(order.Items, func(item entity.OrderItem) bool {
+		return item.ProductID == filter.ProductID
+	}):
+		return false
+	case filter.CreatedFrom != nil && order.CreatedAt.Before(*filter.CreatedFrom):
+		return false
+	case filter.CreatedTo != nil && !order.CreatedAt.Before(*filter.CreatedTo):
+		return false
+	}
+	return true
+}
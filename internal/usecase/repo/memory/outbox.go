@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type outboxRepo struct {
+	store *Store
+}
+
+func NewOutboxRepository(store *Store) usecase.OutboxRepository {
+	return &outboxRepo{store}
+}
+
+func (repo *outboxRepo) Append(ctx context.Context, event *entity.Event) error {
+	defer repo.store.lock(ctx)()
+
+	event.ID = uuid.New().String()
+	repo.store.outbox[event.ID] = *event
+	return nil
+}
+
+func (repo *outboxRepo) Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.Event, error) {
+	defer repo.store.lock(ctx)()
+
+	var due []entity.Event
+	for _, event := range repo.store.outbox {
+		if event.Status == entity.EventStatusPending && !event.NextAttemptAt.After(now) {
+			due = append(due, event)
+		}
+	}
+	due = page(due, usecase.Sort{Field: "created_at"}, nil, limit, usecase.EventSortValue,
+		func(e entity.Event) string { return e.ID })
+	for i := range due {
+		due[i].NextAttemptAt = until
+		repo.store.outbox[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (repo *outboxRepo) UpdateDelivery(ctx context.Context, event *entity.Event) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.outbox[event.ID]
+	if !ok {
+		return fmt.Errorf("event %s: %w", event.ID, usecase.ErrNotFound)
+	}
+	stored.Status = event.Status
+	stored.Attempts = event.Attempts
+	stored.NextAttemptAt = event.NextAttemptAt
+	stored.LastError = event.LastError
+	stored.DeliveredAt = event.DeliveredAt
+	repo.store.outbox[event.ID] = stored
+	return nil
+}
+
+func (repo *outboxRepo) FindByID(ctx context.Context, id string) (*entity.Event, error) {
+	defer repo.store.lock(ctx)()
+
+	event, ok := repo.store.outbox[id]
+	if !ok {
+		return nil, fmt.Errorf("event %s: %w", id, usecase.ErrNotFound)
+	}
+	return &event, nil
+}
+
+func (repo *outboxRepo) FindAll(ctx context.Context, query usecase.EventQuery) ([]entity.Event, error) {
+	defer repo.store.lock(ctx)()
+
+	var events []entity.Event
+	for _, event := range repo.store.outbox {
+		if matchEvent(event, query.EventFilter) {
+			events = append(events, event)
+		}
+	}
+	return page(events, query.Sort, query.After, query.Limit, usecase.EventSortValue,
+		func(e entity.Event) string { return e.ID }), nil
+}
+
+func (repo *outboxRepo) PurgeDelivered(ctx context.Context, before time.Time) (int64, error) {
+	defer repo.store.lock(ctx)()
+
+	var purged int64
+	for id, event := range repo.store.outbox {
+		if event.Status == entity.EventStatusDelivered && event.DeliveredAt.Before(before) {
+			delete(repo.store.outbox, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func matchEvent(event entity.Event, filter usecase.EventFilter) bool {
+	switch {
+	case filter.Status != "" && event.Status != filter.Status:
+		return false
+	case filter.Type != "" && event.Type != filter.Type:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type productRepo struct {
+	store *Store
+}
+
+func NewProductRepository(store *Store) usecase.ProductRepository {
+	return &productRepo{store}
+}
+
+func (repo *productRepo) Create(ctx context.Context, product *entity.Product) (*entity.Product, error) {
+	defer repo.store.lock(ctx)()
+
+	product.ID = uuid.New().String()
+	repo.store.products[product.ID] = *product
+	return product, nil
+}
+
+func (repo *productRepo) FindAll(ctx context.Context, query usecase.ProductQuery) ([]entity.Product, error) {
+	defer repo.store.lock(ctx)()
+
+	var products []entity.Product
+	for _, product := range repo.store.products {
+		if matchProduct(product, query.ProductFilter) {
+			products = append(products, product)
+		}
+	}
+	return page(products, query.Sort, query.After, query.Limit, usecase.ProductSortValue,
+		func(p entity.Product) string { return p.ID }), nil
+}
+
+func (repo *productRepo) FindByID(ctx context.Context, id string) (*entity.Product, error) {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok || product.DeletedAt != nil {
+		return nil, fmt.Errorf("product %s: %w", id, usecase.ErrNotFound)
+	}
+	return &product, nil
+}
+
+func (repo *productRepo) FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Product, error) {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok {
+		return nil, fmt.Errorf("product %s: %w", id, usecase.ErrNotFound)
+	}
+	return &product, nil
+}
+
+func (repo *productRepo) Search(ctx context.Context, query string, limit int) ([]entity.ProductMatch, error) {
+	defer repo.store.lock(ctx)()
+
+	candidates := make([]entity.Product, 0, len(repo.store.products))
+	for _, product := range repo.store.products {
+		if product.DeletedAt == nil {
+			candidates = append(candidates, product)
+		}
+	}
+	// Map iteration order is random; rank ties by creation like the listing
+	slices.SortFunc(candidates, func(a, b entity.Product) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return usecase.RankProducts(query, candidates, limit), nil
+}
+
+func (repo *productRepo) Update(ctx context.Context, id string, product *entity.Product) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.products[id]
+	if !ok || stored.DeletedAt != nil {
+		return fmt.Errorf("product %s: %w", id, usecase.ErrNotFound)
+	}
+	if stored.Version != product.Version {
+		return fmt.Errorf("product %s: %w", id, usecase.ErrVersionConflict)
+	}
+	updated := *product
+	updated.ID = id
+	updated.Version++
+	repo.store.products[id] = updated
+	return nil
+}
+
+func (repo *productRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.products[id]; !ok {
+		return fmt.Errorf("product %s: %w", id, usecase.ErrNotFound)
+	}
+	delete(repo.store.products, id)
+	return nil
+}
+
+func (repo *productRepo) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok || product.DeletedAt != nil {
+		return fmt.Errorf("product %s: %w", id, usecase.ErrNotFound)
+	}
+	product.DeletedAt = &at
+	product.UpdatedAt = at
+	product.Version++
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) Restore(ctx context.Context, id string, at time.Time) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok || product.DeletedAt == nil {
+		return fmt.Errorf("product %s: %w", id, usecase.ErrNotFound)
+	}
+	product.DeletedAt = nil
+	product.UpdatedAt = at
+	product.Version++
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	defer repo.store.lock(ctx)()
+
+	var purged int64
+	for id, product := range repo.store.products {
+		if product.DeletedAt != nil && product.DeletedAt.Before(before) {
+			delete(repo.store.products, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (repo *productRepo) DecrementStock(ctx context.Context, id string, quantity int) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok || product.DeletedAt != nil || product.Stock-product.Reserved < quantity {
+		return usecase.ErrInsufficientStock
+	}
+	product.Stock -= quantity
+	product.Version++
+	product.UpdatedAt = time.Now()
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) IncrementStock(ctx context.Context, id string, quantity int) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok {
+		return nil
+	}
+	product.Stock += quantity
+	product.Version++
+	product.UpdatedAt = time.Now()
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) Reserve(ctx context.Context, id string, quantity int) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok || product.DeletedAt != nil || product.Stock-product.Reserved < quantity {
+		return usecase.ErrInsufficientStock
+	}
+	product.Reserved += quantity
+	product.Version++
+	product.UpdatedAt = time.Now()
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) Unreserve(ctx context.Context, id string, quantity int) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok {
+		return nil
+	}
+	product.Reserved -= quantity
+	product.Version++
+	product.UpdatedAt = time.Now()
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) CommitReserved(ctx context.Context, id string, quantity int) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok || product.Stock < quantity {
+		return usecase.ErrInsufficientStock
+	}
+	product.Stock -= quantity
+	product.Reserved -= quantity
+	product.Version++
+	product.UpdatedAt = time.Now()
+	repo.store.products[id] = product
+	return nil
+}
+
+func (repo *productRepo) SetStock(ctx context.Context, id string, stock int) error {
+	defer repo.store.lock(ctx)()
+
+	product, ok := repo.store.products[id]
+	if !ok {
+		return nil
+	}
+	product.Stock = stock
+	product.Version++
+	product.UpdatedAt = time.Now()
+	repo.store.products[id] = product
+	return nil
+}
+
+func matchProduct(product entity.Product, filter usecase.ProductFilter) bool {
+	switch {
+	case !filter.IncludeDeleted && product.DeletedAt != nil:
+		return false
+	case filter.Category != "" && product.Category != filter.Category:
+		return false
+	case filter.MinPrice != nil && product.Price < *filter.MinPrice:
+		return false
+	case filter.MaxPrice != nil && product.Price > *filter.MaxPrice:
+		return false
+	case filter.InStock && product.Stock-product.Reserved <= 0:
+		return false
+	}
+	return true
+}
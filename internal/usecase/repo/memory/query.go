@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"ulab3/internal/usecase"
+)
+
+// page sorts records the way the database backends do, skips everything up to
+// and including the cursor and returns at most limit records.
+func page[T any](records []T, sort usecase.Sort, after *usecase.Cursor, limit int,
+	value func(T, string) interface{}, id func(T) string) []T {
+	compare := func(aValue interface{}, aID string, bValue interface{}, bID string) int {
+		c := usecase.CompareSortValues(aValue, bValue)
+		if c == 0 {
+			c = strings.Compare(aID, bID)
+		}
+		if sort.Desc {
+			c = -c
+		}
+		return c
+	}
+
+	slices.SortFunc(records, func(a, b T) int {
+		return compare(value(a, sort.Field), id(a), value(b, sort.Field), id(b))
+	})
+	if after != nil {
+		start, _ := slices.BinarySearchFunc(records, after, func(record T, after *usecase.Cursor) int {
+			if compare(value(record, sort.Field), id(record), after.Value, after.ID) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		records = records[start:]
+	}
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type refreshTokenRepo struct {
+	store *Store
+}
+
+func NewRefreshTokenRepository(store *Store) usecase.RefreshTokenRepository {
+	return &refreshTokenRepo{store}
+}
+
+func (repo *refreshTokenRepo) Create(ctx context.Context, token *entity.RefreshToken) error {
+	defer repo.store.lock(ctx)()
+
+	repo.store.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (repo *refreshTokenRepo) FindByID(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	defer repo.store.lock(ctx)()
+
+	token, ok := repo.store.refreshTokens[id]
+	if !ok {
+		return nil, fmt.Errorf("refresh token %s: %w", id, usecase.ErrNotFound)
+	}
+	return &token, nil
+}
+
+func (repo *refreshTokenRepo) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	defer repo.store.lock(ctx)()
+
+	token, ok := repo.store.refreshTokens[id]
+	if !ok || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.RevokedAt = &at
+	repo.store.refreshTokens[id] = token
+	return true, nil
+}
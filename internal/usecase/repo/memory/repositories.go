@@ -0,0 +1,25 @@
+package memory
+
+import "ulab3/internal/usecase"
+
+// NewRepositories wires the in-memory repositories over one shared store.
+func NewRepositories() usecase.Repositories {
+	store := NewStore()
+	return usecase.Repositories{
+		Products:      NewProductRepository(store),
+		Orders:        NewOrderRepository(store),
+		Users:         NewUserRepository(store),
+		Customers:     NewCustomerRepository(store),
+		Carts:         NewCartRepository(store),
+		Reservations:  NewReservationRepository(store),
+		Warehouses:    NewWarehouseRepository(store),
+		StockLevels:   NewStockLevelRepository(store),
+		RefreshTokens: NewRefreshTokenRepository(store),
+		Idempotency:   NewIdempotencyRepository(store),
+		Audit:         NewAuditRepository(store),
+		Outbox:        NewOutboxRepository(store),
+		Webhooks:      NewWebhookRepository(store),
+		Deliveries:    NewWebhookDeliveryRepository(store),
+		Transactor:    store,
+	}
+}
@@ -0,0 +1,44 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+	"ulab3/internal/usecase/repo/repotest"
+)
+
+func TestRepositories(t *testing.T) {
+	repotest.Run(t, func(*testing.T) usecase.Repositories {
+		return memory.NewRepositories()
+	})
+}
+
+func TestOrdersAreCopied(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	order := &entity.Order{Items: []entity.OrderItem{{
+		ProductID:   "p1",
+		Quantity:    3,
+		Allocations: []entity.Allocation{{WarehouseID: "w1", Quantity: 3}},
+	}}}
+	if _, err := repos.Orders.Create(ctx, order); err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	order.Items[0].Allocations[0].Quantity = 1
+
+	found, err := repos.Orders.FindByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("find order: %v", err)
+	}
+	found.Items[0].Allocations[0].WarehouseID = "w2"
+
+	stored, err := repos.Orders.FindByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("find order: %v", err)
+	}
+	if got := stored.Items[0].Allocations[0]; got != (entity.Allocation{WarehouseID: "w1", Quantity: 3}) {
+		t.Errorf("stored allocation changed to %+v through a caller's copy", got)
+	}
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type reservationRepo struct {
+	store *Store
+}
+
+func NewReservationRepository(store *Store) usecase.ReservationRepository {
+	return &reservationRepo{store}
+}
+
+func (repo *reservationRepo) Create(ctx context.Context, reservation *entity.Reservation) (*entity.Reservation, error) {
+	defer repo.store.lock(ctx)()
+
+	reservation.ID = uuid.New().String()
+	repo.store.reservations[reservation.ID] = *reservation
+	return reservation, nil
+}
+
+func (repo *reservationRepo) FindByHolder(ctx context.Context, holderType entity.HolderType, holderID string) ([]entity.Reservation, error) {
+	defer repo.store.lock(ctx)()
+
+	var reservations []entity.Reservation
+	for _, reservation := range repo.store.reservations {
+		if reservation.HolderType == holderType && reservation.HolderID == holderID {
+			reservations = append(reservations, reservation)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b entity.Reservation) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return reservations, nil
+}
+
+func (repo *reservationRepo) FindExpired(ctx context.Context, before time.Time, limit int) ([]entity.Reservation, error) {
+	defer repo.store.lock(ctx)()
+
+	var reservations []entity.Reservation
+	for _, reservation := range repo.store.reservations {
+		if reservation.ExpiresAt.Before(before) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b entity.Reservation) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	if len(reservations) > limit {
+		reservations = reservations[:limit]
+	}
+	return reservations, nil
+}
+
+func (repo *reservationRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.reservations[id]; !ok {
+		return fmt.Errorf("reservation %s: %w", id, usecase.ErrNotFound)
+	}
+	delete(repo.store.reservations, id)
+	return nil
+}
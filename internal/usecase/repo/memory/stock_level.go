@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+// levelKey identifies the stock level of a product in a warehouse.
+type levelKey struct {
+	warehouseID string
+	productID   string
+}
+
+type stockLevelRepo struct {
+	store *Store
+}
+
+func NewStockLevelRepository(store *Store) usecase.StockLevelRepository {
+	return &stockLevelRepo{store}
+}
+
+func (repo *stockLevelRepo) FindByProduct(ctx context.Context, productID string) ([]entity.StockLevel, error) {
+	return repo.find(ctx, func(level entity.StockLevel) bool { return level.ProductID == productID },
+		func(a, b entity.StockLevel) int { return strings.Compare(a.WarehouseID, b.WarehouseID) })
+}
+
+func (repo *stockLevelRepo) FindByWarehouse(ctx context.Context, warehouseID string) ([]entity.StockLevel, error) {
+	return repo.find(ctx, func(level entity.StockLevel) bool { return level.WarehouseID == warehouseID },
+		func(a, b entity.StockLevel) int { return strings.Compare(a.ProductID, b.ProductID) })
+}
+
+func (repo *stockLevelRepo) find(ctx context.Context, match func(entity.StockLevel) bool, order func(a, b entity.StockLevel) int) ([]entity.StockLevel, error) {
+	defer repo.store.lock(ctx)()
+
+	var levels []entity.StockLevel
+	for _, level := range repo.store.stockLevels {
+		if match(level) {
+			levels = append(levels, level)
+		}
+	}
+	slices.SortFunc(levels, order)
+	return levels, nil
+}
+
+func (repo *stockLevelRepo) Set(ctx context.Context, level *entity.StockLevel) error {
+	defer repo.store.lock(ctx)()
+
+	key := levelKey{level.WarehouseID, level.ProductID}
+	stored, ok := repo.store.stockLevels[key]
+	if !ok {
+		stored = entity.StockLevel{WarehouseID: level.WarehouseID, ProductID: level.ProductID}
+	}
+	stored.Stock = level.Stock
+	stored.UpdatedAt = level.UpdatedAt
+	repo.store.stockLevels[key] = stored
+	return nil
+}
+
+func (repo *stockLevelRepo) Delete(ctx context.Context, warehouseID, productID string) error {
+	defer repo.store.lock(ctx)()
+
+	key := levelKey{warehouseID, productID}
+	if _, ok := repo.store.stockLevels[key]; !ok {
+		return fmt.Errorf("stock level of product %s in warehouse %s: %w", productID, warehouseID, usecase.ErrNotFound)
+	}
+	delete(repo.store.stockLevels, key)
+	return nil
+}
+
+func (repo *stockLevelRepo) DecrementStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, warehouseID, productID, usecase.ErrInsufficientStock, func(level *entity.StockLevel) error {
+		if level.Stock-level.Reserved < quantity {
+			return usecase.ErrInsufficientStock
+		}
+		level.Stock -= quantity
+		return nil
+	})
+}
+
+func (repo *stockLevelRepo) IncrementStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, warehouseID, productID, nil, func(level *entity.StockLevel) error {
+		level.Stock += quantity
+		return nil
+	})
+}
+
+func (repo *stockLevelRepo) Reserve(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, warehouseID, productID, usecase.ErrInsufficientStock, func(level *entity.StockLevel) error {
+		if level.Stock-level.Reserved < quantity {
+			return usecase.ErrInsufficientStock
+		}
+		level.Reserved += quantity
+		return nil
+	})
+}
+
+func (repo *stockLevelRepo) Unreserve(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, warehouseID, productID, nil, func(level *entity.StockLevel) error {
+		level.Reserved -= quantity
+		return nil
+	})
+}
+
+func (repo *stockLevelRepo) CommitReserved(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, warehouseID, productID, usecase.ErrInsufficientStock, func(level *entity.StockLevel) error {
+		if level.Stock < quantity {
+			return usecase.ErrInsufficientStock
+		}
+		level.Stock -= quantity
+		level.Reserved -= quantity
+		return nil
+	})
+}
+
+// move applies fn to the stored level and stores the result, returning missing
+// if there is no such level.
+func (repo *stockLevelRepo) move(ctx context.Context, warehouseID, productID string, missing error, fn func(level *entity.StockLevel) error) error {
+	defer repo.store.lock(ctx)()
+
+	key := levelKey{warehouseID, productID}
+	level, ok := repo.store.stockLevels[key]
+	if !ok {
+		return missing
+	}
+	if err := fn(&level); err != nil {
+		return err
+	}
+	level.UpdatedAt = time.Now()
+	repo.store.stockLevels[key] = level
+	return nil
+}
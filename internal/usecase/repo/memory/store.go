@@ -0,0 +1,116 @@
+// Package memory keeps every repository in process memory. It needs no
+// external services, which makes it suitable for tests and local development;
+// all data is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"ulab3/internal/entity"
+)
+
+type txKey struct{}
+
+// Store holds the records of every in-memory repository behind one lock, so
+// that a transaction can span several repositories.
+type Store struct {
+	mu            sync.Mutex
+	products      map[string]entity.Product
+	orders        map[string]entity.Order
+	users         map[string]entity.User
+	customers     map[string]entity.Customer
+	carts         map[string]entity.Cart
+	reservations  map[string]entity.Reservation
+	warehouses    map[string]entity.Warehouse
+	stockLevels   map[levelKey]entity.StockLevel
+	refreshTokens map[string]entity.RefreshToken
+	idempotency   map[string]entity.IdempotencyRecord
+	audit         []entity.AuditEntry
+	outbox        map[string]entity.Event
+	webhooks      map[string]entity.Webhook
+	deliveries    map[string]entity.WebhookDelivery
+}
+
+func NewStore() *Store {
+	return &Store{
+		products:      make(map[string]entity.Product),
+		orders:        make(map[string]entity.Order),
+		users:         make(map[string]entity.User),
+		customers:     make(map[string]entity.Customer),
+		carts:         make(map[string]entity.Cart),
+		reservations:  make(map[string]entity.Reservation),
+		warehouses:    make(map[string]entity.Warehouse),
+		stockLevels:   make(map[levelKey]entity.StockLevel),
+		refreshTokens: make(map[string]entity.RefreshToken),
+		idempotency:   make(map[string]entity.IdempotencyRecord),
+		outbox:        make(map[string]entity.Event),
+		webhooks:      make(map[string]entity.Webhook),
+		deliveries:    make(map[string]entity.WebhookDelivery),
+	}
+}
+
+// WithinTransaction runs fn while holding the store lock. If fn fails, every
+// write it made is rolled back. Nested calls join the running transaction.
+func (s *Store) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == s {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	restore := s.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
+// lock acquires the store lock unless ctx belongs to a transaction that
+// already holds it, and returns the matching unlock function.
+func (s *Store) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) == s {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// snapshot records the current tables and returns a function that puts them
+// back. Stored records are never modified in place, so copying the maps is
+// enough.
+func (s *Store) snapshot() func() {
+	products := maps.Clone(s.products)
+	orders := maps.Clone(s.orders)
+	users := maps.Clone(s.users)
+	customers := maps.Clone(s.customers)
+	carts := maps.Clone(s.carts)
+	reservations := maps.Clone(s.reservations)
+	warehouses := maps.Clone(s.warehouses)
+	stockLevels := maps.Clone(s.stockLevels)
+	refreshTokens := maps.Clone(s.refreshTokens)
+	idempotency := maps.Clone(s.idempotency)
+	outbox := maps.Clone(s.outbox)
+	webhooks := maps.Clone(s.webhooks)
+	deliveries := maps.Clone(s.deliveries)
+	// The audit log only grows, so its length marks the snapshot
+	audit := len(s.audit)
+	return func() {
+		s.products = products
+		s.orders = orders
+		s.users = users
+		s.customers = customers
+		s.carts = carts
+		s.reservations = reservations
+		s.warehouses = warehouses
+		s.stockLevels = stockLevels
+		s.refreshTokens = refreshTokens
+		s.idempotency = idempotency
+		s.audit = s.audit[:audit]
+		s.outbox = outbox
+		s.webhooks = webhooks
+		s.deliveries = deliveries
+	}
+}
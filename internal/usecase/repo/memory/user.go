@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type userRepo struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) usecase.UserRepository {
+	return &userRepo{store}
+}
+
+func (repo *userRepo) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	defer repo.store.lock(ctx)()
+
+	for _, existing := range repo.store.users {
+		if existing.Email == user.Email {
+			return nil, usecase.ErrEmailTaken
+		}
+	}
+	user.ID = uuid.New().String()
+	repo.store.users[user.ID] = *user
+	return user, nil
+}
+
+func (repo *userRepo) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %s: %w", id, usecase.ErrNotFound)
+	}
+	return &user, nil
+}
+
+func (repo *userRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	defer repo.store.lock(ctx)()
+
+	for _, user := range repo.store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s: %w", email, usecase.ErrNotFound)
+}
+
+func (repo *userRepo) UpdateRole(ctx context.Context, id string, role entity.Role, updatedAt time.Time) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[id]
+	if !ok {
+		return nil
+	}
+	user.Role = role
+	user.UpdatedAt = updatedAt
+	repo.store.users[id] = user
+	return nil
+}
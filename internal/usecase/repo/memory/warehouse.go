@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type warehouseRepo struct {
+	store *Store
+}
+
+func NewWarehouseRepository(store *Store) usecase.WarehouseRepository {
+	return &warehouseRepo{store}
+}
+
+func (repo *warehouseRepo) Create(ctx context.Context, warehouse *entity.Warehouse) (*entity.Warehouse, error) {
+	defer repo.store.lock(ctx)()
+
+	warehouse.ID = uuid.New().String()
+	repo.store.warehouses[warehouse.ID] = *warehouse
+	return warehouse, nil
+}
+
+func (repo *warehouseRepo) FindByID(ctx context.Context, id string) (*entity.Warehouse, error) {
+	defer repo.store.lock(ctx)()
+
+	warehouse, ok := repo.store.warehouses[id]
+	if !ok {
+		return nil, fmt.Errorf("warehouse %s: %w", id, usecase.ErrNotFound)
+	}
+	return &warehouse, nil
+}
+
+func (repo *warehouseRepo) FindAll(ctx context.Context) ([]entity.Warehouse, error) {
+	defer repo.store.lock(ctx)()
+
+	var warehouses []entity.Warehouse
+	for _, warehouse := range repo.store.warehouses {
+		warehouses = append(warehouses, warehouse)
+	}
+	slices.SortFunc(warehouses, func(a, b entity.Warehouse) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.ID, b.ID))
+	})
+	return warehouses, nil
+}
+
+func (repo *warehouseRepo) Update(ctx context.Context, warehouse *entity.Warehouse) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.warehouses[warehouse.ID]
+	if !ok {
+		return fmt.Errorf("warehouse %s: %w", warehouse.ID, usecase.ErrNotFound)
+	}
+	stored.Name = warehouse.Name
+	stored.Country = warehouse.Country
+	stored.Latitude = warehouse.Latitude
+	stored.Longitude = warehouse.Longitude
+	stored.Priority = warehouse.Priority
+	stored.UpdatedAt = warehouse.UpdatedAt
+	repo.store.warehouses[warehouse.ID] = stored
+	return nil
+}
+
+func (repo *warehouseRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.warehouses[id]; !ok {
+		return fmt.Errorf("warehouse %s: %w", id, usecase.ErrNotFound)
+	}
+	delete(repo.store.warehouses, id)
+	return nil
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type webhookRepo struct {
+	store *Store
+}
+
+func NewWebhookRepository(store *Store) usecase.WebhookRepository {
+	return &webhookRepo{store}
+}
+
+// cloneWebhook copies the webhook's event types so callers never share memory
+// with the stored record.
+func cloneWebhook(webhook entity.Webhook) entity.Webhook {
+	webhook.EventTypes = slices.Clone(webhook.EventTypes)
+	return webhook
+}
+
+func (repo *webhookRepo) Create(ctx context.Context, webhook *entity.Webhook) (*entity.Webhook, error) {
+	defer repo.store.lock(ctx)()
+
+	webhook.ID = uuid.New().String()
+	repo.store.webhooks[webhook.ID] = cloneWebhook(*webhook)
+	return webhook, nil
+}
+
+func (repo *webhookRepo) FindByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	defer repo.store.lock(ctx)()
+
+	webhook, ok := repo.store.webhooks[id]
+	if !ok {
+		return nil, fmt.Errorf("webhook %s: %w", id, usecase.ErrNotFound)
+	}
+	webhook = cloneWebhook(webhook)
+	return &webhook, nil
+}
+
+func (repo *webhookRepo) FindAll(ctx context.Context, query usecase.WebhookQuery) ([]entity.Webhook, error) {
+	defer repo.store.lock(ctx)()
+
+	var webhooks []entity.Webhook
+	for _, webhook := range repo.store.webhooks {
+		if query.OwnerID == "" || webhook.OwnerID == query.OwnerID {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	return page(webhooks, query.Sort, query.After, query.Limit, usecase.WebhookSortValue,
+		func(w entity.Webhook) string { return w.ID }), nil
+}
+
+func (repo *webhookRepo) FindSubscribed(ctx context.Context, eventType entity.EventType) ([]entity.Webhook, error) {
+	defer repo.store.lock(ctx)()
+
+	var webhooks []entity.Webhook
+	for _, webhook := range repo.store.webhooks {
+		if slices.Contains(webhook.EventTypes, eventType) {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	return webhooks, nil
+}
+
+func (repo *webhookRepo) Update(ctx context.Context, webhook *entity.Webhook) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.webhooks[webhook.ID]
+	if !ok {
+		return fmt.Errorf("webhook %s: %w", webhook.ID, usecase.ErrNotFound)
+	}
+	stored.URL = webhook.URL
+	stored.EventTypes = slices.Clone(webhook.EventTypes)
+	stored.Secret = webhook.Secret
+	stored.UpdatedAt = webhook.UpdatedAt
+	repo.store.webhooks[webhook.ID] = stored
+	return nil
+}
+
+func (repo *webhookRepo) Delete(ctx context.Context, id string) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.webhooks[id]; !ok {
+		return fmt.Errorf("webhook %s: %w", id, usecase.ErrNotFound)
+	}
+	delete(repo.store.webhooks, id)
+	return nil
+}
+
+type deliveryRepo struct {
+	store *Store
+}
+
+func NewWebhookDeliveryRepository(store *Store) usecase.WebhookDeliveryRepository {
+	return &deliveryRepo{store}
+}
+
+// cloneDelivery copies the delivery's attempt log so callers never share
+// memory with the stored record.
+func cloneDelivery(delivery entity.WebhookDelivery) entity.WebhookDelivery {
+	delivery.Attempts = slices.Clone(delivery.Attempts)
+	return delivery
+}
+
+func (repo *deliveryRepo) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	defer repo.store.lock(ctx)()
+
+	for _, stored := range repo.store.deliveries {
+		if stored.WebhookID == delivery.WebhookID && stored.EventID == delivery.EventID {
+			return fmt.Errorf("delivery of event %s to webhook %s: %w", delivery.EventID, delivery.WebhookID, usecase.ErrConflict)
+		}
+	}
+	delivery.ID = uuid.New().String()
+	repo.store.deliveries[delivery.ID] = cloneDelivery(*delivery)
+	return nil
+}
+
+func (repo *deliveryRepo) Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	defer repo.store.lock(ctx)()
+
+	var due []entity.WebhookDelivery
+	for _, delivery := range repo.store.deliveries {
+		if delivery.Status == entity.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, cloneDelivery(delivery))
+		}
+	}
+	due = page(due, usecase.Sort{Field: "created_at"}, nil, limit, usecase.WebhookDeliverySortValue,
+		func(d entity.WebhookDelivery) string { return d.ID })
+	for i := range due {
+		due[i].NextAttemptAt = until
+		repo.store.deliveries[due[i].ID] = cloneDelivery(due[i])
+	}
+	return due, nil
+}
+
+func (repo *deliveryRepo) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	defer repo.store.lock(ctx)()
+
+	stored, ok := repo.store.deliveries[delivery.ID]
+	if !ok {
+		return fmt.Errorf("webhook delivery %s: %w", delivery.ID, usecase.ErrNotFound)
+	}
+	stored.Status = delivery.Status
+	stored.AttemptCount = delivery.AttemptCount
+	stored.Attempts = slices.Clone(delivery.Attempts)
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.DeliveredAt = delivery.DeliveredAt
+	repo.store.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (repo *deliveryRepo) FindByID(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	defer repo.store.lock(ctx)()
+
+	delivery, ok := repo.store.deliveries[id]
+	if !ok {
+		return nil, fmt.Errorf("webhook delivery %s: %w", id, usecase.ErrNotFound)
+	}
+	delivery = cloneDelivery(delivery)
+	return &delivery, nil
+}
+
+func (repo *deliveryRepo) FindAll(ctx context.Context, query usecase.WebhookDeliveryQuery) ([]entity.WebhookDelivery, error) {
+	defer repo.store.lock(ctx)()
+
+	var deliveries []entity.WebhookDelivery
+	for _, delivery := range repo.store.deliveries {
+		if matchDelivery(delivery, query.WebhookDeliveryFilter) {
+			deliveries = append(deliveries, cloneDelivery(delivery))
+		}
+	}
+	return page(deliveries, query.Sort, query.After, query.Limit, usecase.WebhookDeliverySortValue,
+		func(d entity.WebhookDelivery) string { return d.ID }), nil
+}
+
+func (repo *deliveryRepo) DeleteByWebhook(ctx context.Context, webhookID string) error {
+	defer repo.store.lock(ctx)()
+
+	for id, delivery := range repo.store.deliveries {
+		if delivery.WebhookID == webhookID {
+			delete(repo.store.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func matchDelivery(delivery entity.WebhookDelivery, filter usecase.WebhookDeliveryFilter) bool {
+	switch {
+	case filter.WebhookID != "" && delivery.WebhookID != filter.WebhookID:
+		return false
+	case filter.Status != "" && delivery.Status != filter.Status:
+		return false
+	}
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"time"
 	"ulab3/internal/entity"
 	"ulab3/internal/usecase"
 )
@@ -13,6 +14,30 @@ type orderRepo struct {
 	collection *mongo.Collection
 }
 
+// orderDocument is the stored shape of an order. ProductID and Quantity are
+// only present on documents written before orders carried line items.
+type orderDocument struct {
+	entity.Order `bson:",inline"`
+	ProductID    string `bson:"product_id,omitempty"`
+	Quantity     int    `bson:"quantity,omitempty"`
+}
+
+func (doc *orderDocument) toEntity() *entity.Order {
+	order := doc.Order
+	if len(order.Items) == 0 && doc.ProductID != "" {
+		item := entity.OrderItem{
+			ProductID: doc.ProductID,
+			Quantity:  doc.Quantity,
+			LineTotal: order.TotalPrice,
+		}
+		if doc.Quantity > 0 {
+			item.UnitPrice = order.TotalPrice / float64(doc.Quantity)
+		}
+		order.Items = []entity.OrderItem{item}
+	}
+	return &order
+}
+
 func NewOrderRepository(collection *mongo.Collection) usecase.OrderRepository {
 	return &orderRepo{collection}
 }
@@ -26,8 +51,36 @@ func (repo *orderRepo) Create(ctx context.Context, order *entity.Order) (*entity
 	return order, nil
 }
 
-func (repo *orderRepo) FindAll(ctx context.Context) ([]entity.Order, error) {
-	cursor, err := repo.collection.Find(ctx, bson.M{})
+func (repo *orderRepo) FindAll(ctx context.Context, query usecase.OrderQuery) ([]entity.Order, error) {
+	filter := bson.M{}
+	if !query.IncludeDeleted {
+		filter["deleted_at"] = nil
+	}
+	if query.UserID != "" {
+		filter["user_id"] = query.UserID
+	}
+	if query.CustomerID != "" {
+		filter["customer_id"] = query.CustomerID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.ProductID != "" {
+		filter["items.product_id"] = query.ProductID
+	}
+	created := bson.M{}
+	if query.CreatedFrom != nil {
+		created["$gte"] = *query.CreatedFrom
+	}
+	if query.CreatedTo != nil {
+		created["$lt"] = *query.CreatedTo
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
 	if err != nil {
 		return nil, err
 	}
@@ -35,31 +88,138 @@ func (repo *orderRepo) FindAll(ctx context.Context) ([]entity.Order, error) {
 
 	var orders []entity.Order
 	for cursor.Next(ctx) {
-		var order entity.Order
-		if err := cursor.Decode(&order); err != nil {
+		var doc orderDocument
+		if err := cursor.Decode(&doc); err != nil {
 			return nil, err
 		}
-		orders = append(orders, order)
+		orders = append(orders, *doc.toEntity())
 	}
 	return orders, nil
 }
 
 func (repo *orderRepo) FindByID(ctx context.Context, id string) (*entity.Order, error) {
-	var order entity.Order
-	err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&order)
+	return repo.findOne(ctx, bson.M{"id": id, "deleted_at": nil}, id)
+}
+
+func (repo *orderRepo) FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Order, error) {
+	return repo.findOne(ctx, bson.M{"id": id}, id)
+}
+
+func (repo *orderRepo) findOne(ctx context.Context, filter bson.M, id string) (*entity.Order, error) {
+	var doc orderDocument
+	err := repo.collection.FindOne(ctx, filter).Decode(&doc)
 	if err != nil {
-		return nil, err
+		return nil, findError(err, "order", id)
 	}
-	return &order, nil
+	return doc.toEntity(), nil
 }
 
 func (repo *orderRepo) Update(ctx context.Context, id string, order *entity.Order) error {
-	update := bson.M{"$set": order}
-	_, err := repo.collection.UpdateOne(ctx, bson.M{"id": id}, update)
-	return err
+	updated := *order
+	updated.ID = id
+	updated.Version++
+	filter := bson.M{"id": id, "version": order.Version, "deleted_at": nil}
+	result, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$set": &updated})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return versionError(ctx, repo.collection, "order", id)
+	}
+	return nil
 }
 
 func (repo *orderRepo) Delete(ctx context.Context, id string) error {
-	_, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
-	return err
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("order", id)
+	}
+	return nil
+}
+
+func (repo *orderRepo) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	return softDelete(ctx, repo.collection, "order", id, at)
+}
+
+func (repo *orderRepo) Restore(ctx context.Context, id string, at time.Time) error {
+	return restore(ctx, repo.collection, "order", id, at)
+}
+
+func (repo *orderRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, repo.collection, before)
+}
+
+func (repo *orderRepo) UpdateStatus(ctx context.Context, id string, from entity.OrderStatus, change entity.StatusChange) error {
+	filter := bson.M{"id": id, "status": from}
+	update := bson.M{
+		"$set":  bson.M{"status": change.To, "updated_at": change.At},
+		"$push": bson.M{"status_history": change},
+		"$inc":  bson.M{"version": 1},
+	}
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return usecase.ErrOrderStatusChanged
+	}
+	return nil
+}
+
+func (repo *orderRepo) MarkStockReleased(ctx context.Context, id string) (bool, error) {
+	filter := bson.M{"id": id, "stock_released": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"stock_released": true}, "$inc": bson.M{"version": 1}}
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// MigrateVersions gives products and orders stored before records carried a
+// version their first one. It is safe to run on every start.
+func MigrateVersions(ctx context.Context, db *mongo.Database) (int64, error) {
+	var migrated int64
+	for _, collection := range []string{"products", "orders"} {
+		filter := bson.M{"version": bson.M{"$exists": false}}
+		result, err := db.Collection(collection).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"version": 1}})
+		if err != nil {
+			return migrated, err
+		}
+		migrated += result.ModifiedCount
+	}
+	return migrated, nil
+}
+
+// MigrateLegacyOrders rewrites single-product order documents into the line
+// item shape, deriving the unit price from the stored total. It is safe to run
+// on every start: documents that already have items are left untouched.
+func MigrateLegacyOrders(ctx context.Context, collection *mongo.Collection) (int64, error) {
+	filter := bson.M{
+		"product_id": bson.M{"$exists": true},
+		"items":      bson.M{"$exists": false},
+	}
+	unitPrice := bson.M{"$cond": bson.A{
+		bson.M{"$gt": bson.A{"$quantity", 0}},
+		bson.M{"$divide": bson.A{"$total_price", "$quantity"}},
+		0,
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"items": bson.A{bson.M{
+			"product_id": "$product_id",
+			"quantity":   "$quantity",
+			"unit_price": unitPrice,
+			"line_total": "$total_price",
+		}}}}},
+		{{Key: "$unset", Value: bson.A{"product_id", "quantity"}}},
+	}
+
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
 }
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type outboxRepo struct {
+	collection *mongo.Collection
+}
+
+func NewOutboxRepository(collection *mongo.Collection) usecase.OutboxRepository {
+	return &outboxRepo{collection}
+}
+
+func (repo *outboxRepo) Append(ctx context.Context, event *entity.Event) error {
+	event.ID = uuid.New().String()
+	_, err := repo.collection.InsertOne(ctx, event)
+	return err
+}
+
+// Claim leases due events one at a time, so that concurrent dispatchers never
+// claim the same event.
+func (repo *outboxRepo) Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.Event, error) {
+	filter := bson.M{"status": entity.EventStatusPending, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": until}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var events []entity.Event
+	for len(events) < limit {
+		var event entity.Event
+		err := repo.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (repo *outboxRepo) UpdateDelivery(ctx context.Context, event *entity.Event) error {
+	update := bson.M{"$set": bson.M{
+		"status":          event.Status,
+		"attempts":        event.Attempts,
+		"next_attempt_at": event.NextAttemptAt,
+		"last_error":      event.LastError,
+		"delivered_at":    event.DeliveredAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"id": event.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("event", event.ID)
+	}
+	return nil
+}
+
+func (repo *outboxRepo) FindByID(ctx context.Context, id string) (*entity.Event, error) {
+	var event entity.Event
+	if err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&event); err != nil {
+		return nil, findError(err, "event", id)
+	}
+	return &event, nil
+}
+
+func (repo *outboxRepo) FindAll(ctx context.Context, query usecase.EventQuery) ([]entity.Event, error) {
+	filter := bson.M{}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.Type != "" {
+		filter["type"] = query.Type
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []entity.Event
+	for cursor.Next(ctx) {
+		var event entity.Event
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (repo *outboxRepo) PurgeDelivered(ctx context.Context, before time.Time) (int64, error) {
+	filter := bson.M{"status": entity.EventStatusDelivered, "delivered_at": bson.M{"$lt": before}}
+	result, err := repo.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+type outboxFeed struct {
+	collection *mongo.Collection
+}
+
+// NewOutboxFeed follows the outbox through a change stream, which needs a
+// replica set or sharded cluster.
+func NewOutboxFeed(collection *mongo.Collection) usecase.EventFeed {
+	return &outboxFeed{collection}
+}
+
+// Watch only sees inserts, which the server reports once their transaction
+// commits.
+func (feed *outboxFeed) Watch(ctx context.Context, fn func(event entity.Event)) error {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	stream, err := feed.collection.Watch(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change struct {
+			Event entity.Event `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		fn(change.Event)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const auditColumns = `id, actor_id, actor_role, action, entity_type, entity_id, changes, request_id, created_at`
+
+var auditSortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+type auditRepo struct {
+	db *sqlx.DB
+}
+
+// auditRow is the stored shape of an audit entry; the changes live in a JSONB
+// column.
+type auditRow struct {
+	entity.AuditEntry
+	Changes jsonColumn[map[string]entity.FieldChange] `db:"changes"`
+}
+
+func (row *auditRow) toEntity() entity.AuditEntry {
+	entry := row.AuditEntry
+	entry.Changes = row.Changes.V
+	return entry
+}
+
+func NewAuditRepository(db *sqlx.DB) usecase.AuditRepository {
+	return &auditRepo{db}
+}
+
+func (repo *auditRepo) Append(ctx context.Context, entry *entity.AuditEntry) error {
+	entry.ID = uuid.New().String()
+	row := auditRow{AuditEntry: *entry}
+	row.Changes.V = entry.Changes
+	if row.Changes.V == nil {
+		row.Changes.V = map[string]entity.FieldChange{}
+	}
+	query := `INSERT INTO audit_log (` + auditColumns + `)
+		VALUES (:id, :actor_id, :actor_role, :action, :entity_type, :entity_id, :changes, :request_id, :created_at)`
+	_, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, row)
+	return err
+}
+
+func (repo *auditRepo) FindAll(ctx context.Context, query usecase.AuditQuery) ([]entity.AuditEntry, error) {
+	var where whereClause
+	if query.EntityType != "" {
+		where.add("entity_type = ?", query.EntityType)
+	}
+	if query.EntityID != "" {
+		where.add("entity_id = ?", query.EntityID)
+	}
+	if query.ActorID != "" {
+		where.add("actor_id = ?", query.ActorID)
+	}
+	if query.From != nil {
+		where.add("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		where.add("created_at < ?", *query.To)
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, auditSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []auditRow
+	statement := repo.db.Rebind(`SELECT ` + auditColumns + ` FROM audit_log` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, statement, where.args...); err != nil {
+		return nil, err
+	}
+
+	var entries []entity.AuditEntry
+	for i := range rows {
+		entries = append(entries, rows[i].toEntity())
+	}
+	return entries, nil
+}
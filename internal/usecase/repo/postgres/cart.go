@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const cartColumns = `id, user_id, items, created_at, updated_at, expires_at`
+
+type cartRepo struct {
+	db *sqlx.DB
+}
+
+// cartRow is the stored shape of a cart; the lines live in a JSONB column.
+type cartRow struct {
+	entity.Cart
+	Items jsonColumn[[]entity.CartItem] `db:"items"`
+}
+
+func newCartRow(cart *entity.Cart) cartRow {
+	row := cartRow{Cart: *cart}
+	row.Items.V = cart.Items
+	if row.Items.V == nil {
+		row.Items.V = []entity.CartItem{}
+	}
+	return row
+}
+
+func NewCartRepository(db *sqlx.DB) usecase.CartRepository {
+	return &cartRepo{db}
+}
+
+func (repo *cartRepo) Create(ctx context.Context, cart *entity.Cart) (*entity.Cart, error) {
+	cart.ID = uuid.New().String()
+	query := `INSERT INTO carts (` + cartColumns + `)
+		VALUES (:id, :user_id, :items, :created_at, :updated_at, :expires_at)`
+	if _, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, newCartRow(cart)); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (repo *cartRepo) FindByID(ctx context.Context, id string) (*entity.Cart, error) {
+	var row cartRow
+	query := `SELECT ` + cartColumns + ` FROM carts WHERE id = $1 AND expires_at > NOW()`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &row, query, id); err != nil {
+		return nil, findError(err, "cart", id)
+	}
+	cart := row.Cart
+	cart.Items = row.Items.V
+	return &cart, nil
+}
+
+func (repo *cartRepo) Update(ctx context.Context, cart *entity.Cart) error {
+	row := newCartRow(cart)
+	query := `UPDATE carts SET items = $2, updated_at = $3, expires_at = $4
+		WHERE id = $1 AND expires_at > NOW()`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, row.ID, row.Items, row.UpdatedAt, row.ExpiresAt)
+	return affectedOne(result, err, "cart", cart.ID)
+}
+
+func (repo *cartRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM carts WHERE id = $1 AND expires_at > NOW()`, id)
+	return affectedOne(result, err, "cart", id)
+}
+
+func (repo *cartRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM carts WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
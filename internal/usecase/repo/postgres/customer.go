@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const customerColumns = `id, user_id, name, email, phone, addresses, status, created_at, updated_at`
+
+var customerSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+}
+
+type customerRepo struct {
+	db *sqlx.DB
+}
+
+// customerRow is the stored shape of a customer; the addresses live in a JSONB
+// column.
+type customerRow struct {
+	entity.Customer
+	Addresses jsonColumn[[]entity.Address] `db:"addresses"`
+}
+
+func newCustomerRow(customer *entity.Customer) customerRow {
+	row := customerRow{Customer: *customer}
+	row.Addresses.V = customer.Addresses
+	if row.Addresses.V == nil {
+		row.Addresses.V = []entity.Address{}
+	}
+	return row
+}
+
+func (row *customerRow) toEntity() entity.Customer {
+	customer := row.Customer
+	customer.Addresses = row.Addresses.V
+	return customer
+}
+
+func NewCustomerRepository(db *sqlx.DB) usecase.CustomerRepository {
+	return &customerRepo{db}
+}
+
+func (repo *customerRepo) Create(ctx context.Context, customer *entity.Customer) (*entity.Customer, error) {
+	customer.ID = uuid.New().String()
+	query := `INSERT INTO customers (` + customerColumns + `)
+		VALUES (:id, :user_id, :name, :email, :phone, :addresses, :status, :created_at, :updated_at)`
+	if _, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, newCustomerRow(customer)); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+func (repo *customerRepo) FindByID(ctx context.Context, id string) (*entity.Customer, error) {
+	var row customerRow
+	query := `SELECT ` + customerColumns + ` FROM customers WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &row, query, id); err != nil {
+		return nil, findError(err, "customer", id)
+	}
+	customer := row.toEntity()
+	return &customer, nil
+}
+
+func (repo *customerRepo) FindAll(ctx context.Context, query usecase.CustomerQuery) ([]entity.Customer, error) {
+	var where whereClause
+	if query.UserID != "" {
+		where.add("user_id = ?", query.UserID)
+	}
+	if query.Status != "" {
+		where.add("status = ?", query.Status)
+	}
+	if query.Email != "" {
+		where.add("email = ?", query.Email)
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, customerSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []customerRow
+	statement := repo.db.Rebind(`SELECT ` + customerColumns + ` FROM customers` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, statement, where.args...); err != nil {
+		return nil, err
+	}
+
+	var customers []entity.Customer
+	for i := range rows {
+		customers = append(customers, rows[i].toEntity())
+	}
+	return customers, nil
+}
+
+func (repo *customerRepo) Update(ctx context.Context, customer *entity.Customer) error {
+	row := newCustomerRow(customer)
+	query := `UPDATE customers
+		SET name = $2, email = $3, phone = $4, addresses = $5, status = $6, updated_at = $7
+		WHERE id = $1`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, row.ID, row.Name, row.Email, row.Phone,
+		row.Addresses, row.Status, row.UpdatedAt)
+	return affectedOne(result, err, "customer", customer.ID)
+}
+
+func (repo *customerRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM customers WHERE id = $1`, id)
+	return affectedOne(result, err, "customer", id)
+}
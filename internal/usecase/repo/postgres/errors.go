@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/usecase"
+)
+
+// findError translates sql.ErrNoRows into usecase.ErrNotFound and passes
+// every other error through.
+func findError(err error, kind, key string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound(kind, key)
+	}
+	return err
+}
+
+// notFound reports that no record of the kind matches key.
+func notFound(kind, key string) error {
+	return fmt.Errorf("%s %s: %w", kind, key, usecase.ErrNotFound)
+}
+
+// affectedOne returns usecase.ErrNotFound when an update or delete changed no
+// rows.
+func affectedOne(result sql.Result, err error, kind, key string) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound(kind, key)
+	}
+	return nil
+}
+
+// versionedOne explains a versioned update that changed no rows: either no
+// live row of the table has the ID, or another write moved its version on.
+func versionedOne(ctx context.Context, db sqlx.QueryerContext, result sql.Result, err error, table, kind, id string) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1 AND deleted_at IS NULL)`
+	if err := sqlx.GetContext(ctx, db, &exists, query, id); err != nil {
+		return err
+	}
+	if !exists {
+		return notFound(kind, id)
+	}
+	return fmt.Errorf("%s %s: %w", kind, id, usecase.ErrVersionConflict)
+}
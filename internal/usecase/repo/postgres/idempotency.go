@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const idempotencyColumns = `id, fingerprint, status, content_type, body, created_at, expires_at`
+
+type idempotencyRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) usecase.IdempotencyRepository {
+	return &idempotencyRepo{db}
+}
+
+func (repo *idempotencyRepo) Create(ctx context.Context, record *entity.IdempotencyRecord) error {
+	// An expired record gives way to the new one
+	query := `INSERT INTO idempotency_keys (` + idempotencyColumns + `)
+		VALUES (:id, :fingerprint, :status, :content_type, :body, :created_at, :expires_at)
+		ON CONFLICT (id) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, status = EXCLUDED.status,
+			content_type = EXCLUDED.content_type, body = EXCLUDED.body,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()`
+	result, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, record)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("idempotency key %s: %w", record.Key, usecase.ErrConflict)
+	}
+	return nil
+}
+
+func (repo *idempotencyRepo) FindByKey(ctx context.Context, key string) (*entity.IdempotencyRecord, error) {
+	var record entity.IdempotencyRecord
+	query := `SELECT ` + idempotencyColumns + ` FROM idempotency_keys WHERE id = $1 AND expires_at > NOW()`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &record, query, key); err != nil {
+		return nil, findError(err, "idempotency key", key)
+	}
+	return &record, nil
+}
+
+func (repo *idempotencyRepo) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	query := `UPDATE idempotency_keys SET status = $2, content_type = $3, body = $4, expires_at = $5 WHERE id = $1`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, record.Key, record.Status, record.ContentType, record.Body, record.ExpiresAt)
+	return affectedOne(result, err, "idempotency key", record.Key)
+}
+
+func (repo *idempotencyRepo) Delete(ctx context.Context, key string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE id = $1`, key)
+	return affectedOne(result, err, "idempotency key", key)
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const orderColumns = `id, user_id, customer_id, items, total_price, status, status_history, stock_released, stock_held, version, created_at, updated_at, deleted_at`
+
+var orderSortColumns = map[string]string{
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+	"total_price": "total_price",
+}
+
+type orderRepo struct {
+	db *sqlx.DB
+}
+
+// orderRow is the stored shape of an order; line items and status history
+// live in JSONB columns.
+type orderRow struct {
+	entity.Order
+	Items         jsonColumn[[]entity.OrderItem]    `db:"items"`
+	StatusHistory jsonColumn[[]entity.StatusChange] `db:"status_history"`
+}
+
+func newOrderRow(order *entity.Order) orderRow {
+	row := orderRow{Order: *order}
+	row.Items.V = order.Items
+	if row.Items.V == nil {
+		row.Items.V = []entity.OrderItem{}
+	}
+	row.StatusHistory.V = order.StatusHistory
+	if row.StatusHistory.V == nil {
+		row.StatusHistory.V = []entity.StatusChange{}
+	}
+	return row
+}
+
+func (row *orderRow) toEntity() *entity.Order {
+	order := row.Order
+	order.Items = row.Items.V
+	order.StatusHistory = row.StatusHistory.V
+	return &order
+}
+
+func NewOrderRepository(db *sqlx.DB) usecase.OrderRepository {
+	return &orderRepo{db}
+}
+
+func (repo *orderRepo) Create(ctx context.Context, order *entity.Order) (*entity.Order, error) {
+	order.ID = uuid.New().String()
+	query := `INSERT INTO orders (` + orderColumns + `)
+		VALUES (:id, :user_id, :customer_id, :items, :total_price, :status, :status_history, :stock_released, :stock_held, :version, :created_at, :updated_at, :deleted_at)`
+	_, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, newOrderRow(order))
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (repo *orderRepo) FindAll(ctx context.Context, query usecase.OrderQuery) ([]entity.Order, error) {
+	var where whereClause
+	if !query.IncludeDeleted {
+		where.add("deleted_at IS NULL")
+	}
+	if query.UserID != "" {
+		where.add("user_id = ?", query.UserID)
+	}
+	if query.CustomerID != "" {
+		where.add("customer_id = ?", query.CustomerID)
+	}
+	if query.Status != "" {
+		where.add("status = ?", query.Status)
+	}
+	if query.ProductID != "" {
+		contains := jsonColumn[[]map[string]string]{V: []map[string]string{{"product_id": query.ProductID}}}
+		where.add("items @> ?::jsonb", contains)
+	}
+	if query.CreatedFrom != nil {
+		where.add("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		where.add("created_at < ?", *query.CreatedTo)
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, orderSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []orderRow
+	statement := repo.db.Rebind(`SELECT ` + orderColumns + ` FROM orders` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, statement, where.args...); err != nil {
+		return nil, err
+	}
+
+	var orders []entity.Order
+	for i := range rows {
+		orders = append(orders, *rows[i].toEntity())
+	}
+	return orders, nil
+}
+
+func (repo *orderRepo) FindByID(ctx context.Context, id string) (*entity.Order, error) {
+	return repo.findOne(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+func (repo *orderRepo) FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Order, error) {
+	return repo.findOne(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id)
+}
+
+func (repo *orderRepo) findOne(ctx context.Context, query, id string) (*entity.Order, error) {
+	var row orderRow
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &row, query, id); err != nil {
+		return nil, findError(err, "order", id)
+	}
+	return row.toEntity(), nil
+}
+
+func (repo *orderRepo) Update(ctx context.Context, id string, order *entity.Order) error {
+	row := newOrderRow(order)
+	query := `UPDATE orders
+		SET items = $2, total_price = $3, status = $4, status_history = $5,
+			stock_released = $6, stock_held = $7, created_at = $8, updated_at = $9, version = version + 1
+		WHERE id = $1 AND version = $10 AND deleted_at IS NULL`
+	db := conn(ctx, repo.db)
+	result, err := db.ExecContext(ctx, query, id, row.Items, row.TotalPrice, row.Status,
+		row.StatusHistory, row.StockReleased, row.StockHeld, row.CreatedAt, row.UpdatedAt, row.Version)
+	return versionedOne(ctx, db, result, err, "orders", "order", id)
+}
+
+func (repo *orderRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, id)
+	return affectedOne(result, err, "order", id)
+}
+
+func (repo *orderRepo) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	return softDelete(ctx, conn(ctx, repo.db), "orders", "order", id, at)
+}
+
+func (repo *orderRepo) Restore(ctx context.Context, id string, at time.Time) error {
+	return restore(ctx, conn(ctx, repo.db), "orders", "order", id, at)
+}
+
+func (repo *orderRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, conn(ctx, repo.db), "orders", before)
+}
+
+func (repo *orderRepo) UpdateStatus(ctx context.Context, id string, from entity.OrderStatus, change entity.StatusChange) error {
+	query := `UPDATE orders
+		SET status = $3, status_history = status_history || $4::jsonb, updated_at = $5,
+			version = version + 1
+		WHERE id = $1 AND status = $2`
+	history := jsonColumn[[]entity.StatusChange]{V: []entity.StatusChange{change}}
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, id, from, change.To, history, change.At)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return usecase.ErrOrderStatusChanged
+	}
+	return nil
+}
+
+func (repo *orderRepo) MarkStockReleased(ctx context.Context, id string) (bool, error) {
+	query := `UPDATE orders SET stock_released = TRUE, version = version + 1
+		WHERE id = $1 AND NOT stock_released`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"slices"
+	"strings"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const eventColumns = `id, type, aggregate_type, aggregate_id, payload, request_id, created_at,
+	status, attempts, next_attempt_at, last_error, delivered_at`
+
+var eventSortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+type outboxRepo struct {
+	db *sqlx.DB
+}
+
+// eventRow is the stored shape of an event; the payload lives in a JSONB
+// column.
+type eventRow struct {
+	entity.Event
+	Payload jsonColumn[json.RawMessage] `db:"payload"`
+}
+
+func (row *eventRow) toEntity() entity.Event {
+	event := row.Event
+	event.Payload = row.Payload.V
+	return event
+}
+
+func NewOutboxRepository(db *sqlx.DB) usecase.OutboxRepository {
+	return &outboxRepo{db}
+}
+
+func (repo *outboxRepo) Append(ctx context.Context, event *entity.Event) error {
+	event.ID = uuid.New().String()
+	row := eventRow{Event: *event}
+	row.Payload.V = event.Payload
+	query := `INSERT INTO outbox (` + eventColumns + `)
+		VALUES (:id, :type, :aggregate_type, :aggregate_id, :payload, :request_id, :created_at,
+			:status, :attempts, :next_attempt_at, :last_error, :delivered_at)`
+	_, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, row)
+	return err
+}
+
+// Claim skips rows another dispatcher has locked, so that concurrent
+// dispatchers never claim the same event.
+func (repo *outboxRepo) Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.Event, error) {
+	query := `UPDATE outbox SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY created_at, id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + eventColumns
+	var rows []eventRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, query, now, until, entity.EventStatusPending, limit); err != nil {
+		return nil, err
+	}
+
+	events := eventsFromRows(rows)
+	// RETURNING keeps no order
+	slices.SortFunc(events, func(a, b entity.Event) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return events, nil
+}
+
+func (repo *outboxRepo) UpdateDelivery(ctx context.Context, event *entity.Event) error {
+	query := `UPDATE outbox
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6
+		WHERE id = $1`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, event.ID, event.Status, event.Attempts,
+		event.NextAttemptAt, event.LastError, event.DeliveredAt)
+	return affectedOne(result, err, "event", event.ID)
+}
+
+func (repo *outboxRepo) FindByID(ctx context.Context, id string) (*entity.Event, error) {
+	var row eventRow
+	query := `SELECT ` + eventColumns + ` FROM outbox WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &row, query, id); err != nil {
+		return nil, findError(err, "event", id)
+	}
+	event := row.toEntity()
+	return &event, nil
+}
+
+func (repo *outboxRepo) FindAll(ctx context.Context, query usecase.EventQuery) ([]entity.Event, error) {
+	var where whereClause
+	if query.Status != "" {
+		where.add("status = ?", query.Status)
+	}
+	if query.Type != "" {
+		where.add("type = ?", query.Type)
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, eventSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []eventRow
+	statement := repo.db.Rebind(`SELECT ` + eventColumns + ` FROM outbox` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, statement, where.args...); err != nil {
+		return nil, err
+	}
+	return eventsFromRows(rows), nil
+}
+
+func (repo *outboxRepo) PurgeDelivered(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE status = $1 AND delivered_at < $2`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, entity.EventStatusDelivered, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func eventsFromRows(rows []eventRow) []entity.Event {
+	var events []entity.Event
+	for i := range rows {
+		events = append(events, rows[i].toEntity())
+	}
+	return events
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/pkg/search"
+)
+
+const productColumns = `id, name, price, stock, reserved, category, version, created_at, updated_at, deleted_at`
+
+var productSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+	"price":      "price",
+	"stock":      "stock",
+}
+
+type productRepo struct {
+	db *sqlx.DB
+}
+
+func NewProductRepository(db *sqlx.DB) usecase.ProductRepository {
+	return &productRepo{db}
+}
+
+func (repo *productRepo) Create(ctx context.Context, product *entity.Product) (*entity.Product, error) {
+	product.ID = uuid.New().String()
+	query := `INSERT INTO products (` + productColumns + `)
+		VALUES (:id, :name, :price, :stock, :reserved, :category, :version, :created_at, :updated_at, :deleted_at)`
+	_, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, product)
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (repo *productRepo) FindAll(ctx context.Context, query usecase.ProductQuery) ([]entity.Product, error) {
+	var where whereClause
+	if !query.IncludeDeleted {
+		where.add("deleted_at IS NULL")
+	}
+	if query.Category != "" {
+		where.add("category = ?", query.Category)
+	}
+	if query.MinPrice != nil {
+		where.add("price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		where.add("price <= ?", *query.MaxPrice)
+	}
+	if query.InStock {
+		where.add("stock - reserved > 0")
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, productSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []entity.Product
+	statement := repo.db.Rebind(`SELECT ` + productColumns + ` FROM products` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &products, statement, where.args...); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (repo *productRepo) FindByID(ctx context.Context, id string) (*entity.Product, error) {
+	return repo.findOne(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+func (repo *productRepo) FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Product, error) {
+	return repo.findOne(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, id)
+}
+
+func (repo *productRepo) findOne(ctx context.Context, query, id string) (*entity.Product, error) {
+	var product entity.Product
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &product, query, id); err != nil {
+		return nil, findError(err, "product", id)
+	}
+	return &product, nil
+}
+
+// searchCandidateLimit caps how many products a search ranks in process.
+const searchCandidateLimit = 500
+
+func (repo *productRepo) Search(ctx context.Context, query string, limit int) ([]entity.ProductMatch, error) {
+	// Narrow the candidates with trigram-indexed substring matches on the
+	// first letters of each word, so misspellings later in a word still match
+	var patterns []string
+	for _, prefix := range search.Prefixes(search.Tokenize(query), 2) {
+		patterns = append(patterns, "%"+prefix+"%")
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var candidates []entity.Product
+	statement := `SELECT ` + productColumns + ` FROM products
+		WHERE (name ILIKE ANY($1) OR category ILIKE ANY($1)) AND deleted_at IS NULL
+		ORDER BY created_at, id LIMIT $2`
+	err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &candidates, statement, pq.Array(patterns), searchCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	return usecase.RankProducts(query, candidates, limit), nil
+}
+
+func (repo *productRepo) Update(ctx context.Context, id string, product *entity.Product) error {
+	query := `UPDATE products
+		SET name = $2, price = $3, stock = $4, category = $5, created_at = $6, updated_at = $7,
+			version = version + 1
+		WHERE id = $1 AND version = $8 AND deleted_at IS NULL`
+	db := conn(ctx, repo.db)
+	result, err := db.ExecContext(ctx, query, id, product.Name, product.Price, product.Stock,
+		product.Category, product.CreatedAt, product.UpdatedAt, product.Version)
+	return versionedOne(ctx, db, result, err, "products", "product", id)
+}
+
+func (repo *productRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM products WHERE id = $1`, id)
+	return affectedOne(result, err, "product", id)
+}
+
+func (repo *productRepo) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	return softDelete(ctx, conn(ctx, repo.db), "products", "product", id, at)
+}
+
+func (repo *productRepo) Restore(ctx context.Context, id string, at time.Time) error {
+	return restore(ctx, conn(ctx, repo.db), "products", "product", id, at)
+}
+
+func (repo *productRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, conn(ctx, repo.db), "products", before)
+}
+
+func (repo *productRepo) DecrementStock(ctx context.Context, id string, quantity int) error {
+	return repo.moveStock(ctx, `UPDATE products SET stock = stock - $2, updated_at = $3, version = version + 1
+		WHERE id = $1 AND stock - reserved >= $2 AND deleted_at IS NULL`, id, quantity)
+}
+
+func (repo *productRepo) IncrementStock(ctx context.Context, id string, quantity int) error {
+	query := `UPDATE products SET stock = stock + $2, updated_at = $3, version = version + 1 WHERE id = $1`
+	_, err := conn(ctx, repo.db).ExecContext(ctx, query, id, quantity, time.Now())
+	return err
+}
+
+func (repo *productRepo) Reserve(ctx context.Context, id string, quantity int) error {
+	return repo.moveStock(ctx, `UPDATE products SET reserved = reserved + $2, updated_at = $3, version = version + 1
+		WHERE id = $1 AND stock - reserved >= $2 AND deleted_at IS NULL`, id, quantity)
+}
+
+func (repo *productRepo) Unreserve(ctx context.Context, id string, quantity int) error {
+	query := `UPDATE products SET reserved = reserved - $2, updated_at = $3, version = version + 1 WHERE id = $1`
+	_, err := conn(ctx, repo.db).ExecContext(ctx, query, id, quantity, time.Now())
+	return err
+}
+
+func (repo *productRepo) CommitReserved(ctx context.Context, id string, quantity int) error {
+	return repo.moveStock(ctx, `UPDATE products SET stock = stock - $2, reserved = reserved - $2, updated_at = $3,
+		version = version + 1 WHERE id = $1 AND stock >= $2`, id, quantity)
+}
+
+func (repo *productRepo) SetStock(ctx context.Context, id string, stock int) error {
+	query := `UPDATE products SET stock = $2, updated_at = $3, version = version + 1 WHERE id = $1`
+	_, err := conn(ctx, repo.db).ExecContext(ctx, query, id, stock, time.Now())
+	return err
+}
+
+// moveStock runs a conditional stock update taking the product ID, the
+// quantity and the update time, returning ErrInsufficientStock if its
+// condition does not hold.
+func (repo *productRepo) moveStock(ctx context.Context, query, id string, quantity int) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, id, quantity, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return usecase.ErrInsufficientStock
+	}
+	return nil
+}
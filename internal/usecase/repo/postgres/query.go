@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"ulab3/internal/usecase"
+)
+
+// whereClause collects SQL conditions written with ? placeholders together
+// with their arguments. Queries are rebound to $n placeholders before use.
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// keyset adds the condition that starts a page strictly after the cursor and
+// returns the matching ORDER BY and LIMIT. columns maps sort fields to the
+// columns allowed in the query.
+func (w *whereClause) keyset(sort usecase.Sort, after *usecase.Cursor, limit int, columns map[string]string) (string, error) {
+	column, ok := columns[sort.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: cannot sort by %q", usecase.ErrInvalidQuery, sort.Field)
+	}
+	op, direction := ">", "ASC"
+	if sort.Desc {
+		op, direction = "<", "DESC"
+	}
+	if after != nil {
+		w.add(fmt.Sprintf("(%s, id) %s (?, ?)", column, op), after.Value, after.ID)
+	}
+	w.args = append(w.args, limit)
+	return fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, direction, direction), nil
+}
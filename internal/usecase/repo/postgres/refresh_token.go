@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const refreshTokenColumns = `id, user_id, expires_at, revoked_at, created_at`
+
+type refreshTokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewRefreshTokenRepository(db *sqlx.DB) usecase.RefreshTokenRepository {
+	return &refreshTokenRepo{db}
+}
+
+func (repo *refreshTokenRepo) Create(ctx context.Context, token *entity.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (` + refreshTokenColumns + `)
+		VALUES (:id, :user_id, :expires_at, :revoked_at, :created_at)`
+	_, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, token)
+	return err
+}
+
+func (repo *refreshTokenRepo) FindByID(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &token, query, id); err != nil {
+		return nil, findError(err, "refresh token", id)
+	}
+	return &token, nil
+}
+
+func (repo *refreshTokenRepo) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, id, at)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/usecase"
+)
+
+// NewRepositories wires the PostgreSQL repositories over the given database.
+func NewRepositories(db *sqlx.DB) usecase.Repositories {
+	return usecase.Repositories{
+		Products:      NewProductRepository(db),
+		Orders:        NewOrderRepository(db),
+		Users:         NewUserRepository(db),
+		Customers:     NewCustomerRepository(db),
+		Carts:         NewCartRepository(db),
+		Reservations:  NewReservationRepository(db),
+		Warehouses:    NewWarehouseRepository(db),
+		StockLevels:   NewStockLevelRepository(db),
+		RefreshTokens: NewRefreshTokenRepository(db),
+		Idempotency:   NewIdempotencyRepository(db),
+		Audit:         NewAuditRepository(db),
+		Outbox:        NewOutboxRepository(db),
+		Webhooks:      NewWebhookRepository(db),
+		Deliveries:    NewWebhookDeliveryRepository(db),
+		Transactor:    NewTransactor(db),
+	}
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const reservationColumns = `id, product_id, quantity, holder_type, holder_id, warehouse_id, created_at, expires_at`
+
+type reservationRepo struct {
+	db *sqlx.DB
+}
+
+func NewReservationRepository(db *sqlx.DB) usecase.ReservationRepository {
+	return &reservationRepo{db}
+}
+
+func (repo *reservationRepo) Create(ctx context.Context, reservation *entity.Reservation) (*entity.Reservation, error) {
+	reservation.ID = uuid.New().String()
+	query := `INSERT INTO reservations (` + reservationColumns + `)
+		VALUES (:id, :product_id, :quantity, :holder_type, :holder_id, :warehouse_id, :created_at, :expires_at)`
+	if _, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, reservation); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+func (repo *reservationRepo) FindByHolder(ctx context.Context, holderType entity.HolderType, holderID string) ([]entity.Reservation, error) {
+	var reservations []entity.Reservation
+	query := `SELECT ` + reservationColumns + ` FROM reservations
+		WHERE holder_type = $1 AND holder_id = $2 ORDER BY created_at, id`
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &reservations, query, holderType, holderID); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (repo *reservationRepo) FindExpired(ctx context.Context, before time.Time, limit int) ([]entity.Reservation, error) {
+	var reservations []entity.Reservation
+	query := `SELECT ` + reservationColumns + ` FROM reservations
+		WHERE expires_at < $1 ORDER BY expires_at, id LIMIT $2`
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &reservations, query, before, limit); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (repo *reservationRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM reservations WHERE id = $1`, id)
+	return affectedOne(result, err, "reservation", id)
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// softDelete marks the row of the table with the ID deleted, unless it
+// already is.
+func softDelete(ctx context.Context, db sqlx.ExecerContext, table, kind, id string, at time.Time) error {
+	query := `UPDATE ` + table + ` SET deleted_at = $2, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL`
+	result, err := db.ExecContext(ctx, query, id, at)
+	return affectedOne(result, err, kind, id)
+}
+
+// restore clears the deletion of the deleted row of the table with the ID.
+func restore(ctx context.Context, db sqlx.ExecerContext, table, kind, id string, at time.Time) error {
+	query := `UPDATE ` + table + ` SET deleted_at = NULL, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := db.ExecContext(ctx, query, id, at)
+	return affectedOne(result, err, kind, id)
+}
+
+// purge removes the rows of the table deleted before the given time.
+func purge(ctx context.Context, db sqlx.ExecerContext, table string, before time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM `+table+` WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const stockLevelColumns = `warehouse_id, product_id, stock, reserved, updated_at`
+
+type stockLevelRepo struct {
+	db *sqlx.DB
+}
+
+func NewStockLevelRepository(db *sqlx.DB) usecase.StockLevelRepository {
+	return &stockLevelRepo{db}
+}
+
+func (repo *stockLevelRepo) FindByProduct(ctx context.Context, productID string) ([]entity.StockLevel, error) {
+	var levels []entity.StockLevel
+	query := `SELECT ` + stockLevelColumns + ` FROM stock_levels WHERE product_id = $1 ORDER BY warehouse_id`
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &levels, query, productID); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+func (repo *stockLevelRepo) FindByWarehouse(ctx context.Context, warehouseID string) ([]entity.StockLevel, error) {
+	var levels []entity.StockLevel
+	query := `SELECT ` + stockLevelColumns + ` FROM stock_levels WHERE warehouse_id = $1 ORDER BY product_id`
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &levels, query, warehouseID); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+func (repo *stockLevelRepo) Set(ctx context.Context, level *entity.StockLevel) error {
+	query := `INSERT INTO stock_levels (warehouse_id, product_id, stock, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (warehouse_id, product_id) DO UPDATE SET stock = EXCLUDED.stock, updated_at = EXCLUDED.updated_at`
+	_, err := conn(ctx, repo.db).ExecContext(ctx, query, level.WarehouseID, level.ProductID, level.Stock, level.UpdatedAt)
+	return err
+}
+
+func (repo *stockLevelRepo) Delete(ctx context.Context, warehouseID, productID string) error {
+	query := `DELETE FROM stock_levels WHERE warehouse_id = $1 AND product_id = $2`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, warehouseID, productID)
+	return affectedOne(result, err, "stock level", productID+" in warehouse "+warehouseID)
+}
+
+func (repo *stockLevelRepo) DecrementStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, `UPDATE stock_levels SET stock = stock - $3, updated_at = $4
+		WHERE warehouse_id = $1 AND product_id = $2 AND stock - reserved >= $3`, warehouseID, productID, quantity, usecase.ErrInsufficientStock)
+}
+
+func (repo *stockLevelRepo) IncrementStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, `UPDATE stock_levels SET stock = stock + $3, updated_at = $4
+		WHERE warehouse_id = $1 AND product_id = $2`, warehouseID, productID, quantity, nil)
+}
+
+func (repo *stockLevelRepo) Reserve(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, `UPDATE stock_levels SET reserved = reserved + $3, updated_at = $4
+		WHERE warehouse_id = $1 AND product_id = $2 AND stock - reserved >= $3`, warehouseID, productID, quantity, usecase.ErrInsufficientStock)
+}
+
+func (repo *stockLevelRepo) Unreserve(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, `UPDATE stock_levels SET reserved = reserved - $3, updated_at = $4
+		WHERE warehouse_id = $1 AND product_id = $2`, warehouseID, productID, quantity, nil)
+}
+
+func (repo *stockLevelRepo) CommitReserved(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, `UPDATE stock_levels SET stock = stock - $3, reserved = reserved - $3, updated_at = $4
+		WHERE warehouse_id = $1 AND product_id = $2 AND stock >= $3`, warehouseID, productID, quantity, usecase.ErrInsufficientStock)
+}
+
+// move runs a stock update taking the warehouse ID, the product ID, the
+// quantity and the update time, returning missing if it changed no level.
+func (repo *stockLevelRepo) move(ctx context.Context, query, warehouseID, productID string, quantity int, missing error) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, warehouseID, productID, quantity, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return missing
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/usecase"
+)
+
+type txKey struct{}
+
+type transactor struct {
+	db *sqlx.DB
+}
+
+func NewTransactor(db *sqlx.DB) usecase.Transactor {
+	return &transactor{db}
+}
+
+// WithinTransaction runs fn in a database transaction carried by the context
+// passed to fn. Nested calls join the transaction that is already open.
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// conn returns the transaction carried by ctx, or db outside a transaction.
+func conn(ctx context.Context, db *sqlx.DB) sqlx.ExtContext {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// jsonColumn stores a value in a JSONB column.
+type jsonColumn[T any] struct {
+	V T
+}
+
+func (j jsonColumn[T]) Value() (driver.Value, error) {
+	return json.Marshal(j.V)
+}
+
+func (j *jsonColumn[T]) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, &j.V)
+	case string:
+		return json.Unmarshal([]byte(data), &j.V)
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON column", src)
+	}
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const userColumns = `id, email, password_hash, role, created_at, updated_at`
+
+// uniqueViolation is the PostgreSQL error code for a duplicate key.
+const uniqueViolation = "23505"
+
+type userRepo struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) usecase.UserRepository {
+	return &userRepo{db}
+}
+
+func (repo *userRepo) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	user.ID = uuid.New().String()
+	query := `INSERT INTO users (` + userColumns + `)
+		VALUES (:id, :email, :password_hash, :role, :created_at, :updated_at)`
+	_, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, user)
+	if isUniqueViolation(err) {
+		return nil, usecase.ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (repo *userRepo) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	var user entity.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &user, query, id); err != nil {
+		return nil, findError(err, "user", id)
+	}
+	return &user, nil
+}
+
+func (repo *userRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	var user entity.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &user, query, email); err != nil {
+		return nil, findError(err, "user", email)
+	}
+	return &user, nil
+}
+
+func (repo *userRepo) UpdateRole(ctx context.Context, id string, role entity.Role, updatedAt time.Time) error {
+	query := `UPDATE users SET role = $2, updated_at = $3 WHERE id = $1`
+	_, err := conn(ctx, repo.db).ExecContext(ctx, query, id, role, updatedAt)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const warehouseColumns = `id, name, country, latitude, longitude, priority, created_at, updated_at`
+
+type warehouseRepo struct {
+	db *sqlx.DB
+}
+
+func NewWarehouseRepository(db *sqlx.DB) usecase.WarehouseRepository {
+	return &warehouseRepo{db}
+}
+
+func (repo *warehouseRepo) Create(ctx context.Context, warehouse *entity.Warehouse) (*entity.Warehouse, error) {
+	warehouse.ID = uuid.New().String()
+	query := `INSERT INTO warehouses (` + warehouseColumns + `)
+		VALUES (:id, :name, :country, :latitude, :longitude, :priority, :created_at, :updated_at)`
+	if _, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, warehouse); err != nil {
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+func (repo *warehouseRepo) FindByID(ctx context.Context, id string) (*entity.Warehouse, error) {
+	var warehouse entity.Warehouse
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &warehouse, query, id); err != nil {
+		return nil, findError(err, "warehouse", id)
+	}
+	return &warehouse, nil
+}
+
+func (repo *warehouseRepo) FindAll(ctx context.Context) ([]entity.Warehouse, error) {
+	var warehouses []entity.Warehouse
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses ORDER BY priority, id`
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &warehouses, query); err != nil {
+		return nil, err
+	}
+	return warehouses, nil
+}
+
+func (repo *warehouseRepo) Update(ctx context.Context, warehouse *entity.Warehouse) error {
+	query := `UPDATE warehouses
+		SET name = $2, country = $3, latitude = $4, longitude = $5, priority = $6, updated_at = $7
+		WHERE id = $1`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, warehouse.ID, warehouse.Name, warehouse.Country,
+		warehouse.Latitude, warehouse.Longitude, warehouse.Priority, warehouse.UpdatedAt)
+	return affectedOne(result, err, "warehouse", warehouse.ID)
+}
+
+func (repo *warehouseRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM warehouses WHERE id = $1`, id)
+	return affectedOne(result, err, "warehouse", id)
+}
@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"slices"
+	"strings"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+const webhookColumns = `id, owner_id, url, event_types, secret, created_at, updated_at`
+
+var webhookSortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+type webhookRepo struct {
+	db *sqlx.DB
+}
+
+// webhookRow is the stored shape of a webhook; the event types live in a JSONB
+// column.
+type webhookRow struct {
+	entity.Webhook
+	EventTypes jsonColumn[[]entity.EventType] `db:"event_types"`
+}
+
+func newWebhookRow(webhook *entity.Webhook) webhookRow {
+	row := webhookRow{Webhook: *webhook}
+	row.EventTypes.V = webhook.EventTypes
+	if row.EventTypes.V == nil {
+		row.EventTypes.V = []entity.EventType{}
+	}
+	return row
+}
+
+func (row *webhookRow) toEntity() entity.Webhook {
+	webhook := row.Webhook
+	webhook.EventTypes = row.EventTypes.V
+	return webhook
+}
+
+func NewWebhookRepository(db *sqlx.DB) usecase.WebhookRepository {
+	return &webhookRepo{db}
+}
+
+func (repo *webhookRepo) Create(ctx context.Context, webhook *entity.Webhook) (*entity.Webhook, error) {
+	webhook.ID = uuid.New().String()
+	query := `INSERT INTO webhooks (` + webhookColumns + `)
+		VALUES (:id, :owner_id, :url, :event_types, :secret, :created_at, :updated_at)`
+	if _, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, newWebhookRow(webhook)); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (repo *webhookRepo) FindByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	var row webhookRow
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &row, query, id); err != nil {
+		return nil, findError(err, "webhook", id)
+	}
+	webhook := row.toEntity()
+	return &webhook, nil
+}
+
+func (repo *webhookRepo) FindAll(ctx context.Context, query usecase.WebhookQuery) ([]entity.Webhook, error) {
+	var where whereClause
+	if query.OwnerID != "" {
+		where.add("owner_id = ?", query.OwnerID)
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, webhookSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []webhookRow
+	statement := repo.db.Rebind(`SELECT ` + webhookColumns + ` FROM webhooks` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, statement, where.args...); err != nil {
+		return nil, err
+	}
+	return webhooksFromRows(rows), nil
+}
+
+func (repo *webhookRepo) FindSubscribed(ctx context.Context, eventType entity.EventType) ([]entity.Webhook, error) {
+	var rows []webhookRow
+	contains := jsonColumn[[]entity.EventType]{V: []entity.EventType{eventType}}
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE event_types @> $1::jsonb`
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, query, contains); err != nil {
+		return nil, err
+	}
+	return webhooksFromRows(rows), nil
+}
+
+func (repo *webhookRepo) Update(ctx context.Context, webhook *entity.Webhook) error {
+	row := newWebhookRow(webhook)
+	query := `UPDATE webhooks SET url = $2, event_types = $3, secret = $4, updated_at = $5 WHERE id = $1`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, row.ID, row.URL, row.EventTypes, row.Secret, row.UpdatedAt)
+	return affectedOne(result, err, "webhook", webhook.ID)
+}
+
+func (repo *webhookRepo) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	return affectedOne(result, err, "webhook", id)
+}
+
+func webhooksFromRows(rows []webhookRow) []entity.Webhook {
+	var webhooks []entity.Webhook
+	for i := range rows {
+		webhooks = append(webhooks, rows[i].toEntity())
+	}
+	return webhooks
+}
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, body, status, attempt_count, attempts,
+	next_attempt_at, created_at, delivered_at`
+
+var deliverySortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+type deliveryRepo struct {
+	db *sqlx.DB
+}
+
+// deliveryRow is the stored shape of a webhook delivery; the body and the
+// attempt log live in JSON columns.
+type deliveryRow struct {
+	entity.WebhookDelivery
+	Body     jsonColumn[json.RawMessage]          `db:"body"`
+	Attempts jsonColumn[[]entity.DeliveryAttempt] `db:"attempts"`
+}
+
+func newDeliveryRow(delivery *entity.WebhookDelivery) deliveryRow {
+	row := deliveryRow{WebhookDelivery: *delivery}
+	row.Body.V = delivery.Body
+	row.Attempts.V = delivery.Attempts
+	if row.Attempts.V == nil {
+		row.Attempts.V = []entity.DeliveryAttempt{}
+	}
+	return row
+}
+
+func (row *deliveryRow) toEntity() entity.WebhookDelivery {
+	delivery := row.WebhookDelivery
+	delivery.Body = row.Body.V
+	delivery.Attempts = row.Attempts.V
+	return delivery
+}
+
+func NewWebhookDeliveryRepository(db *sqlx.DB) usecase.WebhookDeliveryRepository {
+	return &deliveryRepo{db}
+}
+
+func (repo *deliveryRepo) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	delivery.ID = uuid.New().String()
+	query := `INSERT INTO webhook_deliveries (` + deliveryColumns + `)
+		VALUES (:id, :webhook_id, :event_id, :event_type, :body, :status, :attempt_count, :attempts,
+			:next_attempt_at, :created_at, :delivered_at)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`
+	result, err := sqlx.NamedExecContext(ctx, conn(ctx, repo.db), query, newDeliveryRow(delivery))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("delivery of event %s to webhook %s: %w", delivery.EventID, delivery.WebhookID, usecase.ErrConflict)
+	}
+	return nil
+}
+
+// Claim skips rows another worker has locked, so that concurrent workers
+// never claim the same delivery.
+func (repo *deliveryRepo) Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY created_at, id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+	var rows []deliveryRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, query, now, until, entity.WebhookDeliveryPending, limit); err != nil {
+		return nil, err
+	}
+
+	deliveries := deliveriesFromRows(rows)
+	// RETURNING keeps no order
+	slices.SortFunc(deliveries, func(a, b entity.WebhookDelivery) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return deliveries, nil
+}
+
+func (repo *deliveryRepo) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	row := newDeliveryRow(delivery)
+	query := `UPDATE webhook_deliveries
+		SET status = $2, attempt_count = $3, attempts = $4, next_attempt_at = $5, delivered_at = $6
+		WHERE id = $1`
+	result, err := conn(ctx, repo.db).ExecContext(ctx, query, row.ID, row.Status, row.AttemptCount,
+		row.Attempts, row.NextAttemptAt, row.DeliveredAt)
+	return affectedOne(result, err, "webhook delivery", delivery.ID)
+}
+
+func (repo *deliveryRepo) FindByID(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	var row deliveryRow
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	if err := sqlx.GetContext(ctx, conn(ctx, repo.db), &row, query, id); err != nil {
+		return nil, findError(err, "webhook delivery", id)
+	}
+	delivery := row.toEntity()
+	return &delivery, nil
+}
+
+func (repo *deliveryRepo) FindAll(ctx context.Context, query usecase.WebhookDeliveryQuery) ([]entity.WebhookDelivery, error) {
+	var where whereClause
+	if query.WebhookID != "" {
+		where.add("webhook_id = ?", query.WebhookID)
+	}
+	if query.Status != "" {
+		where.add("status = ?", query.Status)
+	}
+	orderBy, err := where.keyset(query.Sort, query.After, query.Limit, deliverySortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []deliveryRow
+	statement := repo.db.Rebind(`SELECT ` + deliveryColumns + ` FROM webhook_deliveries` + where.String() + orderBy)
+	if err := sqlx.SelectContext(ctx, conn(ctx, repo.db), &rows, statement, where.args...); err != nil {
+		return nil, err
+	}
+	return deliveriesFromRows(rows), nil
+}
+
+func (repo *deliveryRepo) DeleteByWebhook(ctx context.Context, webhookID string) error {
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = $1`, webhookID)
+	return err
+}
+
+func deliveriesFromRows(rows []deliveryRow) []entity.WebhookDelivery {
+	var deliveries []entity.WebhookDelivery
+	for i := range rows {
+		deliveries = append(deliveries, rows[i].toEntity())
+	}
+	return deliveries
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"sort"
+	"time"
 	"ulab3/internal/entity"
 	"ulab3/internal/usecase"
+	"ulab3/pkg/search"
 )
 
 type productRepo struct {
@@ -26,8 +32,30 @@ func (repo *productRepo) Create(ctx context.Context, product *entity.Product) (*
 	return product, nil
 }
 
-func (repo *productRepo) FindAll(ctx context.Context) ([]entity.Product, error) {
-	cursor, err := repo.collection.Find(ctx, bson.M{})
+func (repo *productRepo) FindAll(ctx context.Context, query usecase.ProductQuery) ([]entity.Product, error) {
+	filter := bson.M{}
+	if !query.IncludeDeleted {
+		filter["deleted_at"] = nil
+	}
+	if query.Category != "" {
+		filter["category"] = query.Category
+	}
+	price := bson.M{}
+	if query.MinPrice != nil {
+		price["$gte"] = *query.MinPrice
+	}
+	if query.MaxPrice != nil {
+		price["$lte"] = *query.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["price"] = price
+	}
+	if query.InStock {
+		filter["$expr"] = available(1)
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
 	if err != nil {
 		return nil, err
 	}
@@ -45,21 +73,201 @@ func (repo *productRepo) FindAll(ctx context.Context) ([]entity.Product, error)
 }
 
 func (repo *productRepo) FindByID(ctx context.Context, id string) (*entity.Product, error) {
+	return repo.findOne(ctx, bson.M{"id": id, "deleted_at": nil}, id)
+}
+
+func (repo *productRepo) FindByIDIncludingDeleted(ctx context.Context, id string) (*entity.Product, error) {
+	return repo.findOne(ctx, bson.M{"id": id}, id)
+}
+
+func (repo *productRepo) findOne(ctx context.Context, filter bson.M, id string) (*entity.Product, error) {
 	var product entity.Product
-	err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&product)
+	err := repo.collection.FindOne(ctx, filter).Decode(&product)
 	if err != nil {
-		return nil, err
+		return nil, findError(err, "product", id)
 	}
 	return &product, nil
 }
 
+// searchCandidateLimit caps how many products a search ranks in process.
+const searchCandidateLimit = 500
+
+// Search combines the text index, which matches whole and stemmed words, with
+// a regular expression over the first letters of each word, which catches
+// misspellings the text index cannot. Candidates are ranked in process.
+func (repo *productRepo) Search(ctx context.Context, query string, limit int) ([]entity.ProductMatch, error) {
+	textScores := make(map[string]float64)
+	var candidates []entity.Product
+
+	findOptions := options.Find().
+		SetProjection(bson.M{"text_score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"text_score": bson.M{"$meta": "textScore"}}).
+		SetLimit(searchCandidateLimit)
+	filter := bson.M{"$text": bson.M{"$search": query}, "deleted_at": nil}
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var hit struct {
+			entity.Product `bson:",inline"`
+			TextScore      float64 `bson:"text_score"`
+		}
+		if err := cursor.Decode(&hit); err != nil {
+			return nil, err
+		}
+		textScores[hit.ID] = hit.TextScore
+		candidates = append(candidates, hit.Product)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	var patterns bson.A
+	for _, prefix := range search.Prefixes(search.Tokenize(query), 2) {
+		pattern := primitive.Regex{Pattern: `(^|\W)` + regexp.QuoteMeta(prefix), Options: "i"}
+		patterns = append(patterns, bson.M{"name": pattern}, bson.M{"category": pattern})
+	}
+	if len(patterns) > 0 && len(candidates) < searchCandidateLimit {
+		seen := make(bson.A, 0, len(textScores))
+		for id := range textScores {
+			seen = append(seen, id)
+		}
+		filter := bson.M{"$or": patterns, "id": bson.M{"$nin": seen}, "deleted_at": nil}
+		cursor, err := repo.collection.Find(ctx, filter, options.Find().SetLimit(int64(searchCandidateLimit-len(candidates))))
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var product entity.Product
+			if err := cursor.Decode(&product); err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, product)
+		}
+		if err := cursor.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Stemmed matches the in-process scorer does not recognise keep the
+	// relevance the text index gave them
+	var matches []entity.ProductMatch
+	for _, product := range candidates {
+		score := max(usecase.ScoreProduct(query, product), textScores[product.ID])
+		if score > 0 {
+			matches = append(matches, entity.ProductMatch{Product: product, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
 func (repo *productRepo) Update(ctx context.Context, id string, product *entity.Product) error {
-	update := bson.M{"$set": product}
+	updated := *product
+	updated.ID = id
+	updated.Version++
+	filter := bson.M{"id": id, "version": product.Version, "deleted_at": nil}
+	result, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$set": &updated})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return versionError(ctx, repo.collection, "product", id)
+	}
+	return nil
+}
+
+func (repo *productRepo) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("product", id)
+	}
+	return nil
+}
+
+func (repo *productRepo) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	return softDelete(ctx, repo.collection, "product", id, at)
+}
+
+func (repo *productRepo) Restore(ctx context.Context, id string, at time.Time) error {
+	return restore(ctx, repo.collection, "product", id, at)
+}
+
+func (repo *productRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, repo.collection, before)
+}
+
+// available matches products with at least quantity of stock that is not
+// reserved. Products stored before reservations existed have no reserved
+// field.
+func available(quantity int) bson.M {
+	unreserved := bson.M{"$subtract": bson.A{"$stock", bson.M{"$ifNull": bson.A{"$reserved", 0}}}}
+	return bson.M{"$gte": bson.A{unreserved, quantity}}
+}
+
+func (repo *productRepo) DecrementStock(ctx context.Context, id string, quantity int) error {
+	return repo.moveStock(ctx, bson.M{"id": id, "deleted_at": nil, "$expr": available(quantity)}, -quantity, 0)
+}
+
+func (repo *productRepo) IncrementStock(ctx context.Context, id string, quantity int) error {
+	update := bson.M{
+		"$inc": bson.M{"stock": quantity, "version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
 	_, err := repo.collection.UpdateOne(ctx, bson.M{"id": id}, update)
 	return err
 }
 
-func (repo *productRepo) Delete(ctx context.Context, id string) error {
-	_, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+func (repo *productRepo) Reserve(ctx context.Context, id string, quantity int) error {
+	return repo.moveStock(ctx, bson.M{"id": id, "deleted_at": nil, "$expr": available(quantity)}, 0, quantity)
+}
+
+func (repo *productRepo) Unreserve(ctx context.Context, id string, quantity int) error {
+	update := bson.M{
+		"$inc": bson.M{"reserved": -quantity, "version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	_, err := repo.collection.UpdateOne(ctx, bson.M{"id": id}, update)
+	return err
+}
+
+func (repo *productRepo) CommitReserved(ctx context.Context, id string, quantity int) error {
+	return repo.moveStock(ctx, bson.M{"id": id, "stock": bson.M{"$gte": quantity}}, -quantity, -quantity)
+}
+
+func (repo *productRepo) SetStock(ctx context.Context, id string, stock int) error {
+	update := bson.M{
+		"$set": bson.M{"stock": stock, "updated_at": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	_, err := repo.collection.UpdateOne(ctx, bson.M{"id": id}, update)
 	return err
 }
+
+// moveStock adds stock and reserved to the product matching the filter,
+// returning ErrInsufficientStock if none does.
+func (repo *productRepo) moveStock(ctx context.Context, filter bson.M, stock, reserved int) error {
+	update := bson.M{
+		"$inc": bson.M{"stock": stock, "reserved": reserved, "version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return usecase.ErrInsufficientStock
+	}
+	return nil
+}
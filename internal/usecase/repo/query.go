@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"ulab3/internal/usecase"
+)
+
+// keysetFilter adds to filter the condition that starts a page strictly after
+// the cursor: a greater sort value, or an equal one with a greater ID.
+func keysetFilter(filter bson.M, sort usecase.Sort, after *usecase.Cursor) {
+	if after == nil {
+		return
+	}
+	op := "$gt"
+	if sort.Desc {
+		op = "$lt"
+	}
+	filter["$or"] = bson.A{
+		bson.M{sort.Field: bson.M{op: after.Value}},
+		bson.M{sort.Field: after.Value, "id": bson.M{op: after.ID}},
+	}
+}
+
+// keysetOptions sorts by the requested field with the ID as tie-breaker.
+func keysetOptions(sort usecase.Sort, limit int) *options.FindOptions {
+	direction := 1
+	if sort.Desc {
+		direction = -1
+	}
+	return options.Find().
+		SetSort(bson.D{{Key: sort.Field, Value: direction}, {Key: "id", Value: direction}}).
+		SetLimit(int64(limit))
+}
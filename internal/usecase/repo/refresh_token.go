@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type refreshTokenRepo struct {
+	collection *mongo.Collection
+}
+
+func NewRefreshTokenRepository(collection *mongo.Collection) usecase.RefreshTokenRepository {
+	return &refreshTokenRepo{collection}
+}
+
+func (repo *refreshTokenRepo) Create(ctx context.Context, token *entity.RefreshToken) error {
+	_, err := repo.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (repo *refreshTokenRepo) FindByID(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&token)
+	if err != nil {
+		return nil, findError(err, "refresh token", id)
+	}
+	return &token, nil
+}
+
+func (repo *refreshTokenRepo) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	filter := bson.M{"id": id, "revoked_at": bson.M{"$exists": false}}
+	result, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
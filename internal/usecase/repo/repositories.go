@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"ulab3/internal/usecase"
+)
+
+// NewRepositories wires the MongoDB repositories over the given database.
+func NewRepositories(db *mongo.Database) usecase.Repositories {
+	return usecase.Repositories{
+		Products:      NewProductRepository(db.Collection("products")),
+		Orders:        NewOrderRepository(db.Collection("orders")),
+		Users:         NewUserRepository(db.Collection("users")),
+		Customers:     NewCustomerRepository(db.Collection("customers")),
+		Carts:         NewCartRepository(db.Collection("carts")),
+		Reservations:  NewReservationRepository(db.Collection("reservations")),
+		Warehouses:    NewWarehouseRepository(db.Collection("warehouses")),
+		StockLevels:   NewStockLevelRepository(db.Collection("stock_levels")),
+		RefreshTokens: NewRefreshTokenRepository(db.Collection("refresh_tokens")),
+		Idempotency:   NewIdempotencyRepository(db.Collection("idempotency_keys")),
+		Audit:         NewAuditRepository(db.Collection("audit_log")),
+		Outbox:        NewOutboxRepository(db.Collection("outbox")),
+		Webhooks:      NewWebhookRepository(db.Collection("webhooks")),
+		Deliveries:    NewWebhookDeliveryRepository(db.Collection("webhook_deliveries")),
+		Transactor:    NewTransactor(db.Client()),
+		Feed:          NewOutboxFeed(db.Collection("outbox")),
+	}
+}
@@ -0,0 +1,45 @@
+package repo_test
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"testing"
+	"time"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo"
+	"ulab3/internal/usecase/repo/repotest"
+)
+
+// TestRepositories runs against the MongoDB at MONGO_TEST_URI, which must be a
+// replica set for transactions, e.g. mongodb://localhost:27017/?replicaSet=rs0.
+// Every check gets a database of its own, dropped afterwards. make
+// repo-test-mongo runs it against a throwaway container.
+func TestRepositories(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	repotest.Run(t, func(t *testing.T) usecase.Repositories {
+		db := client.Database("repotest_" + uuid.New().String()[:8])
+		t.Cleanup(func() { db.Drop(context.Background()) })
+		if err := repo.EnsureIndexes(context.Background(), db); err != nil {
+			t.Fatalf("ensure indexes: %v", err)
+		}
+		return repo.NewRepositories(db)
+	})
+}
@@ -0,0 +1,1372 @@
+// Package repotest checks that a storage backend behaves the way the services
+// expect: ID generation, not-found errors, conditional stock and status
+// updates, soft deletion, transaction rollback, idempotency key expiry, the
+// audit log, the event outbox, webhook deliveries, customers, cart expiry,
+// stock reservations, and warehouses with their stock levels. Every backend
+// runs it from its tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type check struct {
+	name string
+	run  func(ctx context.Context, repos usecase.Repositories) error
+}
+
+var checks = []check{
+	{"products", testProducts},
+	{"stock", testStock},
+	{"orders", testOrders},
+	{"soft delete", testSoftDelete},
+	{"pagination", testPagination},
+	{"search", testSearch},
+	{"transactions", testTransactions},
+	{"idempotency", testIdempotency},
+	{"audit", testAudit},
+	{"outbox", testOutbox},
+	{"webhooks", testWebhooks},
+	{"customers", testCustomers},
+	{"carts", testCarts},
+	{"reservations", testReservations},
+	{"warehouses", testWarehouses},
+}
+
+// Run runs every conformance check against the repositories newRepos
+// returns, each check as a subtest with repositories of its own. The checks
+// create their own records and remove them again, so they can run against a
+// live database. Audit entries cannot be removed; the ones the checks append
+// name the entity type "repotest".
+func Run(t *testing.T, newRepos func(t *testing.T) usecase.Repositories) {
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.run(context.Background(), newRepos(t)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// now returns the current time at the precision every backend can store.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func newProduct(stock int) *entity.Product {
+	return &entity.Product{
+		Name:      "repotest product",
+		Price:     9.5,
+		Stock:     stock,
+		Category:  "repotest",
+		Version:   1,
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	}
+}
+
+func testProducts(ctx context.Context, repos usecase.Repositories) error {
+	product, err := repos.Products.Create(ctx, newProduct(10))
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if product.ID == "" {
+		return errors.New("create did not assign an ID")
+	}
+	defer repos.Products.Delete(ctx, product.ID)
+
+	found, err := repos.Products.FindByID(ctx, product.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.Name != product.Name || found.Price != product.Price || found.Stock != product.Stock ||
+		found.Category != product.Category || !found.CreatedAt.Equal(product.CreatedAt) {
+		return fmt.Errorf("find by ID returned %+v, want %+v", found, product)
+	}
+
+	query := usecase.ProductQuery{
+		ProductFilter: usecase.ProductFilter{Category: product.Category},
+		Sort:          usecase.Sort{Field: "created_at"},
+		Limit:         usecase.MaxPageLimit,
+	}
+	all, err := repos.Products.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all: %w", err)
+	}
+	if !containsProduct(all, product.ID) {
+		return errors.New("find all does not include the created product")
+	}
+
+	product.Name = "repotest product renamed"
+	product.Price = 12
+	if err := repos.Products.Update(ctx, product.ID, product); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	found, err = repos.Products.FindByID(ctx, product.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID after update: %w", err)
+	}
+	if found.Name != product.Name || found.Price != product.Price {
+		return fmt.Errorf("update was not stored: got %+v", found)
+	}
+	if found.Version != product.Version+1 {
+		return fmt.Errorf("update left the version at %d, want %d", found.Version, product.Version+1)
+	}
+	if err := repos.Products.Update(ctx, product.ID, product); !errors.Is(err, usecase.ErrVersionConflict) {
+		return fmt.Errorf("update at a stale version returned %v, want ErrVersionConflict", err)
+	}
+
+	if err := repos.Products.Delete(ctx, product.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Products.FindByID(ctx, product.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of a deleted product returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Products.Update(ctx, product.ID, product); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update of a deleted product returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Products.Delete(ctx, product.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("delete of a deleted product returned %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func testStock(ctx context.Context, repos usecase.Repositories) error {
+	product, err := repos.Products.Create(ctx, newProduct(5))
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer repos.Products.Delete(ctx, product.ID)
+
+	if err := repos.Products.DecrementStock(ctx, product.ID, 3); err != nil {
+		return fmt.Errorf("decrement within stock: %w", err)
+	}
+	if err := repos.Products.DecrementStock(ctx, product.ID, 3); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("decrement beyond stock returned %v, want ErrInsufficientStock", err)
+	}
+	if err := repos.Products.IncrementStock(ctx, product.ID, 4); err != nil {
+		return fmt.Errorf("increment: %w", err)
+	}
+	if err := repos.Products.IncrementStock(ctx, "repotest-missing", 1); err != nil {
+		return fmt.Errorf("increment of a missing product: %w", err)
+	}
+
+	found, err := repos.Products.FindByID(ctx, product.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.Stock != 6 {
+		return fmt.Errorf("stock is %d, want 6", found.Stock)
+	}
+	if found.Version != product.Version+2 {
+		return fmt.Errorf("stock changes left the version at %d, want %d", found.Version, product.Version+2)
+	}
+	return nil
+}
+
+func testOrders(ctx context.Context, repos usecase.Repositories) error {
+	created := now()
+	userID := "repotest-" + uuid.New().String()
+	customerID := "repotest-" + uuid.New().String()
+	order, err := repos.Orders.Create(ctx, &entity.Order{
+		UserID:     userID,
+		CustomerID: customerID,
+		Items: []entity.OrderItem{
+			{ProductID: "repotest-a", Quantity: 2, UnitPrice: 1.5, LineTotal: 3},
+			{ProductID: "repotest-b", Quantity: 1, UnitPrice: 4, LineTotal: 4,
+				Allocations: []entity.Allocation{{WarehouseID: "repotest-warehouse", Quantity: 1}}},
+		},
+		TotalPrice:    7,
+		Status:        entity.OrderStatusPending,
+		StatusHistory: []entity.StatusChange{{To: entity.OrderStatusPending, At: created}},
+		Version:       1,
+		CreatedAt:     created,
+		UpdatedAt:     created,
+	})
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if order.ID == "" {
+		return errors.New("create did not assign an ID")
+	}
+	defer repos.Orders.Delete(ctx, order.ID)
+
+	found, err := repos.Orders.FindByID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if len(found.Items) != 2 || !reflect.DeepEqual(found.Items[1], order.Items[1]) || found.TotalPrice != order.TotalPrice {
+		return fmt.Errorf("find by ID returned items %+v, want %+v", found.Items, order.Items)
+	}
+	if found.UserID != userID || found.CustomerID != customerID {
+		return fmt.Errorf("find by ID returned user %q and customer %q, want %q and %q", found.UserID, found.CustomerID, userID, customerID)
+	}
+
+	query := usecase.OrderQuery{
+		OrderFilter: usecase.OrderFilter{UserID: userID},
+		Sort:        usecase.Sort{Field: "created_at"},
+		Limit:       10,
+	}
+	owned, err := repos.Orders.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all by user: %w", err)
+	}
+	if len(owned) != 1 || owned[0].ID != order.ID {
+		return fmt.Errorf("find all by user returned %d orders, want only %s", len(owned), order.ID)
+	}
+	query.OrderFilter = usecase.OrderFilter{CustomerID: customerID}
+	placed, err := repos.Orders.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all by customer: %w", err)
+	}
+	if len(placed) != 1 || placed[0].ID != order.ID {
+		return fmt.Errorf("find all by customer returned %d orders, want only %s", len(placed), order.ID)
+	}
+
+	change := entity.StatusChange{From: entity.OrderStatusPending, To: entity.OrderStatusPaid, At: now()}
+	if err := repos.Orders.UpdateStatus(ctx, order.ID, entity.OrderStatusPending, change); err != nil {
+		return fmt.Errorf("update status: %w", err)
+	}
+	err = repos.Orders.UpdateStatus(ctx, order.ID, entity.OrderStatusPending, change)
+	if !errors.Is(err, usecase.ErrOrderStatusChanged) {
+		return fmt.Errorf("stale status update returned %v, want ErrOrderStatusChanged", err)
+	}
+	found, err = repos.Orders.FindByID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID after status update: %w", err)
+	}
+	if found.Status != entity.OrderStatusPaid || len(found.StatusHistory) != 2 {
+		return fmt.Errorf("status update was not stored: status %s, history %+v", found.Status, found.StatusHistory)
+	}
+
+	if released, err := repos.Orders.MarkStockReleased(ctx, order.ID); err != nil || !released {
+		return fmt.Errorf("first stock release returned %v, %v; want true", released, err)
+	}
+	if released, err := repos.Orders.MarkStockReleased(ctx, order.ID); err != nil || released {
+		return fmt.Errorf("second stock release returned %v, %v; want false", released, err)
+	}
+
+	found, err = repos.Orders.FindByID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID after stock release: %w", err)
+	}
+	if found.Version != order.Version+2 {
+		return fmt.Errorf("status update and stock release left the version at %d, want %d", found.Version, order.Version+2)
+	}
+	if err := repos.Orders.Update(ctx, order.ID, order); !errors.Is(err, usecase.ErrVersionConflict) {
+		return fmt.Errorf("update at a stale version returned %v, want ErrVersionConflict", err)
+	}
+	if err := repos.Orders.Update(ctx, order.ID, found); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := repos.Orders.Delete(ctx, order.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Orders.FindByID(ctx, order.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of a deleted order returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Orders.Update(ctx, order.ID, order); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update of a deleted order returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Orders.Delete(ctx, order.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("delete of a deleted order returned %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func testSoftDelete(ctx context.Context, repos usecase.Repositories) error {
+	product, err := repos.Products.Create(ctx, newProduct(5))
+	if err != nil {
+		return fmt.Errorf("create product: %w", err)
+	}
+	defer repos.Products.Delete(ctx, product.ID)
+	userID := "repotest-" + uuid.New().String()
+	order, err := repos.Orders.Create(ctx, &entity.Order{
+		UserID:    userID,
+		Items:     []entity.OrderItem{{ProductID: product.ID, Quantity: 1, UnitPrice: 9.5, LineTotal: 9.5}},
+		Status:    entity.OrderStatusPending,
+		Version:   1,
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+	if err != nil {
+		return fmt.Errorf("create order: %w", err)
+	}
+	defer repos.Orders.Delete(ctx, order.ID)
+
+	// Deleted long ago, so that purging up to just after it cannot touch
+	// records of a live database
+	deleted := now().AddDate(-100, 0, 0)
+	if err := repos.Products.SoftDelete(ctx, product.ID, deleted); err != nil {
+		return fmt.Errorf("soft delete product: %w", err)
+	}
+	if err := repos.Products.SoftDelete(ctx, product.ID, deleted); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("soft delete of a deleted product returned %v, want ErrNotFound", err)
+	}
+	if _, err := repos.Products.FindByID(ctx, product.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of a deleted product returned %v, want ErrNotFound", err)
+	}
+	found, err := repos.Products.FindByIDIncludingDeleted(ctx, product.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID including deleted: %w", err)
+	}
+	if found.DeletedAt == nil || !found.DeletedAt.Equal(deleted) {
+		return fmt.Errorf("deleted product has deleted_at %v, want %v", found.DeletedAt, deleted)
+	}
+	if err := repos.Products.Update(ctx, product.ID, found); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update of a deleted product returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Products.DecrementStock(ctx, product.ID, 1); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("decrement of a deleted product returned %v, want ErrInsufficientStock", err)
+	}
+
+	query := usecase.ProductQuery{
+		ProductFilter: usecase.ProductFilter{Category: product.Category},
+		Sort:          usecase.Sort{Field: "created_at"},
+		Limit:         usecase.MaxPageLimit,
+	}
+	all, err := repos.Products.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all: %w", err)
+	}
+	if containsProduct(all, product.ID) {
+		return errors.New("find all includes a deleted product")
+	}
+	query.IncludeDeleted = true
+	if all, err = repos.Products.FindAll(ctx, query); err != nil {
+		return fmt.Errorf("find all including deleted: %w", err)
+	}
+	if !containsProduct(all, product.ID) {
+		return errors.New("find all including deleted leaves out a deleted product")
+	}
+
+	if err := repos.Products.Restore(ctx, product.ID, now()); err != nil {
+		return fmt.Errorf("restore product: %w", err)
+	}
+	if err := repos.Products.Restore(ctx, product.ID, now()); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("restore of a live product returned %v, want ErrNotFound", err)
+	}
+	if found, err = repos.Products.FindByID(ctx, product.ID); err != nil {
+		return fmt.Errorf("find by ID of a restored product: %w", err)
+	}
+	if found.DeletedAt != nil {
+		return fmt.Errorf("restored product still has deleted_at %v", found.DeletedAt)
+	}
+	if found.Version != product.Version+2 {
+		return fmt.Errorf("soft delete and restore left the version at %d, want %d", found.Version, product.Version+2)
+	}
+
+	if err := repos.Orders.SoftDelete(ctx, order.ID, deleted); err != nil {
+		return fmt.Errorf("soft delete order: %w", err)
+	}
+	if _, err := repos.Orders.FindByID(ctx, order.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of a deleted order returned %v, want ErrNotFound", err)
+	}
+	orderQuery := usecase.OrderQuery{
+		OrderFilter: usecase.OrderFilter{UserID: userID},
+		Sort:        usecase.Sort{Field: "created_at"},
+		Limit:       10,
+	}
+	owned, err := repos.Orders.FindAll(ctx, orderQuery)
+	if err != nil {
+		return fmt.Errorf("find all orders: %w", err)
+	}
+	if len(owned) != 0 {
+		return fmt.Errorf("find all returned %d deleted orders, want none", len(owned))
+	}
+	orderQuery.IncludeDeleted = true
+	if owned, err = repos.Orders.FindAll(ctx, orderQuery); err != nil {
+		return fmt.Errorf("find all orders including deleted: %w", err)
+	}
+	if len(owned) != 1 || owned[0].ID != order.ID {
+		return fmt.Errorf("find all including deleted returned %d orders, want only %s", len(owned), order.ID)
+	}
+
+	// Only the order is still deleted, so only the order is purged
+	if err := repos.Products.SoftDelete(ctx, product.ID, now()); err != nil {
+		return fmt.Errorf("soft delete product again: %w", err)
+	}
+	purged, err := repos.Orders.Purge(ctx, deleted.Add(time.Second))
+	if err != nil {
+		return fmt.Errorf("purge orders: %w", err)
+	}
+	if purged != 1 {
+		return fmt.Errorf("purge removed %d orders, want 1", purged)
+	}
+	if _, err := repos.Orders.FindByIDIncludingDeleted(ctx, order.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of a purged order returned %v, want ErrNotFound", err)
+	}
+	if purged, err = repos.Products.Purge(ctx, deleted.Add(time.Second)); err != nil {
+		return fmt.Errorf("purge products: %w", err)
+	}
+	if purged != 0 {
+		return fmt.Errorf("purge removed %d products deleted after the cutoff, want 0", purged)
+	}
+	if _, err := repos.Products.FindByIDIncludingDeleted(ctx, product.ID); err != nil {
+		return fmt.Errorf("find by ID of a product deleted after the cutoff: %w", err)
+	}
+	return nil
+}
+
+func testPagination(ctx context.Context, repos usecase.Repositories) error {
+	category := "repotest-" + uuid.New().String()
+	for _, price := range []float64{3, 1, 2, 2} {
+		product := newProduct(0)
+		product.Category = category
+		product.Price = price
+		created, err := repos.Products.Create(ctx, product)
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		defer repos.Products.Delete(ctx, created.ID)
+	}
+	outside, err := repos.Products.Create(ctx, newProduct(1))
+	if err != nil {
+		return fmt.Errorf("create outside the category: %w", err)
+	}
+	defer repos.Products.Delete(ctx, outside.ID)
+
+	query := usecase.ProductQuery{
+		ProductFilter: usecase.ProductFilter{Category: category},
+		Sort:          usecase.Sort{Field: "price", Desc: true},
+		Limit:         2,
+	}
+	first, err := repos.Products.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("first page: %w", err)
+	}
+	if len(first) != 2 || first[0].Price != 3 || first[1].Price != 2 {
+		return fmt.Errorf("first page has prices %v, want [3 2]", prices(first))
+	}
+
+	last := first[len(first)-1]
+	query.After = &usecase.Cursor{Value: last.Price, ID: last.ID}
+	second, err := repos.Products.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("second page: %w", err)
+	}
+	if len(second) != 2 || second[0].Price != 2 || second[1].Price != 1 || second[0].ID == last.ID {
+		return fmt.Errorf("second page has prices %v, want [2 1] without repeating the cursor", prices(second))
+	}
+
+	min, max := 1.5, 2.5
+	query = usecase.ProductQuery{
+		ProductFilter: usecase.ProductFilter{Category: category, MinPrice: &min, MaxPrice: &max},
+		Sort:          usecase.Sort{Field: "created_at"},
+		Limit:         10,
+	}
+	ranged, err := repos.Products.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("price range: %w", err)
+	}
+	if len(ranged) != 2 {
+		return fmt.Errorf("price range returned %d products, want 2", len(ranged))
+	}
+	return nil
+}
+
+func testSearch(ctx context.Context, repos usecase.Repositories) error {
+	product := newProduct(1)
+	product.Name = "Zanzibar storm lantern"
+	product, err := repos.Products.Create(ctx, product)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer repos.Products.Delete(ctx, product.ID)
+
+	for _, query := range []string{"zanzibar lantern", "zanzibra lantren"} {
+		matches, err := repos.Products.Search(ctx, query, usecase.MaxPageLimit)
+		if err != nil {
+			return fmt.Errorf("search %q: %w", query, err)
+		}
+		found := false
+		for _, match := range matches {
+			found = found || match.ID == product.ID
+		}
+		if !found {
+			return fmt.Errorf("search %q did not find the product", query)
+		}
+	}
+	return nil
+}
+
+func testTransactions(ctx context.Context, repos usecase.Repositories) error {
+	errRollback := errors.New("rollback")
+
+	var rolledBack string
+	err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		product, err := repos.Products.Create(ctx, newProduct(1))
+		if err != nil {
+			return err
+		}
+		rolledBack = product.ID
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("transaction returned %v, want the error from fn", err)
+	}
+	if _, err := repos.Products.FindByID(ctx, rolledBack); err == nil {
+		repos.Products.Delete(ctx, rolledBack)
+		return errors.New("write survived a rolled back transaction")
+	}
+
+	var committed string
+	err = repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		product, err := repos.Products.Create(ctx, newProduct(1))
+		if err != nil {
+			return err
+		}
+		committed = product.ID
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	defer repos.Products.Delete(ctx, committed)
+	if _, err := repos.Products.FindByID(ctx, committed); err != nil {
+		return fmt.Errorf("committed write not found: %w", err)
+	}
+	return nil
+}
+
+func testIdempotency(ctx context.Context, repos usecase.Repositories) error {
+	created := now()
+	record := &entity.IdempotencyRecord{
+		Key:         "repotest-" + uuid.New().String(),
+		Fingerprint: "repotest",
+		CreatedAt:   created,
+		ExpiresAt:   created.Add(time.Hour),
+	}
+	if err := repos.Idempotency.Create(ctx, record); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer repos.Idempotency.Delete(ctx, record.Key)
+	if err := repos.Idempotency.Create(ctx, record); !errors.Is(err, usecase.ErrConflict) {
+		return fmt.Errorf("create of a held key returned %v, want ErrConflict", err)
+	}
+
+	// Completing a claim keeps the response past the claim's own expiry
+	response := &entity.IdempotencyRecord{Key: record.Key, Status: 201, ContentType: "application/json", Body: []byte(`{"id":"1"}`), ExpiresAt: created.Add(24 * time.Hour)}
+	if err := repos.Idempotency.Complete(ctx, response); err != nil {
+		return fmt.Errorf("complete: %w", err)
+	}
+	found, err := repos.Idempotency.FindByKey(ctx, record.Key)
+	if err != nil {
+		return fmt.Errorf("find by key: %w", err)
+	}
+	if found.Fingerprint != record.Fingerprint || found.Status != response.Status ||
+		found.ContentType != response.ContentType || string(found.Body) != string(response.Body) ||
+		!found.ExpiresAt.Equal(response.ExpiresAt) {
+		return fmt.Errorf("find by key returned %+v, want the completed response", found)
+	}
+
+	if err := repos.Idempotency.Delete(ctx, record.Key); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Idempotency.FindByKey(ctx, record.Key); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by key after delete returned %v, want ErrNotFound", err)
+	}
+
+	// An expired record is invisible and gives way to a new claim
+	expired := *record
+	expired.ExpiresAt = created.Add(-time.Minute)
+	if err := repos.Idempotency.Create(ctx, &expired); err != nil {
+		return fmt.Errorf("create expired: %w", err)
+	}
+	if _, err := repos.Idempotency.FindByKey(ctx, record.Key); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by key of an expired record returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Idempotency.Create(ctx, record); err != nil {
+		return fmt.Errorf("create over an expired record: %w", err)
+	}
+	return nil
+}
+
+func testAudit(ctx context.Context, repos usecase.Repositories) error {
+	entityID := "repotest-" + uuid.New().String()
+	created := now()
+	var appended []*entity.AuditEntry
+	for i, action := range []entity.AuditAction{entity.AuditActionCreate, entity.AuditActionUpdate, entity.AuditActionDelete} {
+		entry := &entity.AuditEntry{
+			ActorID:    "repotest",
+			ActorRole:  entity.RoleAdmin,
+			Action:     action,
+			EntityType: "repotest",
+			EntityID:   entityID,
+			Changes: map[string]entity.FieldChange{
+				"price": {Before: []byte(fmt.Sprint(i)), After: []byte(fmt.Sprint(i + 1))},
+			},
+			RequestID: "repotest",
+			CreatedAt: created.Add(time.Duration(i) * time.Second),
+		}
+		if err := repos.Audit.Append(ctx, entry); err != nil {
+			return fmt.Errorf("append: %w", err)
+		}
+		if entry.ID == "" {
+			return errors.New("append did not assign an ID")
+		}
+		appended = append(appended, entry)
+	}
+
+	// The rolled back entry must not show up below
+	errRollback := errors.New("rollback")
+	err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		entry := *appended[0]
+		if err := repos.Audit.Append(ctx, &entry); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("transaction returned %v, want the error from fn", err)
+	}
+
+	query := usecase.AuditQuery{
+		AuditFilter: usecase.AuditFilter{EntityType: "repotest", EntityID: entityID},
+		Sort:        usecase.Sort{Field: "created_at"},
+		Limit:       2,
+	}
+	first, err := repos.Audit.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all: %w", err)
+	}
+	if len(first) != 2 || first[0].ID != appended[0].ID || first[1].ID != appended[1].ID {
+		return fmt.Errorf("first page returned %d entries, want the first two appended", len(first))
+	}
+	found := first[0]
+	if found.Action != entity.AuditActionCreate || found.ActorID != "repotest" || found.RequestID != "repotest" ||
+		!found.CreatedAt.Equal(created) || string(found.Changes["price"].After) != "1" {
+		return fmt.Errorf("find all returned %+v, want %+v", found, *appended[0])
+	}
+
+	query.After = &usecase.Cursor{Value: first[1].CreatedAt, ID: first[1].ID}
+	rest, err := repos.Audit.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all after cursor: %w", err)
+	}
+	if len(rest) != 1 || rest[0].ID != appended[2].ID {
+		return fmt.Errorf("second page returned %d entries, want only the last appended", len(rest))
+	}
+
+	from, to := created.Add(time.Second), created.Add(2*time.Second)
+	query = usecase.AuditQuery{
+		AuditFilter: usecase.AuditFilter{EntityID: entityID, ActorID: "repotest", From: &from, To: &to},
+		Sort:        usecase.Sort{Field: "created_at", Desc: true},
+		Limit:       10,
+	}
+	ranged, err := repos.Audit.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all in a time range: %w", err)
+	}
+	if len(ranged) != 1 || ranged[0].ID != appended[1].ID {
+		return fmt.Errorf("find all in a time range returned %d entries, want only the update", len(ranged))
+	}
+	return nil
+}
+
+func testOutbox(ctx context.Context, repos usecase.Repositories) error {
+	// Events dated long ago are claimed before any a live database holds
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var appended []*entity.Event
+	for i := range 3 {
+		event := &entity.Event{
+			Type:          "Repotest",
+			AggregateType: "repotest",
+			AggregateID:   "repotest-" + uuid.New().String(),
+			Payload:       []byte(fmt.Sprint(i)),
+			RequestID:     "repotest",
+			CreatedAt:     base.Add(time.Duration(i) * time.Second),
+			Status:        entity.EventStatusPending,
+			NextAttemptAt: base,
+		}
+		if err := repos.Outbox.Append(ctx, event); err != nil {
+			return fmt.Errorf("append: %w", err)
+		}
+		if event.ID == "" {
+			return errors.New("append did not assign an ID")
+		}
+		appended = append(appended, event)
+	}
+	defer func() {
+		for _, event := range appended {
+			delivered := base
+			event.Status, event.DeliveredAt = entity.EventStatusDelivered, &delivered
+			repos.Outbox.UpdateDelivery(ctx, event)
+		}
+		repos.Outbox.PurgeDelivered(ctx, base.Add(time.Hour))
+	}()
+
+	errRollback := errors.New("rollback")
+	var rolledBack string
+	err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		event := *appended[0]
+		if err := repos.Outbox.Append(ctx, &event); err != nil {
+			return err
+		}
+		rolledBack = event.ID
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("transaction returned %v, want the error from fn", err)
+	}
+	if _, err := repos.Outbox.FindByID(ctx, rolledBack); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of a rolled back event returned %v, want ErrNotFound", err)
+	}
+
+	until := now().Add(time.Hour)
+	claimed, err := repos.Outbox.Claim(ctx, now(), until, 2)
+	if err != nil {
+		return fmt.Errorf("claim: %w", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != appended[0].ID || claimed[1].ID != appended[1].ID {
+		return fmt.Errorf("claim returned %d events, want the first two appended", len(claimed))
+	}
+	if !claimed[0].NextAttemptAt.Equal(until) || string(claimed[0].Payload) != "0" {
+		return fmt.Errorf("claim returned %+v, want the event leased until %s", claimed[0], until)
+	}
+
+	// Leased events are left alone until the lease runs out
+	next, err := repos.Outbox.Claim(ctx, now(), until, 1)
+	if err != nil {
+		return fmt.Errorf("claim again: %w", err)
+	}
+	if len(next) != 1 || next[0].ID != appended[2].ID {
+		return fmt.Errorf("claim again returned %d events, want only the last appended", len(next))
+	}
+
+	delivered := base.Add(time.Minute)
+	event := claimed[0]
+	event.Status, event.Attempts, event.LastError, event.DeliveredAt = entity.EventStatusDelivered, 2, "", &delivered
+	if err := repos.Outbox.UpdateDelivery(ctx, &event); err != nil {
+		return fmt.Errorf("update delivery: %w", err)
+	}
+	dead := claimed[1]
+	dead.Status, dead.Attempts, dead.LastError = entity.EventStatusDead, 10, "repotest failure"
+	if err := repos.Outbox.UpdateDelivery(ctx, &dead); err != nil {
+		return fmt.Errorf("update delivery of a dead event: %w", err)
+	}
+	found, err := repos.Outbox.FindByID(ctx, dead.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.Status != entity.EventStatusDead || found.Attempts != 10 || found.LastError != "repotest failure" ||
+		found.RequestID != "repotest" || found.DeliveredAt != nil {
+		return fmt.Errorf("find by ID returned %+v, want %+v", found, dead)
+	}
+	unknown := entity.Event{ID: uuid.New().String(), Status: entity.EventStatusDead}
+	if err := repos.Outbox.UpdateDelivery(ctx, &unknown); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update delivery of an unknown event returned %v, want ErrNotFound", err)
+	}
+
+	query := usecase.EventQuery{
+		EventFilter: usecase.EventFilter{Status: entity.EventStatusDead, Type: "Repotest"},
+		Sort:        usecase.Sort{Field: "created_at"},
+		Limit:       10,
+	}
+	deadEvents, err := repos.Outbox.FindAll(ctx, query)
+	if err != nil {
+		return fmt.Errorf("find all: %w", err)
+	}
+	if len(deadEvents) != 1 || deadEvents[0].ID != dead.ID {
+		return fmt.Errorf("find all dead events returned %d events, want only the dead one", len(deadEvents))
+	}
+
+	purged, err := repos.Outbox.PurgeDelivered(ctx, delivered.Add(time.Second))
+	if err != nil {
+		return fmt.Errorf("purge delivered: %w", err)
+	}
+	if purged != 1 {
+		return fmt.Errorf("purge delivered removed %d events, want 1", purged)
+	}
+	if _, err := repos.Outbox.FindByID(ctx, event.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID after purge returned %v, want ErrNotFound", err)
+	}
+	if _, err := repos.Outbox.FindByID(ctx, dead.ID); err != nil {
+		return fmt.Errorf("purge delivered removed a dead event: %w", err)
+	}
+	return nil
+}
+
+func testWebhooks(ctx context.Context, repos usecase.Repositories) error {
+	created := now()
+	webhook := &entity.Webhook{
+		OwnerID:    "repotest-" + uuid.New().String(),
+		URL:        "http://localhost/repotest",
+		EventTypes: []entity.EventType{"RepotestA", "RepotestB"},
+		Secret:     "repotest secret",
+		CreatedAt:  created,
+		UpdatedAt:  created,
+	}
+	if _, err := repos.Webhooks.Create(ctx, webhook); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if webhook.ID == "" {
+		return errors.New("create did not assign an ID")
+	}
+	defer repos.Webhooks.Delete(ctx, webhook.ID)
+
+	found, err := repos.Webhooks.FindByID(ctx, webhook.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.Secret != webhook.Secret || len(found.EventTypes) != 2 || found.EventTypes[1] != "RepotestB" {
+		return fmt.Errorf("find by ID returned %+v, want %+v", found, webhook)
+	}
+	owned, err := repos.Webhooks.FindAll(ctx, usecase.WebhookQuery{
+		WebhookFilter: usecase.WebhookFilter{OwnerID: webhook.OwnerID},
+		Sort:          usecase.Sort{Field: "created_at"},
+		Limit:         10,
+	})
+	if err != nil {
+		return fmt.Errorf("find all: %w", err)
+	}
+	if len(owned) != 1 || owned[0].ID != webhook.ID {
+		return fmt.Errorf("find all by owner returned %d webhooks, want only the one created", len(owned))
+	}
+
+	webhook.EventTypes = []entity.EventType{"RepotestB"}
+	webhook.UpdatedAt = created.Add(time.Second)
+	if err := repos.Webhooks.Update(ctx, webhook); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	for eventType, want := range map[entity.EventType]bool{"RepotestA": false, "RepotestB": true} {
+		subscribed, err := repos.Webhooks.FindSubscribed(ctx, eventType)
+		if err != nil {
+			return fmt.Errorf("find subscribed: %w", err)
+		}
+		got := slices.ContainsFunc(subscribed, func(w entity.Webhook) bool { return w.ID == webhook.ID })
+		if got != want {
+			return fmt.Errorf("find subscribed to %s returned the webhook: %t, want %t", eventType, got, want)
+		}
+	}
+
+	// Deliveries dated long ago are claimed before any a live database holds
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var deliveries []*entity.WebhookDelivery
+	for i := range 2 {
+		delivery := &entity.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       uuid.New().String(),
+			EventType:     "RepotestB",
+			Body:          []byte(`{"n":` + fmt.Sprint(i) + `}`),
+			Status:        entity.WebhookDeliveryPending,
+			NextAttemptAt: base,
+			CreatedAt:     base.Add(time.Duration(i) * time.Second),
+		}
+		if err := repos.Deliveries.Create(ctx, delivery); err != nil {
+			return fmt.Errorf("create delivery: %w", err)
+		}
+		if delivery.ID == "" {
+			return errors.New("create delivery did not assign an ID")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	defer repos.Deliveries.DeleteByWebhook(ctx, webhook.ID)
+	duplicate := *deliveries[0]
+	if err := repos.Deliveries.Create(ctx, &duplicate); !errors.Is(err, usecase.ErrConflict) {
+		return fmt.Errorf("create of a second delivery of an event returned %v, want ErrConflict", err)
+	}
+
+	until := now().Add(time.Hour)
+	claimed, err := repos.Deliveries.Claim(ctx, now(), until, 1)
+	if err != nil {
+		return fmt.Errorf("claim: %w", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != deliveries[0].ID || !claimed[0].NextAttemptAt.Equal(until) ||
+		string(claimed[0].Body) != `{"n":0}` {
+		return fmt.Errorf("claim returned %+v, want the first delivery leased until %s", claimed, until)
+	}
+	next, err := repos.Deliveries.Claim(ctx, now(), until, 1)
+	if err != nil {
+		return fmt.Errorf("claim again: %w", err)
+	}
+	if len(next) != 1 || next[0].ID != deliveries[1].ID {
+		return fmt.Errorf("claim again returned %d deliveries, want only the second", len(next))
+	}
+
+	delivered := base.Add(time.Minute)
+	delivery := claimed[0]
+	delivery.Status, delivery.AttemptCount, delivery.DeliveredAt = entity.WebhookDeliverySucceeded, 2, &delivered
+	delivery.Attempts = []entity.DeliveryAttempt{
+		{At: base, Error: "connection refused", DurationMS: 3},
+		{At: delivered, StatusCode: 204, DurationMS: 5},
+	}
+	if err := repos.Deliveries.UpdateDelivery(ctx, &delivery); err != nil {
+		return fmt.Errorf("update delivery: %w", err)
+	}
+	stored, err := repos.Deliveries.FindByID(ctx, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("find delivery by ID: %w", err)
+	}
+	if stored.Status != entity.WebhookDeliverySucceeded || stored.AttemptCount != 2 || len(stored.Attempts) != 2 ||
+		stored.Attempts[1].StatusCode != 204 || stored.Attempts[0].Error != "connection refused" || stored.DeliveredAt == nil {
+		return fmt.Errorf("find delivery by ID returned %+v, want %+v", stored, delivery)
+	}
+
+	succeeded, err := repos.Deliveries.FindAll(ctx, usecase.WebhookDeliveryQuery{
+		WebhookDeliveryFilter: usecase.WebhookDeliveryFilter{WebhookID: webhook.ID, Status: entity.WebhookDeliverySucceeded},
+		Sort:                  usecase.Sort{Field: "created_at"},
+		Limit:                 10,
+	})
+	if err != nil {
+		return fmt.Errorf("find all deliveries: %w", err)
+	}
+	if len(succeeded) != 1 || succeeded[0].ID != delivery.ID {
+		return fmt.Errorf("find all succeeded deliveries returned %d deliveries, want only the first", len(succeeded))
+	}
+
+	if err := repos.Deliveries.DeleteByWebhook(ctx, webhook.ID); err != nil {
+		return fmt.Errorf("delete deliveries: %w", err)
+	}
+	if _, err := repos.Deliveries.FindByID(ctx, delivery.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find delivery by ID after delete returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Webhooks.Delete(ctx, webhook.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Webhooks.FindByID(ctx, webhook.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID after delete returned %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func containsProduct(products []entity.Product, id string) bool {
+	for _, product := range products {
+		if product.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func prices(products []entity.Product) []float64 {
+	var result []float64
+	for _, product := range products {
+		result = append(result, product.Price)
+	}
+	return result
+}
+
+func testCustomers(ctx context.Context, repos usecase.Repositories) error {
+	created := now()
+	userID := "repotest-" + uuid.New().String()
+	customer := &entity.Customer{
+		UserID: userID,
+		Name:   "Repotest Customer",
+		Email:  "repotest@example.com",
+		Phone:  "+14155550123",
+		Addresses: []entity.Address{
+			{Label: "billing", Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US"},
+		},
+		Status:    entity.CustomerStatusActive,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if _, err := repos.Customers.Create(ctx, customer); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if customer.ID == "" {
+		return errors.New("create did not assign an ID")
+	}
+	defer repos.Customers.Delete(ctx, customer.ID)
+
+	found, err := repos.Customers.FindByID(ctx, customer.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.UserID != userID || found.Phone != customer.Phone || len(found.Addresses) != 1 ||
+		found.Addresses[0] != customer.Addresses[0] || !found.CreatedAt.Equal(created) {
+		return fmt.Errorf("find by ID returned %+v, want %+v", found, customer)
+	}
+
+	customer.Name = "Repotest Customer Renamed"
+	customer.Addresses = append(customer.Addresses, entity.Address{Line1: "2 Side St", City: "Shelbyville", PostalCode: "54321", Country: "US"})
+	customer.Status = entity.CustomerStatusInactive
+	customer.UpdatedAt = created.Add(time.Second)
+	if err := repos.Customers.Update(ctx, customer); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	for status, want := range map[entity.CustomerStatus]int{entity.CustomerStatusActive: 0, entity.CustomerStatusInactive: 1} {
+		listed, err := repos.Customers.FindAll(ctx, usecase.CustomerQuery{
+			CustomerFilter: usecase.CustomerFilter{UserID: userID, Status: status},
+			Sort:           usecase.Sort{Field: "name"},
+			Limit:          10,
+		})
+		if err != nil {
+			return fmt.Errorf("find all: %w", err)
+		}
+		if len(listed) != want {
+			return fmt.Errorf("find all %s customers returned %d, want %d", status, len(listed), want)
+		}
+		if want == 1 && (listed[0].Name != customer.Name || len(listed[0].Addresses) != 2) {
+			return fmt.Errorf("find all returned %+v, want the updated customer", listed[0])
+		}
+	}
+
+	if err := repos.Customers.Delete(ctx, customer.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Customers.FindByID(ctx, customer.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID after delete returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Customers.Update(ctx, customer); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update after delete returned %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func testCarts(ctx context.Context, repos usecase.Repositories) error {
+	created := now()
+	cart := &entity.Cart{
+		UserID:    "repotest-" + uuid.New().String(),
+		Items:     []entity.CartItem{{ProductID: "repotest-product", Quantity: 2, UnitPrice: 9.5}},
+		CreatedAt: created,
+		UpdatedAt: created,
+		ExpiresAt: created.Add(time.Hour),
+	}
+	if _, err := repos.Carts.Create(ctx, cart); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if cart.ID == "" {
+		return errors.New("create did not assign an ID")
+	}
+	defer repos.Carts.Delete(ctx, cart.ID)
+
+	found, err := repos.Carts.FindByID(ctx, cart.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.UserID != cart.UserID || len(found.Items) != 1 || found.Items[0].ProductID != "repotest-product" ||
+		found.Items[0].Quantity != 2 || found.Items[0].UnitPrice != 9.5 || !found.ExpiresAt.Equal(cart.ExpiresAt) {
+		return fmt.Errorf("find by ID returned %+v, want %+v", found, cart)
+	}
+
+	cart.Items = append(cart.Items, entity.CartItem{ProductID: "repotest-other", Quantity: 1, UnitPrice: 3})
+	cart.UpdatedAt = created.Add(time.Second)
+	cart.ExpiresAt = created.Add(2 * time.Hour)
+	if err := repos.Carts.Update(ctx, cart); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	found, err = repos.Carts.FindByID(ctx, cart.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID after update: %w", err)
+	}
+	if len(found.Items) != 2 || !found.UpdatedAt.Equal(cart.UpdatedAt) || !found.ExpiresAt.Equal(cart.ExpiresAt) {
+		return fmt.Errorf("find by ID after update returned %+v, want %+v", found, cart)
+	}
+
+	// An expired cart is gone for every lookup and write, and is purged
+	expired := &entity.Cart{UserID: cart.UserID, Items: []entity.CartItem{}, CreatedAt: created, UpdatedAt: created, ExpiresAt: created.Add(-time.Minute)}
+	if _, err := repos.Carts.Create(ctx, expired); err != nil {
+		return fmt.Errorf("create expired: %w", err)
+	}
+	if _, err := repos.Carts.FindByID(ctx, expired.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID of an expired cart returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Carts.Update(ctx, expired); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update of an expired cart returned %v, want ErrNotFound", err)
+	}
+	purged, err := repos.Carts.DeleteExpired(ctx, created)
+	if err != nil {
+		return fmt.Errorf("delete expired: %w", err)
+	}
+	if purged < 1 {
+		return fmt.Errorf("delete expired removed %d carts, want at least 1", purged)
+	}
+	if _, err := repos.Carts.FindByID(ctx, cart.ID); err != nil {
+		return fmt.Errorf("delete expired removed a live cart: %w", err)
+	}
+
+	if err := repos.Carts.Delete(ctx, cart.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Carts.FindByID(ctx, cart.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID after delete returned %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func testReservations(ctx context.Context, repos usecase.Repositories) error {
+	product, err := repos.Products.Create(ctx, newProduct(5))
+	if err != nil {
+		return fmt.Errorf("create product: %w", err)
+	}
+	defer repos.Products.Delete(ctx, product.ID)
+
+	// Reserved stock is no longer available, but stays on hand until committed
+	if err := repos.Products.Reserve(ctx, product.ID, 3); err != nil {
+		return fmt.Errorf("reserve within stock: %w", err)
+	}
+	if err := repos.Products.Reserve(ctx, product.ID, 3); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("reserve beyond available stock returned %v, want ErrInsufficientStock", err)
+	}
+	if err := repos.Products.DecrementStock(ctx, product.ID, 3); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("decrement of reserved stock returned %v, want ErrInsufficientStock", err)
+	}
+	if err := repos.Products.CommitReserved(ctx, product.ID, 2); err != nil {
+		return fmt.Errorf("commit reserved: %w", err)
+	}
+	if err := repos.Products.Unreserve(ctx, product.ID, 1); err != nil {
+		return fmt.Errorf("unreserve: %w", err)
+	}
+	if err := repos.Products.Unreserve(ctx, "repotest-missing", 1); err != nil {
+		return fmt.Errorf("unreserve of a missing product: %w", err)
+	}
+	found, err := repos.Products.FindByID(ctx, product.ID)
+	if err != nil {
+		return fmt.Errorf("find product by ID: %w", err)
+	}
+	if found.Stock != 3 || found.Reserved != 0 {
+		return fmt.Errorf("stock is %d with %d reserved, want 3 with 0 reserved", found.Stock, found.Reserved)
+	}
+
+	// Only products with unreserved stock count as in stock
+	category := "repotest-" + uuid.New().String()
+	var inStock string
+	for _, reserve := range []int{2, 1} {
+		product := newProduct(2)
+		product.Category = category
+		created, err := repos.Products.Create(ctx, product)
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		defer repos.Products.Delete(ctx, created.ID)
+		if err := repos.Products.Reserve(ctx, created.ID, reserve); err != nil {
+			return fmt.Errorf("reserve: %w", err)
+		}
+		inStock = created.ID
+	}
+	listed, err := repos.Products.FindAll(ctx, usecase.ProductQuery{
+		ProductFilter: usecase.ProductFilter{Category: category, InStock: true},
+		Sort:          usecase.Sort{Field: "created_at"},
+		Limit:         10,
+	})
+	if err != nil {
+		return fmt.Errorf("list in stock: %w", err)
+	}
+	if len(listed) != 1 || listed[0].ID != inStock {
+		return fmt.Errorf("in stock listing returned %d products, want only the one with unreserved stock", len(listed))
+	}
+
+	created := now()
+	holderID := "repotest-" + uuid.New().String()
+	live := &entity.Reservation{
+		ProductID:  product.ID,
+		Quantity:   2,
+		HolderType: entity.HolderOrder,
+		HolderID:   holderID,
+		CreatedAt:  created,
+		ExpiresAt:  created.Add(time.Hour),
+	}
+	if _, err := repos.Reservations.Create(ctx, live); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if live.ID == "" {
+		return errors.New("create did not assign an ID")
+	}
+	defer repos.Reservations.Delete(ctx, live.ID)
+	// Expired longer ago than anything else, so the first batch holds it
+	expired := &entity.Reservation{
+		ProductID:  product.ID,
+		Quantity:   1,
+		HolderType: entity.HolderOrder,
+		HolderID:   holderID,
+		CreatedAt:  created,
+		ExpiresAt:  created.AddDate(-10, 0, 0),
+	}
+	if _, err := repos.Reservations.Create(ctx, expired); err != nil {
+		return fmt.Errorf("create expired: %w", err)
+	}
+	defer repos.Reservations.Delete(ctx, expired.ID)
+
+	held, err := repos.Reservations.FindByHolder(ctx, entity.HolderOrder, holderID)
+	if err != nil {
+		return fmt.Errorf("find by holder: %w", err)
+	}
+	if len(held) != 2 {
+		return fmt.Errorf("find by holder returned %d reservations, want 2", len(held))
+	}
+	if held, err := repos.Reservations.FindByHolder(ctx, entity.HolderCart, holderID); err != nil || len(held) != 0 {
+		return fmt.Errorf("find by holder of another holder type returned %d reservations and %v, want none", len(held), err)
+	}
+
+	due, err := repos.Reservations.FindExpired(ctx, created, 100)
+	if err != nil {
+		return fmt.Errorf("find expired: %w", err)
+	}
+	if !slices.ContainsFunc(due, func(r entity.Reservation) bool { return r.ID == expired.ID }) {
+		return errors.New("find expired missed an expired reservation")
+	}
+	if slices.ContainsFunc(due, func(r entity.Reservation) bool { return r.ID == live.ID }) {
+		return errors.New("find expired returned a live reservation")
+	}
+
+	// Only one of two concurrent releases may give the stock back
+	if err := repos.Reservations.Delete(ctx, expired.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if err := repos.Reservations.Delete(ctx, expired.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("second delete returned %v, want ErrNotFound", err)
+	}
+	held, err = repos.Reservations.FindByHolder(ctx, entity.HolderOrder, holderID)
+	if err != nil {
+		return fmt.Errorf("find by holder after delete: %w", err)
+	}
+	if len(held) != 1 || held[0].ID != live.ID || held[0].Quantity != 2 || !held[0].ExpiresAt.Equal(live.ExpiresAt) {
+		return fmt.Errorf("find by holder after delete returned %+v, want only %+v", held, live)
+	}
+	return nil
+}
+
+func testWarehouses(ctx context.Context, repos usecase.Repositories) error {
+	created := now()
+	// Priorities above any real warehouse's, so that the two sort last
+	second := &entity.Warehouse{Name: "Repotest Second", Country: "DE", Latitude: 52.52, Longitude: 13.405, Priority: 1000001, CreatedAt: created, UpdatedAt: created}
+	first := &entity.Warehouse{Name: "Repotest First", Country: "US", Latitude: 40.7128, Longitude: -74.006, Priority: 1000000, CreatedAt: created, UpdatedAt: created}
+	for _, warehouse := range []*entity.Warehouse{second, first} {
+		if _, err := repos.Warehouses.Create(ctx, warehouse); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		if warehouse.ID == "" {
+			return errors.New("create did not assign an ID")
+		}
+		defer repos.Warehouses.Delete(ctx, warehouse.ID)
+	}
+
+	found, err := repos.Warehouses.FindByID(ctx, first.ID)
+	if err != nil {
+		return fmt.Errorf("find by ID: %w", err)
+	}
+	if found.Name != first.Name || found.Country != first.Country || found.Latitude != first.Latitude ||
+		found.Longitude != first.Longitude || found.Priority != first.Priority || !found.CreatedAt.Equal(created) {
+		return fmt.Errorf("find by ID returned %+v, want %+v", found, first)
+	}
+	all, err := repos.Warehouses.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("find all: %w", err)
+	}
+	i := slices.IndexFunc(all, func(w entity.Warehouse) bool { return w.ID == first.ID })
+	j := slices.IndexFunc(all, func(w entity.Warehouse) bool { return w.ID == second.ID })
+	if i < 0 || j < 0 || i > j {
+		return fmt.Errorf("find all put the warehouses at %d and %d, want both in priority order", i, j)
+	}
+
+	second.Priority = 999999
+	second.UpdatedAt = created.Add(time.Second)
+	if err := repos.Warehouses.Update(ctx, second); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if found, err := repos.Warehouses.FindByID(ctx, second.ID); err != nil || found.Priority != second.Priority {
+		return fmt.Errorf("find by ID after update returned %+v and %v, want priority %d", found, err, second.Priority)
+	}
+
+	// Stock levels move like a product's stock, one warehouse at a time
+	productID := "repotest-" + uuid.New().String()
+	for _, level := range []*entity.StockLevel{
+		{WarehouseID: first.ID, ProductID: productID, Stock: 5, UpdatedAt: created},
+		{WarehouseID: second.ID, ProductID: productID, Stock: 2, UpdatedAt: created},
+	} {
+		if err := repos.StockLevels.Set(ctx, level); err != nil {
+			return fmt.Errorf("set: %w", err)
+		}
+		defer repos.StockLevels.Delete(ctx, level.WarehouseID, productID)
+	}
+	if err := repos.StockLevels.Reserve(ctx, first.ID, productID, 4); err != nil {
+		return fmt.Errorf("reserve within stock: %w", err)
+	}
+	if err := repos.StockLevels.Reserve(ctx, first.ID, productID, 2); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("reserve beyond available stock returned %v, want ErrInsufficientStock", err)
+	}
+	if err := repos.StockLevels.DecrementStock(ctx, first.ID, productID, 2); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("decrement of reserved stock returned %v, want ErrInsufficientStock", err)
+	}
+	if err := repos.StockLevels.CommitReserved(ctx, first.ID, productID, 3); err != nil {
+		return fmt.Errorf("commit reserved: %w", err)
+	}
+	if err := repos.StockLevels.DecrementStock(ctx, second.ID, productID, 2); err != nil {
+		return fmt.Errorf("decrement within stock: %w", err)
+	}
+	if err := repos.StockLevels.IncrementStock(ctx, second.ID, productID, 1); err != nil {
+		return fmt.Errorf("increment: %w", err)
+	}
+	if err := repos.StockLevels.Reserve(ctx, "repotest-missing", productID, 1); !errors.Is(err, usecase.ErrInsufficientStock) {
+		return fmt.Errorf("reserve in a warehouse without the product returned %v, want ErrInsufficientStock", err)
+	}
+	if err := repos.StockLevels.Unreserve(ctx, "repotest-missing", productID, 1); err != nil {
+		return fmt.Errorf("unreserve in a warehouse without the product: %w", err)
+	}
+	// Setting the stock keeps what is reserved
+	if err := repos.StockLevels.Set(ctx, &entity.StockLevel{WarehouseID: first.ID, ProductID: productID, Stock: 4, UpdatedAt: created}); err != nil {
+		return fmt.Errorf("set existing: %w", err)
+	}
+
+	levels, err := repos.StockLevels.FindByProduct(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("find by product: %w", err)
+	}
+	got := make(map[string][2]int, len(levels))
+	for _, level := range levels {
+		got[level.WarehouseID] = [2]int{level.Stock, level.Reserved}
+	}
+	want := map[string][2]int{first.ID: {4, 1}, second.ID: {1, 0}}
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("find by product returned stock and reserved %v, want %v", got, want)
+	}
+	byWarehouse, err := repos.StockLevels.FindByWarehouse(ctx, first.ID)
+	if err != nil {
+		return fmt.Errorf("find by warehouse: %w", err)
+	}
+	if len(byWarehouse) != 1 || byWarehouse[0].ProductID != productID {
+		return fmt.Errorf("find by warehouse returned %+v, want the one level", byWarehouse)
+	}
+
+	if err := repos.StockLevels.Delete(ctx, second.ID, productID); err != nil {
+		return fmt.Errorf("delete level: %w", err)
+	}
+	if err := repos.StockLevels.Delete(ctx, second.ID, productID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("second delete of a level returned %v, want ErrNotFound", err)
+	}
+
+	// Holds remember the warehouse whose stock they hold
+	reservation := &entity.Reservation{
+		ProductID:   productID,
+		Quantity:    1,
+		HolderType:  entity.HolderOrder,
+		HolderID:    "repotest-" + uuid.New().String(),
+		WarehouseID: first.ID,
+		CreatedAt:   created,
+		ExpiresAt:   created.Add(time.Hour),
+	}
+	if _, err := repos.Reservations.Create(ctx, reservation); err != nil {
+		return fmt.Errorf("create reservation: %w", err)
+	}
+	defer repos.Reservations.Delete(ctx, reservation.ID)
+	held, err := repos.Reservations.FindByHolder(ctx, entity.HolderOrder, reservation.HolderID)
+	if err != nil {
+		return fmt.Errorf("find reservations by holder: %w", err)
+	}
+	if len(held) != 1 || held[0].WarehouseID != first.ID {
+		return fmt.Errorf("find reservations by holder returned %+v, want one in warehouse %s", held, first.ID)
+	}
+
+	if err := repos.Warehouses.Delete(ctx, second.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Warehouses.FindByID(ctx, second.ID); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("find by ID after delete returned %v, want ErrNotFound", err)
+	}
+	if err := repos.Warehouses.Update(ctx, second); !errors.Is(err, usecase.ErrNotFound) {
+		return fmt.Errorf("update after delete returned %v, want ErrNotFound", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type reservationRepo struct {
+	collection *mongo.Collection
+}
+
+func NewReservationRepository(collection *mongo.Collection) usecase.ReservationRepository {
+	return &reservationRepo{collection}
+}
+
+func (repo *reservationRepo) Create(ctx context.Context, reservation *entity.Reservation) (*entity.Reservation, error) {
+	reservation.ID = uuid.New().String()
+	if _, err := repo.collection.InsertOne(ctx, reservation); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+func (repo *reservationRepo) FindByHolder(ctx context.Context, holderType entity.HolderType, holderID string) ([]entity.Reservation, error) {
+	filter := bson.M{"holder_type": holderType, "holder_id": holderID}
+	return repo.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+func (repo *reservationRepo) FindExpired(ctx context.Context, before time.Time, limit int) ([]entity.Reservation, error) {
+	filter := bson.M{"expires_at": bson.M{"$lt": before}}
+	return repo.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}}).SetLimit(int64(limit)))
+}
+
+func (repo *reservationRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]entity.Reservation, error) {
+	cursor, err := repo.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []entity.Reservation
+	for cursor.Next(ctx) {
+		var reservation entity.Reservation
+		if err := cursor.Decode(&reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, nil
+}
+
+func (repo *reservationRepo) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("reservation", id)
+	}
+	return nil
+}
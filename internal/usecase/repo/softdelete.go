@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// softDelete marks the record of the kind with the ID deleted, unless it
+// already is. In queries a nil deleted_at matches both a missing field and an
+// explicit null.
+func softDelete(ctx context.Context, collection *mongo.Collection, kind, id string, at time.Time) error {
+	filter := bson.M{"id": id, "deleted_at": nil}
+	update := bson.M{
+		"$set": bson.M{"deleted_at": at, "updated_at": at},
+		"$inc": bson.M{"version": 1},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound(kind, id)
+	}
+	return nil
+}
+
+// restore clears the deletion of the deleted record of the kind with the ID.
+func restore(ctx context.Context, collection *mongo.Collection, kind, id string, at time.Time) error {
+	filter := bson.M{"id": id, "deleted_at": bson.M{"$ne": nil}}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": at},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound(kind, id)
+	}
+	return nil
+}
+
+// purge removes the records deleted before the given time.
+func purge(ctx context.Context, collection *mongo.Collection, before time.Time) (int64, error) {
+	result, err := collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
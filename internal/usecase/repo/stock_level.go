@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type stockLevelRepo struct {
+	collection *mongo.Collection
+}
+
+func NewStockLevelRepository(collection *mongo.Collection) usecase.StockLevelRepository {
+	return &stockLevelRepo{collection}
+}
+
+func (repo *stockLevelRepo) FindByProduct(ctx context.Context, productID string) ([]entity.StockLevel, error) {
+	return repo.find(ctx, bson.M{"product_id": productID}, "warehouse_id")
+}
+
+func (repo *stockLevelRepo) FindByWarehouse(ctx context.Context, warehouseID string) ([]entity.StockLevel, error) {
+	return repo.find(ctx, bson.M{"warehouse_id": warehouseID}, "product_id")
+}
+
+func (repo *stockLevelRepo) find(ctx context.Context, filter bson.M, sortKey string) ([]entity.StockLevel, error) {
+	cursor, err := repo.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: sortKey, Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var levels []entity.StockLevel
+	for cursor.Next(ctx) {
+		var level entity.StockLevel
+		if err := cursor.Decode(&level); err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+func (repo *stockLevelRepo) Set(ctx context.Context, level *entity.StockLevel) error {
+	update := bson.M{
+		"$set":         bson.M{"stock": level.Stock, "updated_at": level.UpdatedAt},
+		"$setOnInsert": bson.M{"reserved": 0},
+	}
+	_, err := repo.collection.UpdateOne(ctx, levelFilter(level.WarehouseID, level.ProductID), update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (repo *stockLevelRepo) Delete(ctx context.Context, warehouseID, productID string) error {
+	result, err := repo.collection.DeleteOne(ctx, levelFilter(warehouseID, productID))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("stock level", productID+" in warehouse "+warehouseID)
+	}
+	return nil
+}
+
+// unreservedAtLeast matches levels with at least quantity of stock that is
+// not reserved.
+func unreservedAtLeast(quantity int) bson.M {
+	return bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$stock", "$reserved"}}, quantity}}
+}
+
+func (repo *stockLevelRepo) DecrementStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	filter := levelFilter(warehouseID, productID)
+	filter["$expr"] = unreservedAtLeast(quantity)
+	return repo.move(ctx, filter, -quantity, 0, usecase.ErrInsufficientStock)
+}
+
+func (repo *stockLevelRepo) IncrementStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, levelFilter(warehouseID, productID), quantity, 0, nil)
+}
+
+func (repo *stockLevelRepo) Reserve(ctx context.Context, warehouseID, productID string, quantity int) error {
+	filter := levelFilter(warehouseID, productID)
+	filter["$expr"] = unreservedAtLeast(quantity)
+	return repo.move(ctx, filter, 0, quantity, usecase.ErrInsufficientStock)
+}
+
+func (repo *stockLevelRepo) Unreserve(ctx context.Context, warehouseID, productID string, quantity int) error {
+	return repo.move(ctx, levelFilter(warehouseID, productID), 0, -quantity, nil)
+}
+
+func (repo *stockLevelRepo) CommitReserved(ctx context.Context, warehouseID, productID string, quantity int) error {
+	filter := levelFilter(warehouseID, productID)
+	filter["stock"] = bson.M{"$gte": quantity}
+	return repo.move(ctx, filter, -quantity, -quantity, usecase.ErrInsufficientStock)
+}
+
+// move adds stock and reserved to the level matching the filter, returning
+// missing if none does.
+func (repo *stockLevelRepo) move(ctx context.Context, filter bson.M, stock, reserved int, missing error) error {
+	update := bson.M{
+		"$inc": bson.M{"stock": stock, "reserved": reserved},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return missing
+	}
+	return nil
+}
+
+func levelFilter(warehouseID, productID string) bson.M {
+	return bson.M{"warehouse_id": warehouseID, "product_id": productID}
+}
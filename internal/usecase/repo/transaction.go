@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"ulab3/internal/usecase"
+)
+
+type transactor struct {
+	client *mongo.Client
+}
+
+func NewTransactor(client *mongo.Client) usecase.Transactor {
+	return &transactor{client}
+}
+
+// WithinTransaction runs fn in a MongoDB session transaction. Repository calls
+// made with the session context passed to fn are part of the transaction, and
+// nested calls reuse the session that is already open.
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
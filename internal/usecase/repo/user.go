@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type userRepo struct {
+	collection *mongo.Collection
+}
+
+func NewUserRepository(collection *mongo.Collection) usecase.UserRepository {
+	return &userRepo{collection}
+}
+
+func (repo *userRepo) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	user.ID = uuid.New().String()
+	_, err := repo.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, usecase.ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (repo *userRepo) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	var user entity.User
+	err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&user)
+	if err != nil {
+		return nil, findError(err, "user", id)
+	}
+	return &user, nil
+}
+
+func (repo *userRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	var user entity.User
+	err := repo.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		return nil, findError(err, "user", email)
+	}
+	return &user, nil
+}
+
+func (repo *userRepo) UpdateRole(ctx context.Context, id string, role entity.Role, updatedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"role": role, "updated_at": updatedAt}}
+	_, err := repo.collection.UpdateOne(ctx, bson.M{"id": id}, update)
+	return err
+}
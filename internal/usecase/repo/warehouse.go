@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type warehouseRepo struct {
+	collection *mongo.Collection
+}
+
+func NewWarehouseRepository(collection *mongo.Collection) usecase.WarehouseRepository {
+	return &warehouseRepo{collection}
+}
+
+func (repo *warehouseRepo) Create(ctx context.Context, warehouse *entity.Warehouse) (*entity.Warehouse, error) {
+	warehouse.ID = uuid.New().String()
+	if _, err := repo.collection.InsertOne(ctx, warehouse); err != nil {
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+func (repo *warehouseRepo) FindByID(ctx context.Context, id string) (*entity.Warehouse, error) {
+	var warehouse entity.Warehouse
+	if err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&warehouse); err != nil {
+		return nil, findError(err, "warehouse", id)
+	}
+	return &warehouse, nil
+}
+
+func (repo *warehouseRepo) FindAll(ctx context.Context) ([]entity.Warehouse, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := repo.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var warehouses []entity.Warehouse
+	for cursor.Next(ctx) {
+		var warehouse entity.Warehouse
+		if err := cursor.Decode(&warehouse); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+	return warehouses, nil
+}
+
+func (repo *warehouseRepo) Update(ctx context.Context, warehouse *entity.Warehouse) error {
+	update := bson.M{"$set": bson.M{
+		"name":       warehouse.Name,
+		"country":    warehouse.Country,
+		"latitude":   warehouse.Latitude,
+		"longitude":  warehouse.Longitude,
+		"priority":   warehouse.Priority,
+		"updated_at": warehouse.UpdatedAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"id": warehouse.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("warehouse", warehouse.ID)
+	}
+	return nil
+}
+
+func (repo *warehouseRepo) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("warehouse", id)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+type webhookRepo struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookRepository(collection *mongo.Collection) usecase.WebhookRepository {
+	return &webhookRepo{collection}
+}
+
+func (repo *webhookRepo) Create(ctx context.Context, webhook *entity.Webhook) (*entity.Webhook, error) {
+	webhook.ID = uuid.New().String()
+	if _, err := repo.collection.InsertOne(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (repo *webhookRepo) FindByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	if err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&webhook); err != nil {
+		return nil, findError(err, "webhook", id)
+	}
+	return &webhook, nil
+}
+
+func (repo *webhookRepo) FindAll(ctx context.Context, query usecase.WebhookQuery) ([]entity.Webhook, error) {
+	filter := bson.M{}
+	if query.OwnerID != "" {
+		filter["owner_id"] = query.OwnerID
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
+	if err != nil {
+		return nil, err
+	}
+	return decodeWebhooks(ctx, cursor)
+}
+
+func (repo *webhookRepo) FindSubscribed(ctx context.Context, eventType entity.EventType) ([]entity.Webhook, error) {
+	cursor, err := repo.collection.Find(ctx, bson.M{"event_types": eventType})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWebhooks(ctx, cursor)
+}
+
+func (repo *webhookRepo) Update(ctx context.Context, webhook *entity.Webhook) error {
+	update := bson.M{"$set": bson.M{
+		"url":         webhook.URL,
+		"event_types": webhook.EventTypes,
+		"secret":      webhook.Secret,
+		"updated_at":  webhook.UpdatedAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"id": webhook.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("webhook", webhook.ID)
+	}
+	return nil
+}
+
+func (repo *webhookRepo) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound("webhook", id)
+	}
+	return nil
+}
+
+func decodeWebhooks(ctx context.Context, cursor *mongo.Cursor) ([]entity.Webhook, error) {
+	defer cursor.Close(ctx)
+
+	var webhooks []entity.Webhook
+	for cursor.Next(ctx) {
+		var webhook entity.Webhook
+		if err := cursor.Decode(&webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+type deliveryRepo struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookDeliveryRepository(collection *mongo.Collection) usecase.WebhookDeliveryRepository {
+	return &deliveryRepo{collection}
+}
+
+func (repo *deliveryRepo) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	delivery.ID = uuid.New().String()
+	_, err := repo.collection.InsertOne(ctx, delivery)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("delivery of event %s to webhook %s: %w", delivery.EventID, delivery.WebhookID, usecase.ErrConflict)
+	}
+	return err
+}
+
+// Claim leases due deliveries one at a time, so that concurrent workers never
+// claim the same delivery.
+func (repo *deliveryRepo) Claim(ctx context.Context, now, until time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	filter := bson.M{"status": entity.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": until}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var deliveries []entity.WebhookDelivery
+	for len(deliveries) < limit {
+		var delivery entity.WebhookDelivery
+		err := repo.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (repo *deliveryRepo) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	update := bson.M{"$set": bson.M{
+		"status":          delivery.Status,
+		"attempt_count":   delivery.AttemptCount,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+	}}
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"id": delivery.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound("webhook delivery", delivery.ID)
+	}
+	return nil
+}
+
+func (repo *deliveryRepo) FindByID(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	if err := repo.collection.FindOne(ctx, bson.M{"id": id}).Decode(&delivery); err != nil {
+		return nil, findError(err, "webhook delivery", id)
+	}
+	return &delivery, nil
+}
+
+func (repo *deliveryRepo) FindAll(ctx context.Context, query usecase.WebhookDeliveryQuery) ([]entity.WebhookDelivery, error) {
+	filter := bson.M{}
+	if query.WebhookID != "" {
+		filter["webhook_id"] = query.WebhookID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	keysetFilter(filter, query.Sort, query.After)
+
+	cursor, err := repo.collection.Find(ctx, filter, keysetOptions(query.Sort, query.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []entity.WebhookDelivery
+	for cursor.Next(ctx) {
+		var delivery entity.WebhookDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (repo *deliveryRepo) DeleteByWebhook(ctx context.Context, webhookID string) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"webhook_id": webhookID})
+	return err
+}
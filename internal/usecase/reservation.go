@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+)
+
+// holdSweepBatch is how many expired holds the sweeper releases at a time.
+const holdSweepBatch = 100
+
+// stockHolds places, releases and commits the stock reservations of orders
+// and carts. A hold moves the reserved stock together with the stored
+// reservation, so callers run it in a transaction.
+type stockHolds struct {
+	inventory       *inventory
+	reservationRepo ReservationRepository
+	ttl             time.Duration
+	logger          *slog.Logger
+}
+
+// holdItems holds the stock of the allocated lines for the holder: one hold
+// per allocation, or one for the whole line if it has none.
+func (h *stockHolds) holdItems(ctx context.Context, holderType entity.HolderType, holderID string, items []entity.OrderItem) error {
+	for _, item := range items {
+		for _, part := range parts(item) {
+			if err := h.hold(ctx, holderType, holderID, item.ProductID, part.WarehouseID, part.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hold reserves quantity of the product in the warehouse for the holder until
+// the hold TTL from now, failing with ErrInsufficientStock unless that much is
+// available.
+func (h *stockHolds) hold(ctx context.Context, holderType entity.HolderType, holderID, productID, warehouseID string, quantity int) error {
+	if err := h.inventory.reserve(ctx, productID, warehouseID, quantity); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reservation := &entity.Reservation{
+		ProductID:   productID,
+		Quantity:    quantity,
+		HolderType:  holderType,
+		HolderID:    holderID,
+		WarehouseID: warehouseID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(h.ttl),
+	}
+	if _, err := h.reservationRepo.Create(ctx, reservation); err != nil {
+		h.logger.Error("Failed to create reservation", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to create reservation: %w", err)
+	}
+	return nil
+}
+
+// release lets go of every hold of the holder.
+func (h *stockHolds) release(ctx context.Context, holderType entity.HolderType, holderID string) error {
+	reservations, err := h.find(ctx, holderType, holderID)
+	if err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		if _, err := h.drop(ctx, reservation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drop deletes the hold and gives its quantity back to the available stock.
+// It reports false, and leaves the stock alone, if somebody else released
+// the hold first.
+func (h *stockHolds) drop(ctx context.Context, reservation entity.Reservation) (bool, error) {
+	err := h.reservationRepo.Delete(ctx, reservation.ID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete reservation", "id", reservation.ID, "error", err)
+		return false, fmt.Errorf("failed to delete reservation: %w", err)
+	}
+	if err := h.inventory.unreserve(ctx, reservation.ProductID, reservation.WarehouseID, reservation.Quantity); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// commit turns every hold of the holder into a decrement of the stock and
+// returns the holds it committed.
+func (h *stockHolds) commit(ctx context.Context, holderType entity.HolderType, holderID string) ([]entity.Reservation, error) {
+	reservations, err := h.find(ctx, holderType, holderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var committed []entity.Reservation
+	for _, reservation := range reservations {
+		err := h.reservationRepo.Delete(ctx, reservation.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			h.logger.Error("Failed to delete reservation", "id", reservation.ID, "error", err)
+			return nil, fmt.Errorf("failed to delete reservation: %w", err)
+		}
+		if err := h.inventory.commit(ctx, reservation.ProductID, reservation.WarehouseID, reservation.Quantity); err != nil {
+			return nil, err
+		}
+		committed = append(committed, reservation)
+	}
+	return committed, nil
+}
+
+// held returns how much of each product the holder holds.
+func (h *stockHolds) held(ctx context.Context, holderType entity.HolderType, holderID string) (map[string]int, error) {
+	reservations, err := h.find(ctx, holderType, holderID)
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[string]int, len(reservations))
+	for _, reservation := range reservations {
+		held[reservation.ProductID] += reservation.Quantity
+	}
+	return held, nil
+}
+
+func (h *stockHolds) find(ctx context.Context, holderType entity.HolderType, holderID string) ([]entity.Reservation, error) {
+	reservations, err := h.reservationRepo.FindByHolder(ctx, holderType, holderID)
+	if err != nil {
+		h.logger.Error("Failed to fetch reservations", "holder_type", holderType, "holder_id", holderID, "error", err)
+		return nil, fmt.Errorf("failed to fetch reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// HoldSweeper releases the stock holds that expired, so that orders left
+// unpaid and carts left alone give their stock back to the catalog.
+type HoldSweeper struct {
+	holds    *stockHolds
+	tx       Transactor
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+// NewHoldSweeper returns a HoldSweeper that looks for expired holds every
+// interval.
+func NewHoldSweeper(productRepo ProductRepository, levelRepo StockLevelRepository, reservationRepo ReservationRepository, tx Transactor, interval time.Duration, logger *slog.Logger) *HoldSweeper {
+	inventory := &inventory{productRepo: productRepo, levelRepo: levelRepo, logger: logger}
+	return &HoldSweeper{
+		holds:    &stockHolds{inventory: inventory, reservationRepo: reservationRepo, logger: logger},
+		tx:       tx,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run releases expired holds until ctx is cancelled.
+func (s *HoldSweeper) Run(ctx context.Context) {
+	s.logger.Info("Hold sweeper started", "interval", s.interval)
+	poll(ctx, DispatcherConfig{Interval: s.interval, BatchSize: holdSweepBatch}, s.SweepBatch)
+	s.logger.Info("Hold sweeper stopped")
+}
+
+// SweepBatch releases one batch of expired holds and returns how many it
+// released.
+func (s *HoldSweeper) SweepBatch(ctx context.Context) int {
+	expired, err := s.holds.reservationRepo.FindExpired(ctx, time.Now(), holdSweepBatch)
+	if err != nil {
+		s.logger.Error("Failed to fetch expired reservations", "error", err)
+		return 0
+	}
+
+	released := 0
+	for _, reservation := range expired {
+		err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := s.holds.drop(ctx, reservation)
+			return err
+		})
+		if err != nil {
+			s.logger.Error("Failed to release expired hold", "id", reservation.ID, "error", err)
+			continue
+		}
+		released++
+	}
+	if released > 0 {
+		s.logger.Info("Released expired stock holds", "count", released)
+	}
+	return released
+}
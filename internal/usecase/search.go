@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"sort"
+	"ulab3/internal/entity"
+	"ulab3/pkg/search"
+)
+
+// Search weights: a match in the product name counts twice as much as one in
+// the category.
+const (
+	nameWeight     = 2
+	categoryWeight = 1
+)
+
+// ScoreProduct rates how well a product matches the query, for backends that
+// rank search results in process. Zero means no match.
+func ScoreProduct(query string, product entity.Product) float64 {
+	return search.Score(search.Tokenize(query),
+		search.Field{Text: product.Name, Weight: nameWeight},
+		search.Field{Text: product.Category, Weight: categoryWeight},
+	)
+}
+
+// RankProducts scores the candidates against the query and returns the
+// matching ones, best first, cut to limit.
+func RankProducts(query string, candidates []entity.Product, limit int) []entity.ProductMatch {
+	var matches []entity.ProductMatch
+	for _, product := range candidates {
+		if score := ScoreProduct(query, product); score > 0 {
+			matches = append(matches, entity.ProductMatch{Product: product, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
@@ -0,0 +1,65 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+)
+
+func TestRankProducts(t *testing.T) {
+	candidates := []entity.Product{
+		{ID: "category", Name: "Shade", Category: "Lamp"},
+		{ID: "typo", Name: "Lmap Stand"},
+		{ID: "prefix", Name: "Lamplight"},
+		{ID: "unrelated", Name: "Chair", Category: "Furniture"},
+		{ID: "exact", Name: "Desk Lamp"},
+		{ID: "tie", Name: "Floor Lamp"},
+	}
+	ids := func(matches []entity.ProductMatch) []string {
+		ids := []string{}
+		for _, match := range matches {
+			ids = append(ids, match.ID)
+		}
+		return ids
+	}
+
+	// Name matches outrank category ones, exact words outrank prefixes and
+	// typos, and ties keep the candidates' order
+	matches := usecase.RankProducts("lamp", candidates, 10)
+	if got, want := ids(matches), []string{"exact", "tie", "prefix", "typo", "category"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ranked %q, want %q", got, want)
+	}
+	for i := 1; i < len(matches); i++ {
+		if matches[i].Score > matches[i-1].Score {
+			t.Errorf("%s scores %v above %s at %v", matches[i].ID, matches[i].Score, matches[i-1].ID, matches[i-1].Score)
+		}
+	}
+	if got, want := ids(usecase.RankProducts("lamp", candidates, 2)), []string{"exact", "tie"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("limited to 2: %q, want %q", got, want)
+	}
+	for _, query := range []string{"", "  ", "sofa"} {
+		if matches := usecase.RankProducts(query, candidates, 10); len(matches) != 0 {
+			t.Errorf("query %q matched %q", query, ids(matches))
+		}
+	}
+}
+
+func TestSearchProductsRejectsEmptyQueries(t *testing.T) {
+	service := newProductService(memory.NewRepositories())
+	for _, query := range []string{"", " ", "\t\n"} {
+		if _, err := service.SearchProducts(context.Background(), query, 10); !errors.Is(err, usecase.ErrInvalidQuery) {
+			t.Errorf("query %q: %v, want ErrInvalidQuery", query, err)
+		}
+	}
+	result, err := service.SearchProducts(context.Background(), " lamp ", 0)
+	if err != nil {
+		t.Fatalf("SearchProducts: %v", err)
+	}
+	if result.Query != "lamp" || result.Data == nil {
+		t.Errorf("result = %+v, want the trimmed query and an empty list", result)
+	}
+}
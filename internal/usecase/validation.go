@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"math"
+	"reflect"
+	"strings"
+	"ulab3/internal/entity"
+)
+
+// validate checks structs against their binding tags, the same tags gin
+// checks when it binds a request body.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.SetTagName("binding")
+
+	// Report fields under their JSON names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// notblank rejects strings made only of white space
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	// cents rejects amounts with more than two decimal places
+	v.RegisterValidation("cents", func(fl validator.FieldLevel) bool {
+		cents := fl.Field().Float() * 100
+		return math.Abs(cents-math.Round(cents)) < 1e-6
+	})
+	return v
+}
+
+// Validate checks v against its binding tags and returns a *ValidationError
+// listing every field that failed. Values other than structs and pointers to
+// structs have no rules and always pass.
+func Validate(v any) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := validate.Struct(v)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	fields := make([]entity.FieldError, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		// Drop the struct name the namespace starts with
+		_, path, _ := strings.Cut(fe.Namespace(), ".")
+		fields[i] = entity.FieldError{Field: path, Message: ruleMessage(fe)}
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// ruleMessage describes a failed rule to the client.
+func ruleMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	if fe.Param() == "1" {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "cents":
+		return "must have at most two decimal places"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "min":
+		return fmt.Sprintf("must have at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must have at most %s%s", fe.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fe.Param())
+	case "email":
+		return "must be an email address"
+	case "http_url":
+		return "must be an http or https URL"
+	case "unique":
+		return "must not repeat items"
+	case "e164":
+		return "must be a phone number in E.164 format, such as +14155550123"
+	case "iso3166_1_alpha2":
+		return "must be an ISO 3166-1 alpha-2 country code"
+	}
+	return fmt.Sprintf("does not satisfy %s", fe.Tag())
+}
@@ -0,0 +1,100 @@
+package usecase_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+)
+
+func TestValidate(t *testing.T) {
+	item := entity.OrderItem{ProductID: "p1", Quantity: 1}
+	items := func(n int) []entity.OrderItem {
+		items := make([]entity.OrderItem, n)
+		for i := range items {
+			items[i] = item
+		}
+		return items
+	}
+	lat, lon := 52.52, 13.405
+
+	tests := []struct {
+		name  string
+		value any
+		want  []entity.FieldError // nil when the value is valid
+	}{
+		{name: "valid product", value: &entity.Product{Name: "Lamp", Price: 12.5, Stock: 3}},
+		{name: "product missing every field", value: &entity.Product{}, want: []entity.FieldError{
+			{Field: "name", Message: "is required"},
+			{Field: "price", Message: "must be greater than 0"},
+		}},
+		{name: "blank name", value: &entity.Product{Name: "  ", Price: 1}, want: []entity.FieldError{
+			{Field: "name", Message: "must not be blank"},
+		}},
+		{name: "price in fractions of a cent", value: &entity.Product{Name: "Lamp", Price: 1.005}, want: []entity.FieldError{
+			{Field: "price", Message: "must have at most two decimal places"},
+		}},
+		{name: "negative stock", value: &entity.Product{Name: "Lamp", Price: 1, Stock: -1}, want: []entity.FieldError{
+			{Field: "stock", Message: "must be at least 0"},
+		}},
+		{name: "valid order", value: &entity.Order{Items: []entity.OrderItem{item}}},
+		{name: "order without items", value: &entity.Order{}, want: []entity.FieldError{
+			{Field: "items", Message: "is required"},
+		}},
+		{name: "order with an empty item list", value: &entity.Order{Items: []entity.OrderItem{}}, want: []entity.FieldError{
+			{Field: "items", Message: "must have at least 1 item"},
+		}},
+		{name: "largest order", value: &entity.Order{Items: items(100)}},
+		{name: "too many items", value: &entity.Order{Items: items(101)}, want: []entity.FieldError{
+			{Field: "items", Message: "must have at most 100 items"},
+		}},
+		{name: "quantities out of range", value: &entity.Order{Items: []entity.OrderItem{
+			{ProductID: "p1", Quantity: 0},
+			{ProductID: "p2", Quantity: 10000},
+			{ProductID: "p3", Quantity: 10001},
+		}}, want: []entity.FieldError{
+			{Field: "items[0].quantity", Message: "must be at least 1"},
+			{Field: "items[2].quantity", Message: "must be at most 10000"},
+		}},
+		{name: "item missing its product", value: &entity.Order{Items: []entity.OrderItem{{Quantity: -2}}}, want: []entity.FieldError{
+			{Field: "items[0].product_id", Message: "is required"},
+			{Field: "items[0].quantity", Message: "must be at least 1"},
+		}},
+		{name: "cart quantity", value: &entity.CartQuantity{Quantity: 10001}, want: []entity.FieldError{
+			{Field: "quantity", Message: "must be at most 10000"},
+		}},
+		{name: "address with coordinates", value: &entity.Address{Line1: "1 Main St", City: "Berlin", PostalCode: "10115", Country: "DE", Latitude: &lat, Longitude: &lon}},
+		{name: "customer with a bad address", value: &entity.Customer{Name: "Ann", Email: "ann", Addresses: []entity.Address{{Country: "Germany"}}}, want: []entity.FieldError{
+			{Field: "email", Message: "must be an email address"},
+			{Field: "addresses[0].line1", Message: "is required"},
+			{Field: "addresses[0].city", Message: "is required"},
+			{Field: "addresses[0].postal_code", Message: "is required"},
+			{Field: "addresses[0].country", Message: "must be an ISO 3166-1 alpha-2 country code"},
+		}},
+		{name: "nil pointer", value: (*entity.Product)(nil)},
+		{name: "not a struct", value: 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := usecase.Validate(tt.value)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+
+			var validationErr *usecase.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if !errors.Is(err, usecase.ErrValidation) {
+				t.Errorf("%v does not wrap ErrValidation", err)
+			}
+			if !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("fields = %+v, want %+v", validationErr.Fields, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+)
+
+// WarehouseService manages the warehouses products ship from and the stock
+// each keeps. Once a product is stocked in a warehouse its stock is the sum
+// of its stock levels, which this service keeps up to date.
+type WarehouseService struct {
+	warehouseRepo WarehouseRepository
+	levelRepo     StockLevelRepository
+	productRepo   ProductRepository
+	outboxRepo    OutboxRepository
+	broker        *Broker
+	tx            Transactor
+	logger        *slog.Logger
+}
+
+func NewWarehouseService(warehouseRepo WarehouseRepository, levelRepo StockLevelRepository, productRepo ProductRepository, outboxRepo OutboxRepository, broker *Broker, tx Transactor, logger *slog.Logger) *WarehouseService {
+	return &WarehouseService{
+		warehouseRepo: warehouseRepo,
+		levelRepo:     levelRepo,
+		productRepo:   productRepo,
+		outboxRepo:    outboxRepo,
+		broker:        broker,
+		tx:            tx,
+		logger:        logger,
+	}
+}
+
+func (s *WarehouseService) CreateWarehouse(ctx context.Context, warehouse *entity.Warehouse) (*entity.Warehouse, error) {
+	if _, err := authorize(ctx, PermManageWarehouses); err != nil {
+		return nil, err
+	}
+	if err := Validate(warehouse); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Creating warehouse", "name", warehouse.Name)
+
+	now := time.Now()
+	warehouse.CreatedAt = now
+	warehouse.UpdatedAt = now
+	createdWarehouse, err := s.warehouseRepo.Create(ctx, warehouse)
+	if err != nil {
+		s.logger.Error("Failed to create warehouse", "error", err)
+		return nil, fmt.Errorf("failed to create warehouse: %w", err)
+	}
+
+	s.logger.Info("Warehouse created successfully", "id", createdWarehouse.ID)
+	return createdWarehouse, nil
+}
+
+// GetWarehouses lists every warehouse by priority.
+func (s *WarehouseService) GetWarehouses(ctx context.Context) ([]entity.Warehouse, error) {
+	if _, err := authorize(ctx, PermManageWarehouses, PermFulfilOrders); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching warehouses")
+
+	warehouses, err := s.warehouseRepo.FindAll(ctx)
+	if err != nil {
+		s.logger.Error("Failed to fetch warehouses", "error", err)
+		return nil, fmt.Errorf("failed to fetch warehouses: %w", err)
+	}
+	if warehouses == nil {
+		warehouses = []entity.Warehouse{}
+	}
+	return warehouses, nil
+}
+
+func (s *WarehouseService) GetWarehouseByID(ctx context.Context, id string) (*entity.Warehouse, error) {
+	if _, err := authorize(ctx, PermManageWarehouses, PermFulfilOrders); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching warehouse by ID", "id", id)
+	return s.findWarehouse(ctx, id)
+}
+
+// UpdateWarehouse replaces the warehouse's details. Its stock is changed
+// through its stock levels.
+func (s *WarehouseService) UpdateWarehouse(ctx context.Context, id string, warehouse *entity.Warehouse) (*entity.Warehouse, error) {
+	if _, err := authorize(ctx, PermManageWarehouses); err != nil {
+		return nil, err
+	}
+	if err := Validate(warehouse); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Updating warehouse", "id", id)
+
+	existing, err := s.findWarehouse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	existing.Name = warehouse.Name
+	existing.Country = warehouse.Country
+	existing.Latitude = warehouse.Latitude
+	existing.Longitude = warehouse.Longitude
+	existing.Priority = warehouse.Priority
+	existing.UpdatedAt = time.Now()
+	if err := s.warehouseRepo.Update(ctx, existing); err != nil {
+		s.logger.Error("Failed to update warehouse", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update warehouse: %w", err)
+	}
+
+	s.logger.Info("Warehouse updated successfully", "id", id)
+	return existing, nil
+}
+
+// DeleteWarehouse removes a warehouse together with the stock it keeps,
+// which leaves the products it stocked. It fails with ErrStockHeld while
+// pending orders or carts hold any of its stock, and with ErrLastStockLevel
+// while it is the last warehouse of a product and still keeps some of it.
+func (s *WarehouseService) DeleteWarehouse(ctx context.Context, id string) error {
+	if _, err := authorize(ctx, PermManageWarehouses); err != nil {
+		return err
+	}
+	s.logger.Info("Deleting warehouse", "id", id)
+
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		if _, err := s.findWarehouse(ctx, id); err != nil {
+			return err
+		}
+		levels, err := s.levelRepo.FindByWarehouse(ctx, id)
+		if err != nil {
+			s.logger.Error("Failed to fetch stock levels", "warehouse_id", id, "error", err)
+			return fmt.Errorf("failed to fetch stock levels: %w", err)
+		}
+		for _, level := range levels {
+			if err := s.checkRemovable(ctx, level); err != nil {
+				return err
+			}
+		}
+
+		for _, level := range levels {
+			if err := s.levelRepo.Delete(ctx, id, level.ProductID); err != nil {
+				s.logger.Error("Failed to delete stock level", "warehouse_id", id, "product_id", level.ProductID, "error", err)
+				return fmt.Errorf("failed to delete stock level: %w", err)
+			}
+			if err := s.syncStock(ctx, level.ProductID); err != nil {
+				return err
+			}
+		}
+		if err := s.warehouseRepo.Delete(ctx, id); err != nil {
+			s.logger.Error("Failed to delete warehouse", "id", id, "error", err)
+			return fmt.Errorf("failed to delete warehouse: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Warehouse deleted successfully", "id", id)
+	return nil
+}
+
+// GetWarehouseStock lists the stock levels of the warehouse by product ID.
+func (s *WarehouseService) GetWarehouseStock(ctx context.Context, id string) ([]entity.StockLevel, error) {
+	if _, err := authorize(ctx, PermManageWarehouses, PermFulfilOrders); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching warehouse stock", "id", id)
+
+	if _, err := s.findWarehouse(ctx, id); err != nil {
+		return nil, err
+	}
+	levels, err := s.levelRepo.FindByWarehouse(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to fetch stock levels", "warehouse_id", id, "error", err)
+		return nil, fmt.Errorf("failed to fetch stock levels: %w", err)
+	}
+	if levels == nil {
+		levels = []entity.StockLevel{}
+	}
+	return levels, nil
+}
+
+// GetProductStock lists the stock levels of the product by warehouse ID.
+func (s *WarehouseService) GetProductStock(ctx context.Context, productID string) ([]entity.StockLevel, error) {
+	if _, err := authorize(ctx, PermManageWarehouses, PermFulfilOrders); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching product stock", "product_id", productID)
+
+	if _, err := s.findProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	levels, err := s.levelRepo.FindByProduct(ctx, productID)
+	if err != nil {
+		s.logger.Error("Failed to fetch stock levels", "product_id", productID, "error", err)
+		return nil, fmt.Errorf("failed to fetch stock levels: %w", err)
+	}
+	if levels == nil {
+		levels = []entity.StockLevel{}
+	}
+	return levels, nil
+}
+
+// SetStockLevel sets how much of the product the warehouse keeps, stocking
+// the product there if it is not yet. The stock cannot go below what pending
+// orders and carts hold of it, and a product first goes into a warehouse only
+// while none of its stock is held; both fail with ErrStockHeld.
+func (s *WarehouseService) SetStockLevel(ctx context.Context, warehouseID, productID string, level *entity.StockLevel) (*entity.StockLevel, error) {
+	if _, err := authorize(ctx, PermManageWarehouses); err != nil {
+		return nil, err
+	}
+	if err := Validate(level); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Setting stock level", "warehouse_id", warehouseID, "product_id", productID, "stock", level.Stock)
+
+	var stored *entity.StockLevel
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		if _, err := s.findWarehouse(ctx, warehouseID); err != nil {
+			return err
+		}
+		product, err := s.findProduct(ctx, productID)
+		if err != nil {
+			return err
+		}
+		levels, err := s.levelRepo.FindByProduct(ctx, productID)
+		if err != nil {
+			s.logger.Error("Failed to fetch stock levels", "product_id", productID, "error", err)
+			return fmt.Errorf("failed to fetch stock levels: %w", err)
+		}
+		if len(levels) == 0 && product.Reserved > 0 {
+			s.logger.Info("Product stock is held", "product_id", productID)
+			return fmt.Errorf("%w: %d of product %s is held outside any warehouse", ErrStockHeld, product.Reserved, productID)
+		}
+		if existing := findLevel(levels, warehouseID); existing != nil && level.Stock < existing.Reserved {
+			s.logger.Info("Warehouse stock is held", "warehouse_id", warehouseID, "product_id", productID)
+			return fmt.Errorf("%w: warehouse %s holds %d of product %s", ErrStockHeld, warehouseID, existing.Reserved, productID)
+		}
+
+		level.WarehouseID = warehouseID
+		level.ProductID = productID
+		level.UpdatedAt = time.Now()
+		if err := s.levelRepo.Set(ctx, level); err != nil {
+			s.logger.Error("Failed to set stock level", "warehouse_id", warehouseID, "product_id", productID, "error", err)
+			return fmt.Errorf("failed to set stock level: %w", err)
+		}
+		if err := s.syncStock(ctx, productID); err != nil {
+			return err
+		}
+
+		levels, err = s.levelRepo.FindByProduct(ctx, productID)
+		if err != nil {
+			s.logger.Error("Failed to fetch stock levels", "product_id", productID, "error", err)
+			return fmt.Errorf("failed to fetch stock levels: %w", err)
+		}
+		stored = findLevel(levels, warehouseID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Stock level set successfully", "warehouse_id", warehouseID, "product_id", productID)
+	return stored, nil
+}
+
+// DeleteStockLevel stops the warehouse keeping the product, taking its stock
+// there out of the product's. It fails with ErrStockHeld while pending orders
+// or carts hold any of it. The product's last level only goes once its stock
+// is 0, failing with ErrLastStockLevel before, so that removing it never
+// drops stock unnoticed; the product then keeps stock outside any warehouse
+// again, starting from 0.
+func (s *WarehouseService) DeleteStockLevel(ctx context.Context, warehouseID, productID string) error {
+	if _, err := authorize(ctx, PermManageWarehouses); err != nil {
+		return err
+	}
+	s.logger.Info("Deleting stock level", "warehouse_id", warehouseID, "product_id", productID)
+
+	err := withinTransaction(ctx, s.tx, s.broker, func(ctx context.Context) error {
+		levels, err := s.levelRepo.FindByProduct(ctx, productID)
+		if err != nil {
+			s.logger.Error("Failed to fetch stock levels", "product_id", productID, "error", err)
+			return fmt.Errorf("failed to fetch stock levels: %w", err)
+		}
+		level := findLevel(levels, warehouseID)
+		if level == nil {
+			s.logger.Error("Stock level not found", "warehouse_id", warehouseID, "product_id", productID)
+			return fmt.Errorf("stock level not found: %w", ErrNotFound)
+		}
+		if err := s.checkRemovable(ctx, *level); err != nil {
+			return err
+		}
+
+		if err := s.levelRepo.Delete(ctx, warehouseID, productID); err != nil {
+			s.logger.Error("Failed to delete stock level", "warehouse_id", warehouseID, "product_id", productID, "error", err)
+			return fmt.Errorf("failed to delete stock level: %w", err)
+		}
+		return s.syncStock(ctx, productID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Stock level deleted successfully", "warehouse_id", warehouseID, "product_id", productID)
+	return nil
+}
+
+// checkRemovable makes sure the level can go without losing track of stock:
+// none of it may be held, and the product's last level must be empty.
+func (s *WarehouseService) checkRemovable(ctx context.Context, level entity.StockLevel) error {
+	if level.Reserved > 0 {
+		s.logger.Info("Warehouse stock is held", "warehouse_id", level.WarehouseID, "product_id", level.ProductID)
+		return fmt.Errorf("%w: warehouse %s holds %d of product %s", ErrStockHeld, level.WarehouseID, level.Reserved, level.ProductID)
+	}
+	if level.Stock == 0 {
+		return nil
+	}
+	levels, err := s.levelRepo.FindByProduct(ctx, level.ProductID)
+	if err != nil {
+		s.logger.Error("Failed to fetch stock levels", "product_id", level.ProductID, "error", err)
+		return fmt.Errorf("failed to fetch stock levels: %w", err)
+	}
+	if len(levels) == 1 {
+		s.logger.Info("Last stock level still has stock", "warehouse_id", level.WarehouseID, "product_id", level.ProductID)
+		return fmt.Errorf("%w: warehouse %s keeps the last %d of product %s; set its stock to 0 first", ErrLastStockLevel, level.WarehouseID, level.Stock, level.ProductID)
+	}
+	return nil
+}
+
+// syncStock sets the product's stock to the sum of its stock levels and
+// records the change. Products that are gone are left alone.
+func (s *WarehouseService) syncStock(ctx context.Context, productID string) error {
+	product, err := s.productRepo.FindByIDIncludingDeleted(ctx, productID)
+	if errors.Is(err, ErrNotFound) {
+		// Stock kept for a purged product changes nothing
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to fetch product", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to fetch product: %w", err)
+	}
+	levels, err := s.levelRepo.FindByProduct(ctx, productID)
+	if err != nil {
+		s.logger.Error("Failed to fetch stock levels", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to fetch stock levels: %w", err)
+	}
+	stock := 0
+	for _, level := range levels {
+		stock += level.Stock
+	}
+	if stock == product.Stock {
+		return nil
+	}
+
+	if err := s.productRepo.SetStock(ctx, productID, stock); err != nil {
+		s.logger.Error("Failed to update product stock", "product_id", productID, "error", err)
+		return fmt.Errorf("failed to update product stock: %w", err)
+	}
+	change := entity.StockChange{ProductID: productID, Delta: stock - product.Stock, Stock: stock}
+	if err := recordEvent(ctx, s.outboxRepo, entity.EventStockChanged, entityProduct, productID, change); err != nil {
+		s.logger.Error("Failed to record product event", "id", productID, "type", entity.EventStockChanged, "error", err)
+		return err
+	}
+	return nil
+}
+
+// findWarehouse returns the warehouse with the ID.
+func (s *WarehouseService) findWarehouse(ctx context.Context, id string) (*entity.Warehouse, error) {
+	warehouse, err := s.warehouseRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Warehouse not found", "id", id, "error", err)
+		return nil, fmt.Errorf("warehouse not found: %w", err)
+	}
+	return warehouse, nil
+}
+
+// findProduct returns the product with the ID.
+func (s *WarehouseService) findProduct(ctx context.Context, id string) (*entity.Product, error) {
+	product, err := s.productRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Product not found", "id", id, "error", err)
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+	return product, nil
+}
+
+// findLevel returns the level the warehouse keeps, or nil if there is none.
+func findLevel(levels []entity.StockLevel, warehouseID string) *entity.StockLevel {
+	for i := range levels {
+		if levels[i].WarehouseID == warehouseID {
+			return &levels[i]
+		}
+	}
+	return nil
+}
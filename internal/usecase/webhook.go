@@ -0,0 +1,450 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"ulab3/internal/entity"
+	"ulab3/pkg/netguard"
+)
+
+// maxLoggedAttempts bounds the attempt log kept with each delivery.
+const maxLoggedAttempts = 20
+
+// WebhookService manages webhook subscriptions and their deliveries. It is
+// also the publisher that turns outbox events into deliveries for every
+// webhook subscribed to them.
+type WebhookService struct {
+	webhookRepo  WebhookRepository
+	deliveryRepo WebhookDeliveryRepository
+	userRepo     UserRepository
+	tx           Transactor
+	// checkURL vets the destination of a webhook before it is stored
+	checkURL func(ctx context.Context, rawURL string) error
+	logger   *slog.Logger
+}
+
+func NewWebhookService(webhookRepo WebhookRepository, deliveryRepo WebhookDeliveryRepository, userRepo UserRepository, tx Transactor, logger *slog.Logger) *WebhookService {
+	return &WebhookService{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		userRepo:     userRepo,
+		tx:           tx,
+		checkURL:     netguard.CheckURL,
+		logger:       logger,
+	}
+}
+
+// CreateWebhook registers a webhook owned by the actor. A secret is
+// required.
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook *entity.Webhook) (*entity.Webhook, error) {
+	actor, err := authorize(ctx, PermManageOwnWebhooks, PermManageAllWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateWebhook(ctx, webhook, true); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Creating webhook", "url", webhook.URL, "event_types", webhook.EventTypes)
+
+	now := time.Now()
+	webhook.OwnerID = actor.UserID
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	createdWebhook, err := s.webhookRepo.Create(ctx, webhook)
+	if err != nil {
+		s.logger.Error("Failed to create webhook", "error", err)
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	s.logger.Info("Webhook created successfully", "id", createdWebhook.ID)
+	return redactWebhook(createdWebhook), nil
+}
+
+// GetWebhooks lists the webhooks the actor may manage, newest first unless the
+// page asks for another order.
+func (s *WebhookService) GetWebhooks(ctx context.Context, page PageRequest) (*entity.WebhookPage, error) {
+	actor, err := authorize(ctx, PermManageOwnWebhooks, PermManageAllWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching webhooks", "sort", page.Sort, "limit", page.Limit)
+
+	if page.Sort == "" {
+		page.Sort = "-created_at"
+	}
+	sort, after, limit, err := parsePage(page, webhookSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter WebhookFilter
+	if !actor.Can(PermManageAllWebhooks) {
+		filter.OwnerID = actor.UserID
+	}
+	// Ask for one extra record to learn whether another page follows
+	query := WebhookQuery{WebhookFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	webhooks, err := s.webhookRepo.FindAll(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to fetch webhooks", "error", err)
+		return nil, fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	data, pagination := paginate(webhooks, limit, sort, webhookSortFields, func(w entity.Webhook) string { return w.ID })
+	return &entity.WebhookPage{Data: data, Pagination: pagination}, nil
+}
+
+func (s *WebhookService) GetWebhookByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	s.logger.Info("Fetching webhook by ID", "id", id)
+
+	webhook, err := s.findWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return redactWebhook(webhook), nil
+}
+
+// UpdateWebhook replaces the webhook's URL and event types. The secret is
+// replaced too unless the request leaves it empty.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, webhook *entity.Webhook) (*entity.Webhook, error) {
+	if err := s.validateWebhook(ctx, webhook, false); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Updating webhook", "id", id)
+
+	existing, err := s.findWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	existing.URL = webhook.URL
+	existing.EventTypes = webhook.EventTypes
+	if webhook.Secret != "" {
+		existing.Secret = webhook.Secret
+	}
+	existing.UpdatedAt = time.Now()
+	if err := s.webhookRepo.Update(ctx, existing); err != nil {
+		s.logger.Error("Failed to update webhook", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	s.logger.Info("Webhook updated successfully", "id", id)
+	return redactWebhook(existing), nil
+}
+
+// DeleteWebhook removes the webhook together with its deliveries.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	s.logger.Info("Deleting webhook", "id", id)
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.findWebhook(ctx, id); err != nil {
+			return err
+		}
+		if err := s.deliveryRepo.DeleteByWebhook(ctx, id); err != nil {
+			s.logger.Error("Failed to delete webhook deliveries", "id", id, "error", err)
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		if err := s.webhookRepo.Delete(ctx, id); err != nil {
+			s.logger.Error("Failed to delete webhook", "id", id, "error", err)
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Webhook deleted successfully", "id", id)
+	return nil
+}
+
+// GetDeliveries lists the deliveries to one webhook, newest first unless the
+// page asks for another order.
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID string, status entity.WebhookDeliveryStatus, page PageRequest) (*entity.WebhookDeliveryPage, error) {
+	if _, err := s.findWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching webhook deliveries", "webhook_id", webhookID, "status", status, "sort", page.Sort, "limit", page.Limit)
+
+	if page.Sort == "" {
+		page.Sort = "-created_at"
+	}
+	sort, after, limit, err := parsePage(page, deliverySortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ask for one extra record to learn whether another page follows
+	filter := WebhookDeliveryFilter{WebhookID: webhookID, Status: status}
+	query := WebhookDeliveryQuery{WebhookDeliveryFilter: filter, Sort: sort, After: after, Limit: limit + 1}
+	deliveries, err := s.deliveryRepo.FindAll(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to fetch webhook deliveries", "error", err)
+		return nil, fmt.Errorf("failed to fetch webhook deliveries: %w", err)
+	}
+
+	data, pagination := paginate(deliveries, limit, sort, deliverySortFields, func(d entity.WebhookDelivery) string { return d.ID })
+	return &entity.WebhookDeliveryPage{Data: data, Pagination: pagination}, nil
+}
+
+func (s *WebhookService) GetDelivery(ctx context.Context, webhookID, id string) (*entity.WebhookDelivery, error) {
+	if _, err := s.findWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fetching webhook delivery", "webhook_id", webhookID, "id", id)
+	return s.findDelivery(ctx, webhookID, id)
+}
+
+// Redeliver sends a delivery that succeeded or failed again, with a fresh set
+// of attempts. Its attempt log is kept.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, id string) (*entity.WebhookDelivery, error) {
+	if _, err := s.findWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Redelivering webhook delivery", "webhook_id", webhookID, "id", id)
+
+	delivery, err := s.findDelivery(ctx, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == entity.WebhookDeliveryPending {
+		return nil, fmt.Errorf("%w: delivery %s", ErrDeliveryPending, id)
+	}
+
+	delivery.Status = entity.WebhookDeliveryPending
+	delivery.AttemptCount = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+	if err := s.deliveryRepo.UpdateDelivery(ctx, delivery); err != nil {
+		s.logger.Error("Failed to redeliver webhook delivery", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	s.logger.Info("Webhook delivery queued", "id", id)
+	return delivery, nil
+}
+
+// Publish queues a delivery of the event for every webhook subscribed to its
+// type whose owner may see it. Events published again, as the outbox may do,
+// are not queued twice.
+func (s *WebhookService) Publish(ctx context.Context, event entity.Event) error {
+	webhooks, err := s.webhookRepo.FindSubscribed(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to fetch subscribed webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(NewEventMessage(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	for _, webhook := range webhooks {
+		visible, err := s.ownerMaySee(ctx, &webhook, event)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			continue
+		}
+
+		delivery := &entity.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Body:          body,
+			Status:        entity.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+			CreatedAt:     time.Now(),
+		}
+		err = s.deliveryRepo.Create(ctx, delivery)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// ownerMaySee reports whether the webhook's owner may read the record the
+// event is about. Product events are public; order events go to owners who
+// may read every order, or their own ones. Webhooks of removed users get
+// nothing.
+func (s *WebhookService) ownerMaySee(ctx context.Context, webhook *entity.Webhook, event entity.Event) (bool, error) {
+	owner, err := s.userRepo.FindByID(ctx, webhook.OwnerID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch webhook owner: %w", err)
+	}
+	actor := Actor{UserID: owner.ID, Role: owner.Role}
+
+	if event.AggregateType != entityOrder || actor.Can(PermReadAllOrders) {
+		return true, nil
+	}
+	// Every order event names the customer who placed the order
+	var order struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &order); err != nil {
+		return false, fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+	return actor.Can(PermReadOwnOrders) && order.UserID == actor.UserID, nil
+}
+
+// findWebhook returns the webhook with the ID if the actor may manage it.
+func (s *WebhookService) findWebhook(ctx context.Context, id string) (*entity.Webhook, error) {
+	webhook, err := s.webhookRepo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Webhook not found", "id", id, "error", err)
+		return nil, fmt.Errorf("webhook not found: %w", err)
+	}
+	if err := authorizeWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// findDelivery returns the delivery with the ID if it belongs to the webhook.
+func (s *WebhookService) findDelivery(ctx context.Context, webhookID, id string) (*entity.WebhookDelivery, error) {
+	delivery, err := s.deliveryRepo.FindByID(ctx, id)
+	if err == nil && delivery.WebhookID != webhookID {
+		err = fmt.Errorf("webhook delivery %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		s.logger.Error("Webhook delivery not found", "id", id, "error", err)
+		return nil, fmt.Errorf("webhook delivery not found: %w", err)
+	}
+	return delivery, nil
+}
+
+// validateWebhook checks a webhook request. Creating a webhook needs a
+// secret; replacing one may leave it out to keep the current secret. Either
+// way the URL must point to public internet addresses only.
+func (s *WebhookService) validateWebhook(ctx context.Context, webhook *entity.Webhook, requireSecret bool) error {
+	if err := Validate(webhook); err != nil {
+		return err
+	}
+	if requireSecret && webhook.Secret == "" {
+		return &ValidationError{Fields: []entity.FieldError{{Field: "secret", Message: "is required"}}}
+	}
+	// Deliveries and their logged responses must not reach into the
+	// server's own network; the sender checks again as it connects
+	if err := s.checkURL(ctx, webhook.URL); err != nil {
+		s.logger.Info("Webhook URL refused", "url", webhook.URL, "error", err)
+		return &ValidationError{Fields: []entity.FieldError{{Field: "url", Message: "must point to a public internet address"}}}
+	}
+	return nil
+}
+
+// redactWebhook returns a copy of the webhook without its secret.
+func redactWebhook(webhook *entity.Webhook) *entity.Webhook {
+	redacted := *webhook
+	redacted.Secret = ""
+	return &redacted
+}
+
+// WebhookDispatcher sends the queued webhook deliveries, retrying failed ones
+// with exponential backoff until they run out of attempts.
+type WebhookDispatcher struct {
+	webhookRepo  WebhookRepository
+	deliveryRepo WebhookDeliveryRepository
+	sender       WebhookSender
+	config       DispatcherConfig
+	logger       *slog.Logger
+}
+
+func NewWebhookDispatcher(webhookRepo WebhookRepository, deliveryRepo WebhookDeliveryRepository, sender WebhookSender, config DispatcherConfig, logger *slog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		config:       config,
+		logger:       logger,
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	d.logger.Info("Webhook dispatcher started", "interval", d.config.Interval)
+	poll(ctx, d.config, d.DispatchBatch)
+	d.logger.Info("Webhook dispatcher stopped")
+}
+
+// DispatchBatch claims one batch of due deliveries, sends each of them and
+// returns how many it claimed.
+func (d *WebhookDispatcher) DispatchBatch(ctx context.Context) int {
+	now := time.Now()
+	deliveries, err := d.deliveryRepo.Claim(ctx, now, now.Add(d.config.Lease), d.config.BatchSize)
+	if err != nil {
+		d.logger.Error("Failed to claim webhook deliveries", "error", err)
+		return 0
+	}
+	for i := range deliveries {
+		d.deliver(ctx, &deliveries[i])
+	}
+	return len(deliveries)
+}
+
+// deliver makes one attempt at a delivery and records the outcome.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *entity.WebhookDelivery) {
+	webhook, err := d.webhookRepo.FindByID(ctx, delivery.WebhookID)
+	if errors.Is(err, ErrNotFound) {
+		// The webhook was deleted after the delivery was claimed
+		delivery.Status = entity.WebhookDeliveryFailed
+		d.store(ctx, delivery)
+		return
+	}
+	if err != nil {
+		// The lease runs out and the delivery is claimed again
+		d.logger.Error("Failed to fetch webhook", "id", delivery.WebhookID, "error", err)
+		return
+	}
+
+	start := time.Now()
+	status, err := d.sender.Send(ctx, webhook, delivery)
+	now := time.Now()
+	attempt := entity.DeliveryAttempt{At: start, StatusCode: status, DurationMS: now.Sub(start).Milliseconds()}
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("webhook responded %d", status)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	delivery.AttemptCount++
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	if len(delivery.Attempts) > maxLoggedAttempts {
+		delivery.Attempts = delivery.Attempts[len(delivery.Attempts)-maxLoggedAttempts:]
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = entity.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.AttemptCount >= d.config.MaxAttempts:
+		d.logger.Error("Webhook delivery failed for good", "id", delivery.ID, "webhook_id", delivery.WebhookID,
+			"attempts", delivery.AttemptCount, "error", err)
+		delivery.Status = entity.WebhookDeliveryFailed
+	default:
+		d.logger.Info("Webhook delivery failed", "id", delivery.ID, "webhook_id", delivery.WebhookID,
+			"attempts", delivery.AttemptCount, "error", err)
+		delivery.NextAttemptAt = now.Add(backoff(d.config, delivery.AttemptCount))
+	}
+	d.store(ctx, delivery)
+}
+
+func (d *WebhookDispatcher) store(ctx context.Context, delivery *entity.WebhookDelivery) {
+	// Should this fail, the lease runs out and the delivery is sent again
+	if err := d.deliveryRepo.UpdateDelivery(ctx, delivery); err != nil {
+		d.logger.Error("Failed to store webhook delivery", "id", delivery.ID, "error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"ulab3/internal/entity"
+	"ulab3/internal/usecase"
+	"ulab3/internal/usecase/repo/memory"
+)
+
+func TestWebhookURLMustBePublic(t *testing.T) {
+	repos := memory.NewRepositories()
+	service := usecase.NewWebhookService(repos.Webhooks, repos.Deliveries, repos.Users, repos.Transactor,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := usecase.WithActor(context.Background(), usecase.Actor{UserID: "owner", Role: entity.RoleCustomer})
+	webhook := func(url string) *entity.Webhook {
+		return &entity.Webhook{
+			URL:        url,
+			EventTypes: []entity.EventType{entity.EventOrderCreated},
+			Secret:     "0123456789abcdef-secret",
+		}
+	}
+
+	created, err := service.CreateWebhook(ctx, webhook("https://93.184.216.34/hook"))
+	if err != nil {
+		t.Fatalf("CreateWebhook with a public address: %v", err)
+	}
+
+	tests := []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.7/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::ffff:192.168.1.1]/hook",
+		"http://0.0.0.0/hook",
+	}
+	for _, url := range tests {
+		var validation *usecase.ValidationError
+		if _, err := service.CreateWebhook(ctx, webhook(url)); !errors.As(err, &validation) || validation.Fields[0].Field != "url" {
+			t.Errorf("CreateWebhook(%s) = %v, want a validation error on url", url, err)
+		}
+		if _, err := service.UpdateWebhook(ctx, created.ID, webhook(url)); !errors.As(err, &validation) || validation.Fields[0].Field != "url" {
+			t.Errorf("UpdateWebhook(%s) = %v, want a validation error on url", url, err)
+		}
+	}
+
+	stored, err := repos.Webhooks.FindByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("find webhook: %v", err)
+	}
+	if stored.URL != "https://93.184.216.34/hook" {
+		t.Errorf("webhook URL is %s after refused updates", stored.URL)
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS products (
+    id         TEXT PRIMARY KEY,
+    name       TEXT           NOT NULL,
+    price      NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    stock      INTEGER        NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    category   TEXT           NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
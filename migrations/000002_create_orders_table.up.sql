@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id             TEXT PRIMARY KEY,
+    items          JSONB          NOT NULL DEFAULT '[]',
+    total_price    NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    status         TEXT           NOT NULL,
+    status_history JSONB          NOT NULL DEFAULT '[]',
+    stock_released BOOLEAN        NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
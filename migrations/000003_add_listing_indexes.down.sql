@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_orders_total_price;
+DROP INDEX IF EXISTS idx_orders_updated_at;
+DROP INDEX IF EXISTS idx_orders_created_at;
+DROP INDEX IF EXISTS idx_orders_items;
+
+DROP INDEX IF EXISTS idx_products_stock;
+DROP INDEX IF EXISTS idx_products_price;
+DROP INDEX IF EXISTS idx_products_name;
+DROP INDEX IF EXISTS idx_products_created_at;
+DROP INDEX IF EXISTS idx_products_category;
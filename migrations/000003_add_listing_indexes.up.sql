@@ -0,0 +1,10 @@
+CREATE INDEX IF NOT EXISTS idx_products_category ON products (category);
+CREATE INDEX IF NOT EXISTS idx_products_created_at ON products (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_products_name ON products (name, id);
+CREATE INDEX IF NOT EXISTS idx_products_price ON products (price, id);
+CREATE INDEX IF NOT EXISTS idx_products_stock ON products (stock, id);
+
+CREATE INDEX IF NOT EXISTS idx_orders_items ON orders USING GIN (items jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_updated_at ON orders (updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_total_price ON orders (total_price, id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_user_id;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS user_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
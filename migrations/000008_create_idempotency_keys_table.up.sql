@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id           TEXT PRIMARY KEY,
+    fingerprint  TEXT        NOT NULL,
+    status       INTEGER     NOT NULL DEFAULT 0,
+    content_type TEXT        NOT NULL DEFAULT '',
+    body         BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          TEXT PRIMARY KEY,
+    owner_id    TEXT        NOT NULL,
+    url         TEXT        NOT NULL,
+    event_types JSONB       NOT NULL DEFAULT '[]',
+    secret      TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_created_at ON webhooks (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks (owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhooks_event_types ON webhooks USING GIN (event_types);
+
+-- The body is JSON rather than JSONB so that every attempt sends the same bytes
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    webhook_id      TEXT        NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        TEXT        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    body            JSON        NOT NULL,
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempt_count   INTEGER     NOT NULL DEFAULT 0,
+    attempts        JSONB       NOT NULL DEFAULT '[]',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    -- An event is delivered to each webhook once, however often it is published
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT        NOT NULL DEFAULT '',
+    name       TEXT        NOT NULL,
+    email      TEXT        NOT NULL,
+    phone      TEXT        NOT NULL DEFAULT '',
+    addresses  JSONB       NOT NULL DEFAULT '[]',
+    status     TEXT        NOT NULL DEFAULT 'active',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_customers_created_at ON customers (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_customers_name ON customers (name, id);
+CREATE INDEX IF NOT EXISTS idx_customers_user ON customers (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_customers_email ON customers (email);
+
+-- Orders placed before customers existed keep an empty customer_id
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id);
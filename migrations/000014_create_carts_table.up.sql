@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS carts (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT        NOT NULL,
+    items      JSONB       NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_expires_at ON carts (expires_at);
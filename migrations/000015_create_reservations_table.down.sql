@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reservations;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS stock_held;
+
+ALTER TABLE products DROP COLUMN IF EXISTS reserved;
@@ -0,0 +1,17 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reserved INTEGER NOT NULL DEFAULT 0;
+
+-- Orders placed before reservations existed took their stock right away
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS stock_held BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id          TEXT PRIMARY KEY,
+    product_id  TEXT        NOT NULL,
+    quantity    INTEGER     NOT NULL CHECK (quantity > 0),
+    holder_type TEXT        NOT NULL,
+    holder_id   TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_holder ON reservations (holder_type, holder_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_expires_at ON reservations (expires_at, id);
@@ -0,0 +1,4 @@
+ALTER TABLE reservations DROP COLUMN IF EXISTS warehouse_id;
+
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id         TEXT PRIMARY KEY,
+    name       TEXT             NOT NULL,
+    country    TEXT             NOT NULL,
+    latitude   DOUBLE PRECISION NOT NULL,
+    longitude  DOUBLE PRECISION NOT NULL,
+    priority   INTEGER          NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_warehouses_priority ON warehouses (priority, id);
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+    warehouse_id TEXT        NOT NULL,
+    product_id   TEXT        NOT NULL,
+    stock        INTEGER     NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    reserved     INTEGER     NOT NULL DEFAULT 0,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (warehouse_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_levels_product ON stock_levels (product_id, warehouse_id);
+
+-- Holds placed before warehouses existed hold no warehouse's stock
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS warehouse_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,53 @@
+// Package mergepatch applies JSON Merge Patch documents as defined by
+// RFC 7396: object members in the patch replace those in the document, null
+// members remove them, and any other value replaces the target outright.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Apply returns doc with patch merged into it. A patch that is not a JSON
+// object replaces doc as a whole. Numbers are carried over without loss of
+// precision.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	members, ok := target.(map[string]any)
+	if !ok {
+		members = make(map[string]any, len(changes))
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(members, name)
+			continue
+		}
+		members[name] = merge(members[name], value)
+	}
+	return members
+}
+
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,68 @@
+package mergepatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "replaces a member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "adds a member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null deletes a member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "null for a missing member", doc: `{"a":"b"}`, patch: `{"c":null}`, want: `{"a":"b"}`},
+		{name: "empty patch", doc: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+		{name: "nested objects merge", doc: `{"a":{"b":"c","d":"e"},"f":1}`, patch: `{"a":{"b":"x","g":"h"}}`,
+			want: `{"a":{"b":"x","d":"e","g":"h"},"f":1}`},
+		{name: "null deletes a nested member", doc: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"b":null}}`, want: `{"a":{"d":"e"}}`},
+		{name: "object over a scalar", doc: `{"a":"b"}`, patch: `{"a":{"c":"d"}}`, want: `{"a":{"c":"d"}}`},
+		{name: "nulls inside a new object are dropped", doc: `{}`, patch: `{"a":{"b":null,"c":1}}`, want: `{"a":{"c":1}}`},
+		{name: "arrays are replaced whole", doc: `{"a":[1,2,3]}`, patch: `{"a":[4]}`, want: `{"a":[4]}`},
+		{name: "arrays of objects are replaced whole", doc: `{"a":[{"b":"c","d":"e"}]}`, patch: `{"a":[{"b":"x"}]}`, want: `{"a":[{"b":"x"}]}`},
+		{name: "array patch replaces the document", doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{name: "string patch replaces the document", doc: `{"a":"b"}`, patch: `"c"`, want: `"c"`},
+		{name: "null patch replaces the document", doc: `{"a":"b"}`, patch: `null`, want: `null`},
+		{name: "object patch over a non-object document", doc: `["a"]`, patch: `{"a":"b"}`, want: `{"a":"b"}`},
+		{name: "large numbers keep their precision", doc: `{"a":9007199254740993}`, patch: `{"b":1.10}`, want: `{"a":9007199254740993,"b":1.10}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if !sameJSON(t, got, []byte(tt.want)) {
+				t.Errorf("Apply(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMalformed(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":`), []byte(`{}`)); err == nil {
+		t.Error("malformed document was accepted")
+	}
+	if _, err := Apply([]byte(`{}`), []byte(`{"a"}`)); err == nil {
+		t.Error("malformed patch was accepted")
+	}
+}
+
+// sameJSON reports whether a and b encode the same value. Numbers are
+// compared by their text, so a loss of precision shows.
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	values := make([]any, 2)
+	for i, data := range [][]byte{a, b} {
+		value, err := decode(data)
+		if err != nil {
+			t.Fatalf("decode %s: %v", data, err)
+		}
+		values[i] = value
+	}
+	return reflect.DeepEqual(values[0], values[1])
+}
@@ -0,0 +1,87 @@
+// Package netguard keeps outgoing requests to user-supplied URLs, such as
+// webhooks, away from the server's own network: loopback, private,
+// link-local and other non-public addresses are refused.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrNotPublic is returned for destinations that are not on the public
+// internet.
+var ErrNotPublic = errors.New("destination is not a public address")
+
+// reserved lists the special-purpose ranges the netip predicates miss.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// Public reports whether ip is a public unicast address.
+func Public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control refuses connections to addresses that are not public. Set it as
+// the Control of a net.Dialer: it sees the address actually dialled, after
+// name resolution, so a name that resolves differently later cannot slip
+// past an earlier check.
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotPublic, address)
+	}
+	if !Public(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, addrPort.Addr())
+	}
+	return nil
+}
+
+// CheckURL makes sure every address the host of rawURL resolves to is
+// public. Hosts that cannot be resolved are refused too.
+func CheckURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !Public(ip) {
+			return fmt.Errorf("%w: %s", ErrNotPublic, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: %s does not resolve", ErrNotPublic, host)
+	}
+	for _, addr := range addrs {
+		if !Public(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrNotPublic, host, addr)
+		}
+	}
+	return nil
+}
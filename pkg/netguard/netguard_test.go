@@ -0,0 +1,68 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"192.0.2.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+	}
+	for _, tt := range tests {
+		if got := Public(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("Public(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Control of a public address: %v", err)
+	}
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "169.254.169.254:80", "not-an-address"} {
+		if err := Control("tcp", address, nil); !errors.Is(err, ErrNotPublic) {
+			t.Errorf("Control(%q) = %v, want ErrNotPublic", address, err)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, rawURL := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://localhost/hook",
+	} {
+		if err := CheckURL(ctx, rawURL); !errors.Is(err, ErrNotPublic) {
+			t.Errorf("CheckURL(%q) = %v, want ErrNotPublic", rawURL, err)
+		}
+	}
+	if err := CheckURL(ctx, "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("CheckURL of a public address: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"log"
+	"time"
+	"ulab3/config"
+)
+
+func Connection(config config.Config) (*sqlx.DB, error) {
+	// Build the PostgreSQL connection string
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		config.DB_USER, config.DB_PASS, config.DB_HOST, config.DB_PORT, config.DB_NAME)
+
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the connection to PostgreSQL
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Println("Connected to PostgreSQL successfully!")
+	return db, nil
+}
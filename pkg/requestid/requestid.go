@@ -0,0 +1,46 @@
+// Package requestid carries the ID of the request being served through a
+// context, so logs, errors and audit records can refer to it.
+package requestid
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header a request ID is read from and echoed in.
+const Header = "X-Request-ID"
+
+// maxLength bounds the length of a client supplied ID.
+const maxLength = 128
+
+type key struct{}
+
+// New returns a fresh random request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+// With returns a copy of ctx that carries the request ID.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the request ID stored in ctx, or "" if there is none.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// Valid reports whether a client supplied ID is safe to reuse: not empty, not
+// too long, and made of printable ASCII without spaces.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+// Package search scores free-text queries against short fields such as
+// product names. It tolerates typos through edit distance and is meant for
+// ranking candidate sets in process.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	exactScore  = 1.0
+	prefixScore = 0.8
+	fuzzyScore  = 0.6
+)
+
+// Field is a piece of text to match against, weighted by how much a match in
+// it should count.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Tokenize lowercases text and splits it into words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Score rates how well the query words match the fields. Each query word adds
+// the weight of its best match: an exact word scores highest, then a word the
+// query is a prefix of, then a word within a small edit distance. Zero means
+// no query word matched.
+func Score(query []string, fields ...Field) float64 {
+	var total float64
+	for _, term := range query {
+		var best float64
+		for _, field := range fields {
+			for _, word := range Tokenize(field.Text) {
+				if score := matchWord(term, word) * field.Weight; score > best {
+					best = score
+				}
+			}
+		}
+		total += best
+	}
+	return total
+}
+
+// Prefixes returns the first n letters of every word in the query, for
+// backends that narrow candidates with a cheap substring match before scoring.
+func Prefixes(query []string, n int) []string {
+	var prefixes []string
+	for _, term := range query {
+		runes := []rune(term)
+		if len(runes) > n {
+			runes = runes[:n]
+		}
+		prefixes = append(prefixes, string(runes))
+	}
+	return prefixes
+}
+
+func matchWord(term, word string) float64 {
+	switch {
+	case term == word:
+		return exactScore
+	case len(term) >= 2 && strings.HasPrefix(word, term):
+		return prefixScore
+	}
+
+	allowed := maxEdits(term)
+	if allowed == 0 {
+		return 0
+	}
+	// Compare against the word and against its prefix of the same length, so
+	// a misspelt prefix still finds longer words.
+	distance := editDistance(term, word, allowed)
+	if runes := []rune(word); len(runes) > len([]rune(term)) {
+		if d := editDistance(term, string(runes[:len([]rune(term))]), allowed); d < distance {
+			distance = d
+		}
+	}
+	if distance > allowed {
+		return 0
+	}
+	return fuzzyScore / float64(distance)
+}
+
+// maxEdits is how many typos a query word of this length may contain.
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and swaps of adjacent letters that turn a into b, or limit+1 once the
+// distance is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
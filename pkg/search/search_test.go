@@ -0,0 +1,82 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Desk Lamp", want: []string{"desk", "lamp"}},
+		{text: "  USB-C cable, 2m! ", want: []string{"usb", "c", "cable", "2m"}},
+		{text: "Café Crème", want: []string{"café", "crème"}},
+		{text: "", want: []string{}},
+		{text: " \t\n", want: []string{}},
+		{text: "--", want: []string{}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		text  string
+		want  float64
+	}{
+		{name: "exact word", query: "lamp", text: "Desk Lamp", want: exactScore},
+		{name: "prefix", query: "lam", text: "Desk Lamp", want: prefixScore},
+		{name: "one letter is no prefix", query: "l", text: "Desk Lamp", want: 0},
+		{name: "substitution", query: "lump", text: "Desk Lamp", want: fuzzyScore},
+		{name: "insertion", query: "laamp", text: "Desk Lamp", want: fuzzyScore},
+		{name: "deletion", query: "kyboard", text: "Keyboard", want: fuzzyScore},
+		{name: "swapped letters", query: "lmap", text: "Desk Lamp", want: fuzzyScore},
+		{name: "two typos in a long word", query: "kexbord", text: "Keyboard", want: fuzzyScore / 2},
+		{name: "two typos in a short word", query: "lmup", text: "Desk Lamp", want: 0},
+		{name: "three typos in a long word", query: "kexbxrd", text: "Keyboard", want: 0},
+		{name: "no typos in a three-letter word", query: "dex", text: "Desk Lamp", want: 0},
+		{name: "misspelt prefix", query: "keybaord", text: "Keyboards", want: fuzzyScore},
+		{name: "case and punctuation", query: "DESK", text: "desk-lamp", want: exactScore},
+		{name: "best match counts", query: "desk", text: "Desks Desk", want: exactScore},
+		{name: "every word counts", query: "desk lamp", text: "Desk Lamp", want: 2 * exactScore},
+		{name: "unmatched word adds nothing", query: "desk chair", text: "Desk Lamp", want: exactScore},
+		{name: "no match", query: "chair", text: "Desk Lamp", want: 0},
+		{name: "empty query", query: "", text: "Desk Lamp", want: 0},
+		{name: "whitespace query", query: "  \t ", text: "Desk Lamp", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Score(Tokenize(tt.query), Field{Text: tt.text, Weight: 1}); got != tt.want {
+				t.Errorf("Score(%q, %q) = %v, want %v", tt.query, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreWeights(t *testing.T) {
+	query := Tokenize("lamp")
+	name, category := Field{Text: "Desk Lamp", Weight: 2}, Field{Text: "Lamps", Weight: 1}
+	if got := Score(query, name, category); got != 2*exactScore {
+		t.Errorf("Score = %v, want the weighted name match %v", got, 2*exactScore)
+	}
+	if got := Score(query, Field{Text: "Desk", Weight: 2}, category); got != prefixScore {
+		t.Errorf("Score = %v, want the category prefix match %v", got, prefixScore)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	got := Prefixes([]string{"keyboard", "usb", "café"}, 3)
+	if want := []string{"key", "usb", "caf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Prefixes = %q, want %q", got, want)
+	}
+	if got := Prefixes(nil, 3); got != nil {
+		t.Errorf("Prefixes(nil) = %q, want nil", got)
+	}
+}
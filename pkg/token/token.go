@@ -0,0 +1,105 @@
+// Package token issues and verifies the HS256 JSON Web Tokens used for
+// authentication. Access and refresh tokens are signed with different secrets
+// and carry their kind, so one can never be used in place of the other.
+package token
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	kindAccess  = "access"
+	kindRefresh = "refresh"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired, signed
+// with another secret or of the wrong kind.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the contents of a verified token. Subject holds the user ID and
+// ID the unique token ID.
+type Claims struct {
+	Kind string `json:"kind"`
+	jwt.RegisteredClaims
+}
+
+// Token is a signed token together with the claims callers need to track it.
+type Token struct {
+	Value     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+type Manager struct {
+	accessSecret  []byte
+	refreshSecret []byte
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+}
+
+func NewManager(accessSecret, refreshSecret string, accessTTL, refreshTTL time.Duration) *Manager {
+	return &Manager{
+		accessSecret:  []byte(accessSecret),
+		refreshSecret: []byte(refreshSecret),
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+	}
+}
+
+// AccessTTL is how long issued access tokens stay valid.
+func (m *Manager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+func (m *Manager) NewAccessToken(userID string) (Token, error) {
+	return m.sign(kindAccess, userID, m.accessSecret, m.accessTTL)
+}
+
+func (m *Manager) NewRefreshToken(userID string) (Token, error) {
+	return m.sign(kindRefresh, userID, m.refreshSecret, m.refreshTTL)
+}
+
+func (m *Manager) ParseAccessToken(value string) (*Claims, error) {
+	return m.parse(kindAccess, value, m.accessSecret)
+}
+
+func (m *Manager) ParseRefreshToken(value string) (*Claims, error) {
+	return m.parse(kindRefresh, value, m.refreshSecret)
+}
+
+func (m *Manager) sign(kind, userID string, secret []byte, ttl time.Duration) (Token, error) {
+	now := time.Now()
+	claims := Claims{
+		Kind: kind,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	value, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to sign %s token: %w", kind, err)
+	}
+	return Token{Value: value, ID: claims.ID, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+func (m *Manager) parse(kind, value string, secret []byte) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(value, &claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Kind != kind || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
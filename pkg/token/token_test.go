@@ -0,0 +1,133 @@
+package token
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+func newTestManager(ttl time.Duration) *Manager {
+	return NewManager("access-secret", "refresh-secret", ttl, ttl)
+}
+
+func TestRoundTrip(t *testing.T) {
+	m := newTestManager(time.Minute)
+	tests := []struct {
+		name  string
+		sign  func(userID string) (Token, error)
+		parse func(value string) (*Claims, error)
+	}{
+		{"access", m.NewAccessToken, m.ParseAccessToken},
+		{"refresh", m.NewRefreshToken, m.ParseRefreshToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued, err := tt.sign("user-1")
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			claims, err := tt.parse(issued.Value)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if claims.Subject != "user-1" || claims.ID != issued.ID || claims.Kind != tt.name {
+				t.Errorf("claims = %s %s %s, want user-1 %s %s", claims.Subject, claims.ID, claims.Kind, issued.ID, tt.name)
+			}
+			if !claims.ExpiresAt.Time.Equal(issued.ExpiresAt) {
+				t.Errorf("claims expire at %v, token at %v", claims.ExpiresAt.Time, issued.ExpiresAt)
+			}
+		})
+	}
+
+	first, _ := m.NewAccessToken("user-1")
+	second, _ := m.NewAccessToken("user-1")
+	if first.ID == second.ID {
+		t.Error("two tokens share an ID")
+	}
+}
+
+func TestWrongKindRejected(t *testing.T) {
+	// The secrets are the same, so only the kind tells the tokens apart
+	shared := NewManager("secret", "secret", time.Minute, time.Minute)
+	for _, m := range []*Manager{newTestManager(time.Minute), shared} {
+		access, err := m.NewAccessToken("user-1")
+		if err != nil {
+			t.Fatalf("sign access: %v", err)
+		}
+		refresh, err := m.NewRefreshToken("user-1")
+		if err != nil {
+			t.Fatalf("sign refresh: %v", err)
+		}
+		if _, err := m.ParseRefreshToken(access.Value); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("access token parsed as a refresh token: %v", err)
+		}
+		if _, err := m.ParseAccessToken(refresh.Value); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("refresh token parsed as an access token: %v", err)
+		}
+	}
+}
+
+func TestExpiredRejected(t *testing.T) {
+	m := newTestManager(-time.Minute)
+	access, err := m.NewAccessToken("user-1")
+	if err != nil {
+		t.Fatalf("sign access: %v", err)
+	}
+	refresh, err := m.NewRefreshToken("user-1")
+	if err != nil {
+		t.Fatalf("sign refresh: %v", err)
+	}
+	if _, err := m.ParseAccessToken(access.Value); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired access token: %v, want ErrInvalidToken", err)
+	}
+	if _, err := m.ParseRefreshToken(refresh.Value); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired refresh token: %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestForgedRejected(t *testing.T) {
+	m := newTestManager(time.Minute)
+	claims := func() Claims {
+		now := time.Now()
+		return Claims{Kind: kindAccess, RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-1",
+			Subject:   "user-1",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		}}
+	}
+	sign := func(method jwt.SigningMethod, claims Claims, key interface{}) string {
+		t.Helper()
+		value, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return value
+	}
+	noExpiry := claims()
+	noExpiry.ExpiresAt = nil
+	noSubject := claims()
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"HS512", sign(jwt.SigningMethodHS512, claims(), []byte("access-secret"))},
+		{"none", sign(jwt.SigningMethodNone, claims(), jwt.UnsafeAllowNoneSignatureType)},
+		{"other secret", sign(jwt.SigningMethodHS256, claims(), []byte("other-secret"))},
+		{"no expiry", sign(jwt.SigningMethodHS256, noExpiry, []byte("access-secret"))},
+		{"no subject", sign(jwt.SigningMethodHS256, noSubject, []byte("access-secret"))},
+		{"malformed", "not-a-token"},
+	}
+	for _, tt := range tests {
+		if _, err := m.ParseAccessToken(tt.value); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+	// The same claims signed the expected way are accepted
+	if _, err := m.ParseAccessToken(sign(jwt.SigningMethodHS256, claims(), []byte("access-secret"))); err != nil {
+		t.Errorf("HS256: %v", err)
+	}
+}